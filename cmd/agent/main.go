@@ -1,12 +1,14 @@
 package main
 
 import (
+	"cmp"
 	"context"
 	"flag"
 	"fmt"
-	"net"
 	"os"
 	"os/signal"
+	"path/filepath"
+	"strings"
 	"syscall"
 	"time"
 
@@ -36,9 +38,22 @@ var (
 	showVersion     = flag.Bool("version", false, "Show version information")
 	promURL         = flag.String("prometheus", "", "Prometheus URL")
 	tsdbConn        = flag.String("timescale", "", "TimescaleDB connection string")
+	tsdbRetention   = flag.Duration("metrics-retention", 0, "How long TimescaleDB keeps raw PVC samples before only hourly and daily aggregates remain (default 720h)")
+	historyPath     = flag.String("history-path", "", "Directory of the embedded PVC history store used without TimescaleDB (default /var/lib/cloudvault/history)")
 	neo4jURI        = flag.String("neo4j-uri", "", "Neo4j URI for Storage Intelligence Graph")
 	neo4jUser       = flag.String("neo4j-user", "neo4j", "Neo4j username")
 	neo4jPass       = flag.String("neo4j-password", "", "Neo4j password")
+	sigGCGens       = flag.Int("sig-gc-generations", 0, "Sync passes a PVC or pod may go unseen before it is removed from the SIG (default 3)")
+	execFallback    = flag.Bool("usage-exec-fallback", false, "Exec du inside pods when no other usage source reports a PVC")
+	disableEnrich   = flag.String("disable-enrichers", "", "Comma-separated collection enrichers to disable (pods,usage,iops,access,egress,volume,cost)")
+	accessState     = flag.String("access-state", "", "File that persists PVC last-access tracking when TimescaleDB is not used (default access.json in the history directory)")
+	egressSource    = flag.String("egress-source", "", "Egress data source: ebpf, cgroup, prometheus or merged")
+	ebpfVeth        = flag.Bool("ebpf-veth", false, "Also attach eBPF egress monitoring to veth devices")
+	ebpfSrcPorts    = flag.Bool("ebpf-src-ports", false, "Also record source ports in eBPF flows, bucketing ephemeral ports")
+	ebpfPinPath     = flag.String("ebpf-pin-path", "", "bpffs directory where eBPF maps are pinned to survive restarts (default /sys/fs/bpf/cloudvault)")
+	ebpfCleanup     = flag.Bool("ebpf-cleanup", false, "Remove the pinned eBPF maps and exit")
+	cgroupRoot      = flag.String("cgroup-root", "", "Mount point of the host cgroups, searched for the kubelet pod cgroups (default /sys/fs/cgroup)")
+	mountInfo       = flag.String("mountinfo", "", "Mount table used to map traced block devices to PVs (default /proc/self/mountinfo)")
 )
 
 func main() {
@@ -72,6 +87,12 @@ func main() {
 	if *tsdbConn != "" {
 		cfg.TimescaleConn = *tsdbConn
 	}
+	if *tsdbRetention > 0 {
+		cfg.MetricsRetention = *tsdbRetention
+	}
+	if *historyPath != "" {
+		cfg.HistoryPath = *historyPath
+	}
 	if *neo4jURI != "" {
 		cfg.Neo4jURI = *neo4jURI
 	}
@@ -81,6 +102,48 @@ func main() {
 	if *neo4jPass != "" {
 		cfg.Neo4jPassword = *neo4jPass
 	}
+	if *sigGCGens > 0 {
+		cfg.SIGGCGenerations = *sigGCGens
+	}
+	if *execFallback {
+		cfg.UsageExecFallback = true
+	}
+	if *disableEnrich != "" {
+		cfg.DisabledEnrichers = strings.Split(*disableEnrich, ",")
+	}
+	if *accessState != "" {
+		cfg.AccessStatePath = *accessState
+	}
+	if *egressSource != "" {
+		cfg.EgressSource = *egressSource
+	}
+	if *ebpfVeth {
+		cfg.EbpfAttachVeth = true
+	}
+	if *ebpfSrcPorts {
+		cfg.EbpfTrackSrcPorts = true
+	}
+	if *ebpfPinPath != "" {
+		cfg.EbpfPinPath = *ebpfPinPath
+	}
+	if cfg.EbpfPinPath == "" {
+		cfg.EbpfPinPath = ebpf.DefaultPinPath
+	}
+	if *cgroupRoot != "" {
+		cfg.CgroupRoot = *cgroupRoot
+	}
+	if *mountInfo != "" {
+		cfg.MountInfoPath = *mountInfo
+	}
+
+	if *ebpfCleanup {
+		if err := ebpf.Cleanup(cfg.EbpfPinPath); err != nil {
+			slog.Error("Failed to remove pinned eBPF maps", "error", err)
+			os.Exit(1)
+		}
+		fmt.Printf("Removed pinned eBPF maps from %s\n", cfg.EbpfPinPath)
+		os.Exit(0)
+	}
 
 	slog.Info("CloudVault Agent starting", "version", Version, "interval", cfg.Interval)
 
@@ -99,7 +162,7 @@ func main() {
 	}
 
 	if actualPromURL != "" {
-		promClient, err = integrations.NewPrometheusClient(actualPromURL)
+		promClient, err = integrations.NewPrometheusClientWithOptions(actualPromURL, integrations.PrometheusOptionsFromConfig(cfg))
 		if err != nil {
 			slog.Warn("Failed to create Prometheus client", "error", err)
 		} else {
@@ -120,49 +183,72 @@ func main() {
 		"region", clusterInfo.Region)
 
 	// Initialize Storage Intelligence Graph (SIG) - Phase 4 Pillar 1
-	var sig *graph.SIG
+	// Neo4j is optional; without it the graph is kept in memory
+	var sig graph.Graph = graph.NewMemoryGraph()
 	if cfg.Neo4jURI != "" && cfg.Neo4jPassword != "" {
-		sig, err = graph.NewSIG(cfg.Neo4jURI, cfg.Neo4jUser, cfg.Neo4jPassword)
+		neo4jSIG, err := graph.NewSIG(cfg.Neo4jURI, cfg.Neo4jUser, cfg.Neo4jPassword)
 		if err != nil {
-			slog.Warn("Failed to initialize Storage Intelligence Graph", "error", err)
+			slog.Warn("Failed to initialize Storage Intelligence Graph, keeping it in memory", "error", err)
 		} else {
 			slog.Info("Storage Intelligence Graph (Neo4j) enabled", "uri", cfg.Neo4jURI)
-			defer func() { _ = sig.Close(ctx) }()
+			sig = neo4jSIG
 		}
 	}
+	defer func() { _ = sig.Close(ctx) }()
 
 	// Initialize eBPF Agent (Functional kernel monitoring)
-	ebpfAgent, err := ebpf.NewAgent()
+	ebpfAgent, err := ebpf.NewAgentWithOptions(ebpf.Options{
+		TrackSourcePorts: cfg.EbpfTrackSrcPorts,
+		PinPath:          cfg.EbpfPinPath,
+	})
 	if err != nil {
 		slog.Warn("Failed to initialize eBPF agent (probably non-Linux or low privs)", "error", err)
 	} else {
 		slog.Info("eBPF kernel monitoring enabled")
 		defer func() { _ = ebpfAgent.Close() }()
-		// Attach to the first non-loopback UP interface
-		attached := false
-		if ifaces, ierr := net.Interfaces(); ierr == nil {
-			for _, iface := range ifaces {
-				if iface.Flags&net.FlagLoopback != 0 || iface.Flags&net.FlagUp == 0 {
-					continue
-				}
-				if _, aerr := ebpfAgent.AttachToInterface(iface.Name); aerr == nil {
-					slog.Info("eBPF attached to interface", "iface", iface.Name)
-					attached = true
-					break
-				} else {
-					slog.Debug("failed to attach eBPF to interface", "iface", iface.Name, "error", aerr)
-				}
-			}
-		}
-		if !attached {
-			slog.Warn("Could not attach eBPF to any interface")
+		// Attach to every physical interface and follow hotplug for the agent's lifetime
+		ifaces, aerr := ebpfAgent.AttachAll(ctx, ebpf.AttachOptions{Veth: cfg.EbpfAttachVeth})
+		if aerr != nil {
+			slog.Warn("Could not attach eBPF to interfaces", "error", aerr)
+		} else if len(ifaces) == 0 {
+			slog.Warn("No eligible interfaces for eBPF yet, waiting for hotplug")
+		} else {
+			slog.Info("eBPF attached to interfaces", "ifaces", ifaces, "veth", cfg.EbpfAttachVeth)
 		}
 	}
 
 	// Create PVC collector with integrated egress intelligence
-	ebpfProvider := collector.NewEbpfEgressProvider(ebpfAgent)
 	pvcCollector := collector.NewPVCCollector(client, promClient)
-	pvcCollector.SetEgressProvider(ebpfProvider)
+	var egressAgent collector.EgressStatsGetter = ebpfAgent
+	if cfg.EgressSource == "cgroup" {
+		egressAgent = newCgroupEgressAgent(ctx, cfg, ebpfAgent, client)
+	}
+	egressProvider := newEgressProvider(cfg.EgressSource, egressAgent, promClient, client)
+	pvcCollector.SetEgressProvider(egressProvider)
+	pvcCollector.SetExecFallback(cfg.UsageExecFallback)
+
+	// Weighted COMMUNICATES_WITH edges let the SIG find chatty cross-cloud and cross-region pairs
+	// Each agent of the DaemonSet syncs the pods on its own node, so traffic is recorded
+	// and decayed once however many agents share the graph
+	if ebpfAgent != nil || cfg.EgressSource == "prometheus" {
+		commSync := collector.NewCommunicationSync(client, egressProvider, sig)
+		commSync.SetNode(os.Getenv("NODE_NAME"))
+		go commSync.Run(ctx, cfg.Interval)
+	}
+
+	// Block I/O tracing gives IOPS and last access for PVs on this node without Prometheus
+	ioAgent, err := ebpf.NewIOAgentWithOptions(ebpf.Options{PinPath: cfg.EbpfPinPath})
+	if err != nil {
+		slog.Warn("Failed to initialize eBPF block I/O tracing", "error", err)
+	} else {
+		slog.Info("eBPF block I/O tracing enabled")
+		defer func() { _ = ioAgent.Close() }()
+		pvcCollector.SetBlockIOSource(collector.NewBlockIOSource(ioAgent, cfg.MountInfoPath))
+	}
+	if err := pvcCollector.DisableEnrichers(cfg.DisabledEnrichers...); err != nil {
+		slog.Error("Invalid disabled enrichers", "enrichers", cfg.DisabledEnrichers, "error", err)
+		os.Exit(1)
+	}
 
 	// Phase 10: Initialize Multi-Cloud Pricing (Revolutionary - ZERO simulations)
 	awsClient := integrations.NewAWSClient(cfg)
@@ -170,6 +256,7 @@ func main() {
 	azureClient := integrations.NewAzureClient(cfg)
 	pricingProvider := cost.NewMultiCloudPricingProvider(awsClient, gcpClient, azureClient)
 	calculator := cost.NewCalculatorWithProvider(pricingProvider)
+	pvcCollector.SetCalculator(calculator)
 
 	// Create TimescaleDB client (Phase 22: Metrics Persistence)
 	var tsdb *graph.TimescaleDB
@@ -180,13 +267,54 @@ func main() {
 		} else {
 			slog.Info("TimescaleDB persistence enabled")
 			defer func() { _ = tsdb.Close() }()
+			retention := cmp.Or(cfg.MetricsRetention, graph.DefaultMetricsRetention)
+			if err := tsdb.SetRetention(ctx, retention); err != nil {
+				slog.Warn("Failed to set TimescaleDB retention policy", "retention", retention, "error", err)
+			}
+		}
+	}
+
+	// Without TimescaleDB, history and access state are kept on a hostPath or PVC. Agents
+	// sharing a PVC each keep their own, in a directory named after their node.
+	stateDir := cmp.Or(cfg.HistoryPath, graph.DefaultHistoryPath)
+	if node := os.Getenv("NODE_NAME"); node != "" {
+		stateDir = filepath.Join(stateDir, node)
+	}
+
+	// Per-PVC history feeds forecasting and recommendations
+	var history graph.HistoryStore
+	if tsdb != nil {
+		history = tsdb
+	} else {
+		store, err := graph.NewFileHistoryStore(stateDir)
+		if err != nil {
+			slog.Warn("PVC history is disabled", "path", stateDir, "error", err)
+		} else {
+			slog.Info("Embedded PVC history store enabled", "path", stateDir)
+			defer func() { _ = store.Close() }()
+			history = store
 		}
 	}
 
+	// Last-access tracking must survive restarts, otherwise every restart resets idle time
+	if tsdb != nil {
+		pvcCollector.SetAccessStore(tsdb)
+	} else {
+		path := cmp.Or(cfg.AccessStatePath, filepath.Join(stateDir, "access.json"))
+		slog.Info("PVC access tracking persisted to file", "path", path)
+		pvcCollector.SetAccessStore(collector.NewFileAccessStore(path))
+	}
+
 	// Phase 12: Initialize Autonomous Lifecycle Controller with SIG integration
-	recommender := lifecycle.NewIntelligentRecommender(tsdb)
-	lc := lifecycle.NewLifecycleController(cfg.Interval, client, recommender, sig, tsdb)
+	recommender := lifecycle.NewIntelligentRecommender(history)
+	lc := lifecycle.NewLifecycleController(cfg.Interval, client, recommender, sig, history)
 	lc.SetPVCCollector(pvcCollector)
+	lc.SetGCGenerations(cfg.SIGGCGenerations)
+	if err := lc.WatchDeletions(); err != nil {
+		slog.Warn("Deleted pods and PVCs will leave the SIG only when swept", "error", err)
+	}
+	// Started once every handler is added; the cgroup egress provider reads the pod cache too
+	go client.StartInformers(ctx)
 
 	// Initial policy fetch
 	policies, err := client.ListStoragePolicies(ctx)
@@ -209,6 +337,8 @@ func main() {
 
 	// Start Integrated Dashboard Server (Phase 4 Pillar 4)
 	dashServer := dashboard.NewServer(client, promClient, clusterInfo.Provider, false, ebpfAgent)
+	dashServer.SetGraph(sig)
+	dashServer.SetPVCCollector(pvcCollector)
 	go func() {
 		if err := dashServer.Start(8080); err != nil {
 			slog.Error("Dashboard server failed", "error", err)
@@ -223,15 +353,10 @@ func main() {
 	ticker := time.NewTicker(cfg.Interval)
 	defer ticker.Stop()
 
-	// Collect immediately on startup
+	// Collect immediately on startup. History is recorded by the lifecycle controller.
 	slog.Info("Starting metrics collection loop")
 	metrics := collectAndDisplay(ctx, pvcCollector, cfg.Namespace, calculator, clusterInfo.Provider)
-	if tsdb != nil && len(metrics) > 0 {
-		if err := tsdb.RecordMetrics(ctx, metrics); err != nil {
-			slog.Error("Failed to record metrics to TSDB", "error", err)
-		}
-	}
-	if sig != nil && len(metrics) > 0 {
+	if len(metrics) > 0 {
 		if err := sig.SyncPVCs(ctx, metrics); err != nil {
 			slog.Error("Failed to sync PVCs to SIG", "error", err)
 		}
@@ -242,12 +367,7 @@ func main() {
 		select {
 		case <-ticker.C:
 			metrics := collectAndDisplay(ctx, pvcCollector, cfg.Namespace, calculator, clusterInfo.Provider)
-			if tsdb != nil && len(metrics) > 0 {
-				if err := tsdb.RecordMetrics(ctx, metrics); err != nil {
-					slog.Error("Failed to record metrics to TSDB", "error", err)
-				}
-			}
-			if sig != nil && len(metrics) > 0 {
+			if len(metrics) > 0 {
 				if err := sig.SyncPVCs(ctx, metrics); err != nil {
 					slog.Error("Failed to sync PVCs to SIG", "error", err)
 				}
@@ -354,3 +474,49 @@ func collectAndDisplay(ctx context.Context, collector *collector.PVCCollector, n
 
 	return metrics
 }
+
+// newEgressProvider selects the egress source. The eBPF agent only sees traffic on the
+// node it runs on; Prometheus covers every pod but cannot always tell destinations apart.
+func newEgressProvider(source string, agent collector.EgressStatsGetter, promClient *integrations.PrometheusClient, client *collector.KubernetesClient) collector.EgressProvider {
+	ebpfProvider := collector.NewEbpfEgressProvider(agent)
+	promProvider := collector.NewPrometheusEgressProvider(promClient)
+
+	switch source {
+	case "prometheus":
+		slog.Info("Egress source: Prometheus")
+		return promProvider
+	case "merged":
+		slog.Info("Egress source: eBPF merged with Prometheus")
+		merged := collector.NewMergedEgressProvider(ebpfProvider, promProvider)
+		merged.SetPodSource(client)
+		return merged
+	case "cgroup":
+		slog.Info("Egress source: eBPF attributed by pod cgroup")
+		return ebpfProvider
+	case "", "ebpf":
+		return ebpfProvider
+	default:
+		slog.Warn("Unknown egress source, using eBPF", "source", source)
+		return ebpfProvider
+	}
+}
+
+// newCgroupEgressAgent attaches per-cgroup egress accounting to the kubelet's pod cgroups
+// and resolves it to pods through the informer cache, which main starts. It falls back to
+// attribution by source address when that is not possible.
+func newCgroupEgressAgent(ctx context.Context, cfg *types.Config, agent *ebpf.Agent, client *collector.KubernetesClient) collector.EgressStatsGetter {
+	if agent == nil {
+		slog.Warn("Cgroup egress attribution needs the eBPF agent, attributing by source address")
+		return agent
+	}
+	dir, err := collector.FindKubepodsCgroup(cfg.CgroupRoot)
+	if err == nil {
+		err = agent.AttachCgroup(dir)
+	}
+	if err != nil {
+		slog.Warn("Cgroup egress attribution unavailable, attributing by source address", "error", err)
+		return agent
+	}
+	slog.Info("eBPF attached to pod cgroups", "cgroup", dir)
+	return collector.NewCgroupEgressProvider(agent, collector.NewPodCgroupResolver(dir), client)
+}
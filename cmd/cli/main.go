@@ -4,14 +4,15 @@ import (
 	"context"
 	"flag"
 	"fmt"
-	"net"
 	"os"
+	"strings"
 	"text/tabwriter"
 
 	"github.com/cloudvault-io/cloudvault/pkg/collector"
 	"github.com/cloudvault-io/cloudvault/pkg/cost"
 	"github.com/cloudvault-io/cloudvault/pkg/dashboard"
 	"github.com/cloudvault-io/cloudvault/pkg/ebpf"
+	"github.com/cloudvault-io/cloudvault/pkg/graph"
 	"github.com/cloudvault-io/cloudvault/pkg/integrations"
 	"github.com/cloudvault-io/cloudvault/pkg/types"
 )
@@ -41,12 +42,14 @@ func main() {
 		kubeconfig := costCmd.String("kubeconfig", "", "Path to kubeconfig file")
 		namespace := costCmd.String("namespace", "", "Filter by namespace")
 		promURL := costCmd.String("prometheus", "", "Prometheus URL (e.g., http://localhost:9090)")
+		snapshot := costCmd.String("snapshot", "", "Analyze a snapshot file instead of a live cluster")
+		access := addAccessFlags(costCmd)
 
 		if err := costCmd.Parse(os.Args[2:]); err != nil {
 			fmt.Println("Error parsing flags:", err)
 			os.Exit(1)
 		}
-		handleCostCommand(*kubeconfig, *namespace, *promURL)
+		handleCostCommand(*kubeconfig, *namespace, *promURL, *snapshot, access.store())
 
 	case "recommendations", "rec", "recs", "storage":
 		recCmd := flag.NewFlagSet("recommendations", flag.ExitOnError)
@@ -54,12 +57,48 @@ func main() {
 		kubeconfig := recCmd.String("kubeconfig", "", "Path to kubeconfig file")
 		namespace := recCmd.String("namespace", "", "Filter by namespace")
 		promURL := recCmd.String("prometheus", "", "Prometheus URL (e.g., http://localhost:9090)")
+		snapshot := recCmd.String("snapshot", "", "Analyze a snapshot file instead of a live cluster")
+		access := addAccessFlags(recCmd)
 
 		if err := recCmd.Parse(os.Args[2:]); err != nil {
 			fmt.Println("Error parsing flags:", err)
 			os.Exit(1)
 		}
-		handleRecommendationsCommand(*kubeconfig, *namespace, *promURL)
+		handleRecommendationsCommand(*kubeconfig, *namespace, *promURL, *snapshot, access.store())
+
+	case "collect", "snapshot":
+		collectCmd := flag.NewFlagSet("collect", flag.ExitOnError)
+		kubeconfig := collectCmd.String("kubeconfig", "", "Path to kubeconfig file")
+		namespace := collectCmd.String("namespace", "", "Filter by namespace")
+		promURL := collectCmd.String("prometheus", "", "Prometheus URL (e.g., http://localhost:9090)")
+		output := collectCmd.String("output", "cloudvault-snapshot.json", "File to write the snapshot to")
+		access := addAccessFlags(collectCmd)
+
+		if err := collectCmd.Parse(os.Args[2:]); err != nil {
+			fmt.Println("Error parsing flags:", err)
+			os.Exit(1)
+		}
+		handleCollectCommand(*kubeconfig, *namespace, *promURL, *output, access.store())
+
+	case "deps", "dependencies":
+		depsCmd := flag.NewFlagSet("deps", flag.ExitOnError)
+		kubeconfig := depsCmd.String("kubeconfig", "", "Path to kubeconfig file")
+		promURL := depsCmd.String("prometheus", "", "Prometheus URL (e.g., http://localhost:9090)")
+		neo4jURI := depsCmd.String("neo4j-uri", "", "Query the agent's Storage Intelligence Graph in Neo4j instead of the live cluster")
+		neo4jUser := depsCmd.String("neo4j-user", "neo4j", "Neo4j username")
+		neo4jPass := depsCmd.String("neo4j-password", "", "Neo4j password")
+		depth := depsCmd.Int("depth", graph.DefaultDependencyDepth, "Hops of pod-to-pod traffic to follow")
+
+		if err := depsCmd.Parse(os.Args[2:]); err != nil {
+			fmt.Println("Error parsing flags:", err)
+			os.Exit(1)
+		}
+		namespace, pvc, ok := strings.Cut(depsCmd.Arg(0), "/")
+		if !ok || namespace == "" || pvc == "" || depsCmd.NArg() != 1 {
+			fmt.Println("Usage: cloudvault deps [flags] <namespace>/<pvc>")
+			os.Exit(1)
+		}
+		handleDepsCommand(*kubeconfig, *promURL, *neo4jURI, *neo4jUser, *neo4jPass, namespace, pvc, *depth)
 
 	case "dashboard", "dash", "ui":
 		dashCmd := flag.NewFlagSet("dashboard", flag.ExitOnError)
@@ -67,12 +106,14 @@ func main() {
 		promURL := dashCmd.String("prometheus", "", "Prometheus URL (e.g., http://localhost:9090)")
 		port := dashCmd.Int("port", 8080, "Port to run the dashboard on")
 		mock := dashCmd.Bool("mock", false, "Run in mock mode with synthetic data")
+		snapshot := dashCmd.String("snapshot", "", "Serve a snapshot file instead of a live cluster")
+		access := addAccessFlags(dashCmd)
 
 		if err := dashCmd.Parse(os.Args[2:]); err != nil {
 			fmt.Println("Error parsing flags:", err)
 			os.Exit(1)
 		}
-		handleDashboardCommand(*kubeconfig, promURL, port, mock)
+		handleDashboardCommand(*kubeconfig, promURL, port, mock, *snapshot, access.store())
 
 	case "version":
 		handleVersionCommand()
@@ -97,6 +138,8 @@ func printUsage() {
 	fmt.Println("  cost              Show storage costs")
 	fmt.Println("  cost              Show storage costs")
 	fmt.Println("  recommendations   Show optimization recommendations")
+	fmt.Println("  collect           Save a cluster snapshot for offline analysis")
+	fmt.Println("  deps              Show what depends on a PVC")
 	fmt.Println("  dashboard         Start the web dashboard")
 	fmt.Println("  version           Show version information")
 	fmt.Println("  help              Show this help message")
@@ -104,6 +147,12 @@ func printUsage() {
 	fmt.Println("Flags:")
 	fmt.Println("  --kubeconfig      Path to kubeconfig file")
 	fmt.Println("  --namespace       Filter by namespace")
+	fmt.Println("  --snapshot        Read a snapshot file instead of a live cluster")
+	fmt.Println("  --output          Snapshot file written by collect")
+	fmt.Println("  --depth           Hops of pod-to-pod traffic followed by deps")
+	fmt.Println("  --neo4j-uri       Storage Intelligence Graph queried by deps")
+	fmt.Println("  --timescale       TimescaleDB the agent tracks PVC last access in")
+	fmt.Println("  --access-state    Access state file of an agent without TimescaleDB")
 	fmt.Println()
 	fmt.Println("Examples:")
 	fmt.Println("  cloudvault cost")
@@ -111,7 +160,12 @@ func printUsage() {
 	fmt.Println("  cloudvault recommendations")
 	fmt.Println("  cloudvault recommendations")
 	fmt.Println("  cloudvault recommendations --kubeconfig ~/.kube/config")
+	fmt.Println("  cloudvault collect --output snapshot.json")
+	fmt.Println("  cloudvault cost --snapshot snapshot.json")
+	fmt.Println("  cloudvault deps production/postgres-data")
+	fmt.Println("  cloudvault deps --neo4j-uri bolt://localhost:7687 --neo4j-password secret --depth 2 production/postgres-data")
 	fmt.Println("  cloudvault dashboard")
+	fmt.Println("  cloudvault dashboard --snapshot snapshot.json")
 }
 
 func handleVersionCommand() {
@@ -120,60 +174,19 @@ func handleVersionCommand() {
 	fmt.Printf("Built:  %s\n", BuildDate)
 }
 
-func handleCostCommand(kubeconfig, namespace string, promURL string) {
+func handleCostCommand(kubeconfig, namespace, promURL, snapshotPath string, access collector.AccessStore) {
 	ctx := context.Background()
 
-	var clusterInfo *types.ClusterInfo
-	var client *collector.KubernetesClient
-	var promClient *integrations.PrometheusClient
-	var err error
-
-	if promURL != "" {
-		promClient, err = integrations.NewPrometheusClient(promURL)
-		if err != nil {
-			fmt.Fprintf(os.Stderr, "⚠️  Warning: Failed to create Prometheus client: %v\n", err)
-		} else {
-			fmt.Println("🔌 Prometheus integration enabled")
-		}
-	}
-
-	// Create Kubernetes client
-	client, err = collector.NewKubernetesClient(kubeconfig)
-	if err != nil {
-		fmt.Fprintf(os.Stderr, "❌ Error: %v\n", err)
-		os.Exit(1)
-	}
-
-	// Get cluster info
-	clusterInfo, err = client.GetClusterInfo(ctx)
-	if err != nil {
-		fmt.Fprintf(os.Stderr, "❌ Error: %v\n", err)
-		os.Exit(1)
-	}
+	src := openSource(ctx, kubeconfig, promURL, snapshotPath, access)
+	clusterInfo := src.cluster
 
 	fmt.Printf("📊 Cluster: %s (%s %s)\n\n", clusterInfo.Name, clusterInfo.Provider, clusterInfo.Region)
 
-	// Collect PVC metrics
-	var metrics []types.PVCMetric
-
-	pvcCollector := collector.NewPVCCollector(client, promClient)
-	if namespace != "" {
-		metrics, err = pvcCollector.CollectByNamespace(ctx, namespace)
-		if err != nil {
-			fmt.Fprintf(os.Stderr, "❌ Error: %v\n", err)
-			os.Exit(1)
-		}
-		fmt.Printf("📁 Namespace: %s\n\n", namespace)
-	} else {
-		metrics, err = pvcCollector.CollectAll(ctx)
-		if err != nil {
-			fmt.Fprintf(os.Stderr, "❌ Error: %v\n", err)
-			os.Exit(1)
-		}
-	}
+	metrics := src.collectPVCs(ctx, namespace)
 
 	if len(metrics) == 0 {
 		fmt.Println("ℹ️  No PVCs found")
+		printEphemeralCosts(src.ephemeral(ctx, namespace), clusterInfo.Provider)
 		return
 	}
 
@@ -258,66 +271,136 @@ func handleCostCommand(kubeconfig, namespace string, promURL string) {
 	fmt.Printf("   Total PVCs: %d\n", len(metrics))
 	fmt.Printf("   Total Size: %.2f GB\n", totalSizeGB)
 	fmt.Printf("   Average Cost per GB: $%.4f/month\n", summary.TotalMonthlyCost/totalSizeGB)
+
+	printEphemeralCosts(src.ephemeral(ctx, namespace), clusterInfo.Provider)
 }
 
-func handleRecommendationsCommand(kubeconfig, namespace string, promURL string) {
+// printEphemeralCosts shows node root-disk cost attributed to pods, separately from PVC spend
+func printEphemeralCosts(metrics []types.EphemeralStorageMetric, provider string) {
+	if len(metrics) == 0 {
+		return
+	}
+	summary := cost.NewCalculator().GenerateEphemeralSummary(metrics, provider)
+
+	fmt.Printf("\n🧺 Ephemeral Storage (emptyDir, container layers; node root disks): %s (%.2f GB used)\n",
+		cost.FormatCostPerMonth(summary.TotalMonthlyCost),
+		float64(summary.TotalUsedBytes)/(1024*1024*1024))
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 3, ' ', 0)
+	_, _ = fmt.Fprintln(w, "NAMESPACE\tPOD\tNODE\tUSED\tREQUEST\tMONTHLY")
+	for _, m := range summary.TopPods {
+		_, _ = fmt.Fprintf(w, "%s\t%s\t%s\t%.2fGB\t%.2fGB\t%s\n",
+			m.Namespace,
+			m.Pod,
+			m.Node,
+			float64(m.UsedBytes)/(1024*1024*1024),
+			float64(m.RequestBytes)/(1024*1024*1024),
+			cost.FormatCost(m.MonthlyCost))
+	}
+	_ = w.Flush()
+}
+
+func handleCollectCommand(kubeconfig, namespace, promURL, output string, access collector.AccessStore) {
 	ctx := context.Background()
 
-	var clusterInfo *types.ClusterInfo
-	var client *collector.KubernetesClient
-	var promClient *integrations.PrometheusClient
-	var err error
+	src := openSource(ctx, kubeconfig, promURL, "", access)
+	fmt.Printf("📊 Cluster: %s (%s %s)\n\n", src.cluster.Name, src.cluster.Provider, src.cluster.Region)
 
-	if promURL != "" {
-		promClient, err = integrations.NewPrometheusClient(promURL)
-		if err != nil {
-			fmt.Fprintf(os.Stderr, "⚠️  Warning: Failed to create Prometheus client: %v\n", err)
-		} else {
-			fmt.Println("🔌 Prometheus integration enabled")
-		}
+	scope := collector.Scope{}
+	if namespace != "" {
+		scope.Namespaces = []string{namespace}
 	}
-
-	// Create Kubernetes client
-	client, err = collector.NewKubernetesClient(kubeconfig)
+	snap, err := src.live.Snapshot(ctx, scope)
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "❌ Error: %v\n", err)
 		os.Exit(1)
 	}
-
-	// Get cluster info
-	clusterInfo, err = client.GetClusterInfo(ctx)
-	if err != nil {
+	if err := collector.WriteSnapshot(output, snap); err != nil {
 		fmt.Fprintf(os.Stderr, "❌ Error: %v\n", err)
 		os.Exit(1)
 	}
 
-	fmt.Printf("📊 Cluster: %s (%s %s)\n\n", clusterInfo.Name, clusterInfo.Provider, clusterInfo.Region)
+	fmt.Printf("📦 Snapshot written to %s\n", output)
+	fmt.Printf("   PVCs: %d | PVs: %d | StorageClasses: %d | Pod mounts: %d | Ephemeral pods: %d\n",
+		len(snap.PVCs), len(snap.PVs), len(snap.StorageClasses), len(snap.PodMounts), len(snap.Ephemeral))
+	fmt.Printf("   Analyze offline with: cloudvault cost --snapshot %s\n", output)
+}
 
-	// Collect metrics
-	var metrics []types.PVCMetric
+func handleDepsCommand(kubeconfig, promURL, neo4jURI, neo4jUser, neo4jPass, namespace, pvc string, depth int) {
+	ctx := context.Background()
 
-	// Using real collector
-	pvcCollector := collector.NewPVCCollector(client, promClient)
-	if namespace != "" {
-		metrics, err = pvcCollector.CollectByNamespace(ctx, namespace)
+	var sig graph.Graph
+	if neo4jURI != "" {
+		neo4jSIG, err := graph.NewSIG(neo4jURI, neo4jUser, neo4jPass)
 		if err != nil {
 			fmt.Fprintf(os.Stderr, "❌ Error: %v\n", err)
 			os.Exit(1)
 		}
-		fmt.Printf("📁 Namespace: %s\n\n", namespace)
+		sig = neo4jSIG
 	} else {
-		metrics, err = pvcCollector.CollectAll(ctx)
+		// Without the agent's graph, build one from the cluster. It knows which pods use
+		// the PVC and what owns and selects them, but not the traffic between pods.
+		src := openSource(ctx, kubeconfig, promURL, "", nil)
+		fmt.Printf("📊 Cluster: %s (%s %s)\n", src.cluster.Name, src.cluster.Provider, src.cluster.Region)
+		fmt.Println("ℹ️  Traffic between pods is only known to the agent's graph; use --neo4j-uri to include it")
+		fmt.Println()
+
+		placements, err := src.client.ListPodPlacements(ctx)
 		if err != nil {
 			fmt.Fprintf(os.Stderr, "❌ Error: %v\n", err)
 			os.Exit(1)
 		}
+		sig = graph.NewMemoryGraph()
+		if err := sig.SyncPVCs(ctx, src.collectPVCs(ctx, namespace)); err != nil {
+			fmt.Fprintf(os.Stderr, "❌ Error: %v\n", err)
+			os.Exit(1)
+		}
+		if err := sig.SyncPods(ctx, placements); err != nil {
+			fmt.Fprintf(os.Stderr, "❌ Error: %v\n", err)
+			os.Exit(1)
+		}
 	}
+	defer func() { _ = sig.Close(ctx) }()
 
+	deps, err := sig.GetDependencies(ctx, namespace, pvc, depth)
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "❌ Error: %v\n", err)
+		fmt.Fprintf(os.Stderr, "❌ Error: %s/%s: %v\n", namespace, pvc, err)
 		os.Exit(1)
 	}
 
+	fmt.Printf("🔗 Dependencies of %s/%s (depth %d)\n\n", deps.Namespace, deps.PVC, deps.Depth)
+	if len(deps.Pods) == 0 {
+		fmt.Println("✅ No pods use this PVC")
+		return
+	}
+	fmt.Printf("   Pods:     %s\n", strings.Join(deps.Pods, ", "))
+	if len(deps.Services) > 0 {
+		fmt.Printf("   Services: %s\n", strings.Join(deps.Services, ", "))
+	}
+	fmt.Println()
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 3, ' ', 0)
+	_, _ = fmt.Fprintln(w, "NAMESPACE\tKIND\tNAME\tDEPENDS")
+	for _, wl := range deps.Workloads {
+		how := "uses the PVC"
+		if wl.Distance > 0 {
+			how = fmt.Sprintf("traffic, %d hop(s)", wl.Distance)
+		}
+		_, _ = fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", wl.Namespace, wl.Kind, wl.Name, how)
+	}
+	_ = w.Flush()
+}
+
+func handleRecommendationsCommand(kubeconfig, namespace, promURL, snapshotPath string, access collector.AccessStore) {
+	ctx := context.Background()
+
+	src := openSource(ctx, kubeconfig, promURL, snapshotPath, access)
+	clusterInfo := src.cluster
+
+	fmt.Printf("📊 Cluster: %s (%s %s)\n\n", clusterInfo.Name, clusterInfo.Provider, clusterInfo.Region)
+
+	metrics := src.collectPVCs(ctx, namespace)
+
 	if len(metrics) == 0 {
 		fmt.Println("ℹ️  No PVCs found")
 		return
@@ -333,6 +416,12 @@ func handleRecommendationsCommand(kubeconfig, namespace string, promURL string)
 	optimizer := cost.NewOptimizer()
 	recommendations := optimizer.GenerateRecommendations(metrics, clusterInfo.Provider)
 
+	// Pods whose ephemeral storage is far from their requests
+	if ephemeral := src.ephemeral(ctx, namespace); len(ephemeral) > 0 {
+		calculator.AttributeEphemeralCost(ephemeral, clusterInfo.Provider)
+		recommendations = append(recommendations, optimizer.GenerateEphemeralRecommendations(ephemeral)...)
+	}
+
 	if len(recommendations) == 0 {
 		fmt.Println("✅ No optimization opportunities found!")
 		fmt.Println("   Your storage is well-optimized. 🎉")
@@ -358,6 +447,7 @@ func handleRecommendationsCommand(kubeconfig, namespace string, promURL string)
 	zombieRecs := optimizer.FilterByType(recommendations, "delete_zombie")
 	resizeRecs := optimizer.FilterByType(recommendations, "resize")
 	moveCloudRecs := optimizer.FilterByType(recommendations, "move_cloud")
+	ephemeralRecs := optimizer.FilterByType(recommendations, "ephemeral_request")
 
 	if len(storageClassRecs) > 0 {
 		savings := 0.0
@@ -391,6 +481,14 @@ func handleRecommendationsCommand(kubeconfig, namespace string, promURL string)
 		fmt.Printf("   Cross-Cloud Migration: %d (%s/month)\n", len(moveCloudRecs), cost.FormatCost(savings))
 	}
 
+	if len(ephemeralRecs) > 0 {
+		savings := 0.0
+		for _, r := range ephemeralRecs {
+			savings += r.MonthlySavings
+		}
+		fmt.Printf("   Ephemeral Storage Requests: %d (%s/month)\n", len(ephemeralRecs), cost.FormatCost(savings))
+	}
+
 	// Show quick wins
 	quickWins := optimizer.GetQuickWins(recommendations)
 	if len(quickWins) > 0 {
@@ -410,6 +508,8 @@ func displayRecommendation(num int, rec types.Recommendation) {
 		emoji = "📏"
 	case "move_cloud":
 		emoji = "🌐"
+	case "ephemeral_request":
+		emoji = "🧺"
 	}
 
 	// Determine impact indicator
@@ -424,7 +524,11 @@ func displayRecommendation(num int, rec types.Recommendation) {
 	}
 
 	fmt.Printf("%s %d. %s\n", emoji, num, rec.Reasoning)
-	fmt.Printf("   PVC: %s/%s\n", rec.Namespace, rec.PVC)
+	if rec.Pod != "" {
+		fmt.Printf("   Pod: %s/%s\n", rec.Namespace, rec.Pod)
+	} else {
+		fmt.Printf("   PVC: %s/%s\n", rec.Namespace, rec.PVC)
+	}
 	fmt.Printf("   Current: %s → Recommended: %s\n", rec.CurrentState, rec.RecommendedState)
 	fmt.Printf("   Savings: %s/month (%s/year) | %s\n",
 		cost.FormatCost(rec.MonthlySavings),
@@ -433,9 +537,14 @@ func displayRecommendation(num int, rec types.Recommendation) {
 	fmt.Println()
 }
 
-func handleDashboardCommand(kubeconfig string, promURL *string, port *int, mock *bool) {
+func handleDashboardCommand(kubeconfig string, promURL *string, port *int, mock *bool, snapshotPath string, access collector.AccessStore) {
 	ctx := context.Background()
 
+	if snapshotPath != "" {
+		serveSnapshot(snapshotPath, port)
+		return
+	}
+
 	var clusterInfo *types.ClusterInfo
 	var client *collector.KubernetesClient
 	var promClient *integrations.PrometheusClient
@@ -492,24 +601,11 @@ func handleDashboardCommand(kubeconfig string, promURL *string, port *int, mock
 			fmt.Fprintf(os.Stderr, "⚠️  Warning: Failed to initialize eBPF agent: %v\n", err)
 		} else {
 			fmt.Println("🧬 eBPF kernel monitoring enabled")
-			// Auto-detect network interfaces and attach to the first non-loopback one
-			attached := false
-			if ifaces, ierr := net.Interfaces(); ierr == nil {
-				for _, iface := range ifaces {
-					if iface.Flags&net.FlagLoopback != 0 || iface.Flags&net.FlagUp == 0 {
-						continue
-					}
-					if _, aerr := ebpfAgent.AttachToInterface(iface.Name); aerr == nil {
-						fmt.Printf("🔗 eBPF attached to interface: %s\n", iface.Name)
-						attached = true
-						break
-					} else {
-						fmt.Printf("debug: failed to attach to %s: %v\n", iface.Name, aerr)
-					}
-				}
-			}
-			if !attached {
-				fmt.Fprintf(os.Stderr, "⚠️  Warning: Could not attach eBPF to any interface\n")
+			// Attach to every physical interface and follow hotplug while serving
+			if ifaces, aerr := ebpfAgent.AttachAll(ctx, ebpf.AttachOptions{}); aerr != nil {
+				fmt.Fprintf(os.Stderr, "⚠️  Warning: Could not attach eBPF to interfaces: %v\n", aerr)
+			} else {
+				fmt.Printf("🔗 eBPF attached to interfaces: %s\n", strings.Join(ifaces, ", "))
 			}
 		}
 	}
@@ -525,6 +621,39 @@ func handleDashboardCommand(kubeconfig string, promURL *string, port *int, mock
 	}
 
 	server := dashboard.NewServer(client, promClient, provider, isMock, ebpfAgent)
+	if access != nil && client != nil {
+		// Report the access state the agent tracks rather than tracking it from scratch
+		live := collector.NewPVCCollector(client, promClient)
+		live.SetAccessReader(access)
+		server.SetPVCCollector(live)
+	}
+	if err := server.Start(actualPort); err != nil {
+		fmt.Fprintf(os.Stderr, "❌ Dashboard server error: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+// serveSnapshot runs the dashboard over a snapshot file, without cluster, Prometheus or eBPF access
+func serveSnapshot(path string, port *int) {
+	snap, err := collector.LoadSnapshot(path)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "❌ Error: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Printf("📦 Serving snapshot of %s (%s) taken %s\n",
+		snap.Cluster.Name, snap.Cluster.Provider, snap.CreatedAt.Local().Format("2006-01-02 15:04 MST"))
+
+	provider := snap.Cluster.Provider
+	if provider == "" {
+		provider = "aws" // Default fallback
+	}
+	actualPort := 8080
+	if port != nil {
+		actualPort = *port
+	}
+
+	server := dashboard.NewServer(nil, nil, provider, false, nil)
+	server.SetSnapshot(collector.NewSnapshotCollector(snap))
 	if err := server.Start(actualPort); err != nil {
 		fmt.Fprintf(os.Stderr, "❌ Dashboard server error: %v\n", err)
 		os.Exit(1)
@@ -0,0 +1,138 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/cloudvault-io/cloudvault/pkg/collector"
+	"github.com/cloudvault-io/cloudvault/pkg/graph"
+	"github.com/cloudvault-io/cloudvault/pkg/integrations"
+	"github.com/cloudvault-io/cloudvault/pkg/types"
+)
+
+// source is where a command reads cluster data from: a live cluster or a snapshot file
+type source struct {
+	cluster   *types.ClusterInfo
+	collector collector.Collector
+
+	client   *collector.KubernetesClient  // nil when reading a snapshot
+	live     *collector.PVCCollector      // nil when reading a snapshot
+	snapshot *collector.SnapshotCollector // nil when reading a live cluster
+}
+
+// accessFlags locate the access state the agent persists. Last access is only known from
+// state tracked across cycles, so without it a one-off run leaves it unknown.
+type accessFlags struct {
+	timescale *string
+	path      *string
+}
+
+func addAccessFlags(fs *flag.FlagSet) accessFlags {
+	return accessFlags{
+		timescale: fs.String("timescale", "", "TimescaleDB the agent tracks PVC access in"),
+		path:      fs.String("access-state", "", "Access state file of an agent running without TimescaleDB"),
+	}
+}
+
+// store opens the agent's access state, or returns nil when neither flag is set.
+// Errors are fatal.
+func (f accessFlags) store() collector.AccessStore {
+	switch {
+	case *f.timescale != "":
+		tsdb, err := graph.NewTimescaleDB(*f.timescale)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "❌ Error: %v\n", err)
+			os.Exit(1)
+		}
+		return tsdb
+	case *f.path != "":
+		return collector.NewFileAccessStore(*f.path)
+	default:
+		return nil
+	}
+}
+
+// openSource loads the snapshot at snapshotPath, or connects to the cluster when it is empty.
+// Live collections report last access from access when it is set. Errors are fatal.
+func openSource(ctx context.Context, kubeconfig, promURL, snapshotPath string, access collector.AccessStore) *source {
+	if snapshotPath != "" {
+		snap, err := collector.LoadSnapshot(snapshotPath)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "❌ Error: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Printf("📦 Snapshot taken %s\n", snap.CreatedAt.Local().Format("2006-01-02 15:04 MST"))
+		sc := collector.NewSnapshotCollector(snap)
+		return &source{cluster: sc.ClusterInfo(), collector: sc, snapshot: sc}
+	}
+
+	var promClient *integrations.PrometheusClient
+	if promURL != "" {
+		var err error
+		promClient, err = integrations.NewPrometheusClient(promURL)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "⚠️  Warning: Failed to create Prometheus client: %v\n", err)
+		} else {
+			fmt.Println("🔌 Prometheus integration enabled")
+		}
+	}
+
+	// Create Kubernetes client
+	client, err := collector.NewKubernetesClient(kubeconfig)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "❌ Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	// Get cluster info
+	clusterInfo, err := client.GetClusterInfo(ctx)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "❌ Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	live := collector.NewPVCCollector(client, promClient)
+	if access != nil {
+		live.SetAccessReader(access)
+	}
+	return &source{cluster: clusterInfo, collector: live, client: client, live: live}
+}
+
+// collectPVCs returns PVC metrics for one namespace, or all when namespace is empty.
+// Errors are fatal.
+func (s *source) collectPVCs(ctx context.Context, namespace string) []types.PVCMetric {
+	var metrics []types.PVCMetric
+	var err error
+	if namespace != "" {
+		metrics, err = s.collector.CollectByNamespace(ctx, namespace)
+		if err == nil {
+			fmt.Printf("📁 Namespace: %s\n\n", namespace)
+		}
+	} else {
+		metrics, err = s.collector.CollectAll(ctx)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "❌ Error: %v\n", err)
+		os.Exit(1)
+	}
+	return metrics
+}
+
+// ephemeral gathers emptyDir and container-layer usage; failures only print a warning
+func (s *source) ephemeral(ctx context.Context, namespace string) []types.EphemeralStorageMetric {
+	if s.snapshot != nil {
+		return s.snapshot.Ephemeral(namespace)
+	}
+
+	scope := collector.Scope{}
+	if namespace != "" {
+		scope.Namespaces = []string{namespace}
+	}
+	metrics, err := collector.NewEphemeralCollector(s.client).Collect(ctx, scope)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "⚠️  Warning: Failed to collect ephemeral storage: %v\n", err)
+	}
+	return metrics
+}
@@ -0,0 +1,238 @@
+package collector
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/cloudvault-io/cloudvault/pkg/types"
+)
+
+// AccessStore persists per-PVC access state so idle time survives agent restarts.
+// States are keyed by "namespace/name".
+type AccessStore interface {
+	LoadAccessStates(ctx context.Context) (map[string]types.AccessState, error)
+	// SaveAccessStates stores the complete set of known states
+	SaveAccessStates(ctx context.Context, states map[string]types.AccessState) error
+}
+
+// AccessTracker derives when each PVC was last accessed by comparing used bytes and
+// cumulative device I/O counters between collections. Being mounted or listed in
+// Prometheus is not access; only a change in what the volume holds or does is.
+// Sources that see individual requests set LastAccessedAt on the metric before
+// Observe, and that exact time is kept instead.
+//
+// Until a change is observed, a PVC is reported as idle since it was first seen.
+type AccessTracker struct {
+	store AccessStore
+	now   func() time.Time
+	// readOnly reports the store's states without tracking or saving (see NewAccessReader)
+	readOnly bool
+
+	mu     sync.Mutex
+	states map[string]types.AccessState
+}
+
+// NewAccessTracker creates a tracker. A nil store keeps state in memory only.
+func NewAccessTracker(store AccessStore) *AccessTracker {
+	return &AccessTracker{store: store, now: time.Now}
+}
+
+// NewAccessReader creates a tracker that reports the access state another process, the
+// agent, keeps in store. The store is reloaded on every Observe and never written, and
+// PVCs it has no state for keep a zero (unknown) LastAccessedAt.
+func NewAccessReader(store AccessStore) *AccessTracker {
+	return &AccessTracker{store: store, now: time.Now, readOnly: true}
+}
+
+// Observe updates the tracker with this cycle's metrics and sets their LastAccessedAt
+func (t *AccessTracker) Observe(ctx context.Context, metrics []types.PVCMetric) error {
+	if t.readOnly {
+		return t.report(ctx, metrics)
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.states == nil {
+		t.states = make(map[string]types.AccessState)
+		if t.store != nil {
+			loaded, err := t.store.LoadAccessStates(ctx)
+			if err != nil {
+				// Leave states nil so the next cycle retries; otherwise history would be overwritten
+				t.states = nil
+				return fmt.Errorf("failed to load access state: %w", err)
+			}
+			for k, v := range loaded {
+				t.states[k] = v
+			}
+		}
+	}
+
+	now := t.now()
+	for i := range metrics {
+		m := &metrics[i]
+		key := m.Namespace + "/" + m.Name
+		prev, seen := t.states[key]
+
+		state := types.AccessState{
+			Namespace:       m.Namespace,
+			Name:            m.Name,
+			UsedBytes:       m.UsedBytes,
+			ReadBytesTotal:  m.ReadBytesTotal,
+			WriteBytesTotal: m.WriteBytesTotal,
+			FirstSeen:       now,
+			LastSeen:        now,
+		}
+		if seen {
+			state.FirstSeen = prev.FirstSeen
+			state.LastAccessedAt = prev.LastAccessedAt
+			// Keep the last known value of sources that did not report this cycle
+			if state.UsedBytes == 0 {
+				state.UsedBytes = prev.UsedBytes
+			}
+			if state.ReadBytesTotal == 0 && state.WriteBytesTotal == 0 {
+				state.ReadBytesTotal, state.WriteBytesTotal = prev.ReadBytesTotal, prev.WriteBytesTotal
+			}
+		}
+		switch observed := m.LastAccessedAt; {
+		case !observed.IsZero():
+			if observed.After(state.LastAccessedAt) {
+				state.LastAccessedAt = observed
+			}
+		case accessed(m, prev, seen):
+			state.LastAccessedAt = now
+		}
+		t.states[key] = state
+
+		m.LastAccessedAt = state.LastAccessedAt
+		if m.LastAccessedAt.IsZero() {
+			m.LastAccessedAt = state.FirstSeen
+		}
+	}
+
+	for key, s := range t.states {
+		if now.Sub(s.LastSeen) > types.AccessStateRetention {
+			delete(t.states, key)
+		}
+	}
+
+	if t.store == nil {
+		return nil
+	}
+	return t.store.SaveAccessStates(ctx, t.states)
+}
+
+// report sets LastAccessedAt from the stored states, the same way Observe would
+func (t *AccessTracker) report(ctx context.Context, metrics []types.PVCMetric) error {
+	states, err := t.store.LoadAccessStates(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to load access state: %w", err)
+	}
+	for i := range metrics {
+		m := &metrics[i]
+		state, ok := states[m.Namespace+"/"+m.Name]
+		if !ok {
+			continue
+		}
+		last := state.LastAccessedAt
+		if last.IsZero() {
+			last = state.FirstSeen
+		}
+		if last.After(m.LastAccessedAt) {
+			m.LastAccessedAt = last
+		}
+	}
+	return nil
+}
+
+// accessed reports whether the volume saw I/O in the current rate window or changed
+// since the previous collection. A counter that went backwards was reset (node reboot,
+// volume moved to another node) and says nothing about access.
+func accessed(m *types.PVCMetric, prev types.AccessState, seen bool) bool {
+	if m.ReadIOPS+m.WriteIOPS > 0 || m.ReadThroughput+m.WriteThroughput > 0 {
+		return true
+	}
+	if !seen {
+		return false
+	}
+	if m.UsedBytes != 0 && prev.UsedBytes != 0 && m.UsedBytes != prev.UsedBytes {
+		return true
+	}
+	if prev.ReadBytesTotal > 0 && m.ReadBytesTotal > prev.ReadBytesTotal {
+		return true
+	}
+	return prev.WriteBytesTotal > 0 && m.WriteBytesTotal > prev.WriteBytesTotal
+}
+
+// AccessEnricher sets LastAccessedAt from the tracker. It must run after the usage and
+// iops enrichers, whose output it compares between cycles.
+type AccessEnricher struct {
+	tracker *AccessTracker
+}
+
+// Name implements Enricher
+func (e *AccessEnricher) Name() string { return EnricherAccess }
+
+// Enrich implements Enricher. Without a tracker, LastAccessedAt is left to the sources
+// that observe requests.
+func (e *AccessEnricher) Enrich(ctx context.Context, batch *Batch) error {
+	if e.tracker == nil {
+		return nil
+	}
+	return e.tracker.Observe(ctx, batch.Metrics)
+}
+
+// FileAccessStore keeps access state in a JSON file, for agents without TimescaleDB
+type FileAccessStore struct {
+	path string
+}
+
+// NewFileAccessStore creates a store backed by the file at path
+func NewFileAccessStore(path string) *FileAccessStore {
+	return &FileAccessStore{path: path}
+}
+
+// LoadAccessStates implements AccessStore. A missing file is an empty state.
+func (s *FileAccessStore) LoadAccessStates(ctx context.Context) (map[string]types.AccessState, error) {
+	data, err := os.ReadFile(s.path)
+	if os.IsNotExist(err) {
+		return map[string]types.AccessState{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	states := make(map[string]types.AccessState)
+	if err := json.Unmarshal(data, &states); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", s.path, err)
+	}
+	return states, nil
+}
+
+// SaveAccessStates implements AccessStore. The file is replaced atomically.
+func (s *FileAccessStore) SaveAccessStates(ctx context.Context, states map[string]types.AccessState) error {
+	data, err := json.Marshal(states)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(s.path), 0o755); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(s.path), ".access-*.json")
+	if err != nil {
+		return err
+	}
+	defer func() { _ = os.Remove(tmp.Name()) }()
+	if _, err := tmp.Write(data); err != nil {
+		_ = tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), s.path)
+}
@@ -0,0 +1,176 @@
+package collector
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/cloudvault-io/cloudvault/pkg/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newTestTracker returns a tracker whose clock is advanced by the returned function
+func newTestTracker(store AccessStore) (*AccessTracker, func(time.Duration) time.Time) {
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	tracker := NewAccessTracker(store)
+	tracker.now = func() time.Time { return now }
+	return tracker, func(d time.Duration) time.Time {
+		now = now.Add(d)
+		return now
+	}
+}
+
+func TestAccessTracker_IdleSinceFirstSeen(t *testing.T) {
+	tracker, advance := newTestTracker(nil)
+	ctx := context.Background()
+	start := tracker.now()
+
+	metrics := []types.PVCMetric{{Namespace: "prod", Name: "db", UsedBytes: 100, ReadBytesTotal: 5000}}
+	require.NoError(t, tracker.Observe(ctx, metrics))
+	assert.Equal(t, start, metrics[0].LastAccessedAt, "mounted is not accessed")
+
+	// Nothing changed for 40 days
+	advance(40 * 24 * time.Hour)
+	metrics = []types.PVCMetric{{Namespace: "prod", Name: "db", UsedBytes: 100, ReadBytesTotal: 5000}}
+	require.NoError(t, tracker.Observe(ctx, metrics))
+	assert.Equal(t, start, metrics[0].LastAccessedAt)
+	assert.True(t, metrics[0].IsZombie())
+}
+
+func TestAccessTracker_DetectsChanges(t *testing.T) {
+	tests := []struct {
+		name     string
+		next     types.PVCMetric
+		accessed bool
+	}{
+		{"used bytes changed", types.PVCMetric{UsedBytes: 200, ReadBytesTotal: 5000, WriteBytesTotal: 10}, true},
+		{"read counter increased", types.PVCMetric{UsedBytes: 100, ReadBytesTotal: 6000, WriteBytesTotal: 10}, true},
+		{"write counter increased", types.PVCMetric{UsedBytes: 100, ReadBytesTotal: 5000, WriteBytesTotal: 11}, true},
+		{"io rate in window", types.PVCMetric{UsedBytes: 100, ReadBytesTotal: 5000, WriteBytesTotal: 10, ReadIOPS: 0.2}, true},
+		{"counter reset", types.PVCMetric{UsedBytes: 100, ReadBytesTotal: 10, WriteBytesTotal: 1}, false},
+		{"source missing this cycle", types.PVCMetric{}, false},
+		{"unchanged", types.PVCMetric{UsedBytes: 100, ReadBytesTotal: 5000, WriteBytesTotal: 10}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tracker, advance := newTestTracker(nil)
+			ctx := context.Background()
+			start := tracker.now()
+
+			first := []types.PVCMetric{{Namespace: "prod", Name: "db", UsedBytes: 100, ReadBytesTotal: 5000, WriteBytesTotal: 10}}
+			require.NoError(t, tracker.Observe(ctx, first))
+
+			later := advance(time.Hour)
+			next := tt.next
+			next.Namespace, next.Name = "prod", "db"
+			metrics := []types.PVCMetric{next}
+			require.NoError(t, tracker.Observe(ctx, metrics))
+
+			if tt.accessed {
+				assert.Equal(t, later, metrics[0].LastAccessedAt)
+			} else {
+				assert.Equal(t, start, metrics[0].LastAccessedAt)
+			}
+		})
+	}
+}
+
+func TestAccessTracker_ObservedTime(t *testing.T) {
+	tracker, advance := newTestTracker(nil)
+	ctx := context.Background()
+	start := tracker.now()
+
+	// A traced request is kept at its own time rather than the collection time
+	lastIO := start.Add(-10 * time.Minute)
+	metrics := []types.PVCMetric{{Namespace: "prod", Name: "db", ReadIOPS: 3, LastAccessedAt: lastIO}}
+	require.NoError(t, tracker.Observe(ctx, metrics))
+	assert.Equal(t, lastIO, metrics[0].LastAccessedAt)
+
+	// An older observation, as after a tracer restart, does not move it back
+	advance(time.Hour)
+	metrics = []types.PVCMetric{{Namespace: "prod", Name: "db", LastAccessedAt: lastIO.Add(-time.Hour)}}
+	require.NoError(t, tracker.Observe(ctx, metrics))
+	assert.Equal(t, lastIO, metrics[0].LastAccessedAt)
+}
+
+func TestAccessTracker_SurvivesRestart(t *testing.T) {
+	store := NewFileAccessStore(filepath.Join(t.TempDir(), "state", "access.json"))
+	ctx := context.Background()
+
+	tracker, advance := newTestTracker(store)
+	require.NoError(t, tracker.Observe(ctx, []types.PVCMetric{{Namespace: "prod", Name: "db", UsedBytes: 100}}))
+	accessedAt := advance(time.Hour)
+	require.NoError(t, tracker.Observe(ctx, []types.PVCMetric{{Namespace: "prod", Name: "db", UsedBytes: 150}}))
+
+	// A new tracker (agent restart) keeps the last access instead of starting over
+	restarted, advanceRestarted := newTestTracker(store)
+	advanceRestarted(48 * time.Hour)
+	metrics := []types.PVCMetric{{Namespace: "prod", Name: "db", UsedBytes: 150}}
+	require.NoError(t, restarted.Observe(ctx, metrics))
+	assert.Equal(t, accessedAt, metrics[0].LastAccessedAt)
+}
+
+func TestAccessTracker_ExpiresStaleState(t *testing.T) {
+	tracker, advance := newTestTracker(nil)
+	ctx := context.Background()
+
+	require.NoError(t, tracker.Observe(ctx, []types.PVCMetric{{Namespace: "prod", Name: "deleted"}}))
+	advance(types.AccessStateRetention + time.Hour)
+	require.NoError(t, tracker.Observe(ctx, []types.PVCMetric{{Namespace: "prod", Name: "db"}}))
+
+	assert.NotContains(t, tracker.states, "prod/deleted")
+	assert.Contains(t, tracker.states, "prod/db")
+}
+
+func TestFileAccessStore_MissingFile(t *testing.T) {
+	store := NewFileAccessStore(filepath.Join(t.TempDir(), "missing.json"))
+	states, err := store.LoadAccessStates(context.Background())
+	require.NoError(t, err)
+	assert.Empty(t, states)
+}
+
+func TestAccessReader_ReportsStoredState(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "access.json")
+	store := NewFileAccessStore(path)
+	ctx := context.Background()
+
+	// The agent tracked prod/db and saw it accessed an hour after it first appeared
+	agent, advance := newTestTracker(store)
+	require.NoError(t, agent.Observe(ctx, []types.PVCMetric{
+		{Namespace: "prod", Name: "db", UsedBytes: 100},
+		{Namespace: "prod", Name: "idle", UsedBytes: 100},
+	}))
+	firstSeen := agent.now()
+	accessedAt := advance(time.Hour)
+	require.NoError(t, agent.Observe(ctx, []types.PVCMetric{
+		{Namespace: "prod", Name: "db", UsedBytes: 150},
+		{Namespace: "prod", Name: "idle", UsedBytes: 100},
+	}))
+	saved, err := store.LoadAccessStates(ctx)
+	require.NoError(t, err)
+
+	reader := NewAccessReader(store)
+	metrics := []types.PVCMetric{
+		{Namespace: "prod", Name: "db", UsedBytes: 999},
+		{Namespace: "prod", Name: "idle"},
+		{Namespace: "prod", Name: "new"},
+	}
+	require.NoError(t, reader.Observe(ctx, metrics))
+	assert.Equal(t, accessedAt, metrics[0].LastAccessedAt)
+	assert.Equal(t, firstSeen, metrics[1].LastAccessedAt, "idle since the agent first saw it")
+	assert.True(t, metrics[2].LastAccessedAt.IsZero(), "no state is unknown, not recently accessed")
+
+	after, err := store.LoadAccessStates(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, saved, after, "the reader must not write the agent's state")
+}
+
+func TestPVCCollector_NoAccessStore(t *testing.T) {
+	c := NewPVCCollector(nil, nil)
+	batch := &Batch{Metrics: []types.PVCMetric{{Namespace: "prod", Name: "db", UsedBytes: 100}}}
+	require.NoError(t, (&AccessEnricher{tracker: c.access}).Enrich(context.Background(), batch))
+	assert.True(t, batch.Metrics[0].LastAccessedAt.IsZero(), "without stored state access is unknown")
+}
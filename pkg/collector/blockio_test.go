@@ -0,0 +1,132 @@
+package collector
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/cloudvault-io/cloudvault/pkg/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+)
+
+const testMountInfo = `22 1 254:0 / / rw,relatime - ext4 /dev/vda rw
+30 22 0:25 / /var/lib/kubelet/pods/0a1b/volumes/kubernetes.io~projected/kube-api-access-x rw - tmpfs tmpfs rw
+31 22 254:0 /var/lib/kubelet/pods/0a1b/volumes/kubernetes.io~empty-dir/cache /var/lib/kubelet/pods/0a1b/volumes/kubernetes.io~empty-dir/cache rw - ext4 /dev/vda rw
+32 22 259:1 / /var/lib/kubelet/plugins/kubernetes.io/csi/ebs.csi.aws.com/abc/globalmount rw - ext4 /dev/nvme1n1 rw
+33 22 259:1 / /var/lib/kubelet/pods/0a1b/volumes/kubernetes.io~csi/pvc-db/mount rw - ext4 /dev/nvme1n1 rw
+34 22 259:4 / /var/lib/kubelet/pods/9f8e/volumes/kubernetes.io~aws-ebs/pv-logs rw - xfs /dev/nvme2n1p1 rw
+35 22 0:40 / /var/lib/kubelet/pods/9f8e/volumes/kubernetes.io~nfs/pv-shared rw - nfs4 fs:/export rw
+`
+
+func TestParseMountInfo(t *testing.T) {
+	devices, err := ParseMountInfo(strings.NewReader(testMountInfo))
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"259:1": "pvc-db", "259:4": "pv-logs"}, devices)
+}
+
+// testSysfs lays out /sys/dev/block for disk 259:3 with partition 259:4, and disk 259:1
+func testSysfs(t *testing.T) string {
+	t.Helper()
+	root := t.TempDir()
+	disk := filepath.Join(root, "devices/nvme2n1")
+	part := filepath.Join(disk, "nvme2n1p1")
+	other := filepath.Join(root, "devices/nvme1n1")
+	for _, dir := range []string{part, other, filepath.Join(root, "dev/block")} {
+		require.NoError(t, os.MkdirAll(dir, 0o755))
+	}
+	require.NoError(t, os.WriteFile(filepath.Join(disk, "dev"), []byte("259:3\n"), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(part, "dev"), []byte("259:4\n"), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(part, "partition"), []byte("1\n"), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(other, "dev"), []byte("259:1\n"), 0o644))
+	require.NoError(t, os.Symlink(part, filepath.Join(root, "dev/block/259:4")))
+	require.NoError(t, os.Symlink(other, filepath.Join(root, "dev/block/259:1")))
+	return root
+}
+
+func TestDiskDevice(t *testing.T) {
+	sys := testSysfs(t)
+	assert.Equal(t, "259:3", diskDevice(sys, "259:4"), "partitions resolve to their disk")
+	assert.Equal(t, "259:1", diskDevice(sys, "259:1"))
+	assert.Equal(t, "8:0", diskDevice(sys, "8:0"), "unknown devices are kept")
+}
+
+type staticBlockIO struct {
+	devices []types.BlockDeviceIO
+}
+
+func (s *staticBlockIO) GetBlockIO() ([]types.BlockDeviceIO, error) {
+	return s.devices, nil
+}
+
+// newTestBlockIOSource returns a source over testMountInfo whose clock is advanced by
+// the returned function
+func newTestBlockIOSource(t *testing.T, getter BlockIOGetter) (*BlockIOSource, func(time.Duration)) {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "mountinfo")
+	require.NoError(t, os.WriteFile(path, []byte(testMountInfo), 0o644))
+	src := NewBlockIOSource(getter, path)
+	src.sysRoot = testSysfs(t)
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	src.now = func() time.Time { return now }
+	return src, func(d time.Duration) { now = now.Add(d) }
+}
+
+func TestBlockIOSource_VolumeIO(t *testing.T) {
+	lastIO := time.Date(2025, 12, 31, 23, 59, 0, 0, time.UTC)
+	getter := &staticBlockIO{devices: []types.BlockDeviceIO{
+		{Major: 259, Minor: 1, ReadOps: 100, WriteOps: 50, ReadBytes: 4096 * 100, WriteBytes: 4096 * 50, LastIO: lastIO},
+		{Major: 259, Minor: 3, WriteOps: 10, WriteBytes: 1 << 20, LastIO: lastIO},
+		{Major: 254, Minor: 0, ReadOps: 999},
+	}}
+	src, advance := newTestBlockIOSource(t, getter)
+
+	first, err := src.VolumeIO()
+	require.NoError(t, err)
+	require.Len(t, first, 2)
+	assert.Equal(t, "259:1", first["pvc-db"].Device)
+	assert.Zero(t, first["pvc-db"].ReadIOPS, "no rate before a second sample")
+	assert.Equal(t, float64(4096*100), first["pvc-db"].ReadBytesTotal)
+	assert.Equal(t, lastIO, first["pvc-db"].LastIO)
+	assert.Equal(t, "259:3", first["pv-logs"].Device, "mounted partition is traced on its disk")
+
+	advance(10 * time.Second)
+	getter.devices[0].ReadOps += 200
+	getter.devices[0].ReadBytes += 200 * 4096
+	second, err := src.VolumeIO()
+	require.NoError(t, err)
+	assert.Equal(t, 20.0, second["pvc-db"].ReadIOPS)
+	assert.Equal(t, 0.0, second["pvc-db"].WriteIOPS)
+	assert.Equal(t, 20.0*4096, second["pvc-db"].ReadThroughput)
+
+	// A restarted tracer starts its counters over
+	advance(10 * time.Second)
+	getter.devices[0].ReadOps = 5
+	third, err := src.VolumeIO()
+	require.NoError(t, err)
+	assert.Zero(t, third["pvc-db"].ReadIOPS)
+}
+
+func TestIOEnricher_BlockIO(t *testing.T) {
+	lastIO := time.Date(2025, 12, 31, 23, 59, 0, 0, time.UTC)
+	src, _ := newTestBlockIOSource(t, &staticBlockIO{devices: []types.BlockDeviceIO{
+		{Major: 259, Minor: 1, ReadOps: 100, ReadBytes: 4096 * 100, LastIO: lastIO},
+	}})
+	pvc := func(volume string) corev1.PersistentVolumeClaim {
+		return corev1.PersistentVolumeClaim{Spec: corev1.PersistentVolumeClaimSpec{VolumeName: volume}}
+	}
+	batch := &Batch{
+		PVCs:    []corev1.PersistentVolumeClaim{pvc("pvc-db"), pvc("pvc-elsewhere")},
+		Metrics: []types.PVCMetric{{Namespace: "prod", Name: "db"}, {Namespace: "prod", Name: "other"}},
+	}
+
+	require.NoError(t, (&IOEnricher{blockIO: src}).Enrich(context.Background(), batch))
+	assert.Equal(t, float64(4096*100), batch.Metrics[0].ReadBytesTotal)
+	assert.Equal(t, lastIO, batch.Metrics[0].LastAccessedAt)
+	assert.Zero(t, batch.Metrics[1].ReadBytesTotal, "PVs on other nodes are left alone")
+	assert.True(t, batch.Metrics[1].LastAccessedAt.IsZero())
+}
@@ -0,0 +1,166 @@
+package collector
+
+import (
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+// CgroupEgressStatsGetter is the subset of the ebpf.Agent interface reporting egress by cgroup
+type CgroupEgressStatsGetter interface {
+	// GetCgroupEgressStats returns cgroup ID -> destination IP -> bytes
+	GetCgroupEgressStats() (map[uint64]map[string]uint64, error)
+}
+
+// PodUIDLookup finds pods by UID, as KubernetesClient does from its informer cache
+type PodUIDLookup interface {
+	PodByUID(uid string) (*corev1.Pod, bool)
+}
+
+// kubepodsCgroups are the kubelet's pod cgroup roots under the systemd and cgroupfs drivers
+var kubepodsCgroups = []string{"kubepods.slice", "kubepods"}
+
+// FindKubepodsCgroup returns the kubelet's pod cgroup directory below a cgroup mount
+// point, /sys/fs/cgroup when root is empty. The cgroup v2 mount of hybrid hosts,
+// unified/ below it, is searched too.
+func FindKubepodsCgroup(root string) (string, error) {
+	if root == "" {
+		root = "/sys/fs/cgroup"
+	}
+	roots := []string{root, filepath.Join(root, "unified")}
+	for _, r := range roots {
+		for _, name := range kubepodsCgroups {
+			dir := filepath.Join(r, name)
+			if info, err := os.Stat(dir); err == nil && info.IsDir() {
+				return dir, nil
+			}
+		}
+	}
+	return "", fmt.Errorf("no kubepods cgroup found under %s", strings.Join(roots, ", "))
+}
+
+// podUIDPattern matches the pod segment of a kubelet cgroup: "pod<uid>" with cgroupfs,
+// "kubepods-besteffort-pod<uid_with_underscores>.slice" with systemd
+var podUIDPattern = regexp.MustCompile(`pod([0-9a-f]{8}[-_][0-9a-f]{4}[-_][0-9a-f]{4}[-_][0-9a-f]{4}[-_][0-9a-f]{12})`)
+
+// PodCgroupResolver maps cgroup IDs to the UIDs of the pods they belong to by walking the
+// kubelet's pod cgroup hierarchy. A pod's own cgroup and its container cgroups all
+// resolve to the pod.
+type PodCgroupResolver struct {
+	root      string
+	minRescan time.Duration
+
+	mu       sync.Mutex
+	byID     map[uint64]string
+	lastScan time.Time
+}
+
+// NewPodCgroupResolver creates a resolver for the hierarchy below root, the kubepods cgroup
+func NewPodCgroupResolver(root string) *PodCgroupResolver {
+	return &PodCgroupResolver{root: root, minRescan: 10 * time.Second}
+}
+
+// PodUID returns the UID of the pod owning a cgroup. Unknown IDs trigger a rescan, at
+// most every few seconds, so pods started since the last scan are found.
+func (r *PodCgroupResolver) PodUID(cgroupID uint64) (string, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if uid, ok := r.byID[cgroupID]; ok {
+		return uid, true
+	}
+	if time.Since(r.lastScan) < r.minRescan {
+		return "", false
+	}
+	if err := r.scanLocked(); err != nil {
+		slog.Warn("failed to scan pod cgroups", "root", r.root, "error", err)
+		return "", false
+	}
+	uid, ok := r.byID[cgroupID]
+	return uid, ok
+}
+
+func (r *PodCgroupResolver) scanLocked() error {
+	r.lastScan = time.Now()
+	byID := make(map[uint64]string)
+	err := filepath.WalkDir(r.root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			// Cgroups of exiting containers disappear during the walk
+			if os.IsNotExist(err) {
+				return nil
+			}
+			return err
+		}
+		if !d.IsDir() {
+			return nil
+		}
+		match := podUIDPattern.FindStringSubmatch(path)
+		if match == nil {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return nil
+		}
+		if id, ok := cgroupID(info); ok {
+			byID[id] = strings.ReplaceAll(match[1], "_", "-")
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	r.byID = byID
+	return nil
+}
+
+// CgroupEgressProvider attributes eBPF egress recorded per cgroup to pods: cgroup ID to
+// pod UID through the kubelet's cgroup hierarchy, then pod UID to pod through the
+// informer cache. Unlike source addresses this also holds for host-network pods, NATed
+// traffic and reused IPs. Sources are keyed with PodSourceKey.
+type CgroupEgressProvider struct {
+	agent    CgroupEgressStatsGetter
+	resolver *PodCgroupResolver
+	pods     PodUIDLookup
+}
+
+func NewCgroupEgressProvider(agent CgroupEgressStatsGetter, resolver *PodCgroupResolver, pods PodUIDLookup) *CgroupEgressProvider {
+	return &CgroupEgressProvider{agent: agent, resolver: resolver, pods: pods}
+}
+
+// GetEgressStats returns pod source key -> destination IP -> bytes. Traffic of cgroups
+// outside pods, such as system daemons, is dropped.
+func (p *CgroupEgressProvider) GetEgressStats() (map[string]map[string]uint64, error) {
+	byCgroup, err := p.agent.GetCgroupEgressStats()
+	if err != nil {
+		return nil, err
+	}
+
+	stats := make(map[string]map[string]uint64)
+	for id, dsts := range byCgroup {
+		uid, ok := p.resolver.PodUID(id)
+		if !ok {
+			continue
+		}
+		pod, ok := p.pods.PodByUID(uid)
+		if !ok {
+			continue
+		}
+		src := PodSourceKey(pod.Namespace, pod.Name)
+		if _, ok := stats[src]; !ok {
+			stats[src] = make(map[string]uint64)
+		}
+		for dst, bytes := range dsts {
+			stats[src][dst] += bytes
+		}
+	}
+	return stats, nil
+}
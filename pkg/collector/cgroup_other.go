@@ -0,0 +1,10 @@
+//go:build !unix
+
+package collector
+
+import "os"
+
+// cgroupID is unavailable where cgroups do not exist
+func cgroupID(info os.FileInfo) (uint64, bool) {
+	return 0, false
+}
@@ -0,0 +1,135 @@
+package collector
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/cloudvault-io/cloudvault/pkg/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	k8stypes "k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/cache"
+)
+
+const testPodUID = "5f2b9c1e-3a4d-4e6f-8a9b-0c1d2e3f4a5b"
+
+// fakeCgroupAgent reports egress per cgroup ID
+type fakeCgroupAgent map[uint64]map[string]uint64
+
+func (f fakeCgroupAgent) GetCgroupEgressStats() (map[uint64]map[string]uint64, error) {
+	return f, nil
+}
+
+// fakePodLookup finds pods by UID
+type fakePodLookup map[string]*corev1.Pod
+
+func (f fakePodLookup) PodByUID(uid string) (*corev1.Pod, bool) {
+	pod, ok := f[uid]
+	return pod, ok
+}
+
+// mkCgroup creates a cgroup directory and returns its ID
+func mkCgroup(t *testing.T, path string) uint64 {
+	t.Helper()
+	require.NoError(t, os.MkdirAll(path, 0o755))
+	info, err := os.Stat(path)
+	require.NoError(t, err)
+	id, ok := cgroupID(info)
+	if !ok {
+		t.Skip("cgroup IDs are not available on this platform")
+	}
+	return id
+}
+
+func TestPodCgroupResolver(t *testing.T) {
+	root := filepath.Join(t.TempDir(), "kubepods.slice")
+	systemdUID := "kubepods-burstable-pod5f2b9c1e_3a4d_4e6f_8a9b_0c1d2e3f4a5b.slice"
+	podID := mkCgroup(t, filepath.Join(root, "kubepods-burstable.slice", systemdUID))
+	containerID := mkCgroup(t, filepath.Join(root, "kubepods-burstable.slice", systemdUID, "cri-containerd-abc.scope"))
+	qosID := mkCgroup(t, filepath.Join(root, "kubepods-besteffort.slice"))
+
+	r := NewPodCgroupResolver(root)
+	uid, ok := r.PodUID(podID)
+	require.True(t, ok)
+	assert.Equal(t, testPodUID, uid)
+	uid, ok = r.PodUID(containerID)
+	require.True(t, ok)
+	assert.Equal(t, testPodUID, uid, "container cgroups resolve to their pod")
+	_, ok = r.PodUID(qosID)
+	assert.False(t, ok)
+
+	// New pods are found on a miss once the rescan interval has passed
+	r.minRescan = 0
+	cgroupfsUID := "0a1b2c3d-4e5f-6a7b-8c9d-0e1f2a3b4c5d"
+	newID := mkCgroup(t, filepath.Join(root, "besteffort", "pod"+cgroupfsUID, "ctr"))
+	uid, ok = r.PodUID(newID)
+	require.True(t, ok)
+	assert.Equal(t, cgroupfsUID, uid)
+}
+
+func TestFindKubepodsCgroup(t *testing.T) {
+	root := t.TempDir()
+	_, err := FindKubepodsCgroup(root)
+	assert.Error(t, err)
+
+	// Hybrid hosts mount cgroup v2 at unified/
+	require.NoError(t, os.MkdirAll(filepath.Join(root, "unified", "kubepods"), 0o755))
+	dir, err := FindKubepodsCgroup(root)
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Join(root, "unified", "kubepods"), dir)
+
+	require.NoError(t, os.Mkdir(filepath.Join(root, "kubepods.slice"), 0o755))
+	dir, err = FindKubepodsCgroup(root)
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Join(root, "kubepods.slice"), dir)
+}
+
+func TestCgroupEgressProvider(t *testing.T) {
+	root := filepath.Join(t.TempDir(), "kubepods")
+	podID := mkCgroup(t, filepath.Join(root, "pod"+testPodUID, "ctr"))
+
+	agent := fakeCgroupAgent{
+		podID: {"1.1.1.1": 100},
+		1:     {"1.1.1.1": 999}, // root cgroup: system daemons
+	}
+	pods := fakePodLookup{testPodUID: {ObjectMeta: metav1.ObjectMeta{Namespace: "prod", Name: "db-0"}}}
+	provider := NewCgroupEgressProvider(agent, NewPodCgroupResolver(root), pods)
+
+	stats, err := provider.GetEgressStats()
+	require.NoError(t, err)
+	assert.Equal(t, map[string]map[string]uint64{
+		PodSourceKey("prod", "db-0"): {"1.1.1.1": 100},
+	}, stats)
+}
+
+func TestCorrelateEgress_PodSourceKey(t *testing.T) {
+	metrics := []types.PVCMetric{{Name: "data", Namespace: "prod", MountedPods: []string{"db-0"}}}
+	// Host-network pods are not in the IP index, but pod keys still match
+	pods := []corev1.Pod{{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "prod", Name: "db-0"},
+		Spec:       corev1.PodSpec{HostNetwork: true},
+		Status:     corev1.PodStatus{PodIP: "10.0.0.1"},
+	}}
+	egress := map[string]map[string]uint64{PodSourceKey("prod", "db-0"): {"8.8.8.8": 500}}
+
+	CorrelateEgress(metrics, pods, egress, nil)
+	assert.Equal(t, uint64(500), metrics[0].EgressBytes)
+}
+
+func TestKubernetesClient_PodByUID(t *testing.T) {
+	indexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{podUIDIndex: indexPodUID})
+	require.NoError(t, indexer.Add(&corev1.Pod{ObjectMeta: metav1.ObjectMeta{Namespace: "prod", Name: "db-0", UID: k8stypes.UID(testPodUID)}}))
+	k := &KubernetesClient{podIndexer: indexer}
+
+	pod, ok := k.PodByUID(testPodUID)
+	require.True(t, ok)
+	assert.Equal(t, "db-0", pod.Name)
+	_, ok = k.PodByUID("missing")
+	assert.False(t, ok)
+
+	_, ok = (&KubernetesClient{}).PodByUID(testPodUID)
+	assert.False(t, ok)
+}
@@ -0,0 +1,253 @@
+package collector
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"maps"
+	"math"
+	"net"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/cloudvault-io/cloudvault/pkg/integrations"
+	"github.com/cloudvault-io/cloudvault/pkg/types"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+// DefaultCommunicationHalfLife is how long traffic keeps half its weight in the graph
+const DefaultCommunicationHalfLife = time.Hour
+
+// CommunicationRecorder stores observed traffic with decaying weights, decaying only the
+// traffic from sources unless they are nil. The SIG implements it.
+type CommunicationRecorder interface {
+	RecordCommunications(ctx context.Context, comms []types.Communication, decay float64, sources []types.Endpoint) error
+}
+
+// CommunicationSync turns cumulative egress counters into the traffic between pods, and
+// from pods to external addresses, seen since the previous sync
+type CommunicationSync struct {
+	provider EgressProvider
+	recorder CommunicationRecorder
+	resolver *integrations.RegionResolver
+	listPods func(ctx context.Context) ([]corev1.Pod, error)
+	halfLife time.Duration
+	node     string
+	now      func() time.Time
+
+	mu     sync.Mutex
+	prev   map[string]map[string]uint64
+	prevAt time.Time
+}
+
+// NewCommunicationSync creates a sync reading egress from provider, attributing it to
+// the pods client lists and recording it to recorder
+func NewCommunicationSync(client *KubernetesClient, provider EgressProvider, recorder CommunicationRecorder) *CommunicationSync {
+	return &CommunicationSync{
+		provider: provider,
+		recorder: recorder,
+		resolver: integrations.NewRegionResolver(),
+		listPods: func(ctx context.Context) ([]corev1.Pod, error) {
+			pods, err := client.ListPods(ctx)
+			if err != nil {
+				return nil, err
+			}
+			return pods.Items, nil
+		},
+		halfLife: DefaultCommunicationHalfLife,
+		now:      time.Now,
+	}
+}
+
+// SetHalfLife sets how long traffic keeps half its weight
+func (s *CommunicationSync) SetHalfLife(d time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if d > 0 {
+		s.halfLife = d
+	}
+}
+
+// SetNode limits the sync to traffic from pods scheduled on node. Agents sharing a graph
+// each sync their own node, so traffic from cluster-wide sources such as Prometheus is
+// recorded, and decayed, once.
+func (s *CommunicationSync) SetNode(node string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.node = node
+}
+
+// Run syncs every interval until ctx is done. The first sync only takes a baseline.
+func (s *CommunicationSync) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		if err := s.Sync(ctx); err != nil {
+			slog.Warn("Failed to sync pod communications", "error", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Sync records the traffic seen since the previous sync and decays older traffic by
+// the time elapsed. Sources that resolve to no pod or, with SetNode, to a pod on another
+// node, and bytes of unknown destination, are left out.
+func (s *CommunicationSync) Sync(ctx context.Context) error {
+	pods, err := s.listPods(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to list pods: %w", err)
+	}
+	current, err := s.provider.GetEgressBytes(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get egress bytes: %w", err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := s.now()
+	if s.prev == nil {
+		s.prev, s.prevAt = current, now
+		return nil
+	}
+	elapsed := now.Sub(s.prevAt)
+	if elapsed <= 0 {
+		return nil
+	}
+
+	index := NewPodIPIndex(pods)
+	var sources []types.Endpoint
+	local := make(map[PodRef]bool)
+	if s.node != "" {
+		sources = make([]types.Endpoint, 0)
+		for _, pod := range pods {
+			if pod.Spec.NodeName == s.node {
+				sources = append(sources, types.Endpoint{Namespace: pod.Namespace, Pod: pod.Name})
+				local[PodRef{Namespace: pod.Namespace, Name: pod.Name}] = true
+			}
+		}
+	}
+
+	type pair struct {
+		src PodRef
+		dst types.Endpoint
+	}
+	bytesOf := make(map[pair]uint64)
+	var order []pair
+	for srcKey, dsts := range current {
+		src, ok := communicationSource(index, srcKey)
+		if !ok || (s.node != "" && !local[PodRef{Namespace: src.Namespace, Name: src.Name}]) {
+			continue
+		}
+		for dstIP, bytes := range sourceDelta(s.prev[srcKey], dsts) {
+			if bytes == 0 {
+				continue
+			}
+			dst, ok := s.communicationTarget(index, dstIP)
+			if !ok {
+				continue
+			}
+			p := pair{PodRef{Namespace: src.Namespace, Name: src.Name}, dst}
+			if _, seen := bytesOf[p]; !seen {
+				order = append(order, p)
+			}
+			bytesOf[p] += bytes
+		}
+	}
+
+	comms := make([]types.Communication, 0, len(order))
+	for _, p := range order {
+		comms = append(comms, types.Communication{
+			Source:   types.Endpoint{Namespace: p.src.Namespace, Pod: p.src.Name},
+			Target:   p.dst,
+			Bytes:    bytesOf[p],
+			Interval: elapsed,
+			SeenAt:   now,
+		})
+	}
+	decay := math.Exp2(-elapsed.Seconds() / s.halfLife.Seconds())
+	if err := s.recorder.RecordCommunications(ctx, comms, decay, sources); err != nil {
+		return fmt.Errorf("failed to record communications: %w", err)
+	}
+	s.prev, s.prevAt = current, now
+	return nil
+}
+
+// sourceDelta returns the bytes a source sent to each destination since its previous
+// counters. The source's total is differenced first and that delta split over the
+// destinations whose counters grew, because providers that split a pod's total by
+// destination share (see GetPodEgressBytes) move bytes between destinations whenever the
+// mix changes. A new source, or one whose total went backwards after a reset, counts in full.
+func sourceDelta(prev, current map[string]uint64) map[string]uint64 {
+	var prevTotal, total uint64
+	for _, bytes := range prev {
+		prevTotal += bytes
+	}
+	for _, bytes := range current {
+		total += bytes
+	}
+	if prev == nil || total < prevTotal {
+		return current
+	}
+	delta := total - prevTotal
+	if delta == 0 {
+		return nil
+	}
+
+	grown := make(map[string]uint64)
+	var grownTotal uint64
+	for dst, bytes := range current {
+		if bytes > prev[dst] {
+			grown[dst] = bytes - prev[dst]
+			grownTotal += bytes - prev[dst]
+		}
+	}
+	if grownTotal == delta {
+		return grown
+	}
+
+	// Scale the growth down to the delta; the last destination takes the rounding
+	dsts := slices.Sorted(maps.Keys(grown))
+	remaining := delta
+	for i, dst := range dsts {
+		share := min(uint64(float64(delta)*float64(grown[dst])/float64(grownTotal)), remaining)
+		if i == len(dsts)-1 {
+			share = remaining
+		}
+		grown[dst] = share
+		remaining -= share
+	}
+	return grown
+}
+
+// communicationSource resolves an egress source key, a pod IP or a PodSourceKey, to its pod
+func communicationSource(index *PodIPIndex, key string) (PodRef, bool) {
+	if podKey, ok := strings.CutPrefix(key, "pod:"); ok {
+		namespace, name, ok := strings.Cut(podKey, "/")
+		return PodRef{Namespace: namespace, Name: name}, ok
+	}
+	return index.Lookup(key)
+}
+
+// communicationTarget resolves a destination IP to the pod holding it or, failing that,
+// to an external endpoint located by the region resolver. Private addresses outside the
+// pod network have no known location.
+func (s *CommunicationSync) communicationTarget(index *PodIPIndex, ip string) (types.Endpoint, bool) {
+	if ref, ok := index.Lookup(ip); ok {
+		return types.Endpoint{Namespace: ref.Namespace, Pod: ref.Name}, true
+	}
+	if net.ParseIP(ip) == nil {
+		return types.Endpoint{}, false
+	}
+	dst := types.Endpoint{IP: ip}
+	if res := s.resolver.Resolve(ip); res != nil && res.Provider != "internal" {
+		dst.Provider, dst.Region = res.Provider, res.Region
+	}
+	return dst, true
+}
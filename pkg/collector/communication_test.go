@@ -0,0 +1,161 @@
+package collector
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/cloudvault-io/cloudvault/pkg/integrations"
+	"github.com/cloudvault-io/cloudvault/pkg/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+)
+
+type recordedCommunications struct {
+	comms   []types.Communication
+	decay   float64
+	sources []types.Endpoint
+	err     error
+	calls   int
+}
+
+func (r *recordedCommunications) RecordCommunications(ctx context.Context, comms []types.Communication, decay float64, sources []types.Endpoint) error {
+	r.calls++
+	if r.err != nil {
+		return r.err
+	}
+	r.comms, r.decay, r.sources = comms, decay, sources
+	return nil
+}
+
+func newTestCommunicationSync(provider EgressProvider, recorder CommunicationRecorder, pods ...corev1.Pod) (*CommunicationSync, func(time.Duration)) {
+	s := NewCommunicationSync(nil, provider, recorder)
+	s.listPods = func(ctx context.Context) ([]corev1.Pod, error) { return pods, nil }
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	s.now = func() time.Time { return now }
+	return s, func(d time.Duration) { now = now.Add(d) }
+}
+
+func TestCommunicationSync_Sync(t *testing.T) {
+	ctx := context.Background()
+	started := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	provider := &staticEgressProvider{data: map[string]map[string]uint64{
+		"10.0.0.1":              {"10.0.0.2": 1000, "8.8.8.8": 500},
+		PodSourceKey("ns", "w"): {"10.0.0.1": 400},
+	}}
+	recorder := &recordedCommunications{}
+	s, advance := newTestCommunicationSync(provider, recorder,
+		testPod("default", "api", "10.0.0.1", corev1.PodRunning, started),
+		testPod("default", "db", "10.0.0.2", corev1.PodRunning, started),
+	)
+
+	require.NoError(t, s.Sync(ctx))
+	assert.Zero(t, recorder.calls, "the first sync takes a baseline")
+
+	advance(time.Hour)
+	provider.data = map[string]map[string]uint64{
+		"10.0.0.1":              {"10.0.0.2": 4000, "8.8.8.8": 700, "10.9.9.9": 300, integrations.UnknownDestination: 99},
+		"10.0.0.99":             {"10.0.0.2": 700},
+		PodSourceKey("ns", "w"): {"10.0.0.1": 50},
+	}
+	require.NoError(t, s.Sync(ctx))
+	require.Equal(t, 1, recorder.calls)
+	assert.InDelta(t, 0.5, recorder.decay, 1e-9, "an hour is one half-life")
+
+	byTarget := make(map[types.Endpoint]types.Communication)
+	for _, c := range recorder.comms {
+		byTarget[c.Target] = c
+		assert.Equal(t, time.Hour, c.Interval)
+		assert.Equal(t, started.Add(time.Hour), c.SeenAt)
+	}
+	require.Len(t, byTarget, 4, "unknown sources and destinations are left out")
+	api := types.Endpoint{Namespace: "default", Pod: "api"}
+	assert.Equal(t, types.Communication{
+		Source: api, Target: types.Endpoint{Namespace: "default", Pod: "db"},
+		Bytes: 3000, Interval: time.Hour, SeenAt: started.Add(time.Hour),
+	}, byTarget[types.Endpoint{Namespace: "default", Pod: "db"}])
+	assert.Equal(t, uint64(200), byTarget[types.Endpoint{IP: "8.8.8.8", Provider: "internet"}].Bytes)
+	assert.Equal(t, uint64(300), byTarget[types.Endpoint{IP: "10.9.9.9"}].Bytes,
+		"private addresses outside the pod network have no location")
+	assert.Equal(t, types.Endpoint{Namespace: "ns", Pod: "w"}, byTarget[api].Source)
+	assert.Equal(t, uint64(50), byTarget[api].Bytes, "a source whose total went backwards was reset")
+
+	// Nothing new still decays
+	advance(2 * time.Hour)
+	require.NoError(t, s.Sync(ctx))
+	assert.Empty(t, recorder.comms)
+	assert.InDelta(t, 0.25, recorder.decay, 1e-9)
+}
+
+func TestCommunicationSync_Node(t *testing.T) {
+	ctx := context.Background()
+	started := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	onNode := func(pod corev1.Pod, node string) corev1.Pod {
+		pod.Spec.NodeName = node
+		return pod
+	}
+	// A cluster-wide source like Prometheus reports pods on every node
+	provider := &staticEgressProvider{data: map[string]map[string]uint64{
+		"10.0.0.1": {"10.0.0.2": 100},
+		"10.0.0.2": {"10.0.0.1": 100},
+	}}
+	recorder := &recordedCommunications{}
+	s, advance := newTestCommunicationSync(provider, recorder,
+		onNode(testPod("default", "api", "10.0.0.1", corev1.PodRunning, started), "node-a"),
+		onNode(testPod("default", "db", "10.0.0.2", corev1.PodRunning, started), "node-b"),
+	)
+	s.SetNode("node-a")
+	require.NoError(t, s.Sync(ctx))
+
+	advance(time.Minute)
+	provider.data = map[string]map[string]uint64{
+		"10.0.0.1": {"10.0.0.2": 300},
+		"10.0.0.2": {"10.0.0.1": 300},
+	}
+	require.NoError(t, s.Sync(ctx))
+	require.Len(t, recorder.comms, 1, "traffic from pods on other nodes is synced by their agents")
+	assert.Equal(t, types.Endpoint{Namespace: "default", Pod: "api"}, recorder.comms[0].Source)
+	assert.Equal(t, uint64(200), recorder.comms[0].Bytes)
+	assert.Equal(t, []types.Endpoint{{Namespace: "default", Pod: "api"}}, recorder.sources)
+}
+
+func TestSourceDelta(t *testing.T) {
+	prev := map[string]uint64{"a": 600, "b": 400}
+
+	// Exact per-destination counters
+	assert.Equal(t, map[string]uint64{"a": 100, "c": 50},
+		sourceDelta(prev, map[string]uint64{"a": 700, "b": 400, "c": 50}))
+	// A total split by shares that shifted: only the 100 new bytes count, not a reset of a
+	assert.Equal(t, map[string]uint64{"b": 100},
+		sourceDelta(prev, map[string]uint64{"a": 550, "b": 550}))
+	// Growth scaled down to the total's delta
+	delta := sourceDelta(prev, map[string]uint64{"a": 500, "b": 700, "c": 100})
+	assert.Equal(t, map[string]uint64{"b": 225, "c": 75}, delta)
+	assert.Empty(t, sourceDelta(prev, map[string]uint64{"a": 400, "b": 600}))
+	// A new source, or a total that went backwards, counts in full
+	assert.Equal(t, map[string]uint64{"a": 10}, sourceDelta(nil, map[string]uint64{"a": 10}))
+	assert.Equal(t, map[string]uint64{"a": 10, "b": 20}, sourceDelta(prev, map[string]uint64{"a": 10, "b": 20}))
+}
+
+func TestCommunicationSync_RecordError(t *testing.T) {
+	ctx := context.Background()
+	provider := &staticEgressProvider{data: map[string]map[string]uint64{PodSourceKey("default", "api"): {"8.8.8.8": 100}}}
+	recorder := &recordedCommunications{}
+	s, advance := newTestCommunicationSync(provider, recorder)
+	require.NoError(t, s.Sync(ctx))
+
+	advance(time.Minute)
+	provider.data = map[string]map[string]uint64{PodSourceKey("default", "api"): {"8.8.8.8": 300}}
+	recorder.err = errors.New("graph unavailable")
+	assert.Error(t, s.Sync(ctx))
+
+	// The bytes are recorded once the graph is back
+	advance(time.Minute)
+	recorder.err = nil
+	require.NoError(t, s.Sync(ctx))
+	require.Len(t, recorder.comms, 1)
+	assert.Equal(t, uint64(200), recorder.comms[0].Bytes)
+	assert.Equal(t, 2*time.Minute, recorder.comms[0].Interval)
+}
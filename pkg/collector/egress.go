@@ -2,10 +2,14 @@ package collector
 
 import (
 	"context"
-	"fmt"
+	"log/slog"
+	"maps"
+	"sort"
 
 	"github.com/cloudvault-io/cloudvault/pkg/integrations"
 	"github.com/cloudvault-io/cloudvault/pkg/types"
+
+	corev1 "k8s.io/api/core/v1"
 )
 
 // EgressProvider defines the interface for gathering network egress data,
@@ -15,20 +19,163 @@ type EgressProvider interface {
 	GetEgressBytes(ctx context.Context) (map[string]map[string]uint64, error)
 }
 
+// PodSourceKey is the egress source key for traffic attributed to a pod directly rather
+// than through its address
+func PodSourceKey(namespace, name string) string {
+	return "pod:" + namespace + "/" + name
+}
+
 // EgressStatsGetter is the subset of the ebpf.Agent interface needed by the collector.
 // Keeping this interface here avoids a hard dependency on the ebpf package internals.
 type EgressStatsGetter interface {
 	GetEgressStats() (map[string]map[string]uint64, error)
 }
 
-// PrometheusEgressProvider uses metrics from Prometheus (e.g., node_exporter)
+// EgressFlowGetter is implemented by agents that also record the L4 protocol and port
+// of each flow
+type EgressFlowGetter interface {
+	GetEgressFlows() ([]types.EgressFlow, error)
+}
+
+// EgressFlowProvider is implemented by egress providers that can break traffic down by
+// L4 protocol and destination port
+type EgressFlowProvider interface {
+	GetEgressFlows(ctx context.Context) ([]types.EgressFlow, error)
+}
+
+// PrometheusEgressProvider derives per-pod egress from cAdvisor transmit counters, split
+// by destination when Cilium Hubble flow metrics are available (see GetPodEgressBytes)
 type PrometheusEgressProvider struct {
-	// Add Prometheus client reference
+	client *integrations.PrometheusClient
+}
+
+func NewPrometheusEgressProvider(client *integrations.PrometheusClient) *PrometheusEgressProvider {
+	return &PrometheusEgressProvider{client: client}
 }
 
 func (p *PrometheusEgressProvider) GetEgressBytes(ctx context.Context) (map[string]map[string]uint64, error) {
-	// Current implementation: return dummy or from existing c.promClient
-	return make(map[string]map[string]uint64), nil
+	if p.client == nil {
+		return make(map[string]map[string]uint64), nil
+	}
+	return p.client.GetPodEgressBytes(ctx)
+}
+
+// MergedEgressProvider combines several providers. Each source is taken from the first
+// provider that reports it, so a precise source (eBPF on the local node) is preferred and
+// the others fill in pods it cannot see; bytes are never summed across providers.
+//
+// Providers may key a pod by its IP or by PodSourceKey. With a pod source set (see
+// SetPodSource), IPs held by a pod are rewritten to its PodSourceKey before merging, so
+// the same pod reported both ways is still counted once. Without one, the providers must
+// share a keying scheme.
+type MergedEgressProvider struct {
+	providers []EgressProvider
+	listPods  func(ctx context.Context) ([]corev1.Pod, error)
+}
+
+func NewMergedEgressProvider(providers ...EgressProvider) *MergedEgressProvider {
+	return &MergedEgressProvider{providers: providers}
+}
+
+// SetPodSource resolves source IPs to pods through client before merging
+func (p *MergedEgressProvider) SetPodSource(client *KubernetesClient) {
+	p.listPods = func(ctx context.Context) ([]corev1.Pod, error) {
+		pods, err := client.ListPods(ctx)
+		if err != nil {
+			return nil, err
+		}
+		return pods.Items, nil
+	}
+}
+
+// sourceKeys returns the function normalizing a source key to one identity per pod. Keys
+// stay unchanged when there is no pod source or listing pods fails.
+func (p *MergedEgressProvider) sourceKeys(ctx context.Context) func(string) string {
+	if p.listPods == nil {
+		return func(src string) string { return src }
+	}
+	pods, err := p.listPods(ctx)
+	if err != nil {
+		slog.Warn("Failed to list pods, merging egress sources as reported", "error", err)
+		return func(src string) string { return src }
+	}
+	index := NewPodIPIndex(pods)
+	return func(src string) string {
+		if ref, ok := index.Lookup(src); ok {
+			return PodSourceKey(ref.Namespace, ref.Name)
+		}
+		return src
+	}
+}
+
+func (p *MergedEgressProvider) GetEgressBytes(ctx context.Context) (map[string]map[string]uint64, error) {
+	key := p.sourceKeys(ctx)
+	merged := make(map[string]map[string]uint64)
+	var firstErr error
+	failed := 0
+	for _, provider := range p.providers {
+		data, err := provider.GetEgressBytes(ctx)
+		if err != nil {
+			slog.Warn("egress provider failed", "error", err)
+			if firstErr == nil {
+				firstErr = err
+			}
+			failed++
+			continue
+		}
+		// A provider may report one pod under several keys (dual-stack IPs, or an IP and
+		// its PodSourceKey); those are summed before the first-wins rule applies
+		normalized := make(map[string]map[string]uint64)
+		for src, dsts := range data {
+			k := key(src)
+			if _, ok := merged[k]; ok {
+				continue
+			}
+			if normalized[k] == nil {
+				normalized[k] = make(map[string]uint64, len(dsts))
+			}
+			for dst, bytes := range dsts {
+				normalized[k][dst] += bytes
+			}
+		}
+		maps.Copy(merged, normalized)
+	}
+	if failed > 0 && failed == len(p.providers) {
+		return nil, firstErr
+	}
+	return merged, nil
+}
+
+// GetEgressFlows implements EgressFlowProvider over the providers that support it, with
+// the same source keys and first-wins rule per source as GetEgressBytes
+func (p *MergedEgressProvider) GetEgressFlows(ctx context.Context) ([]types.EgressFlow, error) {
+	key := p.sourceKeys(ctx)
+	var merged []types.EgressFlow
+	claimed := make(map[string]bool)
+	for _, provider := range p.providers {
+		fp, ok := provider.(EgressFlowProvider)
+		if !ok {
+			continue
+		}
+		flows, err := fp.GetEgressFlows(ctx)
+		if err != nil {
+			slog.Warn("egress flow provider failed", "error", err)
+			continue
+		}
+		seen := make(map[string]bool)
+		for _, f := range flows {
+			f.Src = key(f.Src)
+			if claimed[f.Src] {
+				continue
+			}
+			seen[f.Src] = true
+			merged = append(merged, f)
+		}
+		for src := range seen {
+			claimed[src] = true
+		}
+	}
+	return merged, nil
 }
 
 // EbpfEgressProvider uses kernel-level eBPF monitoring (Section 141)
@@ -47,31 +194,129 @@ func (p *EbpfEgressProvider) GetEgressBytes(ctx context.Context) (map[string]map
 	return p.agent.GetEgressStats()
 }
 
-// CorrelateEgress correlates granular egress stats with specific PVCs/Pods.
-// Revolutionary: Uses destination-aware tracking to calculate precise inter-cloud fees.
-func CorrelateEgress(metrics []types.PVCMetric, egressData map[string]map[string]uint64, resolver *integrations.RegionResolver) {
+// GetEgressFlows implements EgressFlowProvider when the agent records ports
+func (p *EbpfEgressProvider) GetEgressFlows(ctx context.Context) ([]types.EgressFlow, error) {
+	getter, ok := p.agent.(EgressFlowGetter)
+	if !ok {
+		return nil, nil
+	}
+	return getter.GetEgressFlows()
+}
+
+// CorrelateEgress attributes per-source-IP egress to PVCs through the pods mounting them.
+// Source IPs are resolved with a PodIPIndex built from pods; sources keyed by
+// PodSourceKey match their pod directly. A pod mounting several PVCs contributes its
+// full traffic to each of them, since flows carry no volume information.
+func CorrelateEgress(metrics []types.PVCMetric, pods []corev1.Pod, egressData map[string]map[string]uint64, resolver *integrations.RegionResolver) {
+	if len(egressData) == 0 {
+		return
+	}
+	index := NewPodIPIndex(pods)
+
 	for i := range metrics {
-		// Find pods associated with this PVC IP (simplified for Phase 9)
-		// and sum up their egress traffic by destination.
-		srcIP := metrics[i].Labels["cloudvault.io/pod-ip"]
-		if srcIP == "" {
+		m := &metrics[i]
+
+		byDst := make(map[string]uint64)
+		for _, podName := range m.MountedPods {
+			sources := append([]string{PodSourceKey(m.Namespace, podName)}, index.IPs(m.Namespace, podName)...)
+			for _, src := range sources {
+				for dstIP, bytes := range egressData[src] {
+					byDst[dstIP] += bytes
+				}
+			}
+		}
+		if len(byDst) == 0 {
 			continue
 		}
 
-		if destinations, ok := egressData[srcIP]; ok {
-			totalBytes := uint64(0)
-			for dstIP, bytes := range destinations {
-				totalBytes += bytes
+		m.EgressBytes = 0
+		m.ExternalEgressBytes = 0
+		m.EgressDestinations = make([]types.EgressDestination, 0, len(byDst))
+		for dstIP, bytes := range byDst {
+			dst := types.EgressDestination{IP: dstIP, Bytes: bytes}
+			// Resolve destination to price inter-cloud and internet traffic
+			if res := resolver.Resolve(dstIP); res != nil {
+				dst.Provider = res.Provider
+				dst.Region = res.Region
+				dst.External = res.Provider != "internal"
+			}
+			m.EgressBytes += bytes
+			if dst.External {
+				m.ExternalEgressBytes += bytes
+			}
+			m.EgressDestinations = append(m.EgressDestinations, dst)
+		}
+		sort.Slice(m.EgressDestinations, func(a, b int) bool {
+			da, db := m.EgressDestinations[a], m.EgressDestinations[b]
+			if da.Bytes != db.Bytes {
+				return da.Bytes > db.Bytes
+			}
+			return da.IP < db.IP
+		})
+	}
+}
+
+// CorrelateEgressPorts fills the per-port breakdown of the destinations CorrelateEgress
+// found, from flows reported by an EgressFlowProvider. Sources are matched to pods the
+// same way as in CorrelateEgress.
+func CorrelateEgressPorts(metrics []types.PVCMetric, pods []corev1.Pod, flows []types.EgressFlow) {
+	if len(flows) == 0 {
+		return
+	}
+	index := NewPodIPIndex(pods)
+	bySrc := make(map[string][]types.EgressFlow)
+	for _, f := range flows {
+		bySrc[f.Src] = append(bySrc[f.Src], f)
+	}
 
-				// Calculate real cost if destination is external or cross-region
-				res := resolver.Resolve(dstIP)
-				if res != nil && res.Provider != "internal" {
-					// Precision egress cost calculation logic
-					metrics[i].Labels["cloudvault.io/external-egress"] = "true"
+	type portKey struct {
+		protocol string
+		port     uint16
+	}
+	for i := range metrics {
+		m := &metrics[i]
+		if len(m.EgressDestinations) == 0 {
+			continue
+		}
+
+		byDst := make(map[string]map[portKey]uint64)
+		for _, podName := range m.MountedPods {
+			sources := append([]string{PodSourceKey(m.Namespace, podName)}, index.IPs(m.Namespace, podName)...)
+			for _, src := range sources {
+				for _, f := range bySrc[src] {
+					if byDst[f.Dst] == nil {
+						byDst[f.Dst] = make(map[portKey]uint64)
+					}
+					byDst[f.Dst][portKey{f.Protocol, f.DstPort}] += f.Bytes
 				}
 			}
-			metrics[i].EgressBytes = totalBytes
-			metrics[i].Labels["cloudvault.io/egress-bytes"] = fmt.Sprintf("%d", totalBytes)
+		}
+
+		for j := range m.EgressDestinations {
+			dst := &m.EgressDestinations[j]
+			ports := byDst[dst.IP]
+			if len(ports) == 0 {
+				continue
+			}
+			dst.Ports = make([]types.EgressPort, 0, len(ports))
+			for k, bytes := range ports {
+				dst.Ports = append(dst.Ports, types.EgressPort{Protocol: k.protocol, Port: k.port, Bytes: bytes})
+			}
+			sortEgressPorts(dst.Ports)
 		}
 	}
 }
+
+// sortEgressPorts orders ports largest first, then by protocol and port
+func sortEgressPorts(ports []types.EgressPort) {
+	sort.Slice(ports, func(a, b int) bool {
+		pa, pb := ports[a], ports[b]
+		if pa.Bytes != pb.Bytes {
+			return pa.Bytes > pb.Bytes
+		}
+		if pa.Protocol != pb.Protocol {
+			return pa.Protocol < pb.Protocol
+		}
+		return pa.Port < pb.Port
+	})
+}
@@ -2,10 +2,14 @@ package collector
 
 import (
 	"context"
+	"errors"
 	"testing"
+	"time"
 
 	"github.com/cloudvault-io/cloudvault/pkg/integrations"
 	"github.com/cloudvault-io/cloudvault/pkg/types"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
 // mockEgressAgent implements EgressStatsGetter with stub data for testing.
@@ -34,6 +38,93 @@ func TestPrometheusEgressProvider_GetEgressBytes(t *testing.T) {
 	}
 }
 
+type staticEgressProvider struct {
+	data map[string]map[string]uint64
+	err  error
+}
+
+func (p *staticEgressProvider) GetEgressBytes(ctx context.Context) (map[string]map[string]uint64, error) {
+	return p.data, p.err
+}
+
+func TestMergedEgressProvider_PrefersEarlierProviders(t *testing.T) {
+	ebpfData := &staticEgressProvider{data: map[string]map[string]uint64{
+		"10.0.1.5": {"1.1.1.1": 100},
+	}}
+	promData := &staticEgressProvider{data: map[string]map[string]uint64{
+		"10.0.1.5": {"unknown": 900},
+		"10.0.2.7": {"unknown": 40},
+	}}
+
+	data, err := NewMergedEgressProvider(ebpfData, promData).GetEgressBytes(context.Background())
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if got := data["10.0.1.5"]; len(got) != 1 || got["1.1.1.1"] != 100 {
+		t.Errorf("Expected eBPF data for 10.0.1.5, got %v", got)
+	}
+	if data["10.0.2.7"]["unknown"] != 40 {
+		t.Errorf("Expected Prometheus data to fill in 10.0.2.7, got %v", data["10.0.2.7"])
+	}
+}
+
+func TestMergedEgressProvider_Errors(t *testing.T) {
+	failing := &staticEgressProvider{err: errors.New("map not initialized")}
+	working := &staticEgressProvider{data: map[string]map[string]uint64{"10.0.1.5": {"1.1.1.1": 1}}}
+
+	data, err := NewMergedEgressProvider(failing, working).GetEgressBytes(context.Background())
+	if err != nil {
+		t.Fatalf("Expected partial failure to be tolerated, got %v", err)
+	}
+	if len(data) != 1 {
+		t.Errorf("Expected data from the working provider, got %v", data)
+	}
+
+	if _, err := NewMergedEgressProvider(failing).GetEgressBytes(context.Background()); err == nil {
+		t.Error("Expected error when every provider fails")
+	}
+}
+
+func TestMergedEgressProvider_NormalizesPodKeys(t *testing.T) {
+	cgroupData := &staticEgressProvider{data: map[string]map[string]uint64{
+		PodSourceKey("prod", "db-0"): {"8.8.8.8": 100},
+	}}
+	promData := &staticEgressProvider{data: map[string]map[string]uint64{
+		"10.0.1.5": {"unknown": 900},
+		"10.0.2.7": {"unknown": 40},
+		"10.0.9.9": {"unknown": 7},
+	}}
+	pods := []corev1.Pod{
+		testPod("prod", "db-0", "10.0.1.5", corev1.PodRunning, time.Now()),
+		testPod("prod", "api", "10.0.2.7", corev1.PodRunning, time.Now()),
+	}
+	merged := NewMergedEgressProvider(cgroupData, promData)
+	merged.listPods = func(ctx context.Context) ([]corev1.Pod, error) { return pods, nil }
+
+	data, err := merged.GetEgressBytes(context.Background())
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if got := data[PodSourceKey("prod", "db-0")]; len(got) != 1 || got["8.8.8.8"] != 100 {
+		t.Errorf("Expected only the cgroup data for db-0, got %v", got)
+	}
+	if _, ok := data["10.0.1.5"]; ok {
+		t.Errorf("Expected db-0's IP to be merged into its pod key, got %v", data)
+	}
+	if data[PodSourceKey("prod", "api")]["unknown"] != 40 {
+		t.Errorf("Expected Prometheus data to fill in api, got %v", data)
+	}
+	if data["10.0.9.9"]["unknown"] != 7 {
+		t.Errorf("Expected unresolved IPs to be kept, got %v", data)
+	}
+
+	metrics := []types.PVCMetric{{Name: "data-db-0", Namespace: "prod", MountedPods: []string{"db-0"}}}
+	CorrelateEgress(metrics, pods, data, integrations.NewRegionResolver())
+	if metrics[0].EgressBytes != 100 {
+		t.Errorf("Expected db-0's egress to be counted once, got %d", metrics[0].EgressBytes)
+	}
+}
+
 func TestNewEbpfEgressProvider(t *testing.T) {
 	agent := &mockEgressAgent{}
 	provider := NewEbpfEgressProvider(agent)
@@ -78,7 +169,18 @@ func TestEbpfEgressProvider_GetEgressBytes_NoAgent(t *testing.T) {
 	}
 }
 
-func TestCorrelateEgress_WithoutPodIP(t *testing.T) {
+func testPod(ns, name, ip string, phase corev1.PodPhase, started time.Time) corev1.Pod {
+	return corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: ns},
+		Status: corev1.PodStatus{
+			Phase:     phase,
+			PodIP:     ip,
+			StartTime: &metav1.Time{Time: started},
+		},
+	}
+}
+
+func TestCorrelateEgress_NoMountedPods(t *testing.T) {
 	metrics := []types.PVCMetric{
 		{
 			Name:      "test-pvc",
@@ -86,6 +188,7 @@ func TestCorrelateEgress_WithoutPodIP(t *testing.T) {
 			Labels:    make(map[string]string),
 		},
 	}
+	pods := []corev1.Pod{testPod("default", "app-0", "10.0.0.1", corev1.PodRunning, time.Now())}
 
 	egressData := map[string]map[string]uint64{
 		"10.0.0.1": {
@@ -93,26 +196,24 @@ func TestCorrelateEgress_WithoutPodIP(t *testing.T) {
 		},
 	}
 
-	resolver := integrations.NewRegionResolver()
+	CorrelateEgress(metrics, pods, egressData, integrations.NewRegionResolver())
 
-	CorrelateEgress(metrics, egressData, resolver)
-
-	// Without pod IP, egress should remain 0
+	// Without mounting pods, egress should remain 0
 	if metrics[0].EgressBytes != 0 {
 		t.Errorf("Expected 0 egress bytes, got %d", metrics[0].EgressBytes)
 	}
 }
 
-func TestCorrelateEgress_WithPodIP(t *testing.T) {
+func TestCorrelateEgress_ViaMountingPod(t *testing.T) {
 	metrics := []types.PVCMetric{
 		{
-			Name:      "test-pvc",
-			Namespace: "default",
-			Labels: map[string]string{
-				"cloudvault.io/pod-ip": "10.0.0.1",
-			},
+			Name:        "test-pvc",
+			Namespace:   "default",
+			MountedPods: []string{"app-0"},
+			Labels:      make(map[string]string),
 		},
 	}
+	pods := []corev1.Pod{testPod("default", "app-0", "10.0.0.1", corev1.PodRunning, time.Now())}
 
 	egressData := map[string]map[string]uint64{
 		"10.0.0.1": {
@@ -121,92 +222,78 @@ func TestCorrelateEgress_WithPodIP(t *testing.T) {
 		},
 	}
 
-	resolver := integrations.NewRegionResolver()
-
-	CorrelateEgress(metrics, egressData, resolver)
+	CorrelateEgress(metrics, pods, egressData, integrations.NewRegionResolver())
 
-	expectedTotal := uint64(1500000)
-	if metrics[0].EgressBytes != expectedTotal {
-		t.Errorf("Expected %d egress bytes, got %d", expectedTotal, metrics[0].EgressBytes)
+	if metrics[0].EgressBytes != 1500000 {
+		t.Errorf("Expected 1500000 egress bytes, got %d", metrics[0].EgressBytes)
 	}
-
-	// Check if egress label was set
-	if egressLabel, ok := metrics[0].Labels["cloudvault.io/egress-bytes"]; !ok {
-		t.Error("Expected egress-bytes label to be set")
-	} else if egressLabel != "1500000" {
-		t.Errorf("Expected egress-bytes label '1500000', got '%s'", egressLabel)
+	if metrics[0].ExternalEgressBytes != 1000000 {
+		t.Errorf("Expected 1000000 external egress bytes, got %d", metrics[0].ExternalEgressBytes)
 	}
-}
-
-func TestCorrelateEgress_ExternalEgressDetection(t *testing.T) {
-	metrics := []types.PVCMetric{
-		{
-			Name:      "test-pvc",
-			Namespace: "default",
-			Labels: map[string]string{
-				"cloudvault.io/pod-ip": "10.0.0.1",
-			},
-		},
+	if len(metrics[0].Labels) != 0 {
+		t.Errorf("Expected labels to be left untouched, got %v", metrics[0].Labels)
 	}
 
-	egressData := map[string]map[string]uint64{
-		"10.0.0.1": {
-			"8.8.8.8": 1000000, // Public IP - should be detected as external
-		},
+	dsts := metrics[0].EgressDestinations
+	if len(dsts) != 2 {
+		t.Fatalf("Expected 2 destinations, got %d", len(dsts))
 	}
-
-	resolver := integrations.NewRegionResolver()
-
-	CorrelateEgress(metrics, egressData, resolver)
-
-	// Check if external egress was detected
-	if externalLabel, ok := metrics[0].Labels["cloudvault.io/external-egress"]; ok {
-		if externalLabel != "true" {
-			t.Errorf("Expected external-egress 'true', got '%s'", externalLabel)
-		}
+	if dsts[0].IP != "8.8.8.8" || !dsts[0].External || dsts[0].Provider != "internet" {
+		t.Errorf("Expected largest destination 8.8.8.8 (external), got %+v", dsts[0])
+	}
+	if dsts[1].IP != "10.0.0.2" || dsts[1].External {
+		t.Errorf("Expected internal destination 10.0.0.2, got %+v", dsts[1])
 	}
 }
 
-func TestCorrelateEgress_MultipleDestinations(t *testing.T) {
+func TestCorrelateEgress_MultiplePodsAndDestinations(t *testing.T) {
 	metrics := []types.PVCMetric{
 		{
-			Name:      "test-pvc",
-			Namespace: "default",
-			Labels: map[string]string{
-				"cloudvault.io/pod-ip": "10.0.0.1",
-			},
+			Name:        "test-pvc",
+			Namespace:   "default",
+			MountedPods: []string{"app-0", "app-1"},
 		},
 	}
+	now := time.Now()
+	pods := []corev1.Pod{
+		testPod("default", "app-0", "10.0.0.1", corev1.PodRunning, now),
+		testPod("default", "app-1", "10.0.0.3", corev1.PodRunning, now),
+	}
 
 	egressData := map[string]map[string]uint64{
 		"10.0.0.1": {
 			"8.8.8.8":    1000000,
 			"1.1.1.1":    2000000,
-			"52.94.0.10": 3000000, // AWS IP range
-			"10.0.0.100": 500000,  // Internal
+			"10.0.0.100": 500000, // Internal
+		},
+		"10.0.0.3": {
+			"8.8.8.8":    3000000,
+			"52.94.0.10": 1000000,
 		},
 	}
 
-	resolver := integrations.NewRegionResolver()
-
-	CorrelateEgress(metrics, egressData, resolver)
+	CorrelateEgress(metrics, pods, egressData, integrations.NewRegionResolver())
 
-	expectedTotal := uint64(6500000)
-	if metrics[0].EgressBytes != expectedTotal {
-		t.Errorf("Expected %d egress bytes, got %d", expectedTotal, metrics[0].EgressBytes)
+	if metrics[0].EgressBytes != 7500000 {
+		t.Errorf("Expected 7500000 egress bytes, got %d", metrics[0].EgressBytes)
+	}
+	if len(metrics[0].EgressDestinations) != 4 {
+		t.Fatalf("Expected 4 destinations, got %d", len(metrics[0].EgressDestinations))
+	}
+	if top := metrics[0].EgressDestinations[0]; top.IP != "8.8.8.8" || top.Bytes != 4000000 {
+		t.Errorf("Expected 8.8.8.8 with 4000000 bytes first, got %+v", top)
 	}
 }
 
 func TestCorrelateEgress_NoMatchingIP(t *testing.T) {
 	metrics := []types.PVCMetric{
 		{
-			Name:      "test-pvc",
-			Namespace: "default",
-			Labels: map[string]string{
-				"cloudvault.io/pod-ip": "10.0.0.99",
-			},
+			Name:        "test-pvc",
+			Namespace:   "default",
+			MountedPods: []string{"app-0"},
 		},
 	}
+	pods := []corev1.Pod{testPod("default", "app-0", "10.0.0.99", corev1.PodRunning, time.Now())}
 
 	egressData := map[string]map[string]uint64{
 		"10.0.0.1": {
@@ -214,9 +301,7 @@ func TestCorrelateEgress_NoMatchingIP(t *testing.T) {
 		},
 	}
 
-	resolver := integrations.NewRegionResolver()
-
-	CorrelateEgress(metrics, egressData, resolver)
+	CorrelateEgress(metrics, pods, egressData, integrations.NewRegionResolver())
 
 	// No matching IP, should remain 0
 	if metrics[0].EgressBytes != 0 {
@@ -227,18 +312,14 @@ func TestCorrelateEgress_NoMatchingIP(t *testing.T) {
 func TestCorrelateEgress_EmptyEgressData(t *testing.T) {
 	metrics := []types.PVCMetric{
 		{
-			Name:      "test-pvc",
-			Namespace: "default",
-			Labels: map[string]string{
-				"cloudvault.io/pod-ip": "10.0.0.1",
-			},
+			Name:        "test-pvc",
+			Namespace:   "default",
+			MountedPods: []string{"app-0"},
 		},
 	}
+	pods := []corev1.Pod{testPod("default", "app-0", "10.0.0.1", corev1.PodRunning, time.Now())}
 
-	egressData := map[string]map[string]uint64{}
-	resolver := integrations.NewRegionResolver()
-
-	CorrelateEgress(metrics, egressData, resolver)
+	CorrelateEgress(metrics, pods, map[string]map[string]uint64{}, integrations.NewRegionResolver())
 
 	// Empty data, should remain 0
 	if metrics[0].EgressBytes != 0 {
@@ -249,20 +330,21 @@ func TestCorrelateEgress_EmptyEgressData(t *testing.T) {
 func TestCorrelateEgress_MultiplePVCs(t *testing.T) {
 	metrics := []types.PVCMetric{
 		{
-			Name:      "pvc-1",
-			Namespace: "default",
-			Labels: map[string]string{
-				"cloudvault.io/pod-ip": "10.0.0.1",
-			},
+			Name:        "pvc-1",
+			Namespace:   "default",
+			MountedPods: []string{"app"},
 		},
 		{
-			Name:      "pvc-2",
-			Namespace: "production",
-			Labels: map[string]string{
-				"cloudvault.io/pod-ip": "10.0.0.2",
-			},
+			Name:        "pvc-2",
+			Namespace:   "production",
+			MountedPods: []string{"app"},
 		},
 	}
+	now := time.Now()
+	pods := []corev1.Pod{
+		testPod("default", "app", "10.0.0.1", corev1.PodRunning, now),
+		testPod("production", "app", "10.0.0.2", corev1.PodRunning, now),
+	}
 
 	egressData := map[string]map[string]uint64{
 		"10.0.0.1": {
@@ -273,9 +355,7 @@ func TestCorrelateEgress_MultiplePVCs(t *testing.T) {
 		},
 	}
 
-	resolver := integrations.NewRegionResolver()
-
-	CorrelateEgress(metrics, egressData, resolver)
+	CorrelateEgress(metrics, pods, egressData, integrations.NewRegionResolver())
 
 	if metrics[0].EgressBytes != 1000000 {
 		t.Errorf("PVC-1: Expected 1000000 egress bytes, got %d", metrics[0].EgressBytes)
@@ -285,3 +365,115 @@ func TestCorrelateEgress_MultiplePVCs(t *testing.T) {
 		t.Errorf("PVC-2: Expected 2000000 egress bytes, got %d", metrics[1].EgressBytes)
 	}
 }
+
+func TestCorrelateEgress_IPReusedAfterRestart(t *testing.T) {
+	metrics := []types.PVCMetric{
+		{Name: "old-pvc", Namespace: "default", MountedPods: []string{"job-old"}},
+		{Name: "new-pvc", Namespace: "default", MountedPods: []string{"app-new"}},
+	}
+	now := time.Now()
+	pods := []corev1.Pod{
+		testPod("default", "app-new", "10.0.0.1", corev1.PodRunning, now.Add(-time.Minute)),
+		testPod("default", "job-old", "10.0.0.1", corev1.PodSucceeded, now),
+	}
+
+	egressData := map[string]map[string]uint64{
+		"10.0.0.1": {"8.8.8.8": 1000},
+	}
+
+	CorrelateEgress(metrics, pods, egressData, integrations.NewRegionResolver())
+
+	if metrics[0].EgressBytes != 0 {
+		t.Errorf("Terminated pod should not receive reused IP traffic, got %d", metrics[0].EgressBytes)
+	}
+	if metrics[1].EgressBytes != 1000 {
+		t.Errorf("Running pod should own the reused IP, got %d", metrics[1].EgressBytes)
+	}
+}
+
+// mockFlowAgent also reports per-port flows
+type mockFlowAgent struct {
+	mockEgressAgent
+	flows []types.EgressFlow
+}
+
+func (m *mockFlowAgent) GetEgressFlows() ([]types.EgressFlow, error) {
+	return m.flows, nil
+}
+
+func TestEbpfEgressProvider_GetEgressFlows(t *testing.T) {
+	flows, err := NewEbpfEgressProvider(&mockEgressAgent{}).GetEgressFlows(context.Background())
+	if err != nil || flows != nil {
+		t.Errorf("Expected no flows from an agent without ports, got %v, %v", flows, err)
+	}
+
+	agent := &mockFlowAgent{flows: []types.EgressFlow{{Src: "10.0.1.5", Dst: "1.1.1.1", Protocol: "tcp", DstPort: 443, Bytes: 10}}}
+	flows, err = NewEbpfEgressProvider(agent).GetEgressFlows(context.Background())
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(flows) != 1 || flows[0].DstPort != 443 {
+		t.Errorf("Expected the agent's flows, got %v", flows)
+	}
+}
+
+func TestMergedEgressProvider_GetEgressFlows(t *testing.T) {
+	first := NewEbpfEgressProvider(&mockFlowAgent{flows: []types.EgressFlow{
+		{Src: "10.0.1.5", Dst: "1.1.1.1", Protocol: "tcp", DstPort: 443, Bytes: 10},
+		{Src: "10.0.1.5", Dst: "1.1.1.1", Protocol: "udp", DstPort: 53, Bytes: 2},
+	}})
+	second := NewEbpfEgressProvider(&mockFlowAgent{flows: []types.EgressFlow{
+		{Src: "10.0.1.5", Dst: "1.1.1.1", Protocol: "tcp", DstPort: 443, Bytes: 999},
+		{Src: "10.0.2.7", Dst: "8.8.8.8", Protocol: "tcp", DstPort: 5432, Bytes: 40},
+	}})
+	prom := &staticEgressProvider{data: map[string]map[string]uint64{"10.0.3.1": {"unknown": 1}}}
+
+	flows, err := NewMergedEgressProvider(first, prom, second).GetEgressFlows(context.Background())
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(flows) != 3 {
+		t.Fatalf("Expected 3 flows, got %v", flows)
+	}
+	for _, f := range flows {
+		if f.Src == "10.0.1.5" && f.Bytes == 999 {
+			t.Errorf("Expected 10.0.1.5 to come from the first provider only, got %v", flows)
+		}
+	}
+}
+
+func TestCorrelateEgressPorts(t *testing.T) {
+	metrics := []types.PVCMetric{
+		{Name: "data-pg-0", Namespace: "db", MountedPods: []string{"pg-0"}},
+		{Name: "idle", Namespace: "db"},
+	}
+	pods := []corev1.Pod{testPod("db", "pg-0", "10.0.0.1", corev1.PodRunning, time.Now())}
+	egressData := map[string]map[string]uint64{
+		"10.0.0.1": {"52.94.0.1": 1500, "10.0.0.2": 300},
+	}
+	flows := []types.EgressFlow{
+		{Src: "10.0.0.1", Dst: "52.94.0.1", Protocol: "tcp", DstPort: 443, Bytes: 1000},
+		{Src: "10.0.0.1", Dst: "52.94.0.1", Protocol: "tcp", DstPort: 443, SrcPort: 32768, Bytes: 200},
+		{Src: "10.0.0.1", Dst: "52.94.0.1", Protocol: "icmp", Bytes: 300},
+		{Src: "10.0.0.1", Dst: "10.0.0.2", Protocol: "tcp", DstPort: 5432, Bytes: 300},
+		{Src: "10.0.9.9", Dst: "52.94.0.1", Protocol: "tcp", DstPort: 443, Bytes: 5000},
+	}
+
+	CorrelateEgress(metrics, pods, egressData, integrations.NewRegionResolver())
+	CorrelateEgressPorts(metrics, pods, flows)
+
+	dsts := metrics[0].EgressDestinations
+	if len(dsts) != 2 || dsts[0].IP != "52.94.0.1" {
+		t.Fatalf("Unexpected destinations: %+v", dsts)
+	}
+	want := []types.EgressPort{{Protocol: "tcp", Port: 443, Bytes: 1200}, {Protocol: "icmp", Bytes: 300}}
+	if len(dsts[0].Ports) != 2 || dsts[0].Ports[0] != want[0] || dsts[0].Ports[1] != want[1] {
+		t.Errorf("Expected ports %v, got %v", want, dsts[0].Ports)
+	}
+	if len(dsts[1].Ports) != 1 || dsts[1].Ports[0].Port != 5432 {
+		t.Errorf("Expected replication port 5432, got %v", dsts[1].Ports)
+	}
+	if metrics[1].EgressDestinations != nil {
+		t.Errorf("Expected no destinations for an unmounted PVC, got %v", metrics[1].EgressDestinations)
+	}
+}
@@ -0,0 +1,86 @@
+package collector
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/cloudvault-io/cloudvault/pkg/types"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// Built-in enricher names, usable in Config.DisabledEnrichers
+const (
+	EnricherPods   = "pods"
+	EnricherUsage  = "usage"
+	EnricherIOPS   = "iops"
+	EnricherAccess = "access"
+	EnricherEgress = "egress"
+	EnricherVolume = "volume"
+	EnricherCost   = "cost"
+)
+
+// Scope selects the PVCs a collection covers. The zero value selects every namespace.
+type Scope struct {
+	Namespaces    []string
+	LabelSelector string
+}
+
+// Batch carries the PVCs of one collection cycle through the enricher chain.
+// Metrics is index-aligned with PVCs.
+type Batch struct {
+	Cluster *types.ClusterInfo
+	Scope   Scope
+	PVCs    []corev1.PersistentVolumeClaim
+	Metrics []types.PVCMetric
+
+	// Pods in scope, filled by the pods enricher for use by later enrichers
+	Pods []corev1.Pod
+}
+
+// Enricher adds one kind of data to a batch of PVC metrics. Enrichers run in order
+// and errors are non-fatal: a failed enricher leaves its fields at their zero value.
+type Enricher interface {
+	Name() string
+	Enrich(ctx context.Context, batch *Batch) error
+}
+
+// namespaces returns the namespaces to list, where "" means all namespaces
+func (s Scope) namespaces() []string {
+	if len(s.Namespaces) == 0 {
+		return []string{metav1.NamespaceAll}
+	}
+	return s.Namespaces
+}
+
+// listPVCs lists the PVCs selected by a scope
+func (k *KubernetesClient) listPVCs(ctx context.Context, scope Scope) ([]corev1.PersistentVolumeClaim, error) {
+	var pvcs []corev1.PersistentVolumeClaim
+	for _, ns := range scope.namespaces() {
+		list, err := k.clientset.CoreV1().
+			PersistentVolumeClaims(ns).
+			List(ctx, metav1.ListOptions{LabelSelector: scope.LabelSelector})
+		if err != nil {
+			if ns == metav1.NamespaceAll {
+				return nil, fmt.Errorf("failed to list PVCs: %w", err)
+			}
+			return nil, fmt.Errorf("failed to list PVCs in namespace %s: %w", ns, err)
+		}
+		pvcs = append(pvcs, list.Items...)
+	}
+	return pvcs, nil
+}
+
+// listPodsInScope lists the pods of the scope's namespaces
+func (k *KubernetesClient) listPodsInScope(ctx context.Context, scope Scope) ([]corev1.Pod, error) {
+	var pods []corev1.Pod
+	for _, ns := range scope.namespaces() {
+		list, err := k.clientset.CoreV1().Pods(ns).List(ctx, metav1.ListOptions{})
+		if err != nil {
+			return nil, fmt.Errorf("failed to list pods: %w", err)
+		}
+		pods = append(pods, list.Items...)
+	}
+	return pods, nil
+}
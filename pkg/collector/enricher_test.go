@@ -0,0 +1,194 @@
+package collector
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	storagev1 "k8s.io/api/storage/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/rest"
+)
+
+// newTestCluster serves a minimal API server with two namespaces of PVCs, their pods,
+// a bound PV, a StorageClass and one node with kubelet stats.
+func newTestCluster(t *testing.T) *KubernetesClient {
+	t.Helper()
+
+	class := "fast"
+	pvc := func(ns, name, volume string, labels map[string]string) corev1.PersistentVolumeClaim {
+		return corev1.PersistentVolumeClaim{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: ns, Labels: labels},
+			Spec: corev1.PersistentVolumeClaimSpec{
+				StorageClassName: &class,
+				VolumeName:       volume,
+				Resources: corev1.VolumeResourceRequirements{
+					Requests: corev1.ResourceList{corev1.ResourceStorage: resource.MustParse("100Gi")},
+				},
+			},
+		}
+	}
+	pod := func(ns, name, claim string) corev1.Pod {
+		return corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: ns},
+			Spec: corev1.PodSpec{Volumes: []corev1.Volume{{
+				Name:         "data",
+				VolumeSource: corev1.VolumeSource{PersistentVolumeClaim: &corev1.PersistentVolumeClaimVolumeSource{ClaimName: claim}},
+			}}},
+		}
+	}
+
+	prodPVCs := []corev1.PersistentVolumeClaim{pvc("prod", "db-data", "pv-db", map[string]string{"tier": "db"})}
+	devPVCs := []corev1.PersistentVolumeClaim{pvc("dev", "scratch", "", nil)}
+	prodPods := []corev1.Pod{pod("prod", "db-0", "db-data")}
+	devPods := []corev1.Pod{pod("dev", "tool-0", "scratch")}
+
+	list := func(kind string, items interface{}) func(w http.ResponseWriter, r *http.Request) {
+		apiVersion := "v1"
+		if kind == "StorageClassList" {
+			apiVersion = "storage.k8s.io/v1"
+		}
+		return func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "application/json")
+			_ = json.NewEncoder(w).Encode(map[string]interface{}{"kind": kind, "apiVersion": apiVersion, "items": items})
+		}
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/version", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"gitVersion":"v1.30.0"}`))
+	})
+	mux.HandleFunc("/api/v1/nodes", list("NodeList", []corev1.Node{{
+		ObjectMeta: metav1.ObjectMeta{Name: "node-a", Labels: map[string]string{
+			"eks.amazonaws.com/nodegroup":   "ng",
+			"topology.kubernetes.io/region": "us-east-1",
+		}},
+	}}))
+	mux.HandleFunc("/api/v1/nodes/node-a/proxy/stats/summary", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(testStatsSummary))
+	})
+	mux.HandleFunc("/api/v1/persistentvolumeclaims", func(w http.ResponseWriter, r *http.Request) {
+		items := append(append([]corev1.PersistentVolumeClaim{}, prodPVCs...), devPVCs...)
+		if r.URL.Query().Get("labelSelector") == "tier=db" {
+			items = prodPVCs
+		}
+		list("PersistentVolumeClaimList", items)(w, r)
+	})
+	mux.HandleFunc("/api/v1/namespaces/prod/persistentvolumeclaims", list("PersistentVolumeClaimList", prodPVCs))
+	mux.HandleFunc("/api/v1/namespaces/dev/persistentvolumeclaims", list("PersistentVolumeClaimList", devPVCs))
+	mux.HandleFunc("/api/v1/pods", list("PodList", append(append([]corev1.Pod{}, prodPods...), devPods...)))
+	mux.HandleFunc("/api/v1/namespaces/prod/pods", list("PodList", prodPods))
+	mux.HandleFunc("/api/v1/namespaces/dev/pods", list("PodList", devPods))
+	mux.HandleFunc("/api/v1/persistentvolumes", list("PersistentVolumeList", []corev1.PersistentVolume{{
+		ObjectMeta: metav1.ObjectMeta{Name: "pv-db"},
+		Spec: corev1.PersistentVolumeSpec{PersistentVolumeSource: corev1.PersistentVolumeSource{
+			CSI: &corev1.CSIPersistentVolumeSource{Driver: "ebs.csi.aws.com", VolumeHandle: "vol-0abc123"},
+		}},
+	}}))
+	mux.HandleFunc("/apis/storage.k8s.io/v1/storageclasses", list("StorageClassList", []storagev1.StorageClass{{
+		ObjectMeta:  metav1.ObjectMeta{Name: "fast"},
+		Provisioner: "ebs.csi.aws.com",
+		Parameters:  map[string]string{"type": "io2"},
+	}}))
+
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+
+	client, err := newKubernetesClientForConfig(&rest.Config{Host: server.URL})
+	require.NoError(t, err)
+	return client
+}
+
+type recordingEnricher struct {
+	seen int
+}
+
+func (e *recordingEnricher) Name() string { return "recording" }
+
+func (e *recordingEnricher) Enrich(ctx context.Context, batch *Batch) error {
+	e.seen = len(batch.Metrics)
+	return nil
+}
+
+func TestPVCCollector_CollectByNamespace_ParityWithCollectAll(t *testing.T) {
+	c := NewPVCCollector(newTestCluster(t), nil)
+	ctx := context.Background()
+
+	all, err := c.CollectAll(ctx)
+	require.NoError(t, err)
+	require.Len(t, all, 2)
+
+	scoped, err := c.CollectByNamespace(ctx, "prod")
+	require.NoError(t, err)
+	require.Len(t, scoped, 1)
+
+	for _, m := range all {
+		if m.Namespace == "prod" {
+			assert.Equal(t, m, scoped[0])
+		}
+	}
+
+	m := scoped[0]
+	assert.Equal(t, []string{"db-0"}, m.MountedPods)
+	assert.Equal(t, int64(1073741824), m.UsedBytes, "usage from kubelet stats")
+	assert.Equal(t, "io2", m.VolumeType)
+	assert.Equal(t, "vol-0abc123", m.CSIVolumeHandle)
+	assert.Greater(t, m.MonthlyCost, 0.0)
+	assert.Greater(t, m.HourlyCost, 0.0)
+}
+
+func TestPVCCollector_Collect_LabelSelector(t *testing.T) {
+	c := NewPVCCollector(newTestCluster(t), nil)
+
+	metrics, err := c.Collect(context.Background(), Scope{LabelSelector: "tier=db"})
+	require.NoError(t, err)
+	require.Len(t, metrics, 1)
+	assert.Equal(t, "db-data", metrics[0].Name)
+}
+
+func TestPVCCollector_Collect_MultipleNamespaces(t *testing.T) {
+	c := NewPVCCollector(newTestCluster(t), nil)
+
+	metrics, err := c.Collect(context.Background(), Scope{Namespaces: []string{"prod", "dev"}})
+	require.NoError(t, err)
+	assert.Len(t, metrics, 2)
+}
+
+func TestPVCCollector_DisableEnrichers_Names(t *testing.T) {
+	c := NewPVCCollector(newTestCluster(t), nil)
+	// As split from --disable-enrichers "egress, cost,"
+	require.NoError(t, c.DisableEnrichers("egress", " cost", ""))
+	for _, e := range c.Enrichers() {
+		assert.NotContains(t, []string{EnricherEgress, EnricherCost}, e.Name())
+	}
+
+	c = NewPVCCollector(newTestCluster(t), nil)
+	assert.Error(t, c.DisableEnrichers(EnricherUsage, "costs"))
+	assert.Len(t, c.Enrichers(), 7, "nothing is disabled when a name is unknown")
+}
+
+func TestPVCCollector_DisableAndAddEnrichers(t *testing.T) {
+	c := NewPVCCollector(newTestCluster(t), nil)
+	require.NoError(t, c.DisableEnrichers(EnricherUsage, EnricherCost))
+	custom := &recordingEnricher{}
+	c.AddEnricher(custom)
+
+	var names []string
+	for _, e := range c.Enrichers() {
+		names = append(names, e.Name())
+	}
+	assert.Equal(t, []string{EnricherPods, EnricherIOPS, EnricherAccess, EnricherEgress, EnricherVolume, "recording"}, names)
+
+	metrics, err := c.CollectByNamespace(context.Background(), "prod")
+	require.NoError(t, err)
+	require.Len(t, metrics, 1)
+	assert.Zero(t, metrics[0].UsedBytes)
+	assert.Zero(t, metrics[0].MonthlyCost)
+	assert.Equal(t, 1, custom.seen)
+}
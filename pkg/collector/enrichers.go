@@ -0,0 +1,339 @@
+package collector
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/cloudvault-io/cloudvault/pkg/cost"
+	"github.com/cloudvault-io/cloudvault/pkg/integrations"
+	"github.com/cloudvault-io/cloudvault/pkg/types"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// PodEnricher records which pods mount each PVC and shares the pod list with later enrichers
+type PodEnricher struct {
+	client *KubernetesClient
+}
+
+// Name implements Enricher
+func (e *PodEnricher) Name() string { return EnricherPods }
+
+// Enrich implements Enricher
+func (e *PodEnricher) Enrich(ctx context.Context, batch *Batch) error {
+	pods, err := e.client.listPodsInScope(ctx, batch.Scope)
+	if err != nil {
+		return err
+	}
+	batch.Pods = pods
+
+	pvcToPods := make(map[string][]string)
+	for _, pod := range pods {
+		for _, vol := range pod.Spec.Volumes {
+			if vol.PersistentVolumeClaim != nil {
+				key := fmt.Sprintf("%s/%s", pod.Namespace, vol.PersistentVolumeClaim.ClaimName)
+				pvcToPods[key] = append(pvcToPods[key], pod.Name)
+			}
+		}
+	}
+
+	for i := range batch.Metrics {
+		m := &batch.Metrics[i]
+		m.MountedPods = pvcToPods[fmt.Sprintf("%s/%s", m.Namespace, m.Name)]
+	}
+	return nil
+}
+
+// UsageEnricher fills used bytes from Prometheus, then kubelet stats/summary for PVCs
+// Prometheus does not report, and finally (opt-in) exec'd `du` inside mounting pods.
+type UsageEnricher struct {
+	client        *KubernetesClient
+	promClient    *integrations.PrometheusClient
+	usageProvider UsageProvider
+	execFallback  bool
+}
+
+// Name implements Enricher
+func (e *UsageEnricher) Name() string { return EnricherUsage }
+
+// Enrich implements Enricher
+func (e *UsageEnricher) Enrich(ctx context.Context, batch *Batch) error {
+	// Fetch all Prometheus metrics in ONE batch query (Phase 3 optimization)
+	var promUsage map[string]map[string]*integrations.PVCUsageMetrics
+	if e.promClient != nil {
+		var err error
+		promUsage, err = e.promClient.GetAllPVCMetrics(ctx)
+		if err != nil {
+			slog.Warn("failed to fetch Prometheus PVC usage", "error", err)
+		}
+	}
+	for i := range batch.Metrics {
+		m := &batch.Metrics[i]
+		if usage := promUsage[m.Namespace][m.Name]; usage != nil {
+			m.UsedBytes = usage.UsedBytes
+		}
+	}
+
+	// Fall back to kubelet stats/summary for PVCs Prometheus does not know about
+	if e.usageProvider != nil && missingUsage(batch.Metrics, promUsage) {
+		kubeletUsage, err := e.usageProvider.GetPVCUsage(ctx)
+		if err != nil {
+			slog.Warn("failed to fetch kubelet volume stats", "error", err)
+		}
+		for i := range batch.Metrics {
+			m := &batch.Metrics[i]
+			if usage := kubeletUsage[m.Namespace][m.Name]; usage != nil {
+				applyVolumeUsage(m, usage)
+			}
+		}
+	}
+
+	if e.execFallback {
+		e.execFallbackAll(ctx, batch)
+	}
+	return nil
+}
+
+// execFallbackAll runs du for every PVC still lacking usage, using an adaptive worker pool
+func (e *UsageEnricher) execFallbackAll(ctx context.Context, batch *Batch) {
+	var pending []int
+	for i, m := range batch.Metrics {
+		if m.UsedBytes == 0 && m.SizeBytes > 0 && len(m.MountedPods) > 0 {
+			pending = append(pending, i)
+		}
+	}
+	if len(pending) == 0 {
+		return
+	}
+
+	// Scales from 5 to 50 workers based on volume (Phase 9 optimization)
+	numWorkers := len(pending) / 20
+	if numWorkers < 5 {
+		numWorkers = 5
+	}
+	if numWorkers > 50 {
+		numWorkers = 50
+	}
+
+	jobs := make(chan int, len(pending))
+	for _, i := range pending {
+		jobs <- i
+	}
+	close(jobs)
+
+	var wg sync.WaitGroup
+	for w := 0; w < numWorkers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				if ctx.Err() != nil {
+					return
+				}
+				e.execUsedBytes(ctx, &batch.PVCs[i], &batch.Metrics[i])
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+// execUsedBytes measures usage by running du inside the first running pod that mounts the PVC
+func (e *UsageEnricher) execUsedBytes(ctx context.Context, pvc *corev1.PersistentVolumeClaim, metric *types.PVCMetric) {
+	for _, podName := range metric.MountedPods {
+		pod, err := e.client.GetClientset().CoreV1().Pods(pvc.Namespace).Get(ctx, podName, metav1.GetOptions{})
+		if err != nil || pod.Status.Phase != corev1.PodRunning {
+			continue
+		}
+
+		mountPath := findMountPath(pod, pvc.Name)
+		if mountPath == "" {
+			continue
+		}
+
+		slog.Info("Attempting GetPodVolumeUsedBytes fallback", "pvc", pvc.Name, "pod", podName, "mount", mountPath)
+		used, err := e.client.GetPodVolumeUsedBytes(pvc.Namespace, podName, mountPath)
+		if err == nil && used > 0 {
+			metric.UsedBytes = used
+			slog.Info("Successfully gathered fallback usedBytes", "pvc", pvc.Name, "used", used)
+			return
+		}
+		slog.Warn("Fallback GetPodVolumeUsedBytes failed", "pvc", pvc.Name, "error", err)
+	}
+}
+
+// IOEnricher fills average and p95 IOPS and throughput from Prometheus disk series.
+// PVCs without Prometheus data take the block I/O traced on this node instead, which
+// also gives the time of their last request.
+type IOEnricher struct {
+	promClient *integrations.PrometheusClient
+	window     time.Duration
+	blockIO    *BlockIOSource
+}
+
+// Name implements Enricher
+func (e *IOEnricher) Name() string { return EnricherIOPS }
+
+// Enrich implements Enricher
+func (e *IOEnricher) Enrich(ctx context.Context, batch *Batch) error {
+	var ioMetrics map[string]map[string]*integrations.PVCIOMetrics
+	var promErr error
+	if e.promClient != nil {
+		ioMetrics, promErr = e.promClient.GetAllPVCIOMetrics(ctx, e.window)
+	}
+	for i := range batch.Metrics {
+		m := &batch.Metrics[i]
+		if io := ioMetrics[m.Namespace][m.Name]; io != nil {
+			applyIOMetrics(m, io)
+		}
+	}
+	if e.blockIO == nil {
+		return promErr
+	}
+
+	volumes, err := e.blockIO.VolumeIO()
+	if err != nil {
+		return errors.Join(promErr, fmt.Errorf("failed to read block I/O: %w", err))
+	}
+	for i := range batch.Metrics {
+		m := &batch.Metrics[i]
+		v := volumes[batch.PVCs[i].Spec.VolumeName]
+		if v == nil {
+			continue
+		}
+		if ioMetrics[m.Namespace][m.Name] == nil {
+			applyVolumeIO(m, v)
+		}
+		// Picked up by the access enricher as the exact time of the last access
+		m.LastAccessedAt = v.LastIO
+	}
+	return promErr
+}
+
+// EgressEnricher attributes network egress to PVCs through the pods mounting them.
+// It relies on the pod list gathered by the pods enricher.
+type EgressEnricher struct {
+	provider EgressProvider
+	resolver *integrations.RegionResolver
+}
+
+// Name implements Enricher
+func (e *EgressEnricher) Name() string { return EnricherEgress }
+
+// Enrich implements Enricher
+func (e *EgressEnricher) Enrich(ctx context.Context, batch *Batch) error {
+	if e.provider == nil {
+		return nil
+	}
+	// Fetch all Egress metrics in ONE batch (Phase 9 optimization)
+	egressData, err := e.provider.GetEgressBytes(ctx)
+	if err != nil {
+		return err
+	}
+	CorrelateEgress(batch.Metrics, batch.Pods, egressData, e.resolver)
+
+	if fp, ok := e.provider.(EgressFlowProvider); ok {
+		flows, err := fp.GetEgressFlows(ctx)
+		if err != nil {
+			// Ports are a refinement; the per-destination totals above still stand
+			slog.Warn("failed to get egress flows", "error", err)
+			return nil
+		}
+		CorrelateEgressPorts(batch.Metrics, batch.Pods, flows)
+	}
+	return nil
+}
+
+// VolumeEnricher resolves the bound PV and StorageClass of each PVC
+type VolumeEnricher struct {
+	client *KubernetesClient
+}
+
+// Name implements Enricher
+func (e *VolumeEnricher) Name() string { return EnricherVolume }
+
+// Enrich implements Enricher
+func (e *VolumeEnricher) Enrich(ctx context.Context, batch *Batch) error {
+	volumes, err := listVolumes(ctx, e.client)
+	if err != nil {
+		return err
+	}
+	for i := range batch.Metrics {
+		applyVolumeDetails(&batch.Metrics[i], &batch.PVCs[i], volumes)
+	}
+	return nil
+}
+
+// CostEnricher prices each PVC for the cluster's provider
+type CostEnricher struct {
+	calculator *cost.Calculator
+}
+
+// Name implements Enricher
+func (e *CostEnricher) Name() string { return EnricherCost }
+
+// Enrich implements Enricher
+func (e *CostEnricher) Enrich(ctx context.Context, batch *Batch) error {
+	for i := range batch.Metrics {
+		m := &batch.Metrics[i]
+		monthly := e.calculator.CalculatePVCCost(m, batch.Cluster.Provider)
+		m.HourlyCost = monthly / (24 * 30)
+	}
+	return nil
+}
+
+// missingUsage reports whether any metric lacks a Prometheus usage sample
+func missingUsage(metrics []types.PVCMetric, promUsage map[string]map[string]*integrations.PVCUsageMetrics) bool {
+	for _, m := range metrics {
+		if promUsage[m.Namespace][m.Name] == nil {
+			return true
+		}
+	}
+	return false
+}
+
+// applyIOMetrics copies Prometheus disk rates into the metric
+func applyIOMetrics(metric *types.PVCMetric, io *integrations.PVCIOMetrics) {
+	metric.ReadIOPS = io.ReadIOPS
+	metric.WriteIOPS = io.WriteIOPS
+	metric.ReadThroughput = io.ReadThroughput
+	metric.WriteThroughput = io.WriteThroughput
+	metric.ReadIOPSP95 = io.ReadIOPSP95
+	metric.WriteIOPSP95 = io.WriteIOPSP95
+	metric.ReadThroughputP95 = io.ReadThroughputP95
+	metric.WriteThroughputP95 = io.WriteThroughputP95
+	metric.ReadBytesTotal = io.ReadBytesTotal
+	metric.WriteBytesTotal = io.WriteBytesTotal
+}
+
+// applyVolumeUsage copies kubelet filesystem stats into the metric.
+// Prometheus usage, when present, takes precedence over the kubelet sample.
+func applyVolumeUsage(metric *types.PVCMetric, usage *VolumeUsage) {
+	if metric.UsedBytes == 0 {
+		metric.UsedBytes = usage.UsedBytes
+	}
+	metric.CapacityBytes = usage.CapacityBytes
+	metric.Inodes = usage.Inodes
+	metric.InodesUsed = usage.InodesUsed
+}
+
+// findMountPath returns the path at which a pod's containers mount the given claim
+func findMountPath(pod *corev1.Pod, claimName string) string {
+	for _, vol := range pod.Spec.Volumes {
+		if vol.PersistentVolumeClaim == nil || vol.PersistentVolumeClaim.ClaimName != claimName {
+			continue
+		}
+		for _, container := range pod.Spec.Containers {
+			for _, mount := range container.VolumeMounts {
+				if mount.Name == vol.Name {
+					return mount.MountPath
+				}
+			}
+		}
+	}
+	return ""
+}
@@ -0,0 +1,193 @@
+package collector
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"sort"
+	"sync"
+
+	"github.com/cloudvault-io/cloudvault/pkg/types"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+// EphemeralCollector reports the node disk pods use outside of PVCs: disk-backed emptyDir
+// volumes, container writable layers and logs. That space lives on the node's root volume,
+// which is paid for with the node rather than as a PVC.
+type EphemeralCollector struct {
+	client *KubernetesClient
+	stats  *KubeletStatsProvider
+}
+
+// NewEphemeralCollector creates a collector that reads usage from the kubelet stats/summary endpoint
+func NewEphemeralCollector(client *KubernetesClient) *EphemeralCollector {
+	return &EphemeralCollector{
+		client: client,
+		stats:  NewKubeletStatsProvider(client),
+	}
+}
+
+// podDiskUsage is one pod's entry in a stats summary
+type podDiskUsage struct {
+	namespace, pod, node string
+	ephemeral            int64 // kubelet's own total, 0 on kubelets that do not report it
+	containers           int64
+	volumes              map[string]int64
+	nodeCapacity         int64
+}
+
+// Collect returns the ephemeral storage use of every running pod selected by scope
+func (c *EphemeralCollector) Collect(ctx context.Context, scope Scope) ([]types.EphemeralStorageMetric, error) {
+	if c.client == nil {
+		return nil, fmt.Errorf("kubernetes client not initialized")
+	}
+
+	clusterInfo, err := c.client.GetClusterInfo(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get cluster info: %w", err)
+	}
+
+	pods, err := c.client.listPodsInScope(ctx, scope)
+	if err != nil {
+		return nil, err
+	}
+	specs := make(map[string]*corev1.Pod, len(pods))
+	for i := range pods {
+		specs[pods[i].Namespace+"/"+pods[i].Name] = &pods[i]
+	}
+
+	var usage []podDiskUsage
+	var mu sync.Mutex
+	err = c.stats.forEachNode(ctx, func(nodeName string, data []byte) {
+		parsed, err := parseEphemeralUsage(data, nodeName)
+		if err != nil {
+			slog.Warn("failed to parse kubelet stats summary", "node", nodeName, "error", err)
+			return
+		}
+		mu.Lock()
+		usage = append(usage, parsed...)
+		mu.Unlock()
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// Pods outside the scope are dropped, but still share their node's disk
+	metrics := make([]types.EphemeralStorageMetric, 0, len(usage))
+	nodeWeights := make(map[string]int64)
+	for _, u := range usage {
+		pod, ok := specs[u.namespace+"/"+u.pod]
+		if !ok {
+			nodeWeights[u.node] += u.usedBytes()
+			continue
+		}
+		m := ephemeralMetric(u, pod, clusterInfo.ID)
+		nodeWeights[u.node] += m.Weight()
+		metrics = append(metrics, m)
+	}
+	for i := range metrics {
+		metrics[i].NodeWeightBytes = nodeWeights[metrics[i].Node]
+	}
+
+	sort.Slice(metrics, func(i, j int) bool {
+		if metrics[i].Namespace != metrics[j].Namespace {
+			return metrics[i].Namespace < metrics[j].Namespace
+		}
+		return metrics[i].Pod < metrics[j].Pod
+	})
+	return metrics, nil
+}
+
+// usedBytes is the pod's usage without its spec: the kubelet's total, or else every
+// volume outside a PVC counted as disk
+func (u podDiskUsage) usedBytes() int64 {
+	if u.ephemeral > 0 {
+		return u.ephemeral
+	}
+	used := u.containers
+	for _, bytes := range u.volumes {
+		used += bytes
+	}
+	return used
+}
+
+// ephemeralMetric combines a pod's kubelet usage with its spec
+func ephemeralMetric(u podDiskUsage, pod *corev1.Pod, clusterID string) types.EphemeralStorageMetric {
+	m := types.EphemeralStorageMetric{
+		Namespace:         u.namespace,
+		Pod:               u.pod,
+		Node:              u.node,
+		ClusterID:         clusterID,
+		ContainerBytes:    u.containers,
+		NodeCapacityBytes: u.nodeCapacity,
+	}
+
+	// Only disk-backed emptyDirs count; memory-backed ones are tmpfs and
+	// configMap/secret/projected volumes are negligible
+	for _, vol := range pod.Spec.Volumes {
+		if vol.EmptyDir != nil && vol.EmptyDir.Medium != corev1.StorageMediumMemory {
+			m.EmptyDirBytes += u.volumes[vol.Name]
+		}
+	}
+
+	m.UsedBytes = u.ephemeral
+	if m.UsedBytes == 0 {
+		m.UsedBytes = m.EmptyDirBytes + m.ContainerBytes
+	}
+
+	for _, container := range pod.Spec.Containers {
+		if q, ok := container.Resources.Requests[corev1.ResourceEphemeralStorage]; ok {
+			m.RequestBytes += q.Value()
+		}
+		if q, ok := container.Resources.Limits[corev1.ResourceEphemeralStorage]; ok {
+			m.LimitBytes += q.Value()
+		}
+	}
+	return m
+}
+
+// parseEphemeralUsage extracts per-pod node disk usage from a kubelet stats/summary payload
+func parseEphemeralUsage(data []byte, nodeName string) ([]podDiskUsage, error) {
+	var summary statsSummary
+	if err := json.Unmarshal(data, &summary); err != nil {
+		return nil, err
+	}
+	if summary.Node.NodeName != "" {
+		nodeName = summary.Node.NodeName
+	}
+	var capacity int64
+	if summary.Node.Fs != nil {
+		capacity = uint64Value(summary.Node.Fs.CapacityBytes)
+	}
+
+	result := make([]podDiskUsage, 0, len(summary.Pods))
+	for _, pod := range summary.Pods {
+		u := podDiskUsage{
+			namespace:    pod.PodRef.Namespace,
+			pod:          pod.PodRef.Name,
+			node:         nodeName,
+			volumes:      make(map[string]int64),
+			nodeCapacity: capacity,
+		}
+		if pod.EphemeralStorage != nil {
+			u.ephemeral = uint64Value(pod.EphemeralStorage.UsedBytes)
+		}
+		for _, c := range pod.Containers {
+			if c.Rootfs != nil {
+				u.containers += uint64Value(c.Rootfs.UsedBytes)
+			}
+			if c.Logs != nil {
+				u.containers += uint64Value(c.Logs.UsedBytes)
+			}
+		}
+		for _, vol := range pod.Volume {
+			if vol.PVCRef == nil {
+				u.volumes[vol.Name] = uint64Value(vol.UsedBytes)
+			}
+		}
+		result = append(result, u)
+	}
+	return result, nil
+}
@@ -0,0 +1,121 @@
+package collector
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/rest"
+)
+
+const testEphemeralSummary = `{
+  "node": {"nodeName": "node-a", "fs": {"capacityBytes": 107374182400, "usedBytes": 42949672960}},
+  "pods": [
+    {
+      "podRef": {"name": "builder-0", "namespace": "ci"},
+      "containers": [
+        {"name": "build", "rootfs": {"usedBytes": 1000}, "logs": {"usedBytes": 24}}
+      ],
+      "volume": [
+        {"name": "scratch", "usedBytes": 8589934592},
+        {"name": "cache", "usedBytes": 4096},
+        {"name": "kube-api-access", "usedBytes": 12},
+        {"name": "data", "usedBytes": 999, "pvcRef": {"name": "db-data", "namespace": "ci"}}
+      ],
+      "ephemeral-storage": {"usedBytes": 8589936000}
+    },
+    {
+      "podRef": {"name": "legacy-0", "namespace": "ci"},
+      "containers": [{"name": "app", "rootfs": {"usedBytes": 500}}],
+      "volume": [{"name": "tmp", "usedBytes": 1500}]
+    },
+    {
+      "podRef": {"name": "other", "namespace": "kube-system"},
+      "containers": [{"name": "x", "rootfs": {"usedBytes": 1}}]
+    }
+  ]
+}`
+
+func TestParseEphemeralUsage(t *testing.T) {
+	usage, err := parseEphemeralUsage([]byte(testEphemeralSummary), "fallback")
+	require.NoError(t, err)
+	require.Len(t, usage, 3)
+
+	u := usage[0]
+	assert.Equal(t, "node-a", u.node)
+	assert.Equal(t, int64(107374182400), u.nodeCapacity)
+	assert.Equal(t, int64(8589936000), u.ephemeral)
+	assert.Equal(t, int64(1024), u.containers)
+	assert.Equal(t, int64(8589934592), u.volumes["scratch"])
+	assert.NotContains(t, u.volumes, "data", "PVC volumes are not ephemeral")
+}
+
+func TestEphemeralCollector_Collect(t *testing.T) {
+	emptyDir := func(name string, medium corev1.StorageMedium) corev1.Volume {
+		return corev1.Volume{Name: name, VolumeSource: corev1.VolumeSource{EmptyDir: &corev1.EmptyDirVolumeSource{Medium: medium}}}
+	}
+	pods := []corev1.Pod{
+		{
+			ObjectMeta: metav1.ObjectMeta{Name: "builder-0", Namespace: "ci"},
+			Spec: corev1.PodSpec{
+				Volumes: []corev1.Volume{emptyDir("scratch", ""), emptyDir("cache", corev1.StorageMediumMemory)},
+				Containers: []corev1.Container{{
+					Name: "build",
+					Resources: corev1.ResourceRequirements{
+						Requests: corev1.ResourceList{corev1.ResourceEphemeralStorage: resource.MustParse("2Gi")},
+						Limits:   corev1.ResourceList{corev1.ResourceEphemeralStorage: resource.MustParse("20Gi")},
+					},
+				}},
+			},
+		},
+		{
+			ObjectMeta: metav1.ObjectMeta{Name: "legacy-0", Namespace: "ci"},
+			Spec:       corev1.PodSpec{Volumes: []corev1.Volume{emptyDir("tmp", "")}},
+		},
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/version", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"gitVersion":"v1.30.0"}`))
+	})
+	mux.HandleFunc("/api/v1/nodes", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"kind":"NodeList","apiVersion":"v1","items":[{"metadata":{"name":"node-a"}}]}`))
+	})
+	mux.HandleFunc("/api/v1/nodes/node-a/proxy/stats/summary", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(testEphemeralSummary))
+	})
+	mux.HandleFunc("/api/v1/namespaces/ci/pods", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{"kind": "PodList", "apiVersion": "v1", "items": pods})
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	client, err := newKubernetesClientForConfig(&rest.Config{Host: server.URL})
+	require.NoError(t, err)
+
+	metrics, err := NewEphemeralCollector(client).Collect(context.Background(), Scope{Namespaces: []string{"ci"}})
+	require.NoError(t, err)
+	require.Len(t, metrics, 2, "pods outside the scope are dropped")
+
+	builder := metrics[0]
+	assert.Equal(t, "builder-0", builder.Pod)
+	assert.Equal(t, int64(8589936000), builder.UsedBytes)
+	assert.Equal(t, int64(8589934592), builder.EmptyDirBytes, "memory-backed emptyDir excluded")
+	assert.Equal(t, int64(1024), builder.ContainerBytes)
+	assert.Equal(t, int64(2*1024*1024*1024), builder.RequestBytes)
+	assert.Equal(t, int64(20*1024*1024*1024), builder.LimitBytes)
+	assert.Equal(t, int64(107374182400), builder.NodeCapacityBytes)
+
+	legacy := metrics[1]
+	assert.Equal(t, int64(2000), legacy.UsedBytes, "summed when the kubelet reports no total")
+	assert.Equal(t, int64(8589936000+2000+1), legacy.NodeWeightBytes, "pods outside the scope share the node")
+}
@@ -0,0 +1,207 @@
+package collector
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+// defaultStatsConcurrency bounds the number of in-flight stats/summary requests
+const defaultStatsConcurrency = 10
+
+// VolumeUsage holds filesystem statistics for a single PVC as reported by the kubelet
+type VolumeUsage struct {
+	Node           string
+	UsedBytes      int64
+	CapacityBytes  int64
+	AvailableBytes int64
+	Inodes         int64
+	InodesUsed     int64
+	InodesFree     int64
+	Time           time.Time
+}
+
+// UsageProvider supplies filesystem usage for mounted PVCs, keyed by namespace and PVC name
+type UsageProvider interface {
+	GetPVCUsage(ctx context.Context) (map[string]map[string]*VolumeUsage, error)
+}
+
+// statsSummary is the subset of the kubelet stats/summary response we consume
+type statsSummary struct {
+	Node struct {
+		NodeName string   `json:"nodeName"`
+		Fs       *fsStats `json:"fs"`
+	} `json:"node"`
+	Pods []struct {
+		PodRef struct {
+			Name      string `json:"name"`
+			Namespace string `json:"namespace"`
+		} `json:"podRef"`
+		Containers []struct {
+			Name   string   `json:"name"`
+			Rootfs *fsStats `json:"rootfs"`
+			Logs   *fsStats `json:"logs"`
+		} `json:"containers"`
+		EphemeralStorage *fsStats `json:"ephemeral-storage"`
+		Volume           []struct {
+			Name           string    `json:"name"`
+			Time           time.Time `json:"time"`
+			UsedBytes      *uint64   `json:"usedBytes"`
+			CapacityBytes  *uint64   `json:"capacityBytes"`
+			AvailableBytes *uint64   `json:"availableBytes"`
+			Inodes         *uint64   `json:"inodes"`
+			InodesUsed     *uint64   `json:"inodesUsed"`
+			InodesFree     *uint64   `json:"inodesFree"`
+			PVCRef         *struct {
+				Name      string `json:"name"`
+				Namespace string `json:"namespace"`
+			} `json:"pvcRef"`
+		} `json:"volume"`
+	} `json:"pods"`
+}
+
+// fsStats is a kubelet filesystem usage sample
+type fsStats struct {
+	UsedBytes     *uint64 `json:"usedBytes"`
+	CapacityBytes *uint64 `json:"capacityBytes"`
+}
+
+// KubeletStatsProvider reads PVC usage from the kubelet stats/summary endpoint of every node.
+// It needs neither Prometheus nor exec access into application containers.
+type KubeletStatsProvider struct {
+	client      *KubernetesClient
+	concurrency int
+}
+
+// NewKubeletStatsProvider creates a provider that queries nodes through the API server proxy
+func NewKubeletStatsProvider(client *KubernetesClient) *KubeletStatsProvider {
+	return &KubeletStatsProvider{
+		client:      client,
+		concurrency: defaultStatsConcurrency,
+	}
+}
+
+// SetConcurrency sets the maximum number of nodes queried in parallel
+func (p *KubeletStatsProvider) SetConcurrency(n int) {
+	if n > 0 {
+		p.concurrency = n
+	}
+}
+
+// GetPVCUsage fetches the stats summary of all nodes and returns the usage of every PVC found
+func (p *KubeletStatsProvider) GetPVCUsage(ctx context.Context) (map[string]map[string]*VolumeUsage, error) {
+	if p.client == nil {
+		return nil, fmt.Errorf("kubernetes client not initialized")
+	}
+
+	result := make(map[string]map[string]*VolumeUsage)
+	var mu sync.Mutex
+	err := p.forEachNode(ctx, func(nodeName string, data []byte) {
+		usage, err := parseStatsSummary(data, nodeName)
+		if err != nil {
+			slog.Warn("failed to parse kubelet stats summary", "node", nodeName, "error", err)
+			return
+		}
+
+		mu.Lock()
+		mergeVolumeUsage(result, usage)
+		mu.Unlock()
+	})
+	return result, err
+}
+
+// forEachNode fetches the stats summary of every node, at most p.concurrency at a time,
+// and passes it to fn. fn is called concurrently; nodes that fail are logged and skipped.
+func (p *KubeletStatsProvider) forEachNode(ctx context.Context, fn func(nodeName string, data []byte)) error {
+	nodes, err := p.client.ListNodes(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to list nodes: %w", err)
+	}
+
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, p.concurrency)
+
+	for _, node := range nodes.Items {
+		wg.Add(1)
+		go func(nodeName string) {
+			defer wg.Done()
+
+			select {
+			case sem <- struct{}{}:
+				defer func() { <-sem }()
+			case <-ctx.Done():
+				return
+			}
+
+			data, err := p.client.GetNodeStatsSummary(ctx, nodeName)
+			if err != nil {
+				slog.Warn("failed to fetch kubelet stats summary", "node", nodeName, "error", err)
+				return
+			}
+			fn(nodeName, data)
+		}(node.Name)
+	}
+
+	wg.Wait()
+	return ctx.Err()
+}
+
+// parseStatsSummary extracts per-PVC volume stats from a kubelet stats/summary payload
+func parseStatsSummary(data []byte, nodeName string) (map[string]map[string]*VolumeUsage, error) {
+	var summary statsSummary
+	if err := json.Unmarshal(data, &summary); err != nil {
+		return nil, err
+	}
+	if summary.Node.NodeName != "" {
+		nodeName = summary.Node.NodeName
+	}
+
+	result := make(map[string]map[string]*VolumeUsage)
+	for _, pod := range summary.Pods {
+		for _, vol := range pod.Volume {
+			if vol.PVCRef == nil {
+				continue
+			}
+			usage := &VolumeUsage{
+				Node:           nodeName,
+				UsedBytes:      uint64Value(vol.UsedBytes),
+				CapacityBytes:  uint64Value(vol.CapacityBytes),
+				AvailableBytes: uint64Value(vol.AvailableBytes),
+				Inodes:         uint64Value(vol.Inodes),
+				InodesUsed:     uint64Value(vol.InodesUsed),
+				InodesFree:     uint64Value(vol.InodesFree),
+				Time:           vol.Time,
+			}
+			mergeVolumeUsage(result, map[string]map[string]*VolumeUsage{
+				vol.PVCRef.Namespace: {vol.PVCRef.Name: usage},
+			})
+		}
+	}
+	return result, nil
+}
+
+// mergeVolumeUsage folds src into dst. A PVC mounted by several pods (or nodes, for RWX volumes)
+// is reported once per mount; the most recent sample wins.
+func mergeVolumeUsage(dst, src map[string]map[string]*VolumeUsage) {
+	for ns, pvcs := range src {
+		if dst[ns] == nil {
+			dst[ns] = make(map[string]*VolumeUsage)
+		}
+		for name, usage := range pvcs {
+			if existing, ok := dst[ns][name]; ok && existing.Time.After(usage.Time) {
+				continue
+			}
+			dst[ns][name] = usage
+		}
+	}
+}
+
+func uint64Value(v *uint64) int64 {
+	if v == nil {
+		return 0
+	}
+	return int64(*v)
+}
@@ -0,0 +1,108 @@
+package collector
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"k8s.io/client-go/rest"
+)
+
+const testStatsSummary = `{
+  "node": {"nodeName": "node-a"},
+  "pods": [
+    {
+      "podRef": {"name": "db-0", "namespace": "prod"},
+      "volume": [
+        {
+          "name": "data",
+          "time": "2024-01-01T00:00:10Z",
+          "usedBytes": 1073741824,
+          "capacityBytes": 10737418240,
+          "availableBytes": 9663676416,
+          "inodes": 655360,
+          "inodesUsed": 1200,
+          "inodesFree": 654160,
+          "pvcRef": {"name": "db-data", "namespace": "prod"}
+        },
+        {
+          "name": "kube-api-access",
+          "usedBytes": 4096
+        }
+      ]
+    },
+    {
+      "podRef": {"name": "db-backup", "namespace": "prod"},
+      "volume": [
+        {
+          "name": "data",
+          "time": "2024-01-01T00:00:00Z",
+          "usedBytes": 1,
+          "pvcRef": {"name": "db-data", "namespace": "prod"}
+        }
+      ]
+    }
+  ]
+}`
+
+func TestParseStatsSummary(t *testing.T) {
+	usage, err := parseStatsSummary([]byte(testStatsSummary), "fallback")
+	require.NoError(t, err)
+
+	require.Len(t, usage, 1)
+	require.Len(t, usage["prod"], 1)
+
+	u := usage["prod"]["db-data"]
+	require.NotNil(t, u)
+	assert.Equal(t, "node-a", u.Node)
+	assert.Equal(t, int64(1073741824), u.UsedBytes, "most recent sample should win")
+	assert.Equal(t, int64(10737418240), u.CapacityBytes)
+	assert.Equal(t, int64(9663676416), u.AvailableBytes)
+	assert.Equal(t, int64(655360), u.Inodes)
+	assert.Equal(t, int64(1200), u.InodesUsed)
+	assert.Equal(t, int64(654160), u.InodesFree)
+}
+
+func TestParseStatsSummary_Invalid(t *testing.T) {
+	_, err := parseStatsSummary([]byte("not json"), "node-a")
+	assert.Error(t, err)
+}
+
+func TestKubeletStatsProvider_GetPVCUsage(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/v1/nodes", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"kind":"NodeList","apiVersion":"v1","items":[
+			{"metadata":{"name":"node-a"}},
+			{"metadata":{"name":"node-b"}}
+		]}`))
+	})
+	mux.HandleFunc("/api/v1/nodes/node-a/proxy/stats/summary", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(testStatsSummary))
+	})
+	mux.HandleFunc("/api/v1/nodes/node-b/proxy/stats/summary", func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "kubelet unreachable", http.StatusServiceUnavailable)
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	client, err := newKubernetesClientForConfig(&rest.Config{Host: server.URL})
+	require.NoError(t, err)
+
+	provider := NewKubeletStatsProvider(client)
+	provider.SetConcurrency(1)
+
+	usage, err := provider.GetPVCUsage(context.Background())
+	require.NoError(t, err)
+	require.NotNil(t, usage["prod"]["db-data"])
+	assert.Equal(t, int64(1073741824), usage["prod"]["db-data"].UsedBytes)
+}
+
+func TestKubeletStatsProvider_NilClient(t *testing.T) {
+	provider := NewKubeletStatsProvider(nil)
+	_, err := provider.GetPVCUsage(context.Background())
+	assert.Error(t, err)
+}
@@ -12,6 +12,7 @@ import (
 
 	"github.com/cloudvault-io/cloudvault/pkg/types"
 
+	apimeta "k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/runtime/schema"
@@ -22,6 +23,7 @@ import (
 
 	"github.com/cloudvault-io/cloudvault/pkg/types/apis/v1alpha1"
 	corev1 "k8s.io/api/core/v1"
+	storagev1 "k8s.io/api/storage/v1"
 	"k8s.io/client-go/informers"
 	corelisters "k8s.io/client-go/listers/core/v1"
 	"k8s.io/client-go/tools/cache"
@@ -30,12 +32,15 @@ import (
 
 // KubernetesClient wraps the Kubernetes clientset with CloudVault-specific logic
 type KubernetesClient struct {
-	clientset *kubernetes.Clientset
-	dynamic   dynamic.Interface
-	config    *rest.Config
-	factory   informers.SharedInformerFactory
-	podLister corelisters.PodLister
-	podSynced cache.InformerSynced
+	clientset  *kubernetes.Clientset
+	dynamic    dynamic.Interface
+	config     *rest.Config
+	factory    informers.SharedInformerFactory
+	podLister  corelisters.PodLister
+	podIndexer cache.Indexer
+	podSynced  cache.InformerSynced
+	// informersOnce lets the agent and its dashboard both ask for the informers
+	informersOnce sync.Once
 
 	// Cache for cluster info
 	clusterInfo      *types.ClusterInfo
@@ -67,6 +72,11 @@ func NewKubernetesClient(kubeconfig string) (*KubernetesClient, error) {
 	config.QPS = 25
 	config.Burst = 50
 
+	return newKubernetesClientForConfig(config)
+}
+
+// newKubernetesClientForConfig builds the clientset, dynamic client and informers for a rest.Config
+func newKubernetesClientForConfig(config *rest.Config) (*KubernetesClient, error) {
 	// Create clientset
 	clientset, err := kubernetes.NewForConfig(config)
 	if err != nil {
@@ -82,26 +92,81 @@ func NewKubernetesClient(kubeconfig string) (*KubernetesClient, error) {
 	// Initialize Informer Factory
 	factory := informers.NewSharedInformerFactory(clientset, 10*time.Minute)
 	podInformer := factory.Core().V1().Pods()
+	if err := podInformer.Informer().AddIndexers(cache.Indexers{podUIDIndex: indexPodUID}); err != nil {
+		return nil, fmt.Errorf("failed to index pods by UID: %w", err)
+	}
 
 	return &KubernetesClient{
-		clientset: clientset,
-		dynamic:   dynClient,
-		config:    config,
-		factory:   factory,
-		podLister: podInformer.Lister(),
-		podSynced: podInformer.Informer().HasSynced,
+		clientset:  clientset,
+		dynamic:    dynClient,
+		config:     config,
+		factory:    factory,
+		podLister:  podInformer.Lister(),
+		podIndexer: podInformer.Informer().GetIndexer(),
+		podSynced:  podInformer.Informer().HasSynced,
 	}, nil
 }
 
-// StartInformers starts the background caching workers.
+// podUIDIndex names the informer index of pods by UID
+const podUIDIndex = "uid"
+
+func indexPodUID(obj interface{}) ([]string, error) {
+	pod, ok := obj.(*corev1.Pod)
+	if !ok {
+		return nil, nil
+	}
+	return []string{string(pod.UID)}, nil
+}
+
+// PodByUID returns a pod from the informer cache. It finds nothing until
+// StartInformers has run.
+func (k *KubernetesClient) PodByUID(uid string) (*corev1.Pod, bool) {
+	if k.podIndexer == nil {
+		return nil, false
+	}
+	objs, err := k.podIndexer.ByIndex(podUIDIndex, uid)
+	if err != nil || len(objs) == 0 {
+		return nil, false
+	}
+	pod, ok := objs[0].(*corev1.Pod)
+	return pod, ok
+}
+
+// StartInformers starts the background caching workers and waits for the pod cache.
+// Only the first call starts them; later calls return once it has.
 func (k *KubernetesClient) StartInformers(ctx context.Context) {
-	k.factory.Start(ctx.Done())
-	slog.Info("Kubernetes informers started")
-	if !cache.WaitForCacheSync(ctx.Done(), k.podSynced) {
-		slog.Error("Failed to sync Kubernetes pod cache")
-	} else {
-		slog.Info("Kubernetes pod cache synced")
+	k.informersOnce.Do(func() {
+		k.factory.Start(ctx.Done())
+		slog.Info("Kubernetes informers started")
+		if !cache.WaitForCacheSync(ctx.Done(), k.podSynced) {
+			slog.Error("Failed to sync Kubernetes pod cache")
+		} else {
+			slog.Info("Kubernetes pod cache synced")
+		}
+	})
+}
+
+// OnDelete calls onPod and onPVC with the namespace and name of pods and PVCs deleted
+// from the cluster. Handlers must be added before StartInformers.
+func (k *KubernetesClient) OnDelete(onPod, onPVC func(namespace, name string)) error {
+	handler := func(notify func(namespace, name string)) cache.ResourceEventHandlerFuncs {
+		return cache.ResourceEventHandlerFuncs{DeleteFunc: func(obj interface{}) {
+			// Deletions missed while disconnected arrive as tombstones
+			if tombstone, ok := obj.(cache.DeletedFinalStateUnknown); ok {
+				obj = tombstone.Obj
+			}
+			if meta, err := apimeta.Accessor(obj); err == nil {
+				notify(meta.GetNamespace(), meta.GetName())
+			}
+		}}
+	}
+	if _, err := k.factory.Core().V1().Pods().Informer().AddEventHandler(handler(onPod)); err != nil {
+		return fmt.Errorf("failed to watch pod deletions: %w", err)
 	}
+	if _, err := k.factory.Core().V1().PersistentVolumeClaims().Informer().AddEventHandler(handler(onPVC)); err != nil {
+		return fmt.Errorf("failed to watch PVC deletions: %w", err)
+	}
+	return nil
 }
 
 // GetClusterInfo retrieves cluster metadata including cloud provider detection
@@ -334,6 +399,36 @@ func (k *KubernetesClient) ListPods(ctx context.Context) (*corev1.PodList, error
 	return k.clientset.CoreV1().Pods("").List(ctx, metav1.ListOptions{})
 }
 
+// ListNodes fetches all nodes in the cluster
+func (k *KubernetesClient) ListNodes(ctx context.Context) (*corev1.NodeList, error) {
+	return k.clientset.CoreV1().Nodes().List(ctx, metav1.ListOptions{})
+}
+
+// ListServices returns all services in the cluster
+func (k *KubernetesClient) ListServices(ctx context.Context) (*corev1.ServiceList, error) {
+	return k.clientset.CoreV1().Services("").List(ctx, metav1.ListOptions{})
+}
+
+// ListPersistentVolumes fetches all PersistentVolumes in the cluster
+func (k *KubernetesClient) ListPersistentVolumes(ctx context.Context) (*corev1.PersistentVolumeList, error) {
+	return k.clientset.CoreV1().PersistentVolumes().List(ctx, metav1.ListOptions{})
+}
+
+// ListStorageClasses fetches all StorageClasses in the cluster
+func (k *KubernetesClient) ListStorageClasses(ctx context.Context) (*storagev1.StorageClassList, error) {
+	return k.clientset.StorageV1().StorageClasses().List(ctx, metav1.ListOptions{})
+}
+
+// GetNodeStatsSummary fetches the kubelet stats summary of a node through the API server proxy
+func (k *KubernetesClient) GetNodeStatsSummary(ctx context.Context, nodeName string) ([]byte, error) {
+	return k.clientset.CoreV1().RESTClient().Get().
+		Resource("nodes").
+		Name(nodeName).
+		SubResource("proxy").
+		Suffix("stats/summary").
+		DoRaw(ctx)
+}
+
 // GetPodVolumeUsedBytes tries to determine volume usage safely bypassing prometheus dependencies
 func (k *KubernetesClient) GetPodVolumeUsedBytes(namespace, podName, mountPath string) (int64, error) {
 	req := k.clientset.CoreV1().RESTClient().Post().
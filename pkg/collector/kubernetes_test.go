@@ -1,9 +1,17 @@
 package collector
 
 import (
+	"context"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/kubernetes/fake"
 )
 
 func TestDetectCloudProvider(t *testing.T) {
@@ -73,3 +81,36 @@ func TestKubernetesClient_Getters(t *testing.T) {
 	assert.Nil(t, k.GetConfig())
 	assert.Nil(t, k.GetDynamicClient())
 }
+
+func TestKubernetesClient_OnDelete(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	clientset := fake.NewSimpleClientset(
+		&corev1.Pod{ObjectMeta: metav1.ObjectMeta{Namespace: "prod", Name: "api"}},
+		&corev1.PersistentVolumeClaim{ObjectMeta: metav1.ObjectMeta{Namespace: "prod", Name: "data"}},
+	)
+	factory := informers.NewSharedInformerFactory(clientset, 0)
+	k := &KubernetesClient{factory: factory}
+
+	var mu sync.Mutex
+	var deleted []string
+	record := func(kind string) func(namespace, name string) {
+		return func(namespace, name string) {
+			mu.Lock()
+			defer mu.Unlock()
+			deleted = append(deleted, kind+":"+namespace+"/"+name)
+		}
+	}
+	require.NoError(t, k.OnDelete(record("pod"), record("pvc")))
+	factory.Start(ctx.Done())
+	factory.WaitForCacheSync(ctx.Done())
+
+	require.NoError(t, clientset.CoreV1().Pods("prod").Delete(ctx, "api", metav1.DeleteOptions{}))
+	require.NoError(t, clientset.CoreV1().PersistentVolumeClaims("prod").Delete(ctx, "data", metav1.DeleteOptions{}))
+	assert.Eventually(t, func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		return assert.ObjectsAreEqual([]string{"pod:prod/api", "pvc:prod/data"}, deleted) ||
+			assert.ObjectsAreEqual([]string{"pvc:prod/data", "pod:prod/api"}, deleted)
+	}, 5*time.Second, 10*time.Millisecond)
+}
@@ -0,0 +1,109 @@
+package collector
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/cloudvault-io/cloudvault/pkg/types"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+// ListPodPlacements describes where every running or pending pod runs, for the SIG
+func (k *KubernetesClient) ListPodPlacements(ctx context.Context) ([]types.PodPlacement, error) {
+	pods, err := k.ListPods(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list pods: %w", err)
+	}
+	nodes, err := k.ListNodes(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list nodes: %w", err)
+	}
+	services, err := k.ListServices(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list services: %w", err)
+	}
+	return PodPlacements(pods.Items, nodes.Items, services.Items), nil
+}
+
+// PodPlacements joins pods with the topology labels of their nodes and the services
+// selecting them. Pods that have finished are left out.
+func PodPlacements(pods []corev1.Pod, nodes []corev1.Node, services []corev1.Service) []types.PodPlacement {
+	byName := make(map[string]*corev1.Node, len(nodes))
+	for i := range nodes {
+		byName[nodes[i].Name] = &nodes[i]
+	}
+	selectors := make(map[string][]*corev1.Service)
+	for i := range services {
+		// Services without a selector have their endpoints managed by hand
+		if svc := &services[i]; len(svc.Spec.Selector) > 0 {
+			selectors[svc.Namespace] = append(selectors[svc.Namespace], svc)
+		}
+	}
+
+	var placements []types.PodPlacement
+	for i := range pods {
+		pod := &pods[i]
+		if pod.Status.Phase == corev1.PodSucceeded || pod.Status.Phase == corev1.PodFailed {
+			continue
+		}
+		p := types.PodPlacement{
+			Name:      pod.Name,
+			Namespace: pod.Namespace,
+			Node:      pod.Spec.NodeName,
+			Workload:  podWorkload(pod),
+		}
+		if node := byName[pod.Spec.NodeName]; node != nil {
+			p.Zone = nodeZone(node.Labels)
+			provider, region := detectCloudProvider(node.Labels)
+			if provider != "unknown" {
+				p.Provider = provider
+			}
+			if region != "unknown" {
+				p.Region = region
+			}
+		}
+		for _, vol := range pod.Spec.Volumes {
+			if vol.PersistentVolumeClaim != nil {
+				p.PVCs = append(p.PVCs, vol.PersistentVolumeClaim.ClaimName)
+			}
+		}
+		for _, svc := range selectors[pod.Namespace] {
+			if labels.SelectorFromSet(svc.Spec.Selector).Matches(labels.Set(pod.Labels)) {
+				p.Services = append(p.Services, svc.Name)
+			}
+		}
+		placements = append(placements, p)
+	}
+	return placements
+}
+
+// nodeZone reads a node's zone from its topology labels
+func nodeZone(labels map[string]string) string {
+	for _, key := range zoneLabels {
+		if zone, ok := labels[key]; ok {
+			return zone
+		}
+	}
+	return ""
+}
+
+// podWorkload returns the controller owning a pod. The ReplicaSets of a Deployment are
+// named after it and the pod template hash, so they resolve without an API call.
+func podWorkload(pod *corev1.Pod) types.Workload {
+	owner := metav1.GetControllerOf(pod)
+	if owner == nil {
+		return types.Workload{}
+	}
+	if owner.Kind == "ReplicaSet" {
+		if hash := pod.Labels["pod-template-hash"]; hash != "" {
+			if name, ok := strings.CutSuffix(owner.Name, "-"+hash); ok {
+				return types.Workload{Kind: "Deployment", Name: name}
+			}
+		}
+	}
+	return types.Workload{Kind: owner.Kind, Name: owner.Name}
+}
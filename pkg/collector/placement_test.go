@@ -0,0 +1,72 @@
+package collector
+
+import (
+	"testing"
+
+	"github.com/cloudvault-io/cloudvault/pkg/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func ownedPod(name, node, ownerKind, ownerName string, labels map[string]string) corev1.Pod {
+	controller := true
+	pod := corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "prod", Labels: labels},
+		Spec:       corev1.PodSpec{NodeName: node},
+		Status:     corev1.PodStatus{Phase: corev1.PodRunning},
+	}
+	if ownerKind != "" {
+		pod.OwnerReferences = []metav1.OwnerReference{{Kind: ownerKind, Name: ownerName, Controller: &controller}}
+	}
+	return pod
+}
+
+func TestPodPlacements(t *testing.T) {
+	nodes := []corev1.Node{{ObjectMeta: metav1.ObjectMeta{Name: "ip-10-0-1-5", Labels: map[string]string{
+		"eks.amazonaws.com/nodegroup":   "default",
+		"topology.kubernetes.io/region": "us-east-1",
+		"topology.kubernetes.io/zone":   "us-east-1a",
+	}}}}
+
+	api := ownedPod("api-7d9f8-x2x", "ip-10-0-1-5", "ReplicaSet", "api-7d9f8", map[string]string{"pod-template-hash": "7d9f8", "app": "api"})
+	api.Spec.Volumes = []corev1.Volume{
+		{Name: "data", VolumeSource: corev1.VolumeSource{PersistentVolumeClaim: &corev1.PersistentVolumeClaimVolumeSource{ClaimName: "api-data"}}},
+		{Name: "tmp", VolumeSource: corev1.VolumeSource{EmptyDir: &corev1.EmptyDirVolumeSource{}}},
+	}
+	db := ownedPod("db-0", "ip-10-0-9-9", "StatefulSet", "db", nil)
+	orphanRS := ownedPod("legacy-abc", "", "ReplicaSet", "legacy", nil)
+	bare := ownedPod("debug", "ip-10-0-1-5", "", "", nil)
+	done := ownedPod("migrate-xyz", "ip-10-0-1-5", "Job", "migrate", nil)
+	done.Status.Phase = corev1.PodSucceeded
+
+	services := []corev1.Service{
+		{ObjectMeta: metav1.ObjectMeta{Name: "api", Namespace: "prod"}, Spec: corev1.ServiceSpec{Selector: map[string]string{"app": "api"}}},
+		{ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "prod"}, Spec: corev1.ServiceSpec{Selector: map[string]string{"app": "web"}}},
+		{ObjectMeta: metav1.ObjectMeta{Name: "api", Namespace: "staging"}, Spec: corev1.ServiceSpec{Selector: map[string]string{"app": "api"}}},
+		{ObjectMeta: metav1.ObjectMeta{Name: "external", Namespace: "prod"}},
+	}
+
+	placements := PodPlacements([]corev1.Pod{api, db, orphanRS, bare, done}, nodes, services)
+	require.Len(t, placements, 4, "finished pods are left out")
+
+	assert.Equal(t, types.PodPlacement{
+		Name:      "api-7d9f8-x2x",
+		Namespace: "prod",
+		Node:      "ip-10-0-1-5",
+		Zone:      "us-east-1a",
+		Region:    "us-east-1",
+		Provider:  "aws",
+		Workload:  types.Workload{Kind: "Deployment", Name: "api"},
+		PVCs:      []string{"api-data"},
+		Services:  []string{"api"},
+	}, placements[0])
+	assert.Equal(t, types.Workload{Kind: "StatefulSet", Name: "db"}, placements[1].Workload)
+	assert.Empty(t, placements[1].Zone, "unknown nodes leave the topology empty")
+	assert.Empty(t, placements[1].Provider)
+	assert.Equal(t, types.Workload{Kind: "ReplicaSet", Name: "legacy"}, placements[2].Workload,
+		"ReplicaSets without a template hash are kept")
+	assert.Equal(t, types.Workload{}, placements[3].Workload)
+	assert.Empty(t, placements[3].Services, "services without a selector select nothing")
+}
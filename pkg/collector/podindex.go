@@ -0,0 +1,101 @@
+package collector
+
+import (
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+// PodRef identifies the pod that owns an IP address
+type PodRef struct {
+	Namespace string
+	Name      string
+	Node      string
+}
+
+// PodIPIndex maps pod IPs to the pod currently holding them.
+//
+// Host-network pods are not indexed: they share the node's IP with the kubelet and every
+// other host-network pod, so traffic from that IP cannot be attributed to any one of them.
+// When an IP was reused (a terminated pod still listed next to its replacement), running
+// pods win over terminated ones and, within the same state, the most recently started pod wins.
+type PodIPIndex struct {
+	byIP  map[string]indexedPod
+	byPod map[string][]string // "namespace/name" -> IPs
+}
+
+type indexedPod struct {
+	ref     PodRef
+	running bool
+	started time.Time
+}
+
+// NewPodIPIndex builds an index from a pod list
+func NewPodIPIndex(pods []corev1.Pod) *PodIPIndex {
+	idx := &PodIPIndex{byIP: make(map[string]indexedPod)}
+
+	for i := range pods {
+		pod := &pods[i]
+		if pod.Spec.HostNetwork {
+			continue
+		}
+
+		candidate := indexedPod{
+			ref:     PodRef{Namespace: pod.Namespace, Name: pod.Name, Node: pod.Spec.NodeName},
+			running: pod.Status.Phase == corev1.PodRunning && pod.DeletionTimestamp == nil,
+			started: pod.CreationTimestamp.Time,
+		}
+		if pod.Status.StartTime != nil {
+			candidate.started = pod.Status.StartTime.Time
+		}
+
+		for _, ip := range podIPs(pod) {
+			if existing, ok := idx.byIP[ip]; ok && !candidate.supersedes(existing) {
+				continue
+			}
+			idx.byIP[ip] = candidate
+		}
+	}
+
+	idx.byPod = make(map[string][]string)
+	for ip, p := range idx.byIP {
+		key := p.ref.Namespace + "/" + p.ref.Name
+		idx.byPod[key] = append(idx.byPod[key], ip)
+	}
+	return idx
+}
+
+// Lookup returns the pod holding an IP
+func (idx *PodIPIndex) Lookup(ip string) (PodRef, bool) {
+	p, ok := idx.byIP[ip]
+	return p.ref, ok
+}
+
+// IPs returns the addresses currently attributed to a pod
+func (idx *PodIPIndex) IPs(namespace, name string) []string {
+	return idx.byPod[namespace+"/"+name]
+}
+
+// supersedes reports whether p should replace the current holder of an IP
+func (p indexedPod) supersedes(current indexedPod) bool {
+	if p.running != current.running {
+		return p.running
+	}
+	return p.started.After(current.started)
+}
+
+// podIPs returns all (dual-stack) addresses of a pod
+func podIPs(pod *corev1.Pod) []string {
+	var ips []string
+	seen := make(map[string]bool)
+	for _, ip := range pod.Status.PodIPs {
+		if ip.IP != "" && !seen[ip.IP] {
+			seen[ip.IP] = true
+			ips = append(ips, ip.IP)
+		}
+	}
+	if pod.Status.PodIP != "" && !seen[pod.Status.PodIP] {
+		ips = append(ips, pod.Status.PodIP)
+	}
+	return ips
+}
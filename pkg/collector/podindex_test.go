@@ -0,0 +1,48 @@
+package collector
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestPodIPIndex_HostNetworkExcluded(t *testing.T) {
+	hostPod := testPod("kube-system", "node-agent", "192.168.1.10", corev1.PodRunning, time.Now())
+	hostPod.Spec.HostNetwork = true
+
+	idx := NewPodIPIndex([]corev1.Pod{hostPod})
+
+	_, ok := idx.Lookup("192.168.1.10")
+	assert.False(t, ok)
+	assert.Empty(t, idx.IPs("kube-system", "node-agent"))
+}
+
+func TestPodIPIndex_DualStack(t *testing.T) {
+	pod := testPod("default", "app", "10.0.0.1", corev1.PodRunning, time.Now())
+	pod.Spec.NodeName = "node-a"
+	pod.Status.PodIPs = []corev1.PodIP{{IP: "10.0.0.1"}, {IP: "fd00::1"}}
+
+	idx := NewPodIPIndex([]corev1.Pod{pod})
+
+	ref, ok := idx.Lookup("fd00::1")
+	assert.True(t, ok)
+	assert.Equal(t, PodRef{Namespace: "default", Name: "app", Node: "node-a"}, ref)
+	assert.ElementsMatch(t, []string{"10.0.0.1", "fd00::1"}, idx.IPs("default", "app"))
+}
+
+func TestPodIPIndex_ReusePrefersNewestRunning(t *testing.T) {
+	now := time.Now()
+	older := testPod("default", "older", "10.0.0.1", corev1.PodRunning, now.Add(-time.Hour))
+	newer := testPod("default", "newer", "10.0.0.1", corev1.PodRunning, now)
+	terminating := testPod("default", "terminating", "10.0.0.1", corev1.PodRunning, now.Add(time.Minute))
+	terminating.DeletionTimestamp = &metav1.Time{Time: now}
+
+	idx := NewPodIPIndex([]corev1.Pod{newer, terminating, older})
+
+	ref, ok := idx.Lookup("10.0.0.1")
+	assert.True(t, ok)
+	assert.Equal(t, "newer", ref.Name)
+}
@@ -4,8 +4,10 @@ import (
 	"context"
 	"fmt"
 	"log/slog"
+	"strings"
 	"time"
 
+	"github.com/cloudvault-io/cloudvault/pkg/cost"
 	"github.com/cloudvault-io/cloudvault/pkg/integrations"
 	"github.com/cloudvault-io/cloudvault/pkg/types"
 
@@ -20,23 +22,47 @@ type Collector interface {
 }
 
 // PVCCollector handles the collection of PersistentVolumeClaim metrics from a Kubernetes cluster.
-// It supports both full cluster collection and namespace-scoped collection.
+// Every collection lists the PVCs of a Scope and passes them through the same ordered
+// chain of enrichers, so cluster-wide and namespace-scoped collection report identical data.
 type PVCCollector struct {
 	client         *KubernetesClient
 	promClient     *integrations.PrometheusClient
 	egressProvider EgressProvider
+	usageProvider  UsageProvider
 	regionResolver *integrations.RegionResolver
+	calculator     *cost.Calculator
+	ioWindow       time.Duration
+	blockIO        *BlockIOSource
+
+	// execFallback enables `du` inside application containers when no other usage source has data
+	execFallback bool
+
+	// access remembers counters between cycles to derive last access times; nil until
+	// a store is set, since state that does not outlive the collector would report every
+	// PVC as accessed when the collector started
+	access *AccessTracker
+
+	extraEnrichers []Enricher
+	disabled       map[string]bool
 }
 
 // NewPVCCollector creates a new instance of PVCCollector.
 // client: An initialized KubernetesClient for API interaction.
 // promClient: Optional PrometheusClient for fetching real-time usage metrics.
+// Usage falls back to the kubelet stats/summary endpoint when Prometheus has no data for a PVC.
 func NewPVCCollector(client *KubernetesClient, promClient *integrations.PrometheusClient) *PVCCollector {
-	return &PVCCollector{
+	c := &PVCCollector{
 		client:         client,
 		promClient:     promClient,
 		regionResolver: integrations.NewRegionResolver(),
+		calculator:     cost.NewCalculator(),
+		ioWindow:       integrations.DefaultIOWindow,
+		disabled:       make(map[string]bool),
+	}
+	if client != nil {
+		c.usageProvider = NewKubeletStatsProvider(client)
 	}
+	return c
 }
 
 // SetEgressProvider injects a network monitoring source
@@ -44,242 +70,170 @@ func (c *PVCCollector) SetEgressProvider(p EgressProvider) {
 	c.egressProvider = p
 }
 
-// CollectAll collects metrics for all PVCs in the cluster using concurrent workers.
-// Refactored in Phase 3 to use batch Prometheus queries and background patterns.
-func (c *PVCCollector) CollectAll(ctx context.Context) ([]types.PVCMetric, error) {
-	if c.client == nil {
-		return nil, fmt.Errorf("kubernetes client not initialized")
-	}
-	// Get cluster info first (needed for all metrics)
-	clusterInfo, err := c.client.GetClusterInfo(ctx)
-	if err != nil {
-		return nil, fmt.Errorf("failed to get cluster info: %w", err)
-	}
+// SetBlockIOSource adds block I/O traced on this node as an I/O source for PVCs that
+// Prometheus has no data for
+func (c *PVCCollector) SetBlockIOSource(s *BlockIOSource) {
+	c.blockIO = s
+}
 
-	// List all PVCs across all namespaces
-	pvcs, err := c.client.clientset.CoreV1().
-		PersistentVolumeClaims("").
-		List(ctx, metav1.ListOptions{})
+// SetUsageProvider replaces the fallback usage source (kubelet stats/summary by default)
+func (c *PVCCollector) SetUsageProvider(p UsageProvider) {
+	c.usageProvider = p
+}
 
-	if err != nil {
-		return nil, fmt.Errorf("failed to list PVCs: %w", err)
-	}
+// SetAccessStore tracks last access across cycles in store, so idle time survives
+// restarts. Without a store or reader, LastAccessedAt is only set by sources that see
+// individual requests.
+func (c *PVCCollector) SetAccessStore(store AccessStore) {
+	c.access = NewAccessTracker(store)
+}
 
-	// Fetch all pods for SIG (Storage Intelligence Graph) Pillar (Phase 4)
-	pods, err := c.client.ListPods(ctx)
-	if err != nil {
-		slog.Warn("failed to list pods for intelligence graph", "error", err)
-	}
+// SetAccessReader reports the last access the agent tracks in store, without updating it
+func (c *PVCCollector) SetAccessReader(store AccessStore) {
+	c.access = NewAccessReader(store)
+}
 
-	pvcToPods := make(map[string][]string)
-	if pods != nil {
-		for _, pod := range pods.Items {
-			for _, vol := range pod.Spec.Volumes {
-				if vol.PersistentVolumeClaim != nil {
-					key := fmt.Sprintf("%s/%s", pod.Namespace, vol.PersistentVolumeClaim.ClaimName)
-					pvcToPods[key] = append(pvcToPods[key], pod.Name)
-				}
-			}
-		}
+// SetIOWindow sets the look-back window used for average and p95 I/O rates. Windows
+// shorter than integrations.MinIOWindow are ignored.
+func (c *PVCCollector) SetIOWindow(window time.Duration) {
+	if window >= integrations.MinIOWindow {
+		c.ioWindow = window
 	}
+}
 
-	// Fetch all Prometheus metrics in ONE batch query (Phase 3 optimization)
-	var batchMetrics map[string]map[string]*integrations.PVCUsageMetrics
-	if c.promClient != nil {
-		batchMetrics, _ = c.promClient.GetAllPVCMetrics(ctx)
-	}
+// SetExecFallback enables running `du` inside mounting pods as a last-resort usage source.
+// It is disabled by default: it is slow, needs `du` in the image and requires pods/exec RBAC.
+func (c *PVCCollector) SetExecFallback(enabled bool) {
+	c.execFallback = enabled
+}
 
-	// Fetch all Egress metrics in ONE batch (Phase 9 optimization)
-	var egressData map[string]map[string]uint64
-	if c.egressProvider != nil {
-		egressData, _ = c.egressProvider.GetEgressBytes(ctx)
+// SetCalculator replaces the calculator used by the cost enricher
+func (c *PVCCollector) SetCalculator(calc *cost.Calculator) {
+	if calc != nil {
+		c.calculator = calc
 	}
+}
+
+// AddEnricher appends a custom enricher after the built-in chain
+func (c *PVCCollector) AddEnricher(e Enricher) {
+	c.extraEnrichers = append(c.extraEnrichers, e)
+}
 
-	// Adaptive Worker pool settings (Phase 9 optimization)
-	numPVCs := len(pvcs.Items)
-	numWorkers := 10
-	if numPVCs > 0 {
-		// Scales from 5 to 50 workers based on volume
-		numWorkers = numPVCs / 20
-		if numWorkers < 5 {
-			numWorkers = 5
+// DisableEnrichers removes enrichers from the chain by name. Names are trimmed and empty
+// ones skipped; a name no enricher of the chain has is an error, and nothing is disabled.
+func (c *PVCCollector) DisableEnrichers(names ...string) error {
+	known := make(map[string]bool)
+	for _, e := range c.chain() {
+		known[e.Name()] = true
+	}
+	var disable []string
+	for _, name := range names {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
 		}
-		if numWorkers > 50 {
-			numWorkers = 50
+		if !known[name] {
+			return fmt.Errorf("unknown enricher %q", name)
 		}
+		disable = append(disable, name)
 	}
-
-	if numPVCs == 0 {
-		return []types.PVCMetric{}, nil
-	}
-
-	// Channels for jobs and results
-	jobs := make(chan *corev1.PersistentVolumeClaim, numPVCs)
-	results := make(chan *types.PVCMetric, numPVCs)
-	// errors := make(chan error, numPVCs) // No longer needed for this impl
-
-	// Start workers
-	for i := 0; i < numWorkers; i++ {
-		go func() {
-			for pvc := range jobs {
-				select {
-				case <-ctx.Done():
-					return
-				default:
-				}
-
-				metric := c.initializePVCMetric(pvc, clusterInfo)
-
-				// Apply SIG data (Phase 4 Pillar 1)
-				key := fmt.Sprintf("%s/%s", pvc.Namespace, pvc.Name)
-				if pods, ok := pvcToPods[key]; ok {
-					metric.MountedPods = pods
-				}
-
-				// Apply Prometheus data from batch if available
-				if batchMetrics != nil && batchMetrics[pvc.Namespace] != nil && batchMetrics[pvc.Namespace][pvc.Name] != nil {
-					metric.UsedBytes = batchMetrics[pvc.Namespace][pvc.Name].UsedBytes
-					metric.LastAccessedAt = batchMetrics[pvc.Namespace][pvc.Name].LastActivity
-				}
-
-				slog.Info("PVC Check", "name", pvc.Name, "size", metric.SizeBytes, "mounted", len(metric.MountedPods), "prometheusUsed", metric.UsedBytes)
-
-				// Fallback for missing prometheus metrics by executing exactly against pods
-				if metric.UsedBytes == 0 && metric.SizeBytes > 0 && len(metric.MountedPods) > 0 {
-					for _, podName := range metric.MountedPods {
-						pod, err := c.client.GetClientset().CoreV1().Pods(pvc.Namespace).Get(ctx, podName, metav1.GetOptions{})
-						if err == nil && pod.Status.Phase == corev1.PodRunning {
-							// Find the mount path
-							mountPath := ""
-							for _, vol := range pod.Spec.Volumes {
-								if vol.PersistentVolumeClaim != nil && vol.PersistentVolumeClaim.ClaimName == pvc.Name {
-									for _, container := range pod.Spec.Containers {
-										for _, mount := range container.VolumeMounts {
-											if mount.Name == vol.Name {
-												mountPath = mount.MountPath
-												break
-											}
-										}
-										if mountPath != "" {
-											break
-										}
-									}
-								}
-								if mountPath != "" {
-									break
-								}
-							}
-							if mountPath != "" {
-								slog.Info("Attempting GetPodVolumeUsedBytes fallback", "pvc", pvc.Name, "pod", podName, "mount", mountPath)
-								used, err := c.client.GetPodVolumeUsedBytes(pvc.Namespace, podName, mountPath)
-								if err == nil && used > 0 {
-									metric.UsedBytes = used
-									metric.LastAccessedAt = time.Now()
-									slog.Info("Successfully gathered fallback usedBytes", "pvc", pvc.Name, "used", used)
-									break
-								} else {
-									slog.Warn("Fallback GetPodVolumeUsedBytes failed", "pvc", pvc.Name, "error", err)
-								}
-							}
-						}
-					}
-				}
-
-				// Apply hyper-accurate egress data from pre-fetched batch (Phase 9 optimization)
-				if egressData != nil {
-					CorrelateEgress([]types.PVCMetric{*metric}, egressData, c.regionResolver)
-				}
-
-				results <- metric
-			}
-		}()
+	for _, name := range disable {
+		c.disabled[name] = true
 	}
+	return nil
+}
 
-	// Enqueue jobs
-	for i := range pvcs.Items {
-		jobs <- &pvcs.Items[i]
-	}
-	close(jobs)
-
-	// Collect results
-	var metrics []types.PVCMetric
-	for i := 0; i < numPVCs; i++ {
-		select {
-		case metric := <-results:
-			metrics = append(metrics, *metric)
-		case <-ctx.Done():
-			return nil, ctx.Err()
+// Enrichers returns the effective chain: pods, usage, IOPS, egress, PV details and cost,
+// followed by any custom enrichers, minus those disabled
+func (c *PVCCollector) Enrichers() []Enricher {
+	chain := c.chain()
+	enabled := chain[:0]
+	for _, e := range chain {
+		if !c.disabled[e.Name()] {
+			enabled = append(enabled, e)
 		}
 	}
+	return enabled
+}
 
-	slog.Info("Collection complete", "count", len(metrics), "numPVCs", numPVCs)
-	return metrics, nil
+// chain returns every enricher, built-in and custom, in order
+func (c *PVCCollector) chain() []Enricher {
+	chain := []Enricher{
+		&PodEnricher{client: c.client},
+		&UsageEnricher{client: c.client, promClient: c.promClient, usageProvider: c.usageProvider, execFallback: c.execFallback},
+		&IOEnricher{promClient: c.promClient, window: c.ioWindow, blockIO: c.blockIO},
+		&AccessEnricher{tracker: c.access},
+		&EgressEnricher{provider: c.egressProvider, resolver: c.regionResolver},
+		&VolumeEnricher{client: c.client},
+		&CostEnricher{calculator: c.calculator},
+	}
+	return append(chain, c.extraEnrichers...)
 }
 
-// CollectByNamespace collects metrics for PVCs in a specific namespace using a worker pool.
+// CollectAll collects metrics for all PVCs in the cluster.
+func (c *PVCCollector) CollectAll(ctx context.Context) ([]types.PVCMetric, error) {
+	return c.Collect(ctx, Scope{})
+}
+
+// CollectByNamespace collects metrics for PVCs in a specific namespace.
 func (c *PVCCollector) CollectByNamespace(ctx context.Context, namespace string) ([]types.PVCMetric, error) {
+	return c.Collect(ctx, Scope{Namespaces: []string{namespace}})
+}
+
+// Collect lists the PVCs selected by scope and runs them through the enricher chain.
+// Enricher failures are logged and counted but do not fail the collection.
+func (c *PVCCollector) Collect(ctx context.Context, scope Scope) ([]types.PVCMetric, error) {
+	batch, err := c.collect(ctx, scope)
+	if err != nil {
+		return nil, err
+	}
+	return batch.Metrics, nil
+}
+
+// collect runs a collection and returns the whole batch, including the listed objects
+func (c *PVCCollector) collect(ctx context.Context, scope Scope) (*Batch, error) {
+	if c.client == nil {
+		return nil, fmt.Errorf("kubernetes client not initialized")
+	}
+	// Get cluster info first (needed for all metrics)
 	clusterInfo, err := c.client.GetClusterInfo(ctx)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get cluster info: %w", err)
 	}
 
-	pvcs, err := c.client.clientset.CoreV1().
-		PersistentVolumeClaims(namespace).
-		List(ctx, metav1.ListOptions{})
-
+	pvcs, err := c.client.listPVCs(ctx, scope)
 	if err != nil {
-		return nil, fmt.Errorf("failed to list PVCs in namespace %s: %w", namespace, err)
+		return nil, err
 	}
-
-	numPVCs := len(pvcs.Items)
-	if numPVCs == 0 {
-		return []types.PVCMetric{}, nil
+	if len(pvcs) == 0 {
+		return &Batch{Cluster: clusterInfo, Scope: scope, Metrics: []types.PVCMetric{}}, nil
 	}
 
-	// For single namespace, we use a smaller pool (max 10)
-	numWorkers := numPVCs / 2
-	if numWorkers < 2 {
-		numWorkers = 2
+	batch := &Batch{
+		Cluster: clusterInfo,
+		Scope:   scope,
+		PVCs:    pvcs,
+		Metrics: make([]types.PVCMetric, len(pvcs)),
 	}
-	if numWorkers > 10 {
-		numWorkers = 10
+	for i := range pvcs {
+		batch.Metrics[i] = *c.initializePVCMetric(&pvcs[i], clusterInfo)
 	}
 
-	jobs := make(chan *corev1.PersistentVolumeClaim, numPVCs)
-	results := make(chan *types.PVCMetric, numPVCs)
-
-	for i := 0; i < numWorkers; i++ {
-		go func() {
-			for pvc := range jobs {
-				select {
-				case <-ctx.Done():
-					return
-				default:
-				}
-				metric := c.initializePVCMetric(pvc, clusterInfo)
-				// Note: For namespace-scoped collection, we might skip some cross-ns lookups
-				// if we wanted to be faster, but here we maintain full parity with CollectAll logic
-				results <- metric
-			}
-		}()
-	}
-
-	for i := range pvcs.Items {
-		jobs <- &pvcs.Items[i]
-	}
-	close(jobs)
-
-	var metrics []types.PVCMetric
-	for i := 0; i < numPVCs; i++ {
-		select {
-		case metric := <-results:
-			metrics = append(metrics, *metric)
-		case <-ctx.Done():
+	for _, e := range c.Enrichers() {
+		start := time.Now()
+		err := e.Enrich(ctx, batch)
+		integrations.EnricherDuration.WithLabelValues(e.Name()).Observe(time.Since(start).Seconds())
+		if err != nil {
+			integrations.EnricherErrors.WithLabelValues(e.Name()).Inc()
+			slog.Warn("enricher failed", "enricher", e.Name(), "error", err)
+		}
+		if ctx.Err() != nil {
 			return nil, ctx.Err()
 		}
 	}
 
-	return metrics, nil
+	slog.Info("Collection complete", "count", len(batch.Metrics))
+	return batch, nil
 }
 
 // initializePVCMetric creates a base metric from PVC spec
@@ -23,6 +23,16 @@ func TestNewPVCCollector(t *testing.T) {
 	}
 }
 
+func TestPVCCollector_SetIOWindow(t *testing.T) {
+	collector := NewPVCCollector(nil, nil)
+	collector.SetIOWindow(30 * time.Minute)
+	collector.SetIOWindow(30 * time.Second)
+	collector.SetIOWindow(0)
+	if collector.ioWindow != 30*time.Minute {
+		t.Errorf("Expected windows under a minute to be ignored, got %s", collector.ioWindow)
+	}
+}
+
 func TestCalculateBasicCost(t *testing.T) {
 	tests := []struct {
 		name         string
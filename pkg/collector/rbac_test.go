@@ -0,0 +1,78 @@
+package collector
+
+import (
+	"os"
+	"regexp"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v3"
+)
+
+// chartRBAC is the Helm template granting the agent's ClusterRole
+const chartRBAC = "../../deploy/charts/cloudvault/templates/rbac.yaml"
+
+type policyRule struct {
+	APIGroups []string `yaml:"apiGroups"`
+	Resources []string `yaml:"resources"`
+	Verbs     []string `yaml:"verbs"`
+}
+
+// loadChartClusterRole returns the rules of the chart's ClusterRole. Template actions are
+// blanked out, which leaves valid YAML for the RBAC objects.
+func loadChartClusterRole(t *testing.T) []policyRule {
+	data, err := os.ReadFile(chartRBAC)
+	require.NoError(t, err)
+	plain := regexp.MustCompile(`\{\{[^}]*\}\}`).ReplaceAllString(string(data), "placeholder")
+
+	for _, doc := range strings.Split(plain, "\n---") {
+		var obj struct {
+			Kind  string       `yaml:"kind"`
+			Rules []policyRule `yaml:"rules"`
+		}
+		require.NoError(t, yaml.Unmarshal([]byte(doc), &obj))
+		if obj.Kind == "ClusterRole" {
+			return obj.Rules
+		}
+	}
+	t.Fatalf("no ClusterRole in %s", chartRBAC)
+	return nil
+}
+
+func allows(rules []policyRule, group, resource, verb string) bool {
+	for _, r := range rules {
+		if slices.Contains(r.APIGroups, group) && slices.Contains(r.Resources, resource) && slices.Contains(r.Verbs, verb) {
+			return true
+		}
+	}
+	return false
+}
+
+// TestChartRBAC_CoversCollector fails when the chart's ClusterRole misses a permission the
+// collector's API calls and informers need; the agent would only see 403s in-cluster
+func TestChartRBAC_CoversCollector(t *testing.T) {
+	rules := loadChartClusterRole(t)
+
+	needed := []struct {
+		group, resource string
+		verbs           []string
+	}{
+		{"", "pods", []string{"get", "list", "watch"}},
+		{"", "persistentvolumeclaims", []string{"get", "list", "watch"}},
+		{"", "persistentvolumes", []string{"get", "list", "watch"}},
+		{"", "nodes", []string{"get", "list", "watch"}},
+		{"", "services", []string{"get", "list", "watch"}},
+		{"", "nodes/proxy", []string{"get"}},
+		{"storage.k8s.io", "storageclasses", []string{"get", "list", "watch"}},
+		{"metrics.k8s.io", "pods", []string{"get", "list"}},
+	}
+	for _, n := range needed {
+		for _, verb := range n.verbs {
+			if !allows(rules, n.group, n.resource, verb) {
+				t.Errorf("chart ClusterRole does not allow %s on %q resources %q", verb, n.group, n.resource)
+			}
+		}
+	}
+}
@@ -0,0 +1,227 @@
+package collector
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"time"
+
+	"github.com/cloudvault-io/cloudvault/pkg/types"
+
+	corev1 "k8s.io/api/core/v1"
+	storagev1 "k8s.io/api/storage/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// SnapshotVersion is the snapshot format written by this build. Readers accept
+// any version up to it; fields are only ever added within a version.
+const SnapshotVersion = 1
+
+// Snapshot is a point-in-time copy of everything CloudVault reads from a cluster,
+// so a cluster can be analyzed later without access to it
+type Snapshot struct {
+	Version    int               `json:"version"`
+	CreatedAt  time.Time         `json:"created_at"`
+	Cluster    types.ClusterInfo `json:"cluster"`
+	Namespaces []string          `json:"namespaces,omitempty"` // Empty when all namespaces were collected
+
+	// Kubernetes objects as listed, without managed fields
+	PVCs           []corev1.PersistentVolumeClaim `json:"pvcs"`
+	PVs            []corev1.PersistentVolume      `json:"pvs"`
+	StorageClasses []storagev1.StorageClass       `json:"storage_classes"`
+	PodMounts      []PodMount                     `json:"pod_mounts"`
+
+	// Enriched metrics, carrying usage, I/O and egress as collected
+	Metrics   []types.PVCMetric              `json:"metrics"`
+	Ephemeral []types.EphemeralStorageMetric `json:"ephemeral,omitempty"`
+}
+
+// PodMount is a pod mounting a PVC
+type PodMount struct {
+	Namespace string `json:"namespace"`
+	Pod       string `json:"pod"`
+	Node      string `json:"node"`
+	PVC       string `json:"pvc"`
+	Volume    string `json:"volume"`
+	ReadOnly  bool   `json:"read_only"`
+}
+
+// Snapshot runs a collection over scope and captures its input objects alongside the
+// resulting metrics. PV, StorageClass and ephemeral storage failures are logged and
+// leave those sections empty.
+func (c *PVCCollector) Snapshot(ctx context.Context, scope Scope) (*Snapshot, error) {
+	batch, err := c.collect(ctx, scope)
+	if err != nil {
+		return nil, err
+	}
+
+	snap := &Snapshot{
+		Version:    SnapshotVersion,
+		CreatedAt:  time.Now().UTC(),
+		Cluster:    *batch.Cluster,
+		Namespaces: scope.Namespaces,
+		PVCs:       batch.PVCs,
+		PodMounts:  podMounts(batch.Pods),
+		Metrics:    batch.Metrics,
+	}
+	for i := range snap.PVCs {
+		stripObjectMeta(&snap.PVCs[i].ObjectMeta)
+	}
+
+	if pvs, err := c.client.ListPersistentVolumes(ctx); err != nil {
+		slog.Warn("failed to list PersistentVolumes for snapshot", "error", err)
+	} else {
+		snap.PVs = boundPVs(pvs.Items, snap.PVCs, len(scope.Namespaces) == 0)
+		for i := range snap.PVs {
+			stripObjectMeta(&snap.PVs[i].ObjectMeta)
+		}
+	}
+
+	if classes, err := c.client.ListStorageClasses(ctx); err != nil {
+		slog.Warn("failed to list StorageClasses for snapshot", "error", err)
+	} else {
+		snap.StorageClasses = classes.Items
+		for i := range snap.StorageClasses {
+			stripObjectMeta(&snap.StorageClasses[i].ObjectMeta)
+		}
+	}
+
+	if ephemeral, err := NewEphemeralCollector(c.client).Collect(ctx, scope); err != nil {
+		slog.Warn("failed to collect ephemeral storage for snapshot", "error", err)
+	} else {
+		snap.Ephemeral = ephemeral
+	}
+
+	return snap, nil
+}
+
+// podMounts lists the PVC mounts of pods
+func podMounts(pods []corev1.Pod) []PodMount {
+	var mounts []PodMount
+	for _, pod := range pods {
+		for _, vol := range pod.Spec.Volumes {
+			if vol.PersistentVolumeClaim == nil {
+				continue
+			}
+			mounts = append(mounts, PodMount{
+				Namespace: pod.Namespace,
+				Pod:       pod.Name,
+				Node:      pod.Spec.NodeName,
+				PVC:       vol.PersistentVolumeClaim.ClaimName,
+				Volume:    vol.Name,
+				ReadOnly:  vol.PersistentVolumeClaim.ReadOnly,
+			})
+		}
+	}
+	return mounts
+}
+
+// boundPVs keeps the PVs bound to pvcs, or every PV when all is set so that
+// released and unbound volumes are captured too
+func boundPVs(pvs []corev1.PersistentVolume, pvcs []corev1.PersistentVolumeClaim, all bool) []corev1.PersistentVolume {
+	if all {
+		return pvs
+	}
+	volumes := make(map[string]bool, len(pvcs))
+	claimed := make(map[string]bool, len(pvcs))
+	for _, pvc := range pvcs {
+		volumes[pvc.Spec.VolumeName] = true
+		claimed[pvc.Namespace+"/"+pvc.Name] = true
+	}
+	var result []corev1.PersistentVolume
+	for _, pv := range pvs {
+		ref := pv.Spec.ClaimRef
+		if volumes[pv.Name] || (ref != nil && claimed[ref.Namespace+"/"+ref.Name]) {
+			result = append(result, pv)
+		}
+	}
+	return result
+}
+
+// stripObjectMeta drops server-side bookkeeping that only inflates a snapshot
+func stripObjectMeta(meta *metav1.ObjectMeta) {
+	meta.ManagedFields = nil
+	delete(meta.Annotations, corev1.LastAppliedConfigAnnotation)
+}
+
+// WriteSnapshot saves a snapshot as indented JSON
+func WriteSnapshot(path string, snap *Snapshot) error {
+	data, err := json.MarshalIndent(snap, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode snapshot: %w", err)
+	}
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		return fmt.Errorf("failed to write snapshot: %w", err)
+	}
+	return nil
+}
+
+// LoadSnapshot reads a snapshot written by WriteSnapshot
+func LoadSnapshot(path string) (*Snapshot, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read snapshot: %w", err)
+	}
+	var snap Snapshot
+	if err := json.Unmarshal(data, &snap); err != nil {
+		return nil, fmt.Errorf("failed to decode snapshot %s: %w", path, err)
+	}
+	if snap.Version == 0 {
+		return nil, fmt.Errorf("%s is not a CloudVault snapshot (no version)", path)
+	}
+	if snap.Version > SnapshotVersion {
+		return nil, fmt.Errorf("snapshot %s has version %d, this build reads up to %d", path, snap.Version, SnapshotVersion)
+	}
+	return &snap, nil
+}
+
+// SnapshotCollector serves metrics from a snapshot instead of a live cluster
+type SnapshotCollector struct {
+	snapshot *Snapshot
+}
+
+// NewSnapshotCollector creates a collector backed by snap
+func NewSnapshotCollector(snap *Snapshot) *SnapshotCollector {
+	return &SnapshotCollector{snapshot: snap}
+}
+
+// Snapshot returns the underlying snapshot
+func (c *SnapshotCollector) Snapshot() *Snapshot {
+	return c.snapshot
+}
+
+// ClusterInfo returns the cluster the snapshot was taken from
+func (c *SnapshotCollector) ClusterInfo() *types.ClusterInfo {
+	info := c.snapshot.Cluster
+	return &info
+}
+
+// CollectAll returns a copy of every PVC metric in the snapshot
+func (c *SnapshotCollector) CollectAll(ctx context.Context) ([]types.PVCMetric, error) {
+	return append([]types.PVCMetric{}, c.snapshot.Metrics...), nil
+}
+
+// CollectByNamespace returns a copy of the snapshot's PVC metrics in one namespace
+func (c *SnapshotCollector) CollectByNamespace(ctx context.Context, namespace string) ([]types.PVCMetric, error) {
+	filtered := []types.PVCMetric{}
+	for _, m := range c.snapshot.Metrics {
+		if m.Namespace == namespace {
+			filtered = append(filtered, m)
+		}
+	}
+	return filtered, nil
+}
+
+// Ephemeral returns a copy of the snapshot's ephemeral storage metrics, limited
+// to one namespace unless namespace is empty
+func (c *SnapshotCollector) Ephemeral(namespace string) []types.EphemeralStorageMetric {
+	var result []types.EphemeralStorageMetric
+	for _, m := range c.snapshot.Ephemeral {
+		if namespace == "" || m.Namespace == namespace {
+			result = append(result, m)
+		}
+	}
+	return result
+}
@@ -0,0 +1,77 @@
+package collector
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/cloudvault-io/cloudvault/pkg/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPVCCollector_Snapshot_RoundTrip(t *testing.T) {
+	c := NewPVCCollector(newTestCluster(t), nil)
+
+	snap, err := c.Snapshot(context.Background(), Scope{Namespaces: []string{"prod"}})
+	require.NoError(t, err)
+	assert.Equal(t, SnapshotVersion, snap.Version)
+	require.Len(t, snap.PVCs, 1)
+	require.Len(t, snap.Metrics, 1)
+	require.Len(t, snap.PVs, 1, "only PVs bound to PVCs in scope")
+	assert.Equal(t, "pv-db", snap.PVs[0].Name)
+	require.Len(t, snap.StorageClasses, 1)
+	assert.Equal(t, []PodMount{{Namespace: "prod", Pod: "db-0", PVC: "db-data", Volume: "data"}}, snap.PodMounts)
+
+	path := filepath.Join(t.TempDir(), "snapshot.json")
+	require.NoError(t, WriteSnapshot(path, snap))
+	loaded, err := LoadSnapshot(path)
+	require.NoError(t, err)
+
+	sc := NewSnapshotCollector(loaded)
+	metrics, err := sc.CollectAll(context.Background())
+	require.NoError(t, err)
+	require.Len(t, metrics, 1)
+	assert.Equal(t, snap.Metrics[0].Name, metrics[0].Name)
+	assert.Equal(t, snap.Metrics[0].UsedBytes, metrics[0].UsedBytes)
+	assert.Equal(t, snap.Cluster, *sc.ClusterInfo())
+}
+
+func TestSnapshotCollector_CollectByNamespace(t *testing.T) {
+	sc := NewSnapshotCollector(&Snapshot{
+		Version: SnapshotVersion,
+		Metrics: []types.PVCMetric{
+			{Name: "a", Namespace: "prod"},
+			{Name: "b", Namespace: "dev"},
+		},
+		Ephemeral: []types.EphemeralStorageMetric{{Pod: "p", Namespace: "dev"}},
+	})
+
+	metrics, err := sc.CollectByNamespace(context.Background(), "dev")
+	require.NoError(t, err)
+	require.Len(t, metrics, 1)
+	assert.Equal(t, "b", metrics[0].Name)
+
+	// Callers price metrics in place; the snapshot must not change
+	metrics[0].MonthlyCost = 42
+	all, _ := sc.CollectAll(context.Background())
+	assert.Zero(t, all[1].MonthlyCost)
+
+	assert.Len(t, sc.Ephemeral(""), 1)
+	assert.Empty(t, sc.Ephemeral("prod"))
+}
+
+func TestLoadSnapshot_Version(t *testing.T) {
+	dir := t.TempDir()
+
+	unversioned := filepath.Join(dir, "unversioned.json")
+	require.NoError(t, os.WriteFile(unversioned, []byte(`{"metrics":[]}`), 0o600))
+	_, err := LoadSnapshot(unversioned)
+	assert.ErrorContains(t, err, "not a CloudVault snapshot")
+
+	future := filepath.Join(dir, "future.json")
+	require.NoError(t, os.WriteFile(future, []byte(`{"version":99}`), 0o600))
+	_, err = LoadSnapshot(future)
+	assert.ErrorContains(t, err, "version 99")
+}
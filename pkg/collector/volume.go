@@ -0,0 +1,162 @@
+package collector
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/cloudvault-io/cloudvault/pkg/types"
+
+	corev1 "k8s.io/api/core/v1"
+	storagev1 "k8s.io/api/storage/v1"
+)
+
+// zoneLabels lists the node-affinity keys provisioners use to pin a volume to a zone
+var zoneLabels = []string{
+	"topology.kubernetes.io/zone",
+	"topology.ebs.csi.aws.com/zone",
+	"topology.gke.io/zone",
+	"topology.disk.csi.azure.com/zone",
+	"failure-domain.beta.kubernetes.io/zone",
+}
+
+// volumeIndex holds the PVs and StorageClasses of one collection cycle, keyed by name
+type volumeIndex struct {
+	pvs     map[string]*corev1.PersistentVolume
+	classes map[string]*storagev1.StorageClass
+}
+
+// listVolumes builds a volumeIndex from the API server
+func listVolumes(ctx context.Context, client *KubernetesClient) (*volumeIndex, error) {
+	pvs, err := client.ListPersistentVolumes(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list persistent volumes: %w", err)
+	}
+	classes, err := client.ListStorageClasses(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list storage classes: %w", err)
+	}
+
+	idx := &volumeIndex{
+		pvs:     make(map[string]*corev1.PersistentVolume, len(pvs.Items)),
+		classes: make(map[string]*storagev1.StorageClass, len(classes.Items)),
+	}
+	for i := range pvs.Items {
+		idx.pvs[pvs.Items[i].Name] = &pvs.Items[i]
+	}
+	for i := range classes.Items {
+		idx.classes[classes.Items[i].Name] = &classes.Items[i]
+	}
+	return idx, nil
+}
+
+// applyVolumeDetails fills in what the bound PV and its StorageClass say about the underlying disk
+func applyVolumeDetails(metric *types.PVCMetric, pvc *corev1.PersistentVolumeClaim, idx *volumeIndex) {
+	if idx == nil {
+		return
+	}
+
+	pv := idx.pvs[pvc.Spec.VolumeName]
+	className := metric.StorageClass
+	if pv != nil {
+		metric.VolumeName = pv.Name
+		metric.ReclaimPolicy = string(pv.Spec.PersistentVolumeReclaimPolicy)
+		metric.CSIVolumeHandle = volumeHandleFromPV(pv)
+		metric.Zone = zoneFromPV(pv)
+		if pv.Spec.CSI != nil {
+			metric.Provisioner = pv.Spec.CSI.Driver
+		}
+		if className == "" {
+			className = pv.Spec.StorageClassName
+		}
+	}
+
+	sc := idx.classes[className]
+	if sc == nil {
+		return
+	}
+	if metric.Provisioner == "" {
+		metric.Provisioner = sc.Provisioner
+	}
+	if metric.ReclaimPolicy == "" && sc.ReclaimPolicy != nil {
+		metric.ReclaimPolicy = string(*sc.ReclaimPolicy)
+	}
+	metric.VolumeType = param(sc.Parameters, "type", "skuName", "storageaccounttype")
+	metric.ProvisionedIOPS = provisionedIOPS(sc.Parameters, metric.SizeGB())
+	metric.ProvisionedThroughput = paramInt(sc.Parameters, "throughput", "provisioned-throughput-on-create", "DiskMBpsReadWrite")
+}
+
+// volumeHandleFromPV returns the cloud disk identifier for CSI and in-tree volume sources
+func volumeHandleFromPV(pv *corev1.PersistentVolume) string {
+	switch {
+	case pv.Spec.CSI != nil:
+		return pv.Spec.CSI.VolumeHandle
+	case pv.Spec.AWSElasticBlockStore != nil:
+		return pv.Spec.AWSElasticBlockStore.VolumeID
+	case pv.Spec.GCEPersistentDisk != nil:
+		return pv.Spec.GCEPersistentDisk.PDName
+	case pv.Spec.AzureDisk != nil:
+		return pv.Spec.AzureDisk.DataDiskURI
+	}
+	return ""
+}
+
+// zoneFromPV reads the zone from the PV's required node affinity, falling back to its labels
+func zoneFromPV(pv *corev1.PersistentVolume) string {
+	if pv.Spec.NodeAffinity != nil && pv.Spec.NodeAffinity.Required != nil {
+		for _, term := range pv.Spec.NodeAffinity.Required.NodeSelectorTerms {
+			for _, expr := range term.MatchExpressions {
+				for _, key := range zoneLabels {
+					if expr.Key == key && len(expr.Values) > 0 {
+						return expr.Values[0]
+					}
+				}
+			}
+		}
+	}
+	for _, key := range zoneLabels {
+		if zone, ok := pv.Labels[key]; ok {
+			return zone
+		}
+	}
+	return ""
+}
+
+// provisionedIOPS resolves EBS (iops, iopsPerGB), PD and Azure disk IOPS parameters
+func provisionedIOPS(params map[string]string, sizeGB float64) int64 {
+	if iops := paramInt(params, "iops", "provisioned-iops-on-create", "DiskIOPSReadWrite"); iops > 0 {
+		return iops
+	}
+	if perGB := paramInt(params, "iopsPerGB"); perGB > 0 {
+		return int64(float64(perGB) * sizeGB)
+	}
+	return 0
+}
+
+// param returns the first present StorageClass parameter. Keys are matched
+// case-insensitively since CSI drivers accept both "skuName" and "skuname".
+func param(params map[string]string, keys ...string) string {
+	for _, key := range keys {
+		for k, v := range params {
+			if strings.EqualFold(k, key) && v != "" {
+				return v
+			}
+		}
+	}
+	return ""
+}
+
+// paramInt parses a numeric parameter, ignoring unit suffixes such as "Mi" or "MiB/s"
+func paramInt(params map[string]string, keys ...string) int64 {
+	v := param(params, keys...)
+	end := 0
+	for end < len(v) && v[end] >= '0' && v[end] <= '9' {
+		end++
+	}
+	n, err := strconv.ParseInt(v[:end], 10, 64)
+	if err != nil {
+		return 0
+	}
+	return n
+}
@@ -0,0 +1,129 @@
+package collector
+
+import (
+	"testing"
+
+	"github.com/cloudvault-io/cloudvault/pkg/types"
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	storagev1 "k8s.io/api/storage/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func testVolumeIndex() *volumeIndex {
+	retain := corev1.PersistentVolumeReclaimRetain
+	deletePolicy := corev1.PersistentVolumeReclaimDelete
+
+	return &volumeIndex{
+		pvs: map[string]*corev1.PersistentVolume{
+			"pv-ebs": {
+				ObjectMeta: metav1.ObjectMeta{Name: "pv-ebs"},
+				Spec: corev1.PersistentVolumeSpec{
+					PersistentVolumeReclaimPolicy: retain,
+					PersistentVolumeSource: corev1.PersistentVolumeSource{
+						CSI: &corev1.CSIPersistentVolumeSource{Driver: "ebs.csi.aws.com", VolumeHandle: "vol-0abc123"},
+					},
+					NodeAffinity: &corev1.VolumeNodeAffinity{
+						Required: &corev1.NodeSelector{
+							NodeSelectorTerms: []corev1.NodeSelectorTerm{{
+								MatchExpressions: []corev1.NodeSelectorRequirement{{
+									Key:      "topology.ebs.csi.aws.com/zone",
+									Operator: corev1.NodeSelectorOpIn,
+									Values:   []string{"us-east-1b"},
+								}},
+							}},
+						},
+					},
+				},
+			},
+			"pv-legacy": {
+				ObjectMeta: metav1.ObjectMeta{
+					Name:   "pv-legacy",
+					Labels: map[string]string{"failure-domain.beta.kubernetes.io/zone": "us-central1-a"},
+				},
+				Spec: corev1.PersistentVolumeSpec{
+					StorageClassName: "bulk",
+					PersistentVolumeSource: corev1.PersistentVolumeSource{
+						GCEPersistentDisk: &corev1.GCEPersistentDiskVolumeSource{PDName: "legacy-disk"},
+					},
+				},
+			},
+		},
+		classes: map[string]*storagev1.StorageClass{
+			"fast": {
+				ObjectMeta:    metav1.ObjectMeta{Name: "fast"},
+				Provisioner:   "ebs.csi.aws.com",
+				ReclaimPolicy: &deletePolicy,
+				Parameters:    map[string]string{"type": "io2", "iopsPerGB": "50", "throughput": "250"},
+			},
+			"bulk": {
+				ObjectMeta:  metav1.ObjectMeta{Name: "bulk"},
+				Provisioner: "kubernetes.io/gce-pd",
+				Parameters:  map[string]string{"type": "pd-standard"},
+			},
+			"premium": {
+				ObjectMeta:  metav1.ObjectMeta{Name: "premium"},
+				Provisioner: "disk.csi.azure.com",
+				Parameters:  map[string]string{"skuname": "PremiumV2_LRS", "DiskIOPSReadWrite": "5000", "DiskMBpsReadWrite": "200"},
+			},
+		},
+	}
+}
+
+func TestApplyVolumeDetails_CSI(t *testing.T) {
+	pvc := &corev1.PersistentVolumeClaim{Spec: corev1.PersistentVolumeClaimSpec{VolumeName: "pv-ebs"}}
+	metric := &types.PVCMetric{StorageClass: "fast", SizeBytes: 100 * 1024 * 1024 * 1024}
+
+	applyVolumeDetails(metric, pvc, testVolumeIndex())
+
+	assert.Equal(t, "pv-ebs", metric.VolumeName)
+	assert.Equal(t, "ebs.csi.aws.com", metric.Provisioner)
+	assert.Equal(t, "io2", metric.VolumeType)
+	assert.Equal(t, int64(5000), metric.ProvisionedIOPS)
+	assert.Equal(t, int64(250), metric.ProvisionedThroughput)
+	assert.Equal(t, "us-east-1b", metric.Zone)
+	assert.Equal(t, "vol-0abc123", metric.CSIVolumeHandle)
+	assert.Equal(t, "Retain", metric.ReclaimPolicy, "PV reclaim policy wins over the class default")
+	assert.Equal(t, "io2", metric.PricingClass())
+}
+
+func TestApplyVolumeDetails_InTreeWithoutClaimClass(t *testing.T) {
+	pvc := &corev1.PersistentVolumeClaim{Spec: corev1.PersistentVolumeClaimSpec{VolumeName: "pv-legacy"}}
+	metric := &types.PVCMetric{}
+
+	applyVolumeDetails(metric, pvc, testVolumeIndex())
+
+	assert.Equal(t, "kubernetes.io/gce-pd", metric.Provisioner)
+	assert.Equal(t, "pd-standard", metric.VolumeType)
+	assert.Equal(t, "legacy-disk", metric.CSIVolumeHandle)
+	assert.Equal(t, "us-central1-a", metric.Zone)
+}
+
+func TestApplyVolumeDetails_AzureParameters(t *testing.T) {
+	pvc := &corev1.PersistentVolumeClaim{}
+	metric := &types.PVCMetric{StorageClass: "premium"}
+
+	applyVolumeDetails(metric, pvc, testVolumeIndex())
+
+	assert.Equal(t, "PremiumV2_LRS", metric.VolumeType)
+	assert.Equal(t, int64(5000), metric.ProvisionedIOPS)
+	assert.Equal(t, int64(200), metric.ProvisionedThroughput)
+}
+
+func TestApplyVolumeDetails_Unresolved(t *testing.T) {
+	pvc := &corev1.PersistentVolumeClaim{Spec: corev1.PersistentVolumeClaimSpec{VolumeName: "missing"}}
+	metric := &types.PVCMetric{StorageClass: "gp3"}
+
+	applyVolumeDetails(metric, pvc, nil)
+	applyVolumeDetails(metric, pvc, testVolumeIndex())
+
+	assert.Empty(t, metric.VolumeType)
+	assert.Equal(t, "gp3", metric.PricingClass())
+}
+
+func TestParamInt(t *testing.T) {
+	params := map[string]string{"provisioned-throughput-on-create": "250Mi", "bad": "abc"}
+	assert.Equal(t, int64(250), paramInt(params, "provisioned-throughput-on-create"))
+	assert.Equal(t, int64(0), paramInt(params, "bad"))
+	assert.Equal(t, int64(0), paramInt(params, "missing"))
+}
@@ -34,7 +34,7 @@ func NewCalculatorWithProvider(provider PricingProvider) *Calculator {
 }
 
 // CalculatePVCCost calculates the estimated monthly cost for a PVC based on its size,
-// storage class, and provider. It handles both straightforward per-GB pricing and
+// volume type (falling back to the storage class name), and provider. It handles both straightforward per-GB pricing and
 // complex provisioned IOPS pricing models (like AWS io1/io2).
 //
 // If the specific storage class is not found in the pricing data, it attempts to fallback
@@ -42,15 +42,20 @@ func NewCalculatorWithProvider(provider PricingProvider) *Calculator {
 func (c *Calculator) CalculatePVCCost(metric *types.PVCMetric, provider string) float64 {
 	// Use the provider to get pricing. We pass "us-east-1" as default region for now
 	// until region support is fully plumbed through from the CLI/Agent.
-	pricing := c.pricingProvider.GetPrice(provider, metric.StorageClass, "us-east-1")
+	pricing := c.pricingProvider.GetPrice(provider, metric.PricingClass(), "us-east-1")
 
 	sizeGB := float64(metric.SizeBytes) / (1024 * 1024 * 1024)
 	storageCost := sizeGB * pricing.PerGBMonth
 
-	// Add IOPS cost if provisioned
+	// Add IOPS cost if provisioned. Provisioned IOPS are billed whether used or not,
+	// so prefer the StorageClass value over observed IOPS when known.
+	iops := metric.TotalIOPS()
+	if metric.ProvisionedIOPS > 0 {
+		iops = float64(metric.ProvisionedIOPS)
+	}
 	iopsCost := 0.0
-	if pricing.Provisioned && metric.TotalIOPS() > 3000 {
-		extraIOPS := metric.TotalIOPS() - 3000
+	if pricing.Provisioned && iops > 3000 {
+		extraIOPS := iops - 3000
 		iopsCost = extraIOPS * pricing.PerIOPS
 	}
 
@@ -146,6 +151,8 @@ func (c *Calculator) EstimateSavings(metric *types.PVCMetric, provider, newStora
 	// Create a copy with new storage class
 	testMetric := *metric
 	testMetric.StorageClass = newStorageClass
+	testMetric.VolumeType = ""
+	testMetric.ProvisionedIOPS = 0
 	newCost := c.CalculatePVCCost(&testMetric, provider)
 
 	return currentCost - newCost
@@ -155,7 +162,10 @@ func (c *Calculator) EstimateSavings(metric *types.PVCMetric, provider, newStora
 // It uses a simplified model based on standard cloud egress fees (e.g., $0.09/GB for external egress).
 func (c *Calculator) CalculateEgressCost(bytes int64, srcCloud, srcRegion, dstCloud, dstRegion string) float64 {
 	gb := float64(bytes) / (1024 * 1024 * 1024)
+	return gb * egressPricePerGB(srcCloud, srcRegion, dstCloud, dstRegion)
+}
 
+func egressPricePerGB(srcCloud, srcRegion, dstCloud, dstRegion string) float64 {
 	// Same region, same cloud -> Free
 	if srcCloud == dstCloud && srcRegion == dstRegion {
 		return 0
@@ -163,12 +173,38 @@ func (c *Calculator) CalculateEgressCost(bytes int64, srcCloud, srcRegion, dstCl
 
 	// Same cloud, different region (inter-region fee)
 	if srcCloud == dstCloud {
-		return gb * 0.02 // Approx $0.02/GB
+		return 0.02 // Approx $0.02/GB
 	}
 
 	// Different clouds (external egress fee)
 	// AWS/GCP typically charge ~$0.09/GB for internet egress
-	return gb * 0.09
+	return 0.09
+}
+
+// MonthlyEgressCost estimates a month of traffic at bytesPerSecond from one provider and
+// region to another, priced as CalculateEgressCost does
+func MonthlyEgressCost(bytesPerSecond float64, srcCloud, srcRegion, dstCloud, dstRegion string) float64 {
+	gb := bytesPerSecond * 30 * 24 * 3600 / (1024 * 1024 * 1024)
+	return gb * egressPricePerGB(srcCloud, srcRegion, dstCloud, dstRegion)
+}
+
+// InterZoneTransferPricePerGB is what a provider charges per GB exchanged between two
+// zones of a region. AWS bills $0.01/GB on each side of the transfer.
+func InterZoneTransferPricePerGB(provider string) float64 {
+	if provider == "aws" {
+		return 0.02
+	}
+	return 0.01
+}
+
+// MonthlyTransferCost estimates a month of traffic at bytesPerSecond between zones of
+// one region, or between regions when crossRegion, priced as CalculateEgressCost does
+func MonthlyTransferCost(bytesPerSecond float64, provider string, crossRegion bool) float64 {
+	gb := bytesPerSecond * 30 * 24 * 3600 / (1024 * 1024 * 1024)
+	if crossRegion {
+		return gb * 0.02
+	}
+	return gb * InterZoneTransferPricePerGB(provider)
 }
 
 // FormatCost formats a cost value as a string
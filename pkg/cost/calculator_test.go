@@ -1,6 +1,7 @@
 package cost
 
 import (
+	"math"
 	"testing"
 
 	"github.com/cloudvault-io/cloudvault/pkg/types"
@@ -46,6 +47,26 @@ func TestCalculator_CalculatePVCCost(t *testing.T) {
 			provider:     "aws",
 			expectedCost: 8.0 + (1000 * 0.005), // 8.0 + 5.0 = 13.0
 		},
+		{
+			name: "custom-class-resolved-volume-type",
+			metric: types.PVCMetric{
+				SizeBytes:    100 * 1024 * 1024 * 1024,
+				StorageClass: "fast",
+				VolumeType:   "gp3",
+			},
+			provider:     "aws",
+			expectedCost: 8.0, // priced as gp3, not as unknown "fast"
+		},
+		{
+			name: "provisioned-iops-billed-when-idle",
+			metric: types.PVCMetric{
+				SizeBytes:       100 * 1024 * 1024 * 1024,
+				StorageClass:    "gp3",
+				ProvisionedIOPS: 5000,
+			},
+			provider:     "aws",
+			expectedCost: 8.0 + (2000 * 0.005), // 8.0 + 10.0 = 18.0
+		},
 		{
 			name: "default-100GB",
 			metric: types.PVCMetric{
@@ -126,3 +147,48 @@ func TestCalculator_CalculateEgressCost(t *testing.T) {
 		})
 	}
 }
+
+func TestMonthlyTransferCost(t *testing.T) {
+	// 1 GiB per day for 30 days
+	perSecond := float64(1024*1024*1024) / (24 * 3600)
+	tests := []struct {
+		name        string
+		provider    string
+		crossRegion bool
+		expected    float64
+	}{
+		{"aws-zone", "aws", false, 0.6},
+		{"gcp-zone", "gcp", false, 0.3},
+		{"aws-region", "aws", true, 0.6},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cost := MonthlyTransferCost(perSecond, tt.provider, tt.crossRegion)
+			if math.Abs(cost-tt.expected) > 1e-9 {
+				t.Errorf("Expected transfer cost %.2f, got %.4f", tt.expected, cost)
+			}
+		})
+	}
+}
+
+func TestMonthlyEgressCost(t *testing.T) {
+	// 1 GiB per day for 30 days
+	perSecond := float64(1024*1024*1024) / (24 * 3600)
+	tests := []struct {
+		name                string
+		dstCloud, dstRegion string
+		expected            float64
+	}{
+		{"same-region", "aws", "us-east-1", 0},
+		{"cross-region", "aws", "eu-west-1", 0.6},
+		{"cross-cloud", "gcp", "us-east1", 2.7},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cost := MonthlyEgressCost(perSecond, "aws", "us-east-1", tt.dstCloud, tt.dstRegion)
+			if math.Abs(cost-tt.expected) > 1e-9 {
+				t.Errorf("Expected egress cost %.2f, got %.4f", tt.expected, cost)
+			}
+		})
+	}
+}
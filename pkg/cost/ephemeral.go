@@ -0,0 +1,127 @@
+package cost
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/cloudvault-io/cloudvault/pkg/types"
+)
+
+const gib = 1024 * 1024 * 1024
+
+// rootDiskClass is the volume type nodes usually boot from, used to price node root disks
+func rootDiskClass(provider string) string {
+	switch provider {
+	case "aws":
+		return "gp3"
+	case "gcp":
+		return "pd-balanced"
+	case "azure":
+		return "Premium_LRS"
+	}
+	return "default"
+}
+
+// AttributeEphemeralCost sets MonthlyCost on each metric by splitting the cost of every
+// node's root disk among its pods in proportion to their weight (usage or request).
+// Metrics collected for a scope carry the weight of the whole node, so pods outside it
+// keep their share; otherwise the node's weight is summed from metrics. When the node
+// capacity is unknown, pods are charged for their own weight only.
+func (c *Calculator) AttributeEphemeralCost(metrics []types.EphemeralStorageMetric, provider string) {
+	perGB := c.pricingProvider.GetPrice(provider, rootDiskClass(provider), "us-east-1").PerGBMonth
+
+	weights := make(map[string]int64)
+	for i := range metrics {
+		weights[metrics[i].Node] += metrics[i].Weight()
+	}
+
+	for i := range metrics {
+		m := &metrics[i]
+		weight := m.Weight()
+		total := max(weights[m.Node], m.NodeWeightBytes)
+		if m.NodeCapacityBytes == 0 || total == 0 {
+			m.MonthlyCost = float64(weight) / gib * perGB
+			continue
+		}
+		nodeCost := float64(m.NodeCapacityBytes) / gib * perGB
+		m.MonthlyCost = nodeCost * float64(weight) / float64(total)
+	}
+}
+
+// GenerateEphemeralSummary prices ephemeral storage and aggregates it by namespace and node
+func (c *Calculator) GenerateEphemeralSummary(metrics []types.EphemeralStorageMetric, provider string) *types.EphemeralCostSummary {
+	c.AttributeEphemeralCost(metrics, provider)
+
+	summary := &types.EphemeralCostSummary{
+		ByNamespace: make(map[string]float64),
+		ByNode:      make(map[string]float64),
+	}
+	for _, m := range metrics {
+		summary.TotalMonthlyCost += m.MonthlyCost
+		summary.TotalUsedBytes += m.UsedBytes
+		summary.ByNamespace[m.Namespace] += m.MonthlyCost
+		summary.ByNode[m.Node] += m.MonthlyCost
+	}
+
+	top := append([]types.EphemeralStorageMetric(nil), metrics...)
+	sort.Slice(top, func(i, j int) bool { return top[i].MonthlyCost > top[j].MonthlyCost })
+	if len(top) > 10 {
+		top = top[:10]
+	}
+	summary.TopPods = top
+	return summary
+}
+
+// GenerateEphemeralRecommendations flags pods whose ephemeral usage is far from their request.
+// Using much more than requested risks eviction under node disk pressure; using much less
+// holds node disk back from other pods. Expects costs from AttributeEphemeralCost.
+func (o *Optimizer) GenerateEphemeralRecommendations(metrics []types.EphemeralStorageMetric) []types.Recommendation {
+	var recs []types.Recommendation
+	for i := range metrics {
+		if rec := checkEphemeralRequest(&metrics[i]); rec != nil {
+			recs = append(recs, *rec)
+		}
+	}
+	sort.Slice(recs, func(i, j int) bool {
+		return recs[i].MonthlySavings > recs[j].MonthlySavings
+	})
+	return recs
+}
+
+func checkEphemeralRequest(m *types.EphemeralStorageMetric) *types.Recommendation {
+	used := float64(m.UsedBytes)
+	request := float64(m.RequestBytes)
+
+	switch {
+	case (request > 0 && used > 2*request) || (request == 0 && used > 5*gib):
+		suggested := int64(used * 1.2)
+		return &types.Recommendation{
+			Type:             "ephemeral_request",
+			Namespace:        m.Namespace,
+			Pod:              m.Pod,
+			CurrentState:     fmt.Sprintf("Using %.1f GB ephemeral storage, %.1f GB requested", used/gib, request/gib),
+			RecommendedState: fmt.Sprintf("Request %.1f GB ephemeral-storage", float64(suggested)/gib),
+			Reasoning:        "Pod writes far more to emptyDir/container layers than it requests; it is first in line for eviction when the node runs low on disk.",
+			Impact:           "high",
+		}
+
+	case request >= gib && used < 0.25*request:
+		suggested := int64(used * 1.5)
+		if suggested < 256*1024*1024 {
+			suggested = 256 * 1024 * 1024
+		}
+		// Cost is proportional to the request while usage stays below it
+		savings := m.MonthlyCost * (request - float64(suggested)) / request
+		return &types.Recommendation{
+			Type:             "ephemeral_request",
+			Namespace:        m.Namespace,
+			Pod:              m.Pod,
+			CurrentState:     fmt.Sprintf("Using %.2f GB of %.1f GB requested ephemeral storage", used/gib, request/gib),
+			RecommendedState: fmt.Sprintf("Request %.2f GB ephemeral-storage", float64(suggested)/gib),
+			MonthlySavings:   savings,
+			Reasoning:        "Requested ephemeral storage is reserved on the node's root disk but mostly unused.",
+			Impact:           "low",
+		}
+	}
+	return nil
+}
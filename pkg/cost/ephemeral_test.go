@@ -0,0 +1,75 @@
+package cost
+
+import (
+	"testing"
+
+	"github.com/cloudvault-io/cloudvault/pkg/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAttributeEphemeralCost_SharesNodeRootDisk(t *testing.T) {
+	calc := NewCalculator()
+	metrics := []types.EphemeralStorageMetric{
+		// 100 GB gp3 root disk at $0.08/GB = $8/month, split 3:1
+		{Namespace: "ci", Pod: "a", Node: "node-a", UsedBytes: 3 * gib, NodeCapacityBytes: 100 * gib},
+		{Namespace: "web", Pod: "b", Node: "node-a", UsedBytes: 512 * 1024 * 1024, RequestBytes: gib, NodeCapacityBytes: 100 * gib},
+		// Unknown capacity: charged for its own weight
+		{Namespace: "web", Pod: "c", Node: "node-b", UsedBytes: 10 * gib},
+	}
+
+	calc.AttributeEphemeralCost(metrics, "aws")
+
+	assert.InDelta(t, 6.0, metrics[0].MonthlyCost, 0.001)
+	assert.InDelta(t, 2.0, metrics[1].MonthlyCost, 0.001, "weighted by request when above usage")
+	assert.InDelta(t, 0.8, metrics[2].MonthlyCost, 0.001)
+}
+
+func TestAttributeEphemeralCost_ScopedToNamespace(t *testing.T) {
+	calc := NewCalculator()
+	// Only ci was collected; pods elsewhere on node-a weigh 3 GB more
+	metrics := []types.EphemeralStorageMetric{
+		{Namespace: "ci", Pod: "a", Node: "node-a", UsedBytes: gib, NodeCapacityBytes: 100 * gib, NodeWeightBytes: 4 * gib},
+	}
+
+	calc.AttributeEphemeralCost(metrics, "aws")
+
+	assert.InDelta(t, 2.0, metrics[0].MonthlyCost, 0.001, "a quarter of the $8 node disk")
+}
+
+func TestGenerateEphemeralSummary(t *testing.T) {
+	calc := NewCalculator()
+	metrics := []types.EphemeralStorageMetric{
+		{Namespace: "ci", Pod: "a", Node: "node-a", UsedBytes: 3 * gib, NodeCapacityBytes: 100 * gib},
+		{Namespace: "web", Pod: "b", Node: "node-a", UsedBytes: gib, NodeCapacityBytes: 100 * gib},
+	}
+
+	summary := calc.GenerateEphemeralSummary(metrics, "aws")
+	assert.InDelta(t, 8.0, summary.TotalMonthlyCost, 0.001)
+	assert.Equal(t, int64(4*gib), summary.TotalUsedBytes)
+	assert.InDelta(t, 6.0, summary.ByNamespace["ci"], 0.001)
+	assert.InDelta(t, 8.0, summary.ByNode["node-a"], 0.001)
+	require.Len(t, summary.TopPods, 2)
+	assert.Equal(t, "a", summary.TopPods[0].Pod)
+}
+
+func TestGenerateEphemeralRecommendations(t *testing.T) {
+	metrics := []types.EphemeralStorageMetric{
+		{Namespace: "ci", Pod: "over", UsedBytes: 6 * gib, RequestBytes: gib},
+		{Namespace: "ci", Pod: "unrequested", UsedBytes: 8 * gib},
+		{Namespace: "web", Pod: "under", UsedBytes: 100 * 1024 * 1024, RequestBytes: 10 * gib, MonthlyCost: 4},
+		{Namespace: "web", Pod: "fine", UsedBytes: gib, RequestBytes: 2 * gib},
+	}
+
+	recs := NewOptimizer().GenerateEphemeralRecommendations(metrics)
+	require.Len(t, recs, 3)
+
+	assert.Equal(t, "under", recs[0].Pod, "sorted by savings")
+	assert.Equal(t, "ephemeral_request", recs[0].Type)
+	assert.InDelta(t, 4*(10-0.25)/10, recs[0].MonthlySavings, 0.01)
+
+	for _, rec := range recs[1:] {
+		assert.Equal(t, "high", rec.Impact)
+		assert.Zero(t, rec.MonthlySavings)
+	}
+}
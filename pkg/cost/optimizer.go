@@ -80,7 +80,7 @@ func (o *Optimizer) GenerateRecommendations(metrics []types.PVCMetric, provider
 
 				// RL-Powered
 				bestClass := o.rlAgent.DecidePlacement("standard_workload", []string{"gp3", "sc1", "st1"})
-				if bestClass != m.StorageClass && m.StorageClass == "gp2" {
+				if bestClass != m.PricingClass() && m.PricingClass() == "gp2" {
 					recs = append(recs, types.Recommendation{
 						Type:             "ai_placement",
 						PVC:              m.Name,
@@ -122,7 +122,7 @@ func (o *Optimizer) GenerateRecommendations(metrics []types.PVCMetric, provider
 }
 
 // checkZombieVolume detects "zombie" volumes - those that have effectively been abandoned.
-// It relies on LastAccessedAt data (populated by collectors) to determine if a volume
+// It relies on LastAccessedAt (the collector's access tracker: last I/O or usage change) to determine if a volume
 // has been unused for an extended period (threshold: 30 days).
 //
 // These are often candidates for immediate deletion after backup.
@@ -154,15 +154,20 @@ func (o *Optimizer) checkZombieVolume(m *types.PVCMetric) *types.Recommendation
 // For example, if a volume on high-performance SSD (e.g., AWS io1) has very low IOPS usage,
 // it recommends moving to a general purpose (gp3) or even cold storage (sc1) tier.
 //
+// Tiers are sized for peak (p95) IOPS rather than the average, so a bursty volume that idles
+// most of the window is not moved to a tier that throttles its bursts. Volumes without a
+// p95 are compared by their average.
+//
 // This analysis is provider-specific as storage tier capabilities and pricing vary significantly.
 func (o *Optimizer) checkStorageClassOptimization(m *types.PVCMetric, provider string) *types.Recommendation {
 	_ = o.calculator.CalculatePVCCost(m, provider)
-	totalIOPS := m.TotalIOPS()
+	totalIOPS := m.PeakIOPS()
 	var targetClass string
 	var reasoning string
 
-	// Normalize storage class name to handle common prefixes (e.g., "aws-gp3" -> "gp3")
-	sc := m.StorageClass
+	// Normalize storage class name to handle common prefixes (e.g., "aws-gp3" -> "gp3").
+	// The resolved volume type wins over the class name ("fast" may really be io2).
+	sc := m.PricingClass()
 	if provider == "aws" || (provider == "unknown" && (len(sc) > 4 && sc[:4] == "aws-")) {
 		if len(sc) > 4 && sc[:4] == "aws-" {
 			sc = sc[4:]
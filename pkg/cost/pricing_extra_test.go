@@ -167,6 +167,35 @@ func TestOptimizer_CheckStorageClassOptimization_IO_Sufficient(t *testing.T) {
 	assert.Nil(t, rec, "io2 with high IOPS should not be recommended for downgrade")
 }
 
+func TestOptimizer_CheckStorageClassOptimization_Bursty(t *testing.T) {
+	o := NewOptimizer()
+	pvc := func(class string, avg, p95 float64) *types.PVCMetric {
+		return &types.PVCMetric{
+			Name:         "bursty",
+			Namespace:    "default",
+			StorageClass: class,
+			SizeBytes:    200 * 1024 * 1024 * 1024,
+			ReadIOPS:     avg,
+			ReadIOPSP95:  p95,
+			MonthlyCost:  100.0,
+		}
+	}
+
+	// Quiet on average but bursting past what the cheaper tier sustains
+	assert.Nil(t, o.checkStorageClassOptimization(pvc("gp3", 100, 2000), "aws"))
+	assert.Nil(t, o.checkStorageClassOptimization(pvc("io2", 500, 4000), "aws"))
+
+	// Bursts the cheaper tier still covers are sized by the p95
+	rec := o.checkStorageClassOptimization(pvc("gp3", 100, 800), "aws")
+	require.NotNil(t, rec)
+	assert.Equal(t, "st1", rec.RecommendedState)
+
+	// Without a p95 the average is used
+	rec = o.checkStorageClassOptimization(pvc("gp3", 100, 0), "aws")
+	require.NotNil(t, rec)
+	assert.Equal(t, "sc1", rec.RecommendedState)
+}
+
 func TestOptimizer_CheckOversizedVolume_AlreadyMigrated(t *testing.T) {
 	o := NewOptimizer()
 
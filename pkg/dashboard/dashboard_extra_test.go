@@ -2,6 +2,7 @@ package dashboard
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"net/http"
 	"net/http/httptest"
@@ -9,7 +10,9 @@ import (
 	"testing"
 	"time"
 
+	"github.com/cloudvault-io/cloudvault/pkg/collector"
 	"github.com/cloudvault-io/cloudvault/pkg/ebpf"
+	"github.com/cloudvault-io/cloudvault/pkg/graph"
 	"github.com/cloudvault-io/cloudvault/pkg/orchestrator/governance"
 	"github.com/cloudvault-io/cloudvault/pkg/orchestrator/lifecycle"
 	"github.com/cloudvault-io/cloudvault/pkg/types"
@@ -149,6 +152,48 @@ func TestHandleMigrationStatus_Found(t *testing.T) {
 	assert.Equal(t, http.StatusNotFound, rr.Code)
 }
 
+// ── handlePVCDependencies ─────────────────────────────────────────────────────
+
+func dependenciesRequest(s *Server, namespace, name, query string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodGet, "/api/pvc/"+namespace+"/"+name+"/dependencies"+query, nil)
+	req.SetPathValue("namespace", namespace)
+	req.SetPathValue("name", name)
+	rr := httptest.NewRecorder()
+	s.handlePVCDependencies(rr, req)
+	return rr
+}
+
+func TestHandlePVCDependencies(t *testing.T) {
+	s := newTestServer()
+	assert.Equal(t, http.StatusServiceUnavailable, dependenciesRequest(s, "default", "data", "").Code)
+
+	ctx := context.Background()
+	sig := graph.NewMemoryGraph()
+	require.NoError(t, sig.SyncPVCs(ctx, []types.PVCMetric{{Name: "data", Namespace: "default"}}))
+	require.NoError(t, sig.SyncPods(ctx, []types.PodPlacement{{
+		Name:      "db-0",
+		Namespace: "default",
+		Workload:  types.Workload{Kind: "StatefulSet", Name: "db"},
+		Services:  []string{"db"},
+		PVCs:      []string{"data"},
+	}}))
+	s.SetGraph(sig)
+
+	rr := dependenciesRequest(s, "default", "data", "")
+	require.Equal(t, http.StatusOK, rr.Code)
+	var deps graph.Dependencies
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &deps))
+	assert.Equal(t, graph.DefaultDependencyDepth, deps.Depth)
+	assert.Equal(t, []string{"db-0"}, deps.Pods)
+	assert.Equal(t, []string{"db"}, deps.Services)
+	assert.Equal(t, []graph.DependentWorkload{{Namespace: "default", Kind: "StatefulSet", Name: "db"}}, deps.Workloads)
+
+	assert.Equal(t, http.StatusOK, dependenciesRequest(s, "default", "data", "?depth=0").Code)
+	assert.Equal(t, http.StatusBadRequest, dependenciesRequest(s, "default", "data", "?depth=x").Code)
+	assert.Equal(t, http.StatusBadRequest, dependenciesRequest(s, "default", "data", "?depth=99").Code)
+	assert.Equal(t, http.StatusNotFound, dependenciesRequest(s, "default", "missing", "").Code)
+}
+
 // ── handleApplyMigration ──────────────────────────────────────────────────────
 
 func TestHandleApplyMigration_MethodNotAllowed(t *testing.T) {
@@ -596,6 +641,15 @@ func TestHandleNetwork_NoClientNoAgent(t *testing.T) {
 	assert.NotNil(t, body)
 }
 
+func TestHandleNetwork_PortsBreakdown_Empty(t *testing.T) {
+	s := newTestServer()
+	req := httptest.NewRequest(http.MethodGet, "/api/network?breakdown=ports", nil)
+	rr := httptest.NewRecorder()
+	s.handleNetwork(rr, req)
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.JSONEq(t, `{"flows":[],"by_port":[],"by_protocol":[]}`, rr.Body.String())
+}
+
 // ── handleNetwork with eBPF agent ─────────────────────────────────────────────
 func TestHandleNetwork_WithEbpfAgent(t *testing.T) {
 	agent, err := ebpf.NewAgent()
@@ -646,6 +700,28 @@ func TestReconcile_MockMode(t *testing.T) {
 	assert.NotNil(t, metrics)
 }
 
+func TestReconcile_SnapshotMode(t *testing.T) {
+	s := newTestServer()
+	s.SetSnapshot(collector.NewSnapshotCollector(&collector.Snapshot{
+		Version: collector.SnapshotVersion,
+		Cluster: types.ClusterInfo{Name: "prod-eu", Provider: "aws", Region: "eu-west-1"},
+		Metrics: []types.PVCMetric{
+			{Name: "a", Namespace: "shop", SizeBytes: 10 << 30, StorageClass: "gp3", Provider: "aws"},
+			{Name: "b", Namespace: "shop", SizeBytes: 20 << 30, StorageClass: "gp3", Provider: "aws"},
+			{Name: "c", Namespace: "ci", SizeBytes: 5 << 30, StorageClass: "gp3", Provider: "aws"},
+		},
+		Ephemeral: []types.EphemeralStorageMetric{{Namespace: "ci", Pod: "build-0", Node: "n1", UsedBytes: 1 << 30}},
+	}))
+	s.reconcile()
+
+	s.store.RLock()
+	defer s.store.RUnlock()
+	assert.Len(t, s.store.Metrics, 3, "snapshot takes precedence over mock data")
+	require.NotNil(t, s.store.Summary.Ephemeral)
+	assert.Greater(t, s.store.Summary.Ephemeral.TotalMonthlyCost, 0.0)
+	assert.Equal(t, "eu-west-1", s.clusterInfo(context.Background()).Region)
+}
+
 func TestReconcile_PreservesUserBudget(t *testing.T) {
 	s := newTestServer()
 	s.store.BudgetLimit = 999.0
@@ -656,6 +732,15 @@ func TestReconcile_PreservesUserBudget(t *testing.T) {
 	assert.Equal(t, 999.0, limit)
 }
 
+func TestServer_KeepsPVCCollector(t *testing.T) {
+	s := NewServer(nil, nil, "aws", false, nil)
+	require.NotNil(t, s.pvcCollector, "the collector must outlive reconcile cycles to track access")
+
+	shared := collector.NewPVCCollector(nil, nil)
+	s.SetPVCCollector(shared)
+	assert.Same(t, shared, s.pvcCollector)
+}
+
 func TestReconcile_FiltersAppliedPVCs(t *testing.T) {
 	s := newTestServer()
 	s.store.AppliedPVCs = map[string]bool{"default/pvc-1": true}
@@ -4,11 +4,16 @@ import (
 	"context"
 	"embed"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"io/fs"
 	"log/slog"
+	"net"
 	"net/http"
 	"os"
+	"sort"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
@@ -22,6 +27,7 @@ import (
 	"github.com/cloudvault-io/cloudvault/pkg/collector"
 	"github.com/cloudvault-io/cloudvault/pkg/cost"
 	"github.com/cloudvault-io/cloudvault/pkg/ebpf"
+	"github.com/cloudvault-io/cloudvault/pkg/graph"
 	"github.com/cloudvault-io/cloudvault/pkg/integrations"
 	"github.com/cloudvault-io/cloudvault/pkg/orchestrator/governance"
 	"github.com/cloudvault-io/cloudvault/pkg/orchestrator/lifecycle"
@@ -62,7 +68,11 @@ type Server struct {
 	governance   *governance.AdmissionController
 	provider     string // Cloud provider (aws, gcp, azure)
 	mock         bool
+	snapshot     *collector.SnapshotCollector // Serves data from a snapshot file instead of a cluster
 	ebpfAgent    *ebpf.Agent
+	sig          graph.Graph // Answers dependency queries when set
+	// pvcCollector lives as long as the server so access tracking spans reconcile cycles
+	pvcCollector *collector.PVCCollector
 	store        *MetricsStore
 	// NEW: Migration and multi-cluster support
 	migrationExecutor *lifecycle.MigrationExecutor
@@ -87,6 +97,7 @@ func NewServer(client *collector.KubernetesClient, promClient *integrations.Prom
 		client:       client,
 		promClient:   promClient,
 		orchestrator: orchestrator,
+		pvcCollector: pvcCollector,
 		governance:   governance.NewAdmissionController(),
 		provider:     provider,
 		mock:         mock,
@@ -110,13 +121,36 @@ func NewServer(client *collector.KubernetesClient, promClient *integrations.Prom
 	return s
 }
 
+// SetSnapshot makes the server report the contents of a snapshot instead of a live cluster
+func (s *Server) SetSnapshot(snapshot *collector.SnapshotCollector) {
+	s.snapshot = snapshot
+}
+
+// SetPVCCollector replaces the collector the server and its orchestrator use. The agent
+// passes its own, so the dashboard reports the access state the agent tracks rather
+// than a second tracker overwriting it.
+func (s *Server) SetPVCCollector(c *collector.PVCCollector) {
+	s.pvcCollector = c
+	s.orchestrator.SetPVCCollector(c)
+}
+
+// SetGraph makes the server answer PVC dependency queries from the SIG, and migration
+// plans count the workloads it knows depend on a PVC
+func (s *Server) SetGraph(g graph.Graph) {
+	s.sig = g
+	if s.migrationExecutor != nil {
+		s.migrationExecutor.SetGraph(g)
+	}
+}
+
 // Start starts the HTTP server
 func (s *Server) Start(port int) error {
 	// Start background reconciler (Phase 3 ARCHITECTURE MOVE)
 	// We refresh metrics every 30 seconds to provide real-time updates without taxing the API
 	go s.runReconciler(30 * time.Second)
 
-	// Start Kubernetes Informers (Discovery caching)
+	// Start Kubernetes Informers (Discovery caching); a no-op when the agent sharing the
+	// client already started them
 	if s.client != nil {
 		go s.client.StartInformers(context.Background())
 	}
@@ -137,6 +171,7 @@ func (s *Server) Start(port int) error {
 	// API Endpoints
 	mux.HandleFunc("/api/login", LoginHandler) // Phase 16 Auth
 	mux.HandleFunc("/api/pvc", s.handlePVCs)
+	mux.HandleFunc("GET /api/pvc/{namespace}/{name}/dependencies", s.handlePVCDependencies)
 	mux.HandleFunc("/api/cost", s.handleCost)
 	mux.HandleFunc("/api/recommendations", s.handleRecommendations)
 	mux.HandleFunc("/api/policies", s.handlePolicies)
@@ -263,10 +298,13 @@ func (s *Server) reconcile() {
 	var err error
 
 	var pvcCollector collector.Collector
-	if s.mock {
+	switch {
+	case s.snapshot != nil:
+		pvcCollector = s.snapshot
+	case s.mock:
 		pvcCollector = collector.NewMockPVCCollector()
-	} else {
-		pvcCollector = collector.NewPVCCollector(s.client, s.promClient)
+	default:
+		pvcCollector = s.pvcCollector
 	}
 	metrics, err = pvcCollector.CollectAll(ctx)
 
@@ -293,6 +331,21 @@ func (s *Server) reconcile() {
 	optimizer := cost.NewOptimizer()
 	recommendations := optimizer.GenerateRecommendations(metrics, s.provider)
 
+	// Node root-disk spend on emptyDir and container layers, reported separately from PVCs
+	var ephemeral []types.EphemeralStorageMetric
+	if s.snapshot != nil {
+		ephemeral = s.snapshot.Ephemeral("")
+	} else if !s.mock && s.client != nil {
+		ephemeral, err = collector.NewEphemeralCollector(s.client).Collect(ctx, collector.Scope{})
+		if err != nil {
+			slog.Warn("Failed to collect ephemeral storage", "error", err)
+		}
+	}
+	if len(ephemeral) > 0 {
+		summary.Ephemeral = calculator.GenerateEphemeralSummary(ephemeral, s.provider)
+		recommendations = append(recommendations, optimizer.GenerateEphemeralRecommendations(ephemeral)...)
+	}
+
 	var policies []v1alpha1.StorageLifecyclePolicy
 	// Fetch real policies if not in mock mode
 	// Fetch real policies
@@ -362,6 +415,35 @@ func (s *Server) handlePVCs(w http.ResponseWriter, r *http.Request) {
 	writeJSON(w, metrics)
 }
 
+// GET /api/pvc/{namespace}/{name}/dependencies?depth=N
+func (s *Server) handlePVCDependencies(w http.ResponseWriter, r *http.Request) {
+	if s.sig == nil {
+		writeError(w, "Storage Intelligence Graph not configured", http.StatusServiceUnavailable)
+		return
+	}
+
+	depth := graph.DefaultDependencyDepth
+	if v := r.URL.Query().Get("depth"); v != "" {
+		d, err := strconv.Atoi(v)
+		if err != nil || d < 0 || d > graph.MaxDependencyDepth {
+			writeError(w, fmt.Sprintf("invalid depth: must be between 0 and %d", graph.MaxDependencyDepth), http.StatusBadRequest)
+			return
+		}
+		depth = d
+	}
+
+	deps, err := s.sig.GetDependencies(r.Context(), r.PathValue("namespace"), r.PathValue("name"), depth)
+	if errors.Is(err, graph.ErrPVCNotFound) {
+		writeError(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		writeError(w, fmt.Sprintf("Failed to query dependencies: %v", err), http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, deps)
+}
+
 // GET /api/cost
 func (s *Server) handleCost(w http.ResponseWriter, r *http.Request) {
 	s.store.RLock()
@@ -396,79 +478,206 @@ func (s *Server) handlePolicies(w http.ResponseWriter, r *http.Request) {
 }
 
 func (s *Server) handleNetwork(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Query().Get("breakdown") == "ports" {
+		s.handleNetworkPorts(w, r)
+		return
+	}
+
 	// Aggregated cluster-wide network stats
 	aggregateStats := make(map[string]map[string]uint64)
 
-	// To prevent infinite recursion, only aggregate from other pods if this isn't an internal query
-	if r.URL.Query().Get("internal") != "true" && s.client != nil {
-		// 1. Get all agent pods (cached discovery)
-		pods, err := s.client.ListPodsByLabel(r.Context(), "", "app=cloudvault-agent")
-
-		slog.Info("handleNetwork aggregating from agents", "podsCount", len(pods), "err", err)
+	s.queryAgents(r, "internal=true", func(pod *corev1.Pod, body io.Reader) error {
+		var agentStats map[string]map[string]uint64
+		if err := json.NewDecoder(body).Decode(&agentStats); err != nil {
+			return err
+		}
+		slog.Info("Successfully got network stats from agent", "pod", pod.Name, "numSources", len(agentStats))
+		mergeNetworkStats(aggregateStats, agentStats)
+		return nil
+	})
 
-		if err == nil {
-			// Generate an internal token for dashboard-to-agent communication
-			claims := &Claims{
-				Username: "dashboard-internal",
-				Role:     "admin",
-				RegisteredClaims: jwt.RegisteredClaims{
-					ExpiresAt: jwt.NewNumericDate(time.Now().Add(1 * time.Minute)),
-				},
-			}
-			token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-			tokenString, _ := token.SignedString(jwtKey)
+	// 2. Also include local stats if any
+	if s.ebpfAgent != nil {
+		localStats, _ := s.ebpfAgent.GetEgressStats()
+		mergeNetworkStats(aggregateStats, localStats)
+	}
 
-			client := &http.Client{Timeout: 3 * time.Second} // Bumped timeout just in case
-			for _, pod := range pods {
-				if pod.Status.PodIP == "" || pod.Status.Phase != corev1.PodRunning {
-					continue
-				}
+	writeJSON(w, aggregateStats)
+}
 
-				// Query the agent's network API with the internal token
-				url := fmt.Sprintf("http://%s:8080/api/network?internal=true", pod.Status.PodIP)
-				req, _ := http.NewRequest("GET", url, nil)
-				req.Header.Set("Authorization", "Bearer "+tokenString)
+// networkBreakdown is the /api/network?breakdown=ports response
+type networkBreakdown struct {
+	Flows      []types.EgressFlow `json:"flows"`
+	ByPort     []types.EgressPort `json:"by_port"`
+	ByProtocol []protocolTraffic  `json:"by_protocol"`
+}
 
-				resp, err := client.Do(req)
-				if err != nil {
-					slog.Error("Failed to query remote agent network API", "pod", pod.Name, "ip", pod.Status.PodIP, "error", err)
-					continue
-				}
+// protocolTraffic is the traffic over one L4 protocol
+type protocolTraffic struct {
+	Protocol string `json:"protocol"`
+	Bytes    uint64 `json:"bytes"`
+	Packets  uint64 `json:"packets"`
+}
 
-				var agentStats map[string]map[string]uint64
-				if err := json.NewDecoder(resp.Body).Decode(&agentStats); err == nil {
-					slog.Info("Successfully got network stats from agent", "pod", pod.Name, "numSources", len(agentStats))
-					// Merge stats
-					for src, dests := range agentStats {
-						if _, ok := aggregateStats[src]; !ok {
-							aggregateStats[src] = make(map[string]uint64)
-						}
-						for dst, bytes := range dests {
-							aggregateStats[src][dst] += bytes
-						}
-					}
-				} else {
-					slog.Error("Failed to decode agent network JSON", "pod", pod.Name, "error", err)
-				}
-				resp.Body.Close()
+// handleNetworkPorts serves the cluster-wide egress broken down by L4 protocol and
+// destination port
+func (s *Server) handleNetworkPorts(w http.ResponseWriter, r *http.Request) {
+	type flowKey struct {
+		src, dst, protocol string
+		dstPort, srcPort   uint16
+	}
+	merged := make(map[flowKey]*types.EgressFlow)
+	add := func(flows []types.EgressFlow) {
+		for _, f := range flows {
+			k := flowKey{f.Src, f.Dst, f.Protocol, f.DstPort, f.SrcPort}
+			if cur, ok := merged[k]; ok {
+				cur.Bytes += f.Bytes
+				cur.Packets += f.Packets
+				continue
 			}
+			f := f
+			merged[k] = &f
 		}
 	}
 
-	// 2. Also include local stats if any
+	s.queryAgents(r, "internal=true&breakdown=ports", func(pod *corev1.Pod, body io.Reader) error {
+		var agentStats networkBreakdown
+		if err := json.NewDecoder(body).Decode(&agentStats); err != nil {
+			return err
+		}
+		add(agentStats.Flows)
+		return nil
+	})
 	if s.ebpfAgent != nil {
-		localStats, _ := s.ebpfAgent.GetEgressStats()
-		for src, dests := range localStats {
-			if _, ok := aggregateStats[src]; !ok {
-				aggregateStats[src] = make(map[string]uint64)
-			}
-			for dst, bytes := range dests {
-				aggregateStats[src][dst] += bytes
-			}
+		localFlows, _ := s.ebpfAgent.GetEgressFlows()
+		add(localFlows)
+	}
+
+	type portKey struct {
+		protocol string
+		port     uint16
+	}
+	byPort := make(map[portKey]uint64)
+	byProtocol := make(map[string]*protocolTraffic)
+	resp := networkBreakdown{
+		Flows:      make([]types.EgressFlow, 0, len(merged)),
+		ByPort:     []types.EgressPort{},
+		ByProtocol: []protocolTraffic{},
+	}
+	for _, f := range merged {
+		resp.Flows = append(resp.Flows, *f)
+		byPort[portKey{f.Protocol, f.DstPort}] += f.Bytes
+		p, ok := byProtocol[f.Protocol]
+		if !ok {
+			p = &protocolTraffic{Protocol: f.Protocol}
+			byProtocol[f.Protocol] = p
 		}
+		p.Bytes += f.Bytes
+		p.Packets += f.Packets
+	}
+	for k, bytes := range byPort {
+		resp.ByPort = append(resp.ByPort, types.EgressPort{Protocol: k.protocol, Port: k.port, Bytes: bytes})
+	}
+	for _, p := range byProtocol {
+		resp.ByProtocol = append(resp.ByProtocol, *p)
 	}
 
-	writeJSON(w, aggregateStats)
+	sort.Slice(resp.Flows, func(i, j int) bool {
+		a, b := resp.Flows[i], resp.Flows[j]
+		if a.Bytes != b.Bytes {
+			return a.Bytes > b.Bytes
+		}
+		if a.Src != b.Src {
+			return a.Src < b.Src
+		}
+		if a.Dst != b.Dst {
+			return a.Dst < b.Dst
+		}
+		return a.DstPort < b.DstPort
+	})
+	sort.Slice(resp.ByPort, func(i, j int) bool {
+		a, b := resp.ByPort[i], resp.ByPort[j]
+		if a.Bytes != b.Bytes {
+			return a.Bytes > b.Bytes
+		}
+		if a.Protocol != b.Protocol {
+			return a.Protocol < b.Protocol
+		}
+		return a.Port < b.Port
+	})
+	sort.Slice(resp.ByProtocol, func(i, j int) bool {
+		a, b := resp.ByProtocol[i], resp.ByProtocol[j]
+		if a.Bytes != b.Bytes {
+			return a.Bytes > b.Bytes
+		}
+		return a.Protocol < b.Protocol
+	})
+
+	writeJSON(w, resp)
+}
+
+// queryAgents calls /api/network with query on every running agent pod and hands each
+// response body to decode. Internal queries are not fanned out again, which prevents
+// agents from querying each other recursively.
+func (s *Server) queryAgents(r *http.Request, query string, decode func(pod *corev1.Pod, body io.Reader) error) {
+	if r.URL.Query().Get("internal") == "true" || s.client == nil {
+		return
+	}
+
+	// 1. Get all agent pods (cached discovery)
+	pods, err := s.client.ListPodsByLabel(r.Context(), "", "app=cloudvault-agent")
+
+	slog.Info("handleNetwork aggregating from agents", "podsCount", len(pods), "err", err)
+
+	if err != nil {
+		return
+	}
+
+	// Generate an internal token for dashboard-to-agent communication
+	claims := &Claims{
+		Username: "dashboard-internal",
+		Role:     "admin",
+		RegisteredClaims: jwt.RegisteredClaims{
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(1 * time.Minute)),
+		},
+	}
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	tokenString, _ := token.SignedString(jwtKey)
+
+	client := &http.Client{Timeout: 3 * time.Second} // Bumped timeout just in case
+	for _, pod := range pods {
+		if pod.Status.PodIP == "" || pod.Status.Phase != corev1.PodRunning {
+			continue
+		}
+
+		// Query the agent's network API with the internal token
+		url := fmt.Sprintf("http://%s/api/network?%s", net.JoinHostPort(pod.Status.PodIP, "8080"), query)
+		req, _ := http.NewRequest("GET", url, nil)
+		req.Header.Set("Authorization", "Bearer "+tokenString)
+
+		resp, err := client.Do(req)
+		if err != nil {
+			slog.Error("Failed to query remote agent network API", "pod", pod.Name, "ip", pod.Status.PodIP, "error", err)
+			continue
+		}
+
+		if err := decode(pod, resp.Body); err != nil {
+			slog.Error("Failed to decode agent network JSON", "pod", pod.Name, "error", err)
+		}
+		resp.Body.Close()
+	}
+}
+
+// mergeNetworkStats adds the bytes of stats into aggregate
+func mergeNetworkStats(aggregate, stats map[string]map[string]uint64) {
+	for src, dests := range stats {
+		if _, ok := aggregate[src]; !ok {
+			aggregate[src] = make(map[string]uint64)
+		}
+		for dst, bytes := range dests {
+			aggregate[src][dst] += bytes
+		}
+	}
 }
 
 func (s *Server) handleAIMetrics(w http.ResponseWriter, r *http.Request) {
@@ -624,6 +833,19 @@ func (s *Server) handleMigrationStatus(w http.ResponseWriter, r *http.Request) {
 	writeJSON(w, status)
 }
 
+// clusterInfo describes the cluster being reported on, or returns nil when it is unknown
+func (s *Server) clusterInfo(ctx context.Context) *types.ClusterInfo {
+	if s.snapshot != nil {
+		return s.snapshot.ClusterInfo()
+	}
+	if s.client != nil {
+		if info, err := s.client.GetClusterInfo(ctx); err == nil {
+			return info
+		}
+	}
+	return nil
+}
+
 // GET /api/clusters - Get all registered clusters
 func (s *Server) handleClusters(w http.ResponseWriter, r *http.Request) {
 	if s.clusterRegistry == nil {
@@ -631,11 +853,9 @@ func (s *Server) handleClusters(w http.ResponseWriter, r *http.Request) {
 		ctx := context.Background()
 		region := "unknown"
 		clusterName := "current-cluster"
-		if s.client != nil {
-			if info, err := s.client.GetClusterInfo(ctx); err == nil {
-				region = info.Region
-				clusterName = info.Name
-			}
+		if info := s.clusterInfo(ctx); info != nil {
+			region = info.Region
+			clusterName = info.Name
 		}
 		s.store.RLock()
 		metricCount := len(s.store.Metrics)
@@ -666,10 +886,8 @@ func (s *Server) handleClusterAggregate(w http.ResponseWriter, r *http.Request)
 		// Return single cluster summary with real region
 		ctx := context.Background()
 		region := "unknown"
-		if s.client != nil {
-			if info, err := s.client.GetClusterInfo(ctx); err == nil {
-				region = info.Region
-			}
+		if info := s.clusterInfo(ctx); info != nil {
+			region = info.Region
 		}
 		s.store.RLock()
 		totalCost := s.store.Summary.TotalMonthlyCost
@@ -1281,6 +1499,7 @@ func (s *Server) handleGovernanceStatus(w http.ResponseWriter, r *http.Request)
 		"managed_pvcs":       managedCount,
 		"sig_enabled":        status.SIGEnabled,
 		"timescale_enabled":  status.TimescaleEnabled,
+		"history_enabled":    status.HistoryEnabled,
 		"autonomous_actions": actions,
 		"audit_log":          auditLog,
 		"last_reconcile":     time.Now().Format(time.RFC3339),
@@ -0,0 +1,198 @@
+//go:build linux
+
+package ebpf
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/cilium/ebpf"
+	"github.com/cilium/ebpf/link"
+	"github.com/cilium/ebpf/rlimit"
+	ebpfgen "github.com/cloudvault-io/cloudvault/pkg/ebpf/internal"
+	"github.com/cloudvault-io/cloudvault/pkg/types"
+	"golang.org/x/sys/unix"
+)
+
+// latencySlots is LAT_SLOTS in blockio.c
+const latencySlots = 32
+
+// Operations recorded in lat_key.op
+const (
+	opRead  = 0
+	opWrite = 1
+)
+
+// tracefsRoots are the usual tracefs mount points
+var tracefsRoots = []string{"/sys/kernel/tracing", "/sys/kernel/debug/tracing"}
+
+// IOAgent traces block requests to count completed I/O per device
+type IOAgent struct {
+	objs  ebpfgen.BlockioObjects
+	links []link.Link
+	boot  time.Time // Wall clock time at which the monotonic clock started
+}
+
+// NewIOAgent loads the block I/O programs and attaches them to the block_rq_issue
+// and block_rq_complete tracepoints
+func NewIOAgent() (*IOAgent, error) {
+	return NewIOAgentWithOptions(Options{})
+}
+
+// NewIOAgentWithOptions loads and attaches the block I/O programs. Only
+// Options.PinPath applies.
+func NewIOAgentWithOptions(opts Options) (*IOAgent, error) {
+	if err := rlimit.RemoveMemlock(); err != nil {
+		fmt.Fprintf(os.Stderr, "⚠️  memlock rlimit removal failed (ok on kernel ≥5.11): %v\n", err)
+	}
+
+	offset, err := rwbsOffset()
+	if err != nil {
+		return nil, err
+	}
+	spec, err := ebpfgen.LoadBlockio()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load spec: %w", err)
+	}
+	if err := spec.Variables["rwbs_offset"].Set(offset); err != nil {
+		return nil, fmt.Errorf("failed to set rwbs offset: %w", err)
+	}
+
+	a := &IOAgent{boot: bootTime()}
+	if _, err := loadPinned(spec, &a.objs, opts.PinPath, "blockio"); err != nil {
+		return nil, fmt.Errorf("failed to load objects: %w", err)
+	}
+	for _, tp := range []struct {
+		name string
+		prog *ebpf.Program
+	}{
+		{"block_rq_issue", a.objs.TraceRqIssue},
+		{"block_rq_complete", a.objs.TraceRqComplete},
+	} {
+		l, err := link.Tracepoint("block", tp.name, tp.prog, nil)
+		if err != nil {
+			_ = a.Close()
+			return nil, fmt.Errorf("failed to attach to %s: %w", tp.name, err)
+		}
+		a.links = append(a.links, l)
+	}
+	return a, nil
+}
+
+// rwbsOffset finds where block_rq_complete keeps its rwbs string, which moved when
+// the ioprio field was added
+func rwbsOffset() (uint32, error) {
+	var errs []error
+	for _, root := range tracefsRoots {
+		format, err := os.ReadFile(filepath.Join(root, "events/block/block_rq_complete/format"))
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		return fieldOffset(string(format), "rwbs")
+	}
+	return 0, fmt.Errorf("failed to read block_rq_complete format (is tracefs mounted?): %w", errors.Join(errs...))
+}
+
+// bootTime returns the wall clock time at which CLOCK_MONOTONIC, the clock of
+// bpf_ktime_get_ns, was zero
+func bootTime() time.Time {
+	var ts unix.Timespec
+	if err := unix.ClockGettime(unix.CLOCK_MONOTONIC, &ts); err != nil {
+		return time.Time{}
+	}
+	return time.Now().Add(-time.Duration(ts.Nano()))
+}
+
+// Close detaches the programs and releases their resources
+func (a *IOAgent) Close() error {
+	if a == nil {
+		return nil
+	}
+	var errs []error
+	for _, l := range a.links {
+		errs = append(errs, l.Close())
+	}
+	a.links = nil
+	return errors.Join(append(errs, a.objs.Close())...)
+}
+
+// GetBlockIO retrieves the I/O completed per block device since the programs were
+// first attached, ordered by device number. Pinned counters carry over restarts.
+func (a *IOAgent) GetBlockIO() ([]types.BlockDeviceIO, error) {
+	if a == nil || a.objs.IoStats == nil {
+		return nil, fmt.Errorf("eBPF map not initialized")
+	}
+
+	devices := make(map[uint32]*types.BlockDeviceIO)
+	device := func(dev uint32) *types.BlockDeviceIO {
+		d, ok := devices[dev]
+		if !ok {
+			// The kernel's dev_t: major << 20 | minor
+			d = &types.BlockDeviceIO{
+				Major:        dev >> 20,
+				Minor:        dev & (1<<20 - 1),
+				ReadLatency:  make([]uint64, latencySlots),
+				WriteLatency: make([]uint64, latencySlots),
+			}
+			devices[dev] = d
+		}
+		return d
+	}
+
+	var dev uint32
+	var perCPU []ebpfgen.BlockioIoStats
+	iter := a.objs.IoStats.Iterate()
+	for iter.Next(&dev, &perCPU) {
+		d := device(dev)
+		var last uint64
+		for _, v := range perCPU {
+			d.ReadOps += v.ReadOps
+			d.WriteOps += v.WriteOps
+			d.ReadBytes += v.ReadBytes
+			d.WriteBytes += v.WriteBytes
+			last = max(last, v.LastIoNs)
+		}
+		if last > 0 {
+			d.LastIO = a.boot.Add(time.Duration(last))
+		}
+	}
+	if err := iter.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate I/O stats: %w", err)
+	}
+
+	var key ebpfgen.BlockioLatKey
+	var counts []uint64
+	iter = a.objs.IoLatency.Iterate()
+	for iter.Next(&key, &counts) {
+		if int(key.Slot) >= latencySlots {
+			continue
+		}
+		hist := device(key.Dev).ReadLatency
+		if key.Op == opWrite {
+			hist = device(key.Dev).WriteLatency
+		}
+		for _, c := range counts {
+			hist[key.Slot] += c
+		}
+	}
+	if err := iter.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate I/O latency: %w", err)
+	}
+
+	result := make([]types.BlockDeviceIO, 0, len(devices))
+	for _, d := range devices {
+		result = append(result, *d)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Major != result[j].Major {
+			return result[i].Major < result[j].Major
+		}
+		return result[i].Minor < result[j].Minor
+	})
+	return result, nil
+}
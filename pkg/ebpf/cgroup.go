@@ -0,0 +1,67 @@
+//go:build linux
+
+package ebpf
+
+import (
+	"fmt"
+
+	"github.com/cilium/ebpf"
+	"github.com/cilium/ebpf/link"
+	ebpfgen "github.com/cloudvault-io/cloudvault/pkg/ebpf/internal"
+)
+
+// AttachCgroup attaches per-cgroup egress accounting to a cgroup v2 directory. The
+// program also runs for every cgroup below it, so path is usually the kubelet's
+// kubepods cgroup. The agent detaches it on Close.
+func (a *Agent) AttachCgroup(path string) error {
+	if a == nil || a.objs.CountCgroupEgress == nil {
+		return fmt.Errorf("eBPF programs not loaded (nil agent or stub mode)")
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.closed {
+		return fmt.Errorf("agent is closed")
+	}
+
+	l, err := link.AttachCgroup(link.CgroupOptions{
+		Path:    path,
+		Attach:  ebpf.AttachCGroupInetEgress,
+		Program: a.objs.CountCgroupEgress,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to attach to cgroup %s: %w", path, err)
+	}
+	a.cgroupLinks = append(a.cgroupLinks, l)
+	return nil
+}
+
+// GetCgroupEgressStats retrieves the bytes sent per cgroup ID and destination address.
+// A cgroup ID is the inode number of the cgroup's directory.
+func (a *Agent) GetCgroupEgressStats() (map[uint64]map[string]uint64, error) {
+	if a == nil || a.objs.CgroupEgressMap == nil {
+		return nil, fmt.Errorf("eBPF map not initialized")
+	}
+
+	stats := make(map[uint64]map[string]uint64)
+	var key ebpfgen.EgressCgroupKey
+	var perCPU []ebpfgen.EgressEgressStats
+
+	iter := a.objs.CgroupEgressMap.Iterate()
+	for iter.Next(&key, &perCPU) {
+		dst, ok := keyAddr(key.Family, &key.DstIp)
+		if !ok {
+			continue
+		}
+		if _, ok := stats[key.CgroupId]; !ok {
+			stats[key.CgroupId] = make(map[string]uint64)
+		}
+		for _, v := range perCPU {
+			stats[key.CgroupId][dst.String()] += v.Bytes
+		}
+	}
+	if err := iter.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate cgroup map: %w", err)
+	}
+	return stats, nil
+}
@@ -0,0 +1,24 @@
+package ebpf
+
+import "time"
+
+// FlowDelta is the traffic recorded between two calls to Agent.Delta, as bytes per
+// source and destination address
+type FlowDelta struct {
+	Start   time.Time                    `json:"start"`
+	End     time.Time                    `json:"end"`
+	Egress  map[string]map[string]uint64 `json:"egress"`
+	Ingress map[string]map[string]uint64 `json:"ingress"`
+	Map     MapStats                     `json:"map"`
+}
+
+// MapStats describes how full the flow map is. Counts are cumulative since the
+// programs were loaded.
+type MapStats struct {
+	Entries       int     `json:"entries"`
+	MaxEntries    int     `json:"max_entries"`
+	Pressure      float64 `json:"pressure"` // Entries / MaxEntries
+	NewFlows      uint64  `json:"new_flows"`
+	Evictions     uint64  `json:"evictions"` // Estimated as new flows no longer in the map
+	FailedInserts uint64  `json:"failed_inserts"`
+}
@@ -3,11 +3,14 @@
 package ebpf
 
 import (
-	"encoding/binary"
+	"errors"
 	"fmt"
 	"io"
 	"net"
 	"os"
+	"strconv"
+	"sync"
+	"time"
 
 	"github.com/cilium/ebpf"
 	"github.com/cilium/ebpf/rlimit"
@@ -17,22 +20,88 @@ import (
 // Agent handles the lifecycle of the eBPF egress monitor.
 type Agent struct {
 	objs ebpfgen.EgressObjects
+
+	mu     sync.Mutex
+	links  map[int][]io.Closer // TC attachments by interface index
+	closed bool
+	stop   chan struct{} // Closed with the agent to end interface watches
+
+	cgroupLinks []io.Closer
+
+	deltaMu       sync.Mutex
+	lastRead      time.Time                                             // End of the previous delta window
+	lastFlows     map[ebpfgen.EgressEgressKey]ebpfgen.EgressEgressStats // Counters at lastRead
+	evictions     uint64                                                // Eviction estimate last exported as a metric
+	failedInserts uint64                                                // Failed inserts last exported as a metric
 }
 
-// NewAgent initializes and loads the eBPF programs
+// NewAgent initializes and loads the eBPF programs with default options
 func NewAgent() (*Agent, error) {
+	return NewAgentWithOptions(Options{})
+}
+
+// NewAgentWithOptions initializes and loads the eBPF programs
+func NewAgentWithOptions(opts Options) (*Agent, error) {
 	// Allow the current process to lock memory for eBPF resources.
 	if err := rlimit.RemoveMemlock(); err != nil {
 		fmt.Fprintf(os.Stderr, "⚠️  memlock rlimit removal failed (ok on kernel ≥5.11): %v\n", err)
 	}
 
+	spec, err := ebpfgen.LoadEgress()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load spec: %w", err)
+	}
+	if opts.TrackSourcePorts {
+		if err := spec.Variables["track_src_port"].Set(uint8(1)); err != nil {
+			return nil, fmt.Errorf("failed to enable source ports: %w", err)
+		}
+	}
+
 	// Load pre-compiled programs and maps into the kernel
 	var objs ebpfgen.EgressObjects
-	if err := ebpfgen.LoadEgressObjects(&objs, nil); err != nil {
+	reused, err := loadPinned(spec, &objs, opts.PinPath, "egress")
+	if err != nil {
 		return nil, fmt.Errorf("failed to load objects: %w", err)
 	}
 
-	return &Agent{objs: objs}, nil
+	a := &Agent{
+		objs:     objs,
+		links:    make(map[int][]io.Closer),
+		stop:     make(chan struct{}),
+		lastRead: time.Now(),
+	}
+	if reused {
+		if err := a.resume(); err != nil {
+			_ = a.Close()
+			return nil, err
+		}
+	}
+	return a, nil
+}
+
+// resume takes the counters of pinned maps left by a previous agent as the baseline
+// of Delta and the map metrics, which would otherwise count them again
+func (a *Agent) resume() error {
+	flows, err := a.readFlows()
+	if err != nil {
+		return err
+	}
+	newFlows, err := a.counter(counterNewFlows)
+	if err != nil {
+		return err
+	}
+	failed, err := a.counter(counterFailedInserts)
+	if err != nil {
+		return err
+	}
+	a.deltaMu.Lock()
+	defer a.deltaMu.Unlock()
+	a.lastFlows = flows
+	if newFlows > uint64(len(flows)) {
+		a.evictions = newFlows - uint64(len(flows))
+	}
+	a.failedInserts = failed
+	return nil
 }
 
 // SeedSelfTest inserts a dummy entry into the map to verify the aggregation pipeline
@@ -41,84 +110,226 @@ func (a *Agent) SeedSelfTest() error {
 		return fmt.Errorf("map not initialized")
 	}
 
-	key := ebpfgen.EgressEgressKey{
-		SrcIp: binary.BigEndian.Uint32(net.ParseIP("127.0.0.1").To4()),
-		DstIp: binary.BigEndian.Uint32(net.ParseIP("8.8.8.8").To4()),
+	cpus, err := ebpf.PossibleCPU()
+	if err != nil {
+		return fmt.Errorf("failed to count CPUs: %w", err)
 	}
-	val := ebpfgen.EgressEgressStats{
+	key := newEgressKey(net.ParseIP("127.0.0.1"), net.ParseIP("8.8.8.8"))
+	// The map holds one value per CPU; the entry is recorded on the first
+	vals := make([]ebpfgen.EgressEgressStats, cpus)
+	vals[0] = ebpfgen.EgressEgressStats{
 		Bytes:   1024,
 		Packets: 1,
 	}
 
-	return a.objs.EgressMap.Update(&key, &val, ebpf.UpdateAny)
+	return a.objs.EgressMap.Update(&key, vals, ebpf.UpdateAny)
 }
 
+// Close detaches the agent from every interface and unloads its programs
 func (a *Agent) Close() error {
 	if a == nil {
 		return nil
 	}
-	return a.objs.Close()
+	a.mu.Lock()
+	if !a.closed {
+		a.closed = true
+		close(a.stop)
+	}
+	var errs []error
+	for ifindex := range a.links {
+		errs = append(errs, a.detachLocked(ifindex))
+	}
+	for _, l := range a.cgroupLinks {
+		errs = append(errs, l.Close())
+	}
+	a.cgroupLinks = nil
+	a.mu.Unlock()
+	return errors.Join(append(errs, a.objs.Close())...)
 }
 
-// GetEgressStats retrieves the latest stats from the eBPF map
+// GetEgressStats retrieves the bytes sent per source and destination address
 func (a *Agent) GetEgressStats() (map[string]map[string]uint64, error) {
-	if a == nil || a.objs.EgressMap == nil {
-		return nil, fmt.Errorf("eBPF map not initialized")
-	}
-
-	stats := make(map[string]map[string]uint64)
-	var key ebpfgen.EgressEgressKey
-	var val ebpfgen.EgressEgressStats
+	return a.flowStats(directionEgress)
+}
 
-	iter := a.objs.EgressMap.Iterate()
-	for iter.Next(&key, &val) {
-		srcIP := intToIP(key.SrcIp).String()
-		dstIP := intToIP(key.DstIp).String()
+// GetIngressStats retrieves the bytes received per source and destination address
+func (a *Agent) GetIngressStats() (map[string]map[string]uint64, error) {
+	return a.flowStats(directionIngress)
+}
 
-		if _, ok := stats[srcIP]; !ok {
-			stats[srcIP] = make(map[string]uint64)
+// flowStats aggregates the map entries recorded in one direction. An address pair
+// seen on several interfaces is summed.
+func (a *Agent) flowStats(direction uint8) (map[string]map[string]uint64, error) {
+	flows, err := a.readFlows()
+	if err != nil {
+		return nil, err
+	}
+	stats := make(map[string]map[string]uint64)
+	for key, val := range flows {
+		if key.Direction == direction {
+			addFlow(stats, &key, val.Bytes)
 		}
-		stats[srcIP][dstIP] = val.Bytes
 	}
+	return stats, nil
+}
 
-	if err := iter.Err(); err != nil {
-		return nil, fmt.Errorf("failed to iterate map: %w", err)
+// addFlow adds bytes to the address pair of a key. Keys of unknown families are ignored.
+func addFlow(stats map[string]map[string]uint64, key *ebpfgen.EgressEgressKey, bytes uint64) {
+	src, dst, ok := keyAddrs(key)
+	if !ok {
+		return
+	}
+	srcIP, dstIP := src.String(), dst.String()
+	if _, ok := stats[srcIP]; !ok {
+		stats[srcIP] = make(map[string]uint64)
 	}
+	stats[srcIP][dstIP] += bytes
+}
 
-	return stats, nil
+// Address families recorded in egress_key.family
+const (
+	familyIPv4 = 4
+	familyIPv6 = 6
+)
+
+// IP protocol numbers with names in flows
+const (
+	protoICMP   = 1
+	protoTCP    = 6
+	protoUDP    = 17
+	protoICMPv6 = 58
+)
+
+// protocolName names an IP protocol number for reports
+func protocolName(proto uint8) string {
+	switch proto {
+	case protoICMP:
+		return "icmp"
+	case protoTCP:
+		return "tcp"
+	case protoUDP:
+		return "udp"
+	case protoICMPv6:
+		return "icmpv6"
+	}
+	return strconv.Itoa(int(proto))
 }
 
-func intToIP(val uint32) net.IP {
-	ip := make(net.IP, 4)
-	// eBPF map stored it exactly as bytes from the header, meaning on x86 little-endian
-	// reading it out needs LittleEndian to preserve the original 1st-to-4th byte ordering
-	binary.LittleEndian.PutUint32(ip, val)
-	return ip
+// Traffic directions recorded in egress_key.direction, relative to the interface
+const (
+	directionIngress = 1
+	directionEgress  = 2
+)
+
+// keyAddrs returns the source and destination of a map key. Addresses are stored as
+// network-order bytes, so decoding does not depend on the host's endianness.
+func keyAddrs(key *ebpfgen.EgressEgressKey) (src, dst net.IP, ok bool) {
+	if src, ok = keyAddr(key.Family, &key.SrcIp); !ok {
+		return nil, nil, false
+	}
+	dst, _ = keyAddr(key.Family, &key.DstIp)
+	return src, dst, true
 }
 
-// AttachToInterface attaches the socket filter to a network interface
-func (a *Agent) AttachToInterface(ifaceName string) (io.Closer, error) {
-	if _, err := os.Stat("/sys/fs/bpf"); os.IsNotExist(err) {
-		return nil, fmt.Errorf("eBPF not supported: /sys/fs/bpf not found")
+// keyAddr decodes one address of a map key recorded with the given family
+func keyAddr(family uint8, addr *[16]uint8) (net.IP, bool) {
+	switch family {
+	case familyIPv4:
+		return net.IP(append([]byte(nil), addr[:4]...)), true
+	case familyIPv6:
+		return net.IP(append([]byte(nil), addr[:]...)), true
 	}
+	return nil, false
+}
 
+// newEgressKey builds the map key the eBPF program would record for an outgoing flow
+func newEgressKey(src, dst net.IP) ebpfgen.EgressEgressKey {
+	key := ebpfgen.EgressEgressKey{Direction: directionEgress}
+	if src4, dst4 := src.To4(), dst.To4(); src4 != nil && dst4 != nil {
+		key.Family = familyIPv4
+		copy(key.SrcIp[:], src4)
+		copy(key.DstIp[:], dst4)
+		return key
+	}
+	key.Family = familyIPv6
+	copy(key.SrcIp[:], src.To16())
+	copy(key.DstIp[:], dst.To16())
+	return key
+}
+
+// AttachToInterface attaches the counters to the TC ingress and egress hooks of a
+// network interface. Closing the result detaches them again.
+func (a *Agent) AttachToInterface(ifaceName string) (io.Closer, error) {
 	// Robustness check for nil receiver or stub mode
-	if a == nil || a.objs.CountEgress == nil {
-		return nil, fmt.Errorf("eBPF filter not loaded (nil agent or stub mode)")
+	if a == nil || a.objs.CountEgress == nil || a.objs.CountIngress == nil {
+		return nil, fmt.Errorf("eBPF programs not loaded (nil agent or stub mode)")
 	}
 
 	iface, err := net.InterfaceByName(ifaceName)
 	if err != nil {
 		return nil, fmt.Errorf("failed to find interface %s: %w", ifaceName, err)
 	}
+	if err := a.attach(iface.Index); err != nil {
+		return nil, fmt.Errorf("failed to attach to %s: %w", ifaceName, err)
+	}
+	return interfaceLink{agent: a, ifindex: iface.Index}, nil
+}
+
+// interfaceLink detaches the agent from one interface
+type interfaceLink struct {
+	agent   *Agent
+	ifindex int
+}
+
+func (l interfaceLink) Close() error {
+	return l.agent.detach(l.ifindex)
+}
+
+// attach hooks both programs to an interface. It is a no-op for interfaces the
+// agent is already attached to.
+func (a *Agent) attach(ifindex int) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.closed {
+		return fmt.Errorf("agent is closed")
+	}
+	if _, ok := a.links[ifindex]; ok {
+		return nil
+	}
 
-	l, err := ebpfgen.AttachSocket(ebpfgen.SocketOptions{
-		Program: a.objs.CountEgress,
-		Target:  iface.Index,
-	})
+	egress, err := ebpfgen.AttachTC(ebpfgen.TCOptions{Program: a.objs.CountEgress, Ifindex: ifindex})
+	if err != nil {
+		return fmt.Errorf("egress hook: %w", err)
+	}
+	ingress, err := ebpfgen.AttachTC(ebpfgen.TCOptions{Program: a.objs.CountIngress, Ifindex: ifindex, Ingress: true})
 	if err != nil {
-		return nil, fmt.Errorf("failed to attach socket: %w", err)
+		_ = egress.Close()
+		return fmt.Errorf("ingress hook: %w", err)
 	}
+	a.links[ifindex] = []io.Closer{egress, ingress}
+	return nil
+}
+
+// detach removes both programs from an interface
+func (a *Agent) detach(ifindex int) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.detachLocked(ifindex)
+}
+
+func (a *Agent) detachLocked(ifindex int) error {
+	var errs []error
+	for _, l := range a.links[ifindex] {
+		errs = append(errs, l.Close())
+	}
+	delete(a.links, ifindex)
+	return errors.Join(errs...)
+}
 
-	return l, nil
+// attached reports whether the agent is attached to an interface
+func (a *Agent) attached(ifindex int) bool {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	_, ok := a.links[ifindex]
+	return ok
 }
@@ -3,8 +3,12 @@
 package ebpf
 
 import (
+	"context"
 	"errors"
 	"io"
+	"time"
+
+	"github.com/cloudvault-io/cloudvault/pkg/types"
 )
 
 // Agent handles the lifecycle of the eBPF egress monitor (Mock)
@@ -14,6 +18,10 @@ func NewAgent() (*Agent, error) {
 	return &Agent{}, nil
 }
 
+func NewAgentWithOptions(opts Options) (*Agent, error) {
+	return &Agent{}, nil
+}
+
 // NewMockAgent returns a mock Agent for testing purposes.
 func NewMockAgent() *Agent {
 	return &Agent{}
@@ -34,3 +42,71 @@ func (a *Agent) GetEgressStats() (map[string]map[string]uint64, error) {
 func (a *Agent) AttachToInterface(ifaceName string) (io.Closer, error) {
 	return nil, errors.New("eBPF not supported on this platform")
 }
+
+func (a *Agent) GetIngressStats() (map[string]map[string]uint64, error) {
+	return map[string]map[string]uint64{
+		"1.1.1.1": {
+			"10.0.1.5": 52428800,
+		},
+	}, nil
+}
+
+func (a *Agent) AttachAll(ctx context.Context, opts AttachOptions) ([]string, error) {
+	return nil, errors.New("eBPF not supported on this platform")
+}
+
+func (a *Agent) Delta() (*FlowDelta, error) {
+	egress, _ := a.GetEgressStats()
+	ingress, _ := a.GetIngressStats()
+	end := time.Now()
+	stats, _ := a.MapStats()
+	return &FlowDelta{Start: end.Add(-time.Minute), End: end, Egress: egress, Ingress: ingress, Map: stats}, nil
+}
+
+func (a *Agent) MapStats() (MapStats, error) {
+	return MapStats{Entries: 2, MaxEntries: 65536, Pressure: 2.0 / 65536, NewFlows: 2}, nil
+}
+
+func (a *Agent) AttachCgroup(path string) error {
+	return errors.New("eBPF not supported on this platform")
+}
+
+func (a *Agent) GetCgroupEgressStats() (map[uint64]map[string]uint64, error) {
+	return map[uint64]map[string]uint64{
+		4242: {
+			"1.1.1.1": 157286400,
+		},
+	}, nil
+}
+
+func (a *Agent) GetEgressFlows() ([]types.EgressFlow, error) {
+	return []types.EgressFlow{
+		{Src: "10.0.1.5", Dst: "1.1.1.1", Protocol: "tcp", DstPort: 443, Bytes: 157286400, Packets: 110000},
+	}, nil
+}
+
+// IOAgent traces block requests to count completed I/O per device (Mock)
+type IOAgent struct{}
+
+func NewIOAgent() (*IOAgent, error) {
+	return &IOAgent{}, nil
+}
+
+func NewIOAgentWithOptions(opts Options) (*IOAgent, error) {
+	return &IOAgent{}, nil
+}
+
+func (a *IOAgent) Close() error {
+	return nil
+}
+
+func (a *IOAgent) GetBlockIO() ([]types.BlockDeviceIO, error) {
+	return []types.BlockDeviceIO{
+		{Major: 259, Minor: 1, ReadOps: 1200, WriteOps: 800, ReadBytes: 157286400, WriteBytes: 52428800, LastIO: time.Now()},
+	}, nil
+}
+
+// Cleanup removes pinned eBPF objects; nothing is pinned off Linux
+func Cleanup(root string) error {
+	return nil
+}
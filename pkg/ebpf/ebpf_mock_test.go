@@ -3,6 +3,7 @@
 package ebpf
 
 import (
+	"context"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -38,3 +39,29 @@ func TestMockAgent_AttachToInterface(t *testing.T) {
 	_, err := a.AttachToInterface("eth0")
 	assert.Error(t, err) // always errors on non-linux
 }
+
+func TestMockAgent_AttachAll(t *testing.T) {
+	a := NewMockAgent()
+	_, err := a.AttachAll(context.Background(), AttachOptions{Veth: true})
+	assert.Error(t, err)
+}
+
+func TestMockAgent_Delta(t *testing.T) {
+	a := NewMockAgent()
+	d, err := a.Delta()
+	require.NoError(t, err)
+	assert.True(t, d.End.After(d.Start))
+	assert.NotEmpty(t, d.Egress)
+	assert.NotEmpty(t, d.Ingress)
+	assert.Equal(t, 65536, d.Map.MaxEntries)
+}
+
+func TestMockIOAgent_GetBlockIO(t *testing.T) {
+	a, err := NewIOAgent()
+	require.NoError(t, err)
+	devices, err := a.GetBlockIO()
+	require.NoError(t, err)
+	require.NotEmpty(t, devices)
+	assert.False(t, devices[0].LastIO.IsZero())
+	assert.NoError(t, a.Close())
+}
@@ -40,3 +40,52 @@ func TestGetEgressStats(t *testing.T) {
 		}
 	}
 }
+
+func TestNewIOAgent(t *testing.T) {
+	agent, err := NewIOAgent()
+	if err != nil {
+		t.Skipf("Skipping eBPF test (likely missing privileges or tracefs): %v", err)
+	}
+	defer func() { _ = agent.Close() }()
+
+	devices, err := agent.GetBlockIO()
+	if err != nil {
+		t.Fatalf("GetBlockIO failed: %v", err)
+	}
+	for _, d := range devices {
+		if d.ReadOps+d.WriteOps == 0 {
+			t.Errorf("Device %d:%d listed without I/O", d.Major, d.Minor)
+		}
+	}
+}
+
+func TestFieldOffset(t *testing.T) {
+	// block_rq_complete before and after ioprio was added (6.x)
+	old := `name: block_rq_complete
+format:
+	field:unsigned short common_type;	offset:0;	size:2;	signed:0;
+	field:dev_t dev;	offset:8;	size:4;	signed:0;
+	field:int error;	offset:28;	size:4;	signed:1;
+	field:char rwbs[8];	offset:32;	size:8;	signed:0;
+	field:__data_loc char[] cmd;	offset:40;	size:4;	signed:0;
+`
+	current := `	field:int error;	offset:28;	size:4;	signed:1;
+	field:unsigned short ioprio;	offset:32;	size:2;	signed:0;
+	field:char rwbs[10];	offset:34;	size:10;	signed:0;
+`
+	for _, tt := range []struct {
+		format string
+		want   uint32
+	}{{old, 32}, {current, 34}} {
+		got, err := fieldOffset(tt.format, "rwbs")
+		if err != nil || got != tt.want {
+			t.Errorf("fieldOffset(rwbs) = %d, %v; want %d", got, err, tt.want)
+		}
+	}
+	if off, err := fieldOffset(old, "dev"); err != nil || off != 8 {
+		t.Errorf("fieldOffset(dev) = %d, %v; want 8", off, err)
+	}
+	if _, err := fieldOffset(old, "ioprio"); err == nil {
+		t.Error("Expected an error for a missing field")
+	}
+}
@@ -0,0 +1,182 @@
+//go:build linux
+
+package ebpf
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	ebpfgen "github.com/cloudvault-io/cloudvault/pkg/ebpf/internal"
+	"github.com/cloudvault-io/cloudvault/pkg/integrations"
+	"github.com/cloudvault-io/cloudvault/pkg/types"
+)
+
+// Indexes into the flow_counters map
+const (
+	counterNewFlows      = 0
+	counterFailedInserts = 1
+)
+
+// readFlows returns every flow in the map with its per-CPU values summed
+func (a *Agent) readFlows() (map[ebpfgen.EgressEgressKey]ebpfgen.EgressEgressStats, error) {
+	if a == nil || a.objs.EgressMap == nil {
+		return nil, fmt.Errorf("eBPF map not initialized")
+	}
+
+	flows := make(map[ebpfgen.EgressEgressKey]ebpfgen.EgressEgressStats)
+	var key ebpfgen.EgressEgressKey
+	var perCPU []ebpfgen.EgressEgressStats
+
+	iter := a.objs.EgressMap.Iterate()
+	for iter.Next(&key, &perCPU) {
+		var total ebpfgen.EgressEgressStats
+		for _, v := range perCPU {
+			total.Bytes += v.Bytes
+			total.Packets += v.Packets
+		}
+		flows[key] = total
+	}
+	if err := iter.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate map: %w", err)
+	}
+	return flows, nil
+}
+
+// GetEgressFlows retrieves the bytes sent per address pair, L4 protocol and port,
+// largest first
+func (a *Agent) GetEgressFlows() ([]types.EgressFlow, error) {
+	flows, err := a.readFlows()
+	if err != nil {
+		return nil, err
+	}
+	result := make([]types.EgressFlow, 0, len(flows))
+	for key, val := range flows {
+		if key.Direction != directionEgress {
+			continue
+		}
+		src, dst, ok := keyAddrs(&key)
+		if !ok {
+			continue
+		}
+		result = append(result, types.EgressFlow{
+			Src:      src.String(),
+			Dst:      dst.String(),
+			Protocol: protocolName(key.Protocol),
+			DstPort:  key.DstPort,
+			SrcPort:  key.SrcPortBucket,
+			Bytes:    val.Bytes,
+			Packets:  val.Packets,
+		})
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Bytes > result[j].Bytes })
+	return result, nil
+}
+
+// counter sums one flow_counters slot over all CPUs
+func (a *Agent) counter(index uint32) (uint64, error) {
+	var perCPU []uint64
+	if err := a.objs.FlowCounters.Lookup(&index, &perCPU); err != nil {
+		return 0, fmt.Errorf("failed to read flow counter %d: %w", index, err)
+	}
+	var total uint64
+	for _, v := range perCPU {
+		total += v
+	}
+	return total, nil
+}
+
+// MapStats reports how full the flow map is and how many flows it has lost
+func (a *Agent) MapStats() (MapStats, error) {
+	flows, err := a.readFlows()
+	if err != nil {
+		return MapStats{}, err
+	}
+	return a.mapStats(len(flows))
+}
+
+// mapStats builds MapStats for a map holding entries flows and updates the map metrics
+func (a *Agent) mapStats(entries int) (MapStats, error) {
+	newFlows, err := a.counter(counterNewFlows)
+	if err != nil {
+		return MapStats{}, err
+	}
+	failed, err := a.counter(counterFailedInserts)
+	if err != nil {
+		return MapStats{}, err
+	}
+
+	stats := MapStats{
+		Entries:       entries,
+		MaxEntries:    int(a.objs.EgressMap.MaxEntries()),
+		NewFlows:      newFlows,
+		FailedInserts: failed,
+	}
+	if stats.MaxEntries > 0 {
+		stats.Pressure = float64(entries) / float64(stats.MaxEntries)
+	}
+	// Entries are never deleted, so every recorded flow missing from the map was evicted
+	if newFlows > uint64(entries) {
+		stats.Evictions = newFlows - uint64(entries)
+	}
+
+	a.deltaMu.Lock()
+	defer a.deltaMu.Unlock()
+	integrations.EbpfFlowMapEntries.Set(float64(entries))
+	integrations.EbpfFlowMapPressure.Set(stats.Pressure)
+	if stats.Evictions > a.evictions {
+		integrations.EbpfFlowEvictions.Add(float64(stats.Evictions - a.evictions))
+		a.evictions = stats.Evictions
+	}
+	if failed > a.failedInserts {
+		integrations.EbpfFlowInsertFailures.Add(float64(failed - a.failedInserts))
+		a.failedInserts = failed
+	}
+	return stats, nil
+}
+
+// Delta returns the bytes recorded since the previous call, or since the programs
+// were loaded on the first call. Traffic recorded in reused pinned maps before the
+// agent started is not reported. A flow evicted and seen again within one window
+// only reports the traffic after its return.
+func (a *Agent) Delta() (*FlowDelta, error) {
+	flows, err := a.readFlows()
+	if err != nil {
+		return nil, err
+	}
+	mapStats, err := a.mapStats(len(flows))
+	if err != nil {
+		return nil, err
+	}
+
+	a.deltaMu.Lock()
+	defer a.deltaMu.Unlock()
+
+	delta := &FlowDelta{
+		Start:   a.lastRead,
+		End:     time.Now(),
+		Egress:  make(map[string]map[string]uint64),
+		Ingress: make(map[string]map[string]uint64),
+		Map:     mapStats,
+	}
+	for key, cur := range flows {
+		bytes := cur.Bytes
+		// A smaller count means the entry was evicted and recreated since the last read
+		if prev, ok := a.lastFlows[key]; ok && prev.Bytes <= cur.Bytes {
+			bytes -= prev.Bytes
+		}
+		if bytes == 0 {
+			continue
+		}
+		switch key.Direction {
+		case directionEgress:
+			addFlow(delta.Egress, &key, bytes)
+		case directionIngress:
+			addFlow(delta.Ingress, &key, bytes)
+		}
+	}
+
+	a.lastRead = delta.End
+	a.lastFlows = flows
+	return delta, nil
+}
@@ -0,0 +1,121 @@
+//go:build linux
+
+package ebpf
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net"
+	"os"
+	"path/filepath"
+
+	"github.com/vishvananda/netlink"
+	"golang.org/x/sys/unix"
+)
+
+// sysClassNet is where the kernel exposes network devices; physical ones have a device link
+const sysClassNet = "/sys/class/net"
+
+// AttachAll attaches to every physical Ethernet interface, and to veth devices when
+// opts.Veth is set. Interfaces added later are attached and removed ones detached
+// until ctx is done or the agent is closed. It returns the names of the interfaces
+// attached now.
+func (a *Agent) AttachAll(ctx context.Context, opts AttachOptions) ([]string, error) {
+	if a == nil || a.objs.CountEgress == nil || a.objs.CountIngress == nil {
+		return nil, fmt.Errorf("eBPF programs not loaded (nil agent or stub mode)")
+	}
+
+	// Subscribe before listing so an interface added in between is not missed
+	updates := make(chan netlink.LinkUpdate, 64)
+	done := make(chan struct{})
+	err := netlink.LinkSubscribeWithOptions(updates, done, netlink.LinkSubscribeOptions{
+		ErrorCallback: func(err error) {
+			select {
+			case <-done: // Unsubscribing interrupts the pending receive
+			default:
+				slog.Warn("eBPF interface watch failed", "error", err)
+			}
+		},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to subscribe to link updates: %w", err)
+	}
+
+	links, err := netlink.LinkList()
+	if err != nil {
+		close(done)
+		return nil, fmt.Errorf("failed to list interfaces: %w", err)
+	}
+	var names []string
+	for _, l := range links {
+		if !eligible(l, opts) {
+			continue
+		}
+		attrs := l.Attrs()
+		if err := a.attach(attrs.Index); err != nil {
+			slog.Warn("failed to attach eBPF to interface", "iface", attrs.Name, "error", err)
+			continue
+		}
+		names = append(names, attrs.Name)
+	}
+
+	go a.watchLinks(ctx, opts, updates, done)
+	return names, nil
+}
+
+// watchLinks follows interface hotplug until ctx is done or the agent is closed
+func (a *Agent) watchLinks(ctx context.Context, opts AttachOptions, updates <-chan netlink.LinkUpdate, done chan struct{}) {
+	defer close(done)
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-a.stop:
+			return
+		case u, ok := <-updates:
+			if !ok {
+				return
+			}
+			attrs := u.Link.Attrs()
+			switch u.Header.Type {
+			case unix.RTM_DELLINK:
+				if a.attached(attrs.Index) {
+					// The kernel already dropped the hooks along with the device
+					_ = a.detach(attrs.Index)
+					slog.Info("eBPF detached from removed interface", "iface", attrs.Name)
+				}
+			case unix.RTM_NEWLINK:
+				// NEWLINK also reports state changes of known interfaces
+				if a.attached(attrs.Index) || !eligible(u.Link, opts) {
+					continue
+				}
+				if err := a.attach(attrs.Index); err != nil {
+					slog.Warn("failed to attach eBPF to new interface", "iface", attrs.Name, "error", err)
+					continue
+				}
+				slog.Info("eBPF attached to new interface", "iface", attrs.Name)
+			}
+		}
+	}
+}
+
+// eligible reports whether an interface should be monitored: physical Ethernet
+// devices always, veth devices when requested. Bonds, bridges and VLAN devices
+// are skipped since their traffic is already seen on the underlying devices.
+func eligible(l netlink.Link, opts AttachOptions) bool {
+	attrs := l.Attrs()
+	if attrs.EncapType != "ether" || attrs.Flags&net.FlagLoopback != 0 {
+		return false
+	}
+	if l.Type() == "veth" {
+		return opts.Veth
+	}
+	return isPhysical(attrs.Name)
+}
+
+// isPhysical reports whether an interface is backed by a device
+func isPhysical(name string) bool {
+	_, err := os.Stat(filepath.Join(sysClassNet, name, "device"))
+	return err == nil
+}
@@ -3,78 +3,104 @@
 package ebpfgen
 
 import (
+	"errors"
 	"fmt"
 	"io"
 
 	"github.com/cilium/ebpf"
 	"github.com/cilium/ebpf/link"
+	"github.com/vishvananda/netlink"
 	"golang.org/x/sys/unix"
 )
 
-// SocketOptions specifies parameters for attaching a BPF program to a socket
-type SocketOptions struct {
-	Program *ebpf.Program
-	Target  int
-}
-
-// socketLink implements the link.Link interface for a socket filter
-type socketLink struct {
-	fd int
-}
-
-func (l *socketLink) Close() error {
-	return unix.Close(l.fd)
-}
+// clsactPriority is the filter priority used on kernels without TCX. A fixed
+// priority and handle make a restarted agent replace its own stale filter
+// instead of stacking a second one.
+const (
+	clsactPriority = 0xc10d
+	clsactHandle   = 1
+)
 
-func (l *socketLink) Detach() error {
-	return l.Close()
+// TCOptions specifies a program and the TC hook of an interface to attach it to
+type TCOptions struct {
+	Program *ebpf.Program
+	Ifindex int
+	Ingress bool
 }
 
-func (l *socketLink) Update(_ *ebpf.Program) error {
-	return fmt.Errorf("update not supported for socket link")
-}
+// AttachTC attaches a program to the TC ingress or egress hook of an interface.
+// It uses a TCX link where the kernel supports it (6.6+) and falls back to a
+// direct-action filter on a clsact qdisc otherwise.
+func AttachTC(opts TCOptions) (io.Closer, error) {
+	if opts.Program == nil {
+		return nil, fmt.Errorf("nil program")
+	}
 
-func (l *socketLink) Pin(_ string) error {
-	return fmt.Errorf("pinning not supported for socket link")
-}
+	attach := ebpf.AttachTCXEgress
+	if opts.Ingress {
+		attach = ebpf.AttachTCXIngress
+	}
+	l, err := link.AttachTCX(link.TCXOptions{
+		Interface: opts.Ifindex,
+		Program:   opts.Program,
+		Attach:    attach,
+		Anchor:    link.Tail(),
+	})
+	if err == nil {
+		return l, nil
+	}
+	if !errors.Is(err, ebpf.ErrNotSupported) {
+		return nil, fmt.Errorf("failed to attach TCX program: %w", err)
+	}
 
-func (l *socketLink) Unpin() error {
-	return fmt.Errorf("unpinning not supported for socket link")
+	return attachClsact(opts)
 }
 
-func (l *socketLink) Info() (*link.Info, error) {
-	return nil, fmt.Errorf("info not supported for socket link")
+// clsactFilter removes its filter on Close. The qdisc is left in place since
+// other programs may share it.
+type clsactFilter struct {
+	filter *netlink.BpfFilter
 }
 
-// AttachSocket attaches a BPF program to a socket for a specific interface index.
-func AttachSocket(opts SocketOptions) (io.Closer, error) {
-	if opts.Program == nil {
-		return nil, fmt.Errorf("nil program")
+func (f *clsactFilter) Close() error {
+	if err := netlink.FilterDel(f.filter); err != nil && !errors.Is(err, unix.ENODEV) && !errors.Is(err, unix.ENOENT) {
+		return fmt.Errorf("failed to remove clsact filter: %w", err)
 	}
+	return nil
+}
 
-	fd, err := unix.Socket(unix.AF_PACKET, unix.SOCK_RAW, int(unix.ETH_P_ALL))
-	if err != nil {
-		return nil, fmt.Errorf("failed to create raw socket: %w", err)
+func attachClsact(opts TCOptions) (io.Closer, error) {
+	qdisc := &netlink.GenericQdisc{
+		QdiscAttrs: netlink.QdiscAttrs{
+			LinkIndex: opts.Ifindex,
+			Handle:    netlink.MakeHandle(0xffff, 0),
+			Parent:    netlink.HANDLE_CLSACT,
+		},
+		QdiscType: "clsact",
 	}
-
-	// htons converts host byte order to network byte order
-	var htons = func(i uint16) uint16 {
-		return (i << 8) | (i >> 8)
+	// Adding rather than replacing keeps filters other tools installed on an existing clsact
+	if err := netlink.QdiscAdd(qdisc); err != nil && !errors.Is(err, unix.EEXIST) {
+		return nil, fmt.Errorf("failed to add clsact qdisc: %w", err)
 	}
 
-	sll := &unix.SockaddrLinklayer{
-		Ifindex:  opts.Target,
-		Protocol: htons(unix.ETH_P_ALL),
+	parent := uint32(netlink.HANDLE_MIN_EGRESS)
+	if opts.Ingress {
+		parent = netlink.HANDLE_MIN_INGRESS
 	}
-	if err := unix.Bind(fd, sll); err != nil {
-		unix.Close(fd)
-		return nil, fmt.Errorf("failed to bind to interface index %d: %w", opts.Target, err)
+	filter := &netlink.BpfFilter{
+		FilterAttrs: netlink.FilterAttrs{
+			LinkIndex: opts.Ifindex,
+			Parent:    parent,
+			Handle:    clsactHandle,
+			Priority:  clsactPriority,
+			Protocol:  unix.ETH_P_ALL,
+		},
+		Fd:           opts.Program.FD(),
+		Name:         "cloudvault",
+		DirectAction: true,
 	}
-
-	if err := unix.SetsockoptInt(fd, unix.SOL_SOCKET, unix.SO_ATTACH_BPF, opts.Program.FD()); err != nil {
-		unix.Close(fd)
-		return nil, fmt.Errorf("failed to attach BPF program: %w", err)
+	if err := netlink.FilterReplace(filter); err != nil {
+		return nil, fmt.Errorf("failed to attach clsact filter: %w", err)
 	}
-
-	return &socketLink{fd: fd}, nil
+	return &clsactFilter{filter: filter}, nil
 }
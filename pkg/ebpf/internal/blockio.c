@@ -0,0 +1,165 @@
+// +build ignore
+
+#include <linux/bpf.h>
+#include <bpf/bpf_helpers.h>
+
+#define OP_READ 0
+#define OP_WRITE 1
+
+// Latency histogram slots: slot 0 counts requests under 1us, slot i those taking
+// [2^(i-1), 2^i) us. The last slot also takes everything slower.
+#define LAT_SLOTS 32
+
+#define SECTOR_SIZE 512
+
+// Fields shared by block_rq_issue and block_rq_complete. Their offsets have not
+// changed across kernel versions; rwbs moved when ioprio was added (6.x), so its
+// offset is read from the tracepoint format at load time.
+struct block_rq_ctx {
+    __u64 common;
+    __u32 dev;
+    __u32 pad;
+    __u64 sector;
+    __u32 nr_sector;
+};
+
+// Set at load time from /sys/kernel/tracing/events/block/block_rq_complete/format
+volatile const __u32 rwbs_offset = 32;
+
+// A request in flight, identified by its device and start sector
+struct rq_key {
+    __u32 dev;
+    __u32 pad;
+    __u64 sector;
+};
+
+// Devices are kernel dev_t values: major << 20 | minor
+struct io_stats {
+    __u64 read_ops;
+    __u64 write_ops;
+    __u64 read_bytes;
+    __u64 write_bytes;
+    __u64 last_io_ns; // bpf_ktime_get_ns of the last completion
+};
+
+struct lat_key {
+    __u32 dev;
+    __u8 op;
+    __u8 slot;
+    __u16 pad;
+};
+
+// Issue times of requests in flight. Requests whose completion is never seen
+// (the program was attached mid-flight) are evicted once the map is full.
+struct {
+    __uint(type, BPF_MAP_TYPE_LRU_HASH);
+    __type(key, struct rq_key);
+    __type(value, __u64);
+    __uint(max_entries, 16384);
+} inflight SEC(".maps");
+
+struct {
+    __uint(type, BPF_MAP_TYPE_PERCPU_HASH);
+    __type(key, __u32);
+    __type(value, struct io_stats);
+    __uint(max_entries, 1024);
+} io_stats SEC(".maps");
+
+struct {
+    __uint(type, BPF_MAP_TYPE_PERCPU_HASH);
+    __type(key, struct lat_key);
+    __type(value, __u64);
+    __uint(max_entries, 8192);
+} io_latency SEC(".maps");
+
+// log2 rounded down, for v > 0
+static __always_inline __u32 log2l(__u64 v) {
+    __u32 r, shift;
+
+    r = (v > 0xFFFFFFFF) << 5; v >>= r;
+    shift = (v > 0xFFFF) << 4; v >>= shift; r |= shift;
+    shift = (v > 0xFF) << 3; v >>= shift; r |= shift;
+    shift = (v > 0xF) << 2; v >>= shift; r |= shift;
+    shift = (v > 0x3) << 1; v >>= shift; r |= shift;
+    r |= (v >> 1);
+    return r;
+}
+
+SEC("tracepoint/block/block_rq_issue")
+int trace_rq_issue(struct block_rq_ctx *ctx) {
+    struct rq_key key = {
+        .dev = ctx->dev,
+        .sector = ctx->sector,
+    };
+    __u64 ts = bpf_ktime_get_ns();
+
+    bpf_map_update_elem(&inflight, &key, &ts, BPF_ANY);
+    return 0;
+}
+
+SEC("tracepoint/block/block_rq_complete")
+int trace_rq_complete(struct block_rq_ctx *ctx) {
+    struct rq_key key = {
+        .dev = ctx->dev,
+        .sector = ctx->sector,
+    };
+    __u64 now = bpf_ktime_get_ns();
+    __u64 *start = bpf_map_lookup_elem(&inflight, &key);
+    __u64 issued = start ? *start : 0;
+    if (start)
+        bpf_map_delete_elem(&inflight, &key);
+
+    // rwbs starts with the operation, after an F for requests with a preflush
+    char rwbs[2] = {};
+    if (bpf_probe_read_kernel(rwbs, sizeof(rwbs), (void *)ctx + rwbs_offset) < 0)
+        return 0;
+    __u8 op;
+    if (rwbs[0] == 'R')
+        op = OP_READ;
+    else if (rwbs[0] == 'W' || (rwbs[0] == 'F' && rwbs[1] == 'W'))
+        op = OP_WRITE;
+    else
+        return 0; // Flushes and discards move no data
+
+    __u32 dev = ctx->dev;
+    __u64 bytes = (__u64)ctx->nr_sector * SECTOR_SIZE;
+    struct io_stats *stats = bpf_map_lookup_elem(&io_stats, &dev);
+    if (stats) {
+        if (op == OP_READ) {
+            stats->read_ops++;
+            stats->read_bytes += bytes;
+        } else {
+            stats->write_ops++;
+            stats->write_bytes += bytes;
+        }
+        stats->last_io_ns = now;
+    } else {
+        struct io_stats new_stats = {.last_io_ns = now};
+        if (op == OP_READ) {
+            new_stats.read_ops = 1;
+            new_stats.read_bytes = bytes;
+        } else {
+            new_stats.write_ops = 1;
+            new_stats.write_bytes = bytes;
+        }
+        bpf_map_update_elem(&io_stats, &dev, &new_stats, BPF_ANY);
+    }
+
+    if (!issued || now < issued)
+        return 0;
+    __u64 us = (now - issued) / 1000;
+    __u32 slot = us ? log2l(us) + 1 : 0;
+    if (slot >= LAT_SLOTS)
+        slot = LAT_SLOTS - 1;
+    struct lat_key lk = {.dev = dev, .op = op, .slot = slot};
+    __u64 *count = bpf_map_lookup_elem(&io_latency, &lk);
+    if (count) {
+        (*count)++;
+    } else {
+        __u64 one = 1;
+        bpf_map_update_elem(&io_latency, &lk, &one, BPF_ANY);
+    }
+    return 0;
+}
+
+char _license[] SEC("license") = "GPL";
@@ -0,0 +1,169 @@
+// Code generated by bpf2go; DO NOT EDIT.
+//go:build mips || mips64 || ppc64 || s390x
+
+package ebpfgen
+
+import (
+	"bytes"
+	_ "embed"
+	"fmt"
+	"io"
+	"structs"
+
+	"github.com/cilium/ebpf"
+)
+
+type BlockioIoStats struct {
+	_          structs.HostLayout
+	ReadOps    uint64
+	WriteOps   uint64
+	ReadBytes  uint64
+	WriteBytes uint64
+	LastIoNs   uint64
+}
+
+type BlockioLatKey struct {
+	_    structs.HostLayout
+	Dev  uint32
+	Op   uint8
+	Slot uint8
+	Pad  uint16
+}
+
+type BlockioRqKey struct {
+	_      structs.HostLayout
+	Dev    uint32
+	Pad    uint32
+	Sector uint64
+}
+
+// LoadBlockio returns the embedded CollectionSpec for Blockio.
+func LoadBlockio() (*ebpf.CollectionSpec, error) {
+	reader := bytes.NewReader(_BlockioBytes)
+	spec, err := ebpf.LoadCollectionSpecFromReader(reader)
+	if err != nil {
+		return nil, fmt.Errorf("can't load Blockio: %w", err)
+	}
+
+	return spec, err
+}
+
+// LoadBlockioObjects loads Blockio and converts it into a struct.
+//
+// The following types are suitable as obj argument:
+//
+//	*BlockioObjects
+//	*BlockioPrograms
+//	*BlockioMaps
+//
+// See ebpf.CollectionSpec.LoadAndAssign documentation for details.
+func LoadBlockioObjects(obj interface{}, opts *ebpf.CollectionOptions) error {
+	spec, err := LoadBlockio()
+	if err != nil {
+		return err
+	}
+
+	return spec.LoadAndAssign(obj, opts)
+}
+
+// BlockioSpecs contains maps and programs before they are loaded into the kernel.
+//
+// It can be passed ebpf.CollectionSpec.Assign.
+type BlockioSpecs struct {
+	BlockioProgramSpecs
+	BlockioMapSpecs
+	BlockioVariableSpecs
+}
+
+// BlockioProgramSpecs contains programs before they are loaded into the kernel.
+//
+// It can be passed ebpf.CollectionSpec.Assign.
+type BlockioProgramSpecs struct {
+	TraceRqComplete *ebpf.ProgramSpec `ebpf:"trace_rq_complete"`
+	TraceRqIssue    *ebpf.ProgramSpec `ebpf:"trace_rq_issue"`
+}
+
+// BlockioMapSpecs contains maps before they are loaded into the kernel.
+//
+// It can be passed ebpf.CollectionSpec.Assign.
+type BlockioMapSpecs struct {
+	Inflight  *ebpf.MapSpec `ebpf:"inflight"`
+	IoLatency *ebpf.MapSpec `ebpf:"io_latency"`
+	IoStats   *ebpf.MapSpec `ebpf:"io_stats"`
+}
+
+// BlockioVariableSpecs contains global variables before they are loaded into the kernel.
+//
+// It can be passed ebpf.CollectionSpec.Assign.
+type BlockioVariableSpecs struct {
+	RwbsOffset *ebpf.VariableSpec `ebpf:"rwbs_offset"`
+}
+
+// BlockioObjects contains all objects after they have been loaded into the kernel.
+//
+// It can be passed to LoadBlockioObjects or ebpf.CollectionSpec.LoadAndAssign.
+type BlockioObjects struct {
+	BlockioPrograms
+	BlockioMaps
+	BlockioVariables
+}
+
+func (o *BlockioObjects) Close() error {
+	return _BlockioClose(
+		&o.BlockioPrograms,
+		&o.BlockioMaps,
+	)
+}
+
+// BlockioMaps contains all maps after they have been loaded into the kernel.
+//
+// It can be passed to LoadBlockioObjects or ebpf.CollectionSpec.LoadAndAssign.
+type BlockioMaps struct {
+	Inflight  *ebpf.Map `ebpf:"inflight"`
+	IoLatency *ebpf.Map `ebpf:"io_latency"`
+	IoStats   *ebpf.Map `ebpf:"io_stats"`
+}
+
+func (m *BlockioMaps) Close() error {
+	return _BlockioClose(
+		m.Inflight,
+		m.IoLatency,
+		m.IoStats,
+	)
+}
+
+// BlockioVariables contains all global variables after they have been loaded into the kernel.
+//
+// It can be passed to LoadBlockioObjects or ebpf.CollectionSpec.LoadAndAssign.
+type BlockioVariables struct {
+	RwbsOffset *ebpf.Variable `ebpf:"rwbs_offset"`
+}
+
+// BlockioPrograms contains all programs after they have been loaded into the kernel.
+//
+// It can be passed to LoadBlockioObjects or ebpf.CollectionSpec.LoadAndAssign.
+type BlockioPrograms struct {
+	TraceRqComplete *ebpf.Program `ebpf:"trace_rq_complete"`
+	TraceRqIssue    *ebpf.Program `ebpf:"trace_rq_issue"`
+}
+
+func (p *BlockioPrograms) Close() error {
+	return _BlockioClose(
+		p.TraceRqComplete,
+		p.TraceRqIssue,
+	)
+}
+
+func _BlockioClose(closers ...io.Closer) error {
+	for _, closer := range closers {
+		if err := closer.Close(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Do not access this directly.
+//
+//go:embed blockio_bpfeb.o
+var _BlockioBytes []byte
@@ -1,3 +1,4 @@
 package ebpfgen
 
 //go:generate go run github.com/cilium/ebpf/cmd/bpf2go -cc clang -target bpfeb,bpfel Egress egress.c
+//go:generate go run github.com/cilium/ebpf/cmd/bpf2go -cc clang -target bpfeb,bpfel Blockio blockio.c
@@ -3,45 +3,222 @@
 #include <linux/bpf.h>
 #include <linux/if_ether.h>
 #include <linux/ip.h>
+#include <linux/ipv6.h>
 #include <linux/in.h>
+#include <linux/pkt_cls.h>
 #include <bpf/bpf_helpers.h>
+#include <bpf/bpf_endian.h>
+
+// 802.1Q and 802.1ad (QinQ) tags are skipped up to this depth
+#define MAX_VLAN_DEPTH 2
+#define VLAN_HLEN 4
+
+// First port of the Linux ephemeral range. Source ports from it share one bucket.
+#define EPHEMERAL_PORT_MIN 32768
+#define IP_OFFSET 0x1fff
+
+#define FAMILY_IPV4 4
+#define FAMILY_IPV6 6
+
+#define DIRECTION_INGRESS 1
+#define DIRECTION_EGRESS 2
+
+// Indexes into flow_counters
+#define COUNTER_NEW_FLOWS 0
+#define COUNTER_FAILED_INSERTS 1
+#define COUNTER_MAX 2
 
 struct egress_stats {
     __u64 bytes;
     __u64 packets;
 };
 
+// Addresses are kept in network byte order, ports in host order. IPv4 addresses
+// use the first 4 bytes and leave the rest zero. pad keeps the key free of
+// implicit padding. Ports are zero for protocols other than TCP and UDP.
 struct egress_key {
-    __u32 src_ip;
-    __u32 dst_ip;
+    __u8 src_ip[16];
+    __u8 dst_ip[16];
+    __u8 family;
+    __u8 direction;
+    __u8 protocol;
+    __u8 pad;
+    __u16 dst_port;
+    __u16 src_port_bucket; // Zero unless track_src_port is set
+};
+
+// Set at load time to record source ports: exactly below the ephemeral range, as
+// EPHEMERAL_PORT_MIN within it. Servers answering clients are told apart by it.
+volatile const __u8 track_src_port = 0;
+
+// Pod traffic is keyed by the cgroup of the sending socket rather than its source
+// address, which host-network pods share and NAT rewrites
+struct cgroup_key {
+    __u64 cgroup_id;
+    __u8 dst_ip[16];
+    __u8 family;
+    __u8 pad[7];
 };
 
+// Least recently used flows are evicted once the map is full, so new flows are
+// always recorded. Values are per CPU: each CPU only touches its own copy.
 struct {
-    __uint(type, BPF_MAP_TYPE_HASH);
+    __uint(type, BPF_MAP_TYPE_LRU_PERCPU_HASH);
     __type(key, struct egress_key);
     __type(value, struct egress_stats);
     __uint(max_entries, 65536);
 } egress_map SEC(".maps");
 
-SEC("socket")
-int count_egress(struct __sk_buff *skb) {
-    struct egress_key key = {};
-    if (bpf_skb_load_bytes(skb, 26, &key.src_ip, 4) < 0)
-        return 0;
-    if (bpf_skb_load_bytes(skb, 30, &key.dst_ip, 4) < 0)
-        return 0;
+struct {
+    __uint(type, BPF_MAP_TYPE_LRU_PERCPU_HASH);
+    __type(key, struct cgroup_key);
+    __type(value, struct egress_stats);
+    __uint(max_entries, 65536);
+} cgroup_egress_map SEC(".maps");
+
+// Map activity counters, per CPU. Comparing new flows against live entries gives evictions.
+struct {
+    __uint(type, BPF_MAP_TYPE_PERCPU_ARRAY);
+    __type(key, __u32);
+    __type(value, __u64);
+    __uint(max_entries, COUNTER_MAX);
+} flow_counters SEC(".maps");
+
+static __always_inline void bump(__u32 counter) {
+    __u64 *value = bpf_map_lookup_elem(&flow_counters, &counter);
+    if (value)
+        *value += 1;
+}
+
+// read_ports fills in the ports of a TCP or UDP packet whose L4 header starts at
+// offset. An offset of 0 means the packet carries no L4 header.
+static __always_inline void read_ports(struct __sk_buff *skb, __u32 offset, struct egress_key *key) {
+    __be16 ports[2];
+
+    if (!offset || (key->protocol != IPPROTO_TCP && key->protocol != IPPROTO_UDP))
+        return;
+    if (bpf_skb_load_bytes(skb, offset, ports, sizeof(ports)) < 0)
+        return;
+
+    key->dst_port = bpf_ntohs(ports[1]);
+    if (track_src_port) {
+        __u16 src_port = bpf_ntohs(ports[0]);
+        key->src_port_bucket = src_port < EPHEMERAL_PORT_MIN ? src_port : EPHEMERAL_PORT_MIN;
+    }
+}
+
+// count records one packet of an Ethernet device. It never changes the verdict:
+// TC_ACT_UNSPEC continues with the next classifier under clsact and TCX alike.
+static __always_inline int count(struct __sk_buff *skb, __u8 direction) {
+    struct egress_key key = {.direction = direction};
+    __u32 offset = ETH_HLEN;
+    __u32 l4_offset;
+    __be16 proto;
+
+    if (bpf_skb_load_bytes(skb, offsetof(struct ethhdr, h_proto), &proto, sizeof(proto)) < 0)
+        return TC_ACT_UNSPEC;
+
+#pragma unroll
+    for (int i = 0; i < MAX_VLAN_DEPTH; i++) {
+        if (proto != bpf_htons(ETH_P_8021Q) && proto != bpf_htons(ETH_P_8021AD))
+            break;
+        // The encapsulated ethertype follows the 2-byte tag control information
+        if (bpf_skb_load_bytes(skb, offset + 2, &proto, sizeof(proto)) < 0)
+            return TC_ACT_UNSPEC;
+        offset += VLAN_HLEN;
+    }
+
+    if (proto == bpf_htons(ETH_P_IP)) {
+        key.family = FAMILY_IPV4;
+        if (bpf_skb_load_bytes(skb, offset + offsetof(struct iphdr, saddr), key.src_ip, 4) < 0)
+            return TC_ACT_UNSPEC;
+        if (bpf_skb_load_bytes(skb, offset + offsetof(struct iphdr, daddr), key.dst_ip, 4) < 0)
+            return TC_ACT_UNSPEC;
+
+        __u8 ver_ihl;
+        __be16 frag_off;
+        if (bpf_skb_load_bytes(skb, offset + offsetof(struct iphdr, protocol), &key.protocol, 1) < 0)
+            return TC_ACT_UNSPEC;
+        if (bpf_skb_load_bytes(skb, offset, &ver_ihl, 1) < 0)
+            return TC_ACT_UNSPEC;
+        if (bpf_skb_load_bytes(skb, offset + offsetof(struct iphdr, frag_off), &frag_off, sizeof(frag_off)) < 0)
+            return TC_ACT_UNSPEC;
+        // Only the first fragment carries the L4 header
+        l4_offset = frag_off & bpf_htons(IP_OFFSET) ? 0 : offset + (ver_ihl & 0x0f) * 4;
+    } else if (proto == bpf_htons(ETH_P_IPV6)) {
+        key.family = FAMILY_IPV6;
+        if (bpf_skb_load_bytes(skb, offset + offsetof(struct ipv6hdr, saddr), key.src_ip, 16) < 0)
+            return TC_ACT_UNSPEC;
+        if (bpf_skb_load_bytes(skb, offset + offsetof(struct ipv6hdr, daddr), key.dst_ip, 16) < 0)
+            return TC_ACT_UNSPEC;
+
+        // Extension headers are not followed; such packets are counted without ports
+        if (bpf_skb_load_bytes(skb, offset + offsetof(struct ipv6hdr, nexthdr), &key.protocol, 1) < 0)
+            return TC_ACT_UNSPEC;
+        l4_offset = offset + sizeof(struct ipv6hdr);
+    } else {
+        return TC_ACT_UNSPEC;
+    }
+
+    read_ports(skb, l4_offset, &key);
 
     struct egress_stats *stats;
     stats = bpf_map_lookup_elem(&egress_map, &key);
     if (stats) {
-        __sync_fetch_and_add(&stats->bytes, skb->len);
-        __sync_fetch_and_add(&stats->packets, 1);
+        stats->bytes += skb->len;
+        stats->packets += 1;
+    } else {
+        struct egress_stats new_stats = {skb->len, 1};
+        if (bpf_map_update_elem(&egress_map, &key, &new_stats, BPF_ANY) == 0)
+            bump(COUNTER_NEW_FLOWS);
+        else
+            bump(COUNTER_FAILED_INSERTS);
+    }
+
+    return TC_ACT_UNSPEC;
+}
+
+// Attached to the TC egress hook: packets leaving the interface
+SEC("tc")
+int count_egress(struct __sk_buff *skb) {
+    return count(skb, DIRECTION_EGRESS);
+}
+
+// Attached to the TC ingress hook: packets arriving on the interface
+SEC("tc")
+int count_ingress(struct __sk_buff *skb) {
+    return count(skb, DIRECTION_INGRESS);
+}
+
+// Attached to the kubelet's pod cgroup hierarchy, so it sees the traffic of every pod
+// socket. Packet data starts at the IP header here. The packet is always allowed.
+SEC("cgroup_skb/egress")
+int count_cgroup_egress(struct __sk_buff *skb) {
+    struct cgroup_key key = {.cgroup_id = bpf_skb_cgroup_id(skb)};
+
+    if (skb->protocol == bpf_htons(ETH_P_IP)) {
+        key.family = FAMILY_IPV4;
+        if (bpf_skb_load_bytes(skb, offsetof(struct iphdr, daddr), key.dst_ip, 4) < 0)
+            return 1;
+    } else if (skb->protocol == bpf_htons(ETH_P_IPV6)) {
+        key.family = FAMILY_IPV6;
+        if (bpf_skb_load_bytes(skb, offsetof(struct ipv6hdr, daddr), key.dst_ip, 16) < 0)
+            return 1;
+    } else {
+        return 1;
+    }
+
+    struct egress_stats *stats;
+    stats = bpf_map_lookup_elem(&cgroup_egress_map, &key);
+    if (stats) {
+        stats->bytes += skb->len;
+        stats->packets += 1;
     } else {
         struct egress_stats new_stats = {skb->len, 1};
-        bpf_map_update_elem(&egress_map, &key, &new_stats, BPF_ANY);
+        bpf_map_update_elem(&cgroup_egress_map, &key, &new_stats, BPF_ANY);
     }
 
-    return -1;
+    return 1;
 }
 
 char _license[] SEC("license") = "GPL";
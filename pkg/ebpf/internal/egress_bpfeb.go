@@ -13,10 +13,24 @@ import (
 	"github.com/cilium/ebpf"
 )
 
+type EgressCgroupKey struct {
+	_        structs.HostLayout
+	CgroupId uint64
+	DstIp    [16]uint8
+	Family   uint8
+	Pad      [7]uint8
+}
+
 type EgressEgressKey struct {
-	_     structs.HostLayout
-	SrcIp uint32
-	DstIp uint32
+	_             structs.HostLayout
+	SrcIp         [16]uint8
+	DstIp         [16]uint8
+	Family        uint8
+	Direction     uint8
+	Protocol      uint8
+	Pad           uint8
+	DstPort       uint16
+	SrcPortBucket uint16
 }
 
 type EgressEgressStats struct {
@@ -67,20 +81,25 @@ type EgressSpecs struct {
 //
 // It can be passed ebpf.CollectionSpec.Assign.
 type EgressProgramSpecs struct {
-	CountEgress *ebpf.ProgramSpec `ebpf:"count_egress"`
+	CountCgroupEgress *ebpf.ProgramSpec `ebpf:"count_cgroup_egress"`
+	CountEgress       *ebpf.ProgramSpec `ebpf:"count_egress"`
+	CountIngress      *ebpf.ProgramSpec `ebpf:"count_ingress"`
 }
 
 // EgressMapSpecs contains maps before they are loaded into the kernel.
 //
 // It can be passed ebpf.CollectionSpec.Assign.
 type EgressMapSpecs struct {
-	EgressMap *ebpf.MapSpec `ebpf:"egress_map"`
+	CgroupEgressMap *ebpf.MapSpec `ebpf:"cgroup_egress_map"`
+	EgressMap       *ebpf.MapSpec `ebpf:"egress_map"`
+	FlowCounters    *ebpf.MapSpec `ebpf:"flow_counters"`
 }
 
 // EgressVariableSpecs contains global variables before they are loaded into the kernel.
 //
 // It can be passed ebpf.CollectionSpec.Assign.
 type EgressVariableSpecs struct {
+	TrackSrcPort *ebpf.VariableSpec `ebpf:"track_src_port"`
 }
 
 // EgressObjects contains all objects after they have been loaded into the kernel.
@@ -103,12 +122,16 @@ func (o *EgressObjects) Close() error {
 //
 // It can be passed to LoadEgressObjects or ebpf.CollectionSpec.LoadAndAssign.
 type EgressMaps struct {
-	EgressMap *ebpf.Map `ebpf:"egress_map"`
+	CgroupEgressMap *ebpf.Map `ebpf:"cgroup_egress_map"`
+	EgressMap       *ebpf.Map `ebpf:"egress_map"`
+	FlowCounters    *ebpf.Map `ebpf:"flow_counters"`
 }
 
 func (m *EgressMaps) Close() error {
 	return _EgressClose(
+		m.CgroupEgressMap,
 		m.EgressMap,
+		m.FlowCounters,
 	)
 }
 
@@ -116,18 +139,23 @@ func (m *EgressMaps) Close() error {
 //
 // It can be passed to LoadEgressObjects or ebpf.CollectionSpec.LoadAndAssign.
 type EgressVariables struct {
+	TrackSrcPort *ebpf.Variable `ebpf:"track_src_port"`
 }
 
 // EgressPrograms contains all programs after they have been loaded into the kernel.
 //
 // It can be passed to LoadEgressObjects or ebpf.CollectionSpec.LoadAndAssign.
 type EgressPrograms struct {
-	CountEgress *ebpf.Program `ebpf:"count_egress"`
+	CountCgroupEgress *ebpf.Program `ebpf:"count_cgroup_egress"`
+	CountEgress       *ebpf.Program `ebpf:"count_egress"`
+	CountIngress      *ebpf.Program `ebpf:"count_ingress"`
 }
 
 func (p *EgressPrograms) Close() error {
 	return _EgressClose(
+		p.CountCgroupEgress,
 		p.CountEgress,
+		p.CountIngress,
 	)
 }
 
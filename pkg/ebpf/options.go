@@ -0,0 +1,23 @@
+package ebpf
+
+// DefaultPinPath is where the agent pins its maps on bpffs
+const DefaultPinPath = "/sys/fs/bpf/cloudvault"
+
+// Options configures the programs an Agent or IOAgent loads
+type Options struct {
+	// PinPath pins the maps in a directory on bpffs, usually DefaultPinPath, so that
+	// counters survive agent restarts. Maps are not pinned when empty.
+	PinPath string
+
+	// TrackSourcePorts also records source ports: exactly below the ephemeral range
+	// (32768+), as one bucket within it. Traffic a server sends to its clients is then
+	// told apart by the server port rather than the clients' ephemeral ones.
+	TrackSourcePorts bool
+}
+
+// AttachOptions selects the interfaces Agent.AttachAll monitors
+type AttachOptions struct {
+	// Veth also attaches to veth devices. On the host end of a pod's veth pair the
+	// pod's outgoing traffic is ingress and its incoming traffic is egress.
+	Veth bool
+}
@@ -0,0 +1,195 @@
+//go:build linux
+
+package ebpf
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/cilium/ebpf"
+	"github.com/cilium/ebpf/btf"
+	"golang.org/x/sys/unix"
+)
+
+// loadPinned loads spec into objs with its maps pinned in a directory of root named
+// after the object and the layout of its maps, so that counters survive restarts.
+// Maps pinned by an earlier run with the same layout are reused and reused reports
+// whether any were; directories of other layouts are removed first. Without a root,
+// or when root is not on bpffs, the maps are not pinned.
+func loadPinned(spec *ebpf.CollectionSpec, objs any, root, object string) (reused bool, err error) {
+	if root == "" {
+		return false, spec.LoadAndAssign(objs, nil)
+	}
+	dir := filepath.Join(root, object+"-"+layoutFingerprint(spec))
+	if err := preparePinDir(root, object, dir); err != nil {
+		slog.Warn("eBPF maps will not be pinned, counters reset on restart", "path", root, "error", err)
+		return false, spec.LoadAndAssign(objs, nil)
+	}
+
+	for name, m := range spec.Maps {
+		// Internal maps hold the object's global variables, set anew on every load
+		if !strings.HasPrefix(name, ".") {
+			m.Pinning = ebpf.PinByName
+			if _, err := os.Stat(filepath.Join(dir, name)); err == nil {
+				reused = true
+			}
+		}
+	}
+	opts := &ebpf.CollectionOptions{Maps: ebpf.MapOptions{PinPath: dir}}
+	err = spec.LoadAndAssign(objs, opts)
+	if errors.Is(err, ebpf.ErrMapIncompatible) {
+		// The fingerprint misses some property the kernel checks; start over
+		slog.Warn("Pinned eBPF maps are incompatible, recreating them", "path", dir, "error", err)
+		if err := os.RemoveAll(dir); err != nil {
+			return false, fmt.Errorf("failed to remove pinned maps: %w", err)
+		}
+		if err := os.Mkdir(dir, 0o700); err != nil {
+			return false, fmt.Errorf("failed to create pin directory: %w", err)
+		}
+		return false, spec.LoadAndAssign(objs, opts)
+	}
+	return reused, err
+}
+
+// pinnedObjects are the objects loadPinned is called for, each pinning its maps in
+// "<object>-<layout>" directories of the pin root
+var pinnedObjects = []string{"egress", "blockio"}
+
+// checkBPFFS returns an error unless path, or its closest existing parent when it does
+// not exist yet, is on bpffs
+func checkBPFFS(path string) error {
+	var fs unix.Statfs_t
+	for dir := filepath.Clean(path); ; dir = filepath.Dir(dir) {
+		err := unix.Statfs(dir, &fs)
+		if err == nil {
+			break
+		}
+		if !errors.Is(err, unix.ENOENT) || dir == filepath.Dir(dir) {
+			return err
+		}
+	}
+	if fs.Type != unix.BPF_FS_MAGIC {
+		return fmt.Errorf("%s is not on bpffs", path)
+	}
+	return nil
+}
+
+// preparePinDir creates dir on bpffs and removes the pins object left by builds with
+// another map layout, whose counters cannot be carried over
+func preparePinDir(root, object, dir string) error {
+	if err := checkBPFFS(root); err != nil {
+		return err
+	}
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return err
+	}
+
+	stale, err := filepath.Glob(filepath.Join(root, object+"-*"))
+	if err != nil {
+		return err
+	}
+	for _, old := range stale {
+		if old == dir {
+			continue
+		}
+		slog.Info("Removing eBPF maps pinned with an older layout", "path", old)
+		if err := os.RemoveAll(old); err != nil {
+			return fmt.Errorf("failed to remove %s: %w", old, err)
+		}
+	}
+	return nil
+}
+
+// layoutFingerprint identifies the layout of the maps in spec: their types, sizes and
+// the BTF of their keys and values. Any change to it invalidates pinned maps.
+func layoutFingerprint(spec *ebpf.CollectionSpec) string {
+	names := make([]string, 0, len(spec.Maps))
+	for name := range spec.Maps {
+		if !strings.HasPrefix(name, ".") {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	var b strings.Builder
+	for _, name := range names {
+		m := spec.Maps[name]
+		fmt.Fprintf(&b, "%s %s %d %d %d %d ", name, m.Type, m.KeySize, m.ValueSize, m.MaxEntries, m.Flags)
+		describeType(&b, m.Key)
+		b.WriteByte(' ')
+		describeType(&b, m.Value)
+		b.WriteByte('\n')
+	}
+	sum := sha256.Sum256([]byte(b.String()))
+	return hex.EncodeToString(sum[:6])
+}
+
+// describeType writes the memory layout of t, ignoring type names
+func describeType(b *strings.Builder, t btf.Type) {
+	if t == nil {
+		b.WriteByte('?')
+		return
+	}
+	switch t := btf.UnderlyingType(t).(type) {
+	case *btf.Struct:
+		describeMembers(b, "struct", t.Size, t.Members)
+	case *btf.Union:
+		describeMembers(b, "union", t.Size, t.Members)
+	case *btf.Array:
+		fmt.Fprintf(b, "[%d]", t.Nelems)
+		describeType(b, t.Type)
+	case *btf.Int:
+		fmt.Fprintf(b, "int%d", t.Size*8)
+	case *btf.Enum:
+		fmt.Fprintf(b, "enum%d", t.Size*8)
+	default:
+		fmt.Fprintf(b, "%T", t)
+	}
+}
+
+func describeMembers(b *strings.Builder, kind string, size uint32, members []btf.Member) {
+	fmt.Fprintf(b, "%s%d{", kind, size)
+	for _, m := range members {
+		fmt.Fprintf(b, "%s@%d:%d:", m.Name, m.Offset, m.BitfieldSize)
+		describeType(b, m.Type)
+		b.WriteByte(';')
+	}
+	b.WriteByte('}')
+}
+
+// Cleanup removes the maps this package pinned under root, DefaultPinPath when empty,
+// and root itself once empty. Anything else under root is left alone, and a root not on
+// bpffs is refused. Agents started afterwards begin with fresh counters.
+func Cleanup(root string) error {
+	if root == "" {
+		root = DefaultPinPath
+	}
+	if _, err := os.Stat(root); os.IsNotExist(err) {
+		return nil
+	}
+	if err := checkBPFFS(root); err != nil {
+		return fmt.Errorf("refusing to clean up %s: %w", root, err)
+	}
+
+	for _, object := range pinnedObjects {
+		dirs, err := filepath.Glob(filepath.Join(root, object+"-*"))
+		if err != nil {
+			return err
+		}
+		for _, dir := range dirs {
+			if err := os.RemoveAll(dir); err != nil {
+				return fmt.Errorf("failed to remove pinned eBPF objects: %w", err)
+			}
+		}
+	}
+	// Only succeeds once nothing else is pinned there
+	_ = os.Remove(root)
+	return nil
+}
@@ -0,0 +1,45 @@
+package ebpf
+
+import (
+	"bufio"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// fieldOffset returns the offset of a field in a tracepoint format description, as
+// found in /sys/kernel/tracing/events/<group>/<event>/format. Field layouts change
+// between kernel versions, so offsets are looked up rather than hardcoded.
+func fieldOffset(format, field string) (uint32, error) {
+	scanner := bufio.NewScanner(strings.NewReader(format))
+	for scanner.Scan() {
+		// field:char rwbs[10];	offset:34;	size:10;	signed:0;
+		line := strings.TrimSpace(scanner.Text())
+		if !strings.HasPrefix(line, "field:") {
+			continue
+		}
+		parts := strings.Split(line, ";")
+		decl := strings.Fields(strings.TrimPrefix(parts[0], "field:"))
+		if len(decl) == 0 {
+			continue
+		}
+		name := decl[len(decl)-1]
+		if i := strings.IndexByte(name, '['); i >= 0 {
+			name = name[:i]
+		}
+		if name != field {
+			continue
+		}
+		for _, part := range parts[1:] {
+			if v, ok := strings.CutPrefix(strings.TrimSpace(part), "offset:"); ok {
+				off, err := strconv.ParseUint(v, 10, 32)
+				if err != nil {
+					return 0, fmt.Errorf("invalid offset of field %s: %w", field, err)
+				}
+				return uint32(off), nil
+			}
+		}
+		return 0, fmt.Errorf("field %s has no offset", field)
+	}
+	return 0, fmt.Errorf("field %s not found", field)
+}
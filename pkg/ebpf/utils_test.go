@@ -3,23 +3,77 @@
 package ebpf
 
 import (
+	"bytes"
+	"encoding/binary"
+	"net"
+	"os"
+	"path/filepath"
 	"testing"
 
+	"github.com/cilium/ebpf"
+	ebpfgen "github.com/cloudvault-io/cloudvault/pkg/ebpf/internal"
+	"github.com/cloudvault-io/cloudvault/pkg/types"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vishvananda/netlink"
+	"golang.org/x/sys/unix"
 )
 
-func TestIntToIP(t *testing.T) {
-	// 127.0.0.1 in little-endian uint32
-	// IP bytes: [127, 0, 0, 1]
-	// LittleEndian.Uint32([127, 0, 0, 1]) = 1*2^24 + 0*2^16 + 0*2^8 + 127 = 16777343
-	val := uint32(16777343)
-	ip := intToIP(val)
-	assert.Equal(t, "127.0.0.1", ip.String())
+func TestKeyAddrs(t *testing.T) {
+	tests := []struct {
+		name     string
+		src, dst string
+	}{
+		{"ipv4", "10.0.1.5", "8.8.8.8"},
+		{"ipv6", "fd00:10:244::5", "2600:1f18:4a3:6902::1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			key := newEgressKey(net.ParseIP(tt.src), net.ParseIP(tt.dst))
+			src, dst, ok := keyAddrs(&key)
+			require.True(t, ok)
+			assert.Equal(t, tt.src, src.String())
+			assert.Equal(t, tt.dst, dst.String())
+		})
+	}
+}
 
-	// 8.8.8.8
-	// LittleEndian.Uint32([8, 8, 8, 8]) = 0x08080808 = 134744072
-	ip2 := intToIP(uint32(134744072))
-	assert.Equal(t, "8.8.8.8", ip2.String())
+func TestKeyAddrs_UnknownFamily(t *testing.T) {
+	var key ebpfgen.EgressEgressKey
+	_, _, ok := keyAddrs(&key)
+	assert.False(t, ok)
+}
+
+// The kernel writes keys in the host's byte order, which is little-endian on x86 and
+// arm64 and big-endian on s390x. Decoding the same raw bytes with either byte order
+// must give the same addresses, since they are stored as byte arrays.
+func TestKeyAddrs_RawBytesAnyEndianness(t *testing.T) {
+	raw := make([]byte, 40)
+	copy(raw[0:], []byte{10, 0, 1, 5})
+	copy(raw[16:], []byte{1, 1, 1, 1})
+	raw[32] = 4
+
+	for _, order := range []binary.ByteOrder{binary.LittleEndian, binary.BigEndian} {
+		var key ebpfgen.EgressEgressKey
+		require.NoError(t, binary.Read(bytes.NewReader(raw), order, &key))
+		src, dst, ok := keyAddrs(&key)
+		require.True(t, ok)
+		assert.Equal(t, "10.0.1.5", src.String(), order.String())
+		assert.Equal(t, "1.1.1.1", dst.String(), order.String())
+	}
+
+	raw6 := make([]byte, 40)
+	copy(raw6[0:], net.ParseIP("fd00::1"))
+	copy(raw6[16:], net.ParseIP("2001:db8::2"))
+	raw6[32] = 6
+	for _, order := range []binary.ByteOrder{binary.LittleEndian, binary.BigEndian} {
+		var key ebpfgen.EgressEgressKey
+		require.NoError(t, binary.Read(bytes.NewReader(raw6), order, &key))
+		src, dst, ok := keyAddrs(&key)
+		require.True(t, ok)
+		assert.Equal(t, "fd00::1", src.String(), order.String())
+		assert.Equal(t, "2001:db8::2", dst.String(), order.String())
+	}
 }
 
 func TestAgent_Close_Nil(t *testing.T) {
@@ -32,3 +86,229 @@ func TestAgent_Stats_Nil(t *testing.T) {
 	_, err := a.GetEgressStats()
 	assert.Error(t, err)
 }
+
+func TestEligible(t *testing.T) {
+	veth := &netlink.Veth{LinkAttrs: netlink.LinkAttrs{Name: "veth1234", EncapType: "ether"}}
+	assert.False(t, eligible(veth, AttachOptions{}))
+	assert.True(t, eligible(veth, AttachOptions{Veth: true}))
+
+	lo := &netlink.Device{LinkAttrs: netlink.LinkAttrs{Name: "lo", EncapType: "loopback", Flags: net.FlagLoopback}}
+	assert.False(t, eligible(lo, AttachOptions{Veth: true}))
+
+	// Virtual devices without a backing device are skipped
+	bridge := &netlink.Bridge{LinkAttrs: netlink.LinkAttrs{Name: "cv-no-such-bridge", EncapType: "ether"}}
+	assert.False(t, eligible(bridge, AttachOptions{Veth: true}))
+
+	tun := &netlink.Tuntap{LinkAttrs: netlink.LinkAttrs{Name: "tun0", EncapType: "none"}}
+	assert.False(t, eligible(tun, AttachOptions{}))
+}
+
+// seedFlow records bytes for a flow split over the first two CPUs, as the programs would
+func seedFlow(t *testing.T, agent *Agent, key ebpfgen.EgressEgressKey, bytes uint64) {
+	t.Helper()
+	vals := make([]ebpfgen.EgressEgressStats, ebpf.MustPossibleCPU())
+	vals[0] = ebpfgen.EgressEgressStats{Bytes: bytes / 2, Packets: 1}
+	if len(vals) > 1 {
+		vals[1] = ebpfgen.EgressEgressStats{Bytes: bytes - bytes/2, Packets: 1}
+	} else {
+		vals[0].Bytes = bytes
+	}
+	require.NoError(t, agent.objs.EgressMap.Update(&key, vals, ebpf.UpdateAny))
+}
+
+func TestFlowStats_Direction(t *testing.T) {
+	agent, err := NewAgent()
+	if err != nil {
+		t.Skipf("Skipping eBPF test (likely missing privileges): %v", err)
+	}
+	defer func() { _ = agent.Close() }()
+
+	out := newEgressKey(net.ParseIP("10.0.1.5"), net.ParseIP("8.8.8.8"))
+	in := newEgressKey(net.ParseIP("8.8.8.8"), net.ParseIP("10.0.1.5"))
+	in.Direction = directionIngress
+	seedFlow(t, agent, out, 101)
+	seedFlow(t, agent, in, 100)
+
+	egress, err := agent.GetEgressStats()
+	require.NoError(t, err)
+	ingress, err := agent.GetIngressStats()
+	require.NoError(t, err)
+	assert.Equal(t, map[string]map[string]uint64{"10.0.1.5": {"8.8.8.8": 101}}, egress, "per-CPU values are summed")
+	assert.Equal(t, map[string]map[string]uint64{"8.8.8.8": {"10.0.1.5": 100}}, ingress)
+}
+
+func TestDelta(t *testing.T) {
+	agent, err := NewAgent()
+	if err != nil {
+		t.Skipf("Skipping eBPF test (likely missing privileges): %v", err)
+	}
+	defer func() { _ = agent.Close() }()
+
+	key := newEgressKey(net.ParseIP("10.0.1.5"), net.ParseIP("8.8.8.8"))
+	seedFlow(t, agent, key, 1000)
+
+	first, err := agent.Delta()
+	require.NoError(t, err)
+	assert.Equal(t, uint64(1000), first.Egress["10.0.1.5"]["8.8.8.8"])
+	assert.True(t, first.End.After(first.Start))
+	assert.Equal(t, 1, first.Map.Entries)
+	assert.Equal(t, 65536, first.Map.MaxEntries)
+
+	seedFlow(t, agent, key, 1500)
+	second, err := agent.Delta()
+	require.NoError(t, err)
+	assert.Equal(t, first.End, second.Start)
+	assert.Equal(t, uint64(500), second.Egress["10.0.1.5"]["8.8.8.8"])
+
+	third, err := agent.Delta()
+	require.NoError(t, err)
+	assert.Empty(t, third.Egress, "no traffic since the last read")
+
+	// A recreated entry restarts from zero
+	seedFlow(t, agent, key, 200)
+	fourth, err := agent.Delta()
+	require.NoError(t, err)
+	assert.Equal(t, uint64(200), fourth.Egress["10.0.1.5"]["8.8.8.8"])
+}
+
+func TestProtocolName(t *testing.T) {
+	assert.Equal(t, "tcp", protocolName(6))
+	assert.Equal(t, "udp", protocolName(17))
+	assert.Equal(t, "icmpv6", protocolName(58))
+	assert.Equal(t, "132", protocolName(132), "unnamed protocols use their number")
+}
+
+func TestGetEgressFlows(t *testing.T) {
+	agent, err := NewAgent()
+	if err != nil {
+		t.Skipf("Skipping eBPF test (likely missing privileges): %v", err)
+	}
+	defer func() { _ = agent.Close() }()
+
+	https := newEgressKey(net.ParseIP("10.0.1.5"), net.ParseIP("52.94.0.1"))
+	https.Protocol, https.DstPort = protoTCP, 443
+	dns := newEgressKey(net.ParseIP("10.0.1.5"), net.ParseIP("52.94.0.1"))
+	dns.Protocol, dns.DstPort = protoUDP, 53
+	in := newEgressKey(net.ParseIP("52.94.0.1"), net.ParseIP("10.0.1.5"))
+	in.Protocol, in.Direction = protoTCP, directionIngress
+	seedFlow(t, agent, https, 1000)
+	seedFlow(t, agent, dns, 10)
+	seedFlow(t, agent, in, 500)
+
+	flows, err := agent.GetEgressFlows()
+	require.NoError(t, err)
+	require.Len(t, flows, 2, "ingress flows are left out")
+	assert.Equal(t, types.EgressFlow{Src: "10.0.1.5", Dst: "52.94.0.1", Protocol: "tcp", DstPort: 443, Bytes: 1000, Packets: flows[0].Packets}, flows[0])
+	assert.Equal(t, "udp", flows[1].Protocol)
+
+	// The per-address view sums over ports
+	egress, err := agent.GetEgressStats()
+	require.NoError(t, err)
+	assert.Equal(t, uint64(1010), egress["10.0.1.5"]["52.94.0.1"])
+}
+
+func TestNewAgentWithOptions_TrackSourcePorts(t *testing.T) {
+	agent, err := NewAgentWithOptions(Options{TrackSourcePorts: true})
+	if err != nil {
+		t.Skipf("Skipping eBPF test (likely missing privileges): %v", err)
+	}
+	assert.NoError(t, agent.Close())
+}
+
+func TestLayoutFingerprint(t *testing.T) {
+	spec, err := ebpfgen.LoadEgress()
+	require.NoError(t, err)
+	fingerprint := layoutFingerprint(spec)
+	assert.Len(t, fingerprint, 12)
+	assert.Equal(t, fingerprint, layoutFingerprint(spec), "stable for the same spec")
+
+	// Variables are set on every load and do not change the layout
+	require.NoError(t, spec.Variables["track_src_port"].Set(uint8(1)))
+	assert.Equal(t, fingerprint, layoutFingerprint(spec))
+
+	spec.Maps["egress_map"].MaxEntries *= 2
+	assert.NotEqual(t, fingerprint, layoutFingerprint(spec), "resized maps are not reused")
+}
+
+// bpffsTempDir returns a fresh directory on bpffs, skipping the test without one
+func bpffsTempDir(t *testing.T) string {
+	t.Helper()
+	dir, err := os.MkdirTemp("/sys/fs/bpf", "cloudvault-test-")
+	if err != nil {
+		t.Skipf("Skipping pinning test (bpffs not mounted or writable): %v", err)
+	}
+	t.Cleanup(func() { _ = os.RemoveAll(dir) })
+	var fs unix.Statfs_t
+	if err := unix.Statfs(dir, &fs); err != nil || fs.Type != unix.BPF_FS_MAGIC {
+		t.Skip("Skipping pinning test: /sys/fs/bpf is not bpffs")
+	}
+	return dir
+}
+
+func TestNewAgentWithOptions_PinPath(t *testing.T) {
+	root := bpffsTempDir(t)
+	stale := filepath.Join(root, "egress-000000000000")
+	require.NoError(t, os.Mkdir(stale, 0o700))
+
+	agent, err := NewAgentWithOptions(Options{PinPath: root})
+	if err != nil {
+		t.Skipf("Skipping eBPF test (likely missing privileges): %v", err)
+	}
+	assert.NoDirExists(t, stale, "pins of older layouts are removed")
+	key := newEgressKey(net.ParseIP("10.0.1.5"), net.ParseIP("8.8.8.8"))
+	seedFlow(t, agent, key, 1000)
+	require.NoError(t, agent.Close())
+
+	// A restarted agent keeps the counters without reporting them again
+	agent, err = NewAgentWithOptions(Options{PinPath: root})
+	require.NoError(t, err)
+	defer func() { _ = agent.Close() }()
+	egress, err := agent.GetEgressStats()
+	require.NoError(t, err)
+	assert.Equal(t, uint64(1000), egress["10.0.1.5"]["8.8.8.8"])
+	delta, err := agent.Delta()
+	require.NoError(t, err)
+	assert.Empty(t, delta.Egress)
+
+	seedFlow(t, agent, key, 1500)
+	delta, err = agent.Delta()
+	require.NoError(t, err)
+	assert.Equal(t, uint64(500), delta.Egress["10.0.1.5"]["8.8.8.8"])
+}
+
+func TestCleanup(t *testing.T) {
+	root := filepath.Join(bpffsTempDir(t), "cloudvault")
+	agent, err := NewAgentWithOptions(Options{PinPath: root})
+	if err != nil {
+		t.Skipf("Skipping eBPF test (likely missing privileges): %v", err)
+	}
+	require.NoError(t, agent.Close())
+	require.DirExists(t, root)
+	other := filepath.Join(root, "other")
+	require.NoError(t, os.Mkdir(other, 0o700))
+
+	require.NoError(t, Cleanup(root))
+	assert.DirExists(t, other, "only this package's pins are removed")
+	matches, err := filepath.Glob(filepath.Join(root, "egress-*"))
+	require.NoError(t, err)
+	assert.Empty(t, matches)
+
+	require.NoError(t, os.Remove(other))
+	require.NoError(t, Cleanup(root))
+	assert.NoDirExists(t, root, "an empty root is removed")
+	assert.NoError(t, Cleanup(root), "nothing left to remove")
+}
+
+func TestCleanup_RefusesOutsideBPFFS(t *testing.T) {
+	root := t.TempDir()
+	keep := filepath.Join(root, "egress-000000000000", "data")
+	require.NoError(t, os.MkdirAll(filepath.Dir(keep), 0o700))
+	require.NoError(t, os.WriteFile(keep, []byte("x"), 0o600))
+
+	assert.Error(t, Cleanup(root))
+	assert.FileExists(t, keep, "nothing is removed off bpffs")
+
+	dir := filepath.Join(root, "pins", "egress-000000000000")
+	assert.Error(t, preparePinDir(filepath.Join(root, "pins"), "egress", dir))
+	assert.NoDirExists(t, filepath.Join(root, "pins"), "nothing is created off bpffs")
+}
@@ -0,0 +1,93 @@
+package graph
+
+import (
+	"cmp"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/cloudvault-io/cloudvault/pkg/cost"
+	"github.com/cloudvault-io/cloudvault/pkg/types"
+)
+
+// Scopes of a CommunicationPair
+const (
+	ScopeCrossCloud  = "cross-cloud"
+	ScopeCrossRegion = "cross-region"
+)
+
+// minCommunicationBytes is the decayed weight below which a COMMUNICATES_WITH edge is
+// dropped, so that traffic which stopped ages out of the graph
+const minCommunicationBytes = 1024
+
+// CommunicationPair is traffic from a pod to a pod or external address in another
+// cloud or region
+type CommunicationPair struct {
+	Source              types.Endpoint
+	Target              types.Endpoint
+	Scope               string  // ScopeCrossCloud or ScopeCrossRegion
+	Bytes               float64 // Bytes sent, decayed by age
+	BytesPerSecond      float64 // Smoothed send rate
+	LastSeen            time.Time
+	MonthlyTransferCost float64 // Cost of a month at BytesPerSecond
+}
+
+// checkDecay rejects decay factors that would grow or invert edge weights
+func checkDecay(decay float64) error {
+	if decay < 0 || decay > 1 {
+		return fmt.Errorf("decay must be between 0 and 1, got %v", decay)
+	}
+	return nil
+}
+
+// communicationRate is what one interval of traffic adds to an edge's rate once the
+// rate has been scaled by decay, making the rate a moving average
+func communicationRate(c types.Communication, decay float64) float64 {
+	if c.Interval <= 0 {
+		return 0
+	}
+	return (1 - decay) * float64(c.Bytes) / c.Interval.Seconds()
+}
+
+// newCommunicationPair classifies and prices the traffic of p. It reports false when
+// both endpoints are in the same region or either location is unknown.
+func newCommunicationPair(p CommunicationPair) (CommunicationPair, bool) {
+	src, dst := p.Source, p.Target
+	switch {
+	case src.Provider == "" || dst.Provider == "":
+		return p, false
+	case src.Provider != dst.Provider:
+		p.Scope = ScopeCrossCloud
+	case src.Region != "" && dst.Region != "" && src.Region != dst.Region:
+		p.Scope = ScopeCrossRegion
+	default:
+		return p, false
+	}
+	p.MonthlyTransferCost = cost.MonthlyEgressCost(p.BytesPerSecond, src.Provider, src.Region, dst.Provider, dst.Region)
+	return p, true
+}
+
+// sortCommunicationPairs orders pairs by transfer cost, then volume, largest first, and
+// keeps the first limit of them unless limit is zero or less
+func sortCommunicationPairs(pairs []CommunicationPair, limit int) []CommunicationPair {
+	sort.Slice(pairs, func(i, j int) bool {
+		a, b := pairs[i], pairs[j]
+		if a.MonthlyTransferCost != b.MonthlyTransferCost {
+			return a.MonthlyTransferCost > b.MonthlyTransferCost
+		}
+		if a.Bytes != b.Bytes {
+			return a.Bytes > b.Bytes
+		}
+		return cmp.Or(
+			cmp.Compare(a.Source.Namespace, b.Source.Namespace),
+			cmp.Compare(a.Source.Pod, b.Source.Pod),
+			cmp.Compare(a.Target.Namespace, b.Target.Namespace),
+			cmp.Compare(a.Target.Pod, b.Target.Pod),
+			cmp.Compare(a.Target.IP, b.Target.IP),
+		) < 0
+	})
+	if limit > 0 && len(pairs) > limit {
+		pairs = pairs[:limit]
+	}
+	return pairs
+}
@@ -0,0 +1,108 @@
+package graph
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"slices"
+	"sort"
+
+	"github.com/cloudvault-io/cloudvault/pkg/types"
+)
+
+// DefaultDependencyDepth follows traffic one pod away from those using a PVC
+const DefaultDependencyDepth = 1
+
+// MaxDependencyDepth bounds how far GetDependencies follows traffic between pods
+const MaxDependencyDepth = 5
+
+// ErrPVCNotFound is returned for PVCs the graph does not hold
+var ErrPVCNotFound = errors.New("PVC not found in the graph")
+
+// Dependencies is everything that depends on a PVC: the pods using it, the services
+// selecting those pods, and the workloads owning them or exchanging traffic with them
+type Dependencies struct {
+	Namespace string              `json:"namespace"`
+	PVC       string              `json:"pvc"`
+	Depth     int                 `json:"depth"`
+	Pods      []string            `json:"pods"`
+	Services  []string            `json:"services"`
+	Workloads []DependentWorkload `json:"workloads"` // Nearest first
+}
+
+// DependentWorkload is a workload depending on a PVC. A bare pod stands for itself, with
+// kind Pod.
+type DependentWorkload struct {
+	Namespace string `json:"namespace"`
+	Kind      string `json:"kind"`
+	Name      string `json:"name"`
+	Distance  int    `json:"distance"` // 0 for owners of the PVC's pods, otherwise hops of traffic away
+}
+
+// String identifies the workload as "Kind namespace/name"
+func (w DependentWorkload) String() string {
+	return fmt.Sprintf("%s %s/%s", w.Kind, w.Namespace, w.Name)
+}
+
+// checkDepth rejects depths GetDependencies does not follow
+func checkDepth(depth int) error {
+	if depth < 0 || depth > MaxDependencyDepth {
+		return fmt.Errorf("depth must be between 0 and %d, got %d", MaxDependencyDepth, depth)
+	}
+	return nil
+}
+
+// dependentPod is a pod reached from a PVC, Distance hops of traffic away
+type dependentPod struct {
+	namespace string
+	name      string
+	workload  types.Workload
+	services  []string
+	distance  int
+}
+
+// newDependencies assembles the dependencies of a PVC from the pods reached from it.
+// A pod reached several times counts at its shortest distance.
+func newDependencies(namespace, pvc string, depth int, pods []dependentPod) *Dependencies {
+	deps := &Dependencies{Namespace: namespace, PVC: pvc, Depth: depth}
+	workloads := make(map[DependentWorkload]int)
+	services := make(map[string]bool)
+	users := make(map[string]bool)
+	for _, p := range pods {
+		if p.distance == 0 {
+			users[p.name] = true
+			for _, svc := range p.services {
+				services[svc] = true
+			}
+		}
+		w := DependentWorkload{Namespace: p.namespace, Kind: p.workload.Kind, Name: p.workload.Name}
+		if w.Kind == "" {
+			w.Kind, w.Name = "Pod", p.name
+		}
+		if d, ok := workloads[w]; !ok || p.distance < d {
+			workloads[w] = p.distance
+		}
+	}
+
+	for name := range users {
+		deps.Pods = append(deps.Pods, name)
+	}
+	sort.Strings(deps.Pods)
+	for name := range services {
+		deps.Services = append(deps.Services, name)
+	}
+	sort.Strings(deps.Services)
+	for w, distance := range workloads {
+		w.Distance = distance
+		deps.Workloads = append(deps.Workloads, w)
+	}
+	slices.SortFunc(deps.Workloads, func(a, b DependentWorkload) int {
+		return cmp.Or(
+			cmp.Compare(a.Distance, b.Distance),
+			cmp.Compare(a.Namespace, b.Namespace),
+			cmp.Compare(a.Kind, b.Kind),
+			cmp.Compare(a.Name, b.Name),
+		)
+	})
+	return deps
+}
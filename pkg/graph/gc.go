@@ -0,0 +1,23 @@
+package graph
+
+import "fmt"
+
+// DefaultGCGenerations is how many sync generations a PVC or pod may go unseen before
+// a sweep removes it
+const DefaultGCGenerations = 3
+
+// GCResult counts what a sweep removed
+type GCResult struct {
+	PVCs          int
+	Pods          int
+	Relationships int // USES edges between nodes that were kept
+}
+
+// staleGeneration returns the newest generation a sweep removes when keeping the last
+// generations, counting the current one
+func staleGeneration(current int64, generations int) (int64, error) {
+	if generations < 1 {
+		return 0, fmt.Errorf("generations must be at least 1, got %d", generations)
+	}
+	return current - int64(generations), nil
+}
@@ -0,0 +1,58 @@
+package graph
+
+import (
+	"context"
+
+	"github.com/cloudvault-io/cloudvault/pkg/types"
+)
+
+// Graph is the Storage Intelligence Graph: PVCs, the pods using them, where those pods
+// run, what owns and selects them, and the traffic between pods. SIG stores it in Neo4j
+// and MemoryGraph in process memory; both answer queries the same way.
+type Graph interface {
+	// SyncPVCs creates or updates the PVCs of metrics
+	SyncPVCs(ctx context.Context, metrics []types.PVCMetric) error
+	// SyncPods creates or updates pods with their node, zone, owning workload and
+	// selecting services, and the PVCs they use among those in the graph
+	SyncPods(ctx context.Context, pods []types.PodPlacement) error
+	// MapPodToPVC records that a pod uses a PVC already in the graph. A pod of unknown
+	// region takes the PVC's region and provider; unknown PVCs are ignored.
+	MapPodToPVC(ctx context.Context, podName, namespace, pvcName string) error
+	// GetCrossRegionGravity returns "namespace/name" of PVCs used by pods in another
+	// region, once per such pod, most expensive first
+	GetCrossRegionGravity(ctx context.Context) ([]string, error)
+	// GetGravityMismatches returns pods using a PVC in another region or zone, with the
+	// monthly transfer cost that adds, most expensive first
+	GetGravityMismatches(ctx context.Context) ([]GravityMismatch, error)
+	// RecordCommunications scales the bytes and rate of the COMMUNICATES_WITH edges from
+	// the sources pods by decay, between 0 and 1, then adds comms to the edges. Agents
+	// sharing a graph each pass the pods they report, so every edge decays once; nil
+	// sources decays every edge. Edges that decay below 1 KiB are removed.
+	RecordCommunications(ctx context.Context, comms []types.Communication, decay float64, sources []types.Endpoint) error
+	// GetChattyPairs returns traffic crossing a cloud or region boundary, most expensive
+	// first. A limit of zero or less returns every pair.
+	GetChattyPairs(ctx context.Context, limit int) ([]CommunicationPair, error)
+	// GetCrossCloudWorkloads returns communicating pods on different providers
+	GetCrossCloudWorkloads(ctx context.Context) ([]CrossCloudWorkload, error)
+	// GetDependencies returns the pods using a PVC, the services selecting them and the
+	// workloads owning them or exchanging traffic with them, up to depth hops of traffic
+	// away. It returns ErrPVCNotFound for PVCs not in the graph.
+	GetDependencies(ctx context.Context, namespace, pvc string, depth int) (*Dependencies, error)
+	// GetStorageClassUtilization aggregates PVCs by storage class, most expensive first
+	GetStorageClassUtilization(ctx context.Context) ([]StorageClassStats, error)
+	// DeletePVC removes a PVC and its relationships; its pods are kept
+	DeletePVC(ctx context.Context, namespace, name string) error
+	// DeletePod removes a pod and its relationships; its PVCs are kept
+	DeletePod(ctx context.Context, namespace, name string) error
+	// Sweep removes PVCs, pods and USES relationships not synced in the last generations
+	// generations, counting the current one, then starts the next generation. Syncs
+	// stamp what they write with the current generation.
+	Sweep(ctx context.Context, generations int) (GCResult, error)
+	// Close releases the graph's resources
+	Close(ctx context.Context) error
+}
+
+var (
+	_ Graph = (*SIG)(nil)
+	_ Graph = (*MemoryGraph)(nil)
+)
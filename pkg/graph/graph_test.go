@@ -0,0 +1,494 @@
+package graph
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/cloudvault-io/cloudvault/pkg/types"
+	"github.com/neo4j/neo4j-go-driver/v5/neo4j"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const gib = 1024 * 1024 * 1024
+
+// testGraphConformance runs the behaviour every Graph backend shares. newGraph returns
+// an empty graph.
+func testGraphConformance(t *testing.T, newGraph func(t *testing.T) Graph) {
+	ctx := context.Background()
+
+	t.Run("EmptyGraph", func(t *testing.T) {
+		g := newGraph(t)
+		require.NoError(t, g.SyncPVCs(ctx, nil))
+		gravity, err := g.GetCrossRegionGravity(ctx)
+		require.NoError(t, err)
+		assert.Empty(t, gravity)
+		workloads, err := g.GetCrossCloudWorkloads(ctx)
+		require.NoError(t, err)
+		assert.Empty(t, workloads)
+		stats, err := g.GetStorageClassUtilization(ctx)
+		require.NoError(t, err)
+		assert.Empty(t, stats)
+		pairs, err := g.GetChattyPairs(ctx, 0)
+		require.NoError(t, err)
+		assert.Empty(t, pairs)
+	})
+
+	t.Run("StorageClassUtilization", func(t *testing.T) {
+		g := newGraph(t)
+		require.NoError(t, g.SyncPVCs(ctx, []types.PVCMetric{
+			{Namespace: "default", Name: "a", StorageClass: "gp3", SizeBytes: 100 * gib, UsedBytes: 80 * gib, MonthlyCost: 8},
+			{Namespace: "default", Name: "b", StorageClass: "gp3", SizeBytes: 100 * gib, UsedBytes: 40 * gib, MonthlyCost: 8},
+			{Namespace: "prod", Name: "c", StorageClass: "io2", SizeBytes: 200 * gib, UsedBytes: 150 * gib, MonthlyCost: 25},
+			{Namespace: "prod", Name: "pending", StorageClass: "io2", MonthlyCost: 1},
+			{Namespace: "prod", Name: "unclassified", SizeBytes: gib},
+		}))
+		// A resync updates rather than duplicates
+		require.NoError(t, g.SyncPVCs(ctx, []types.PVCMetric{
+			{Namespace: "default", Name: "b", StorageClass: "gp3", SizeBytes: 100 * gib, UsedBytes: 60 * gib, MonthlyCost: 8},
+		}))
+
+		stats, err := g.GetStorageClassUtilization(ctx)
+		require.NoError(t, err)
+		require.Len(t, stats, 2, "PVCs without a storage class are left out")
+		assert.Equal(t, StorageClassStats{
+			StorageClass:     "io2",
+			PVCCount:         2,
+			TotalSizeBytes:   200 * gib,
+			TotalUsedBytes:   150 * gib,
+			TotalMonthlyCost: 26,
+			AvgUtilization:   75,
+		}, stats[0], "PVCs of unknown size are left out of the average")
+		assert.Equal(t, "gp3", stats[1].StorageClass)
+		assert.Equal(t, int64(2), stats[1].PVCCount)
+		assert.InDelta(t, 70, stats[1].AvgUtilization, 1e-9)
+	})
+
+	t.Run("CrossRegionGravity", func(t *testing.T) {
+		g := newGraph(t)
+		require.NoError(t, g.SyncPVCs(ctx, []types.PVCMetric{
+			{Namespace: "default", Name: "db", Region: "us-east-1", Provider: "aws", MonthlyCost: 10},
+			{Namespace: "default", Name: "cache", Region: "us-east-1", Provider: "aws", MonthlyCost: 30},
+			{Namespace: "default", Name: "local", Region: "us-east-1", Provider: "aws"},
+			{Namespace: "default", Name: "unknown"},
+		}))
+		for pod, pvc := range map[string]string{"api": "db", "worker": "cache", "web": "local", "batch": "unknown"} {
+			require.NoError(t, g.MapPodToPVC(ctx, pod, "default", pvc))
+		}
+		require.NoError(t, g.MapPodToPVC(ctx, "ghost", "default", "missing"), "unknown PVCs are ignored")
+
+		gravity, err := g.GetCrossRegionGravity(ctx)
+		require.NoError(t, err)
+		assert.Empty(t, gravity, "pods take the region of their PVC")
+
+		// The volumes moved after the pods were mapped
+		require.NoError(t, g.SyncPVCs(ctx, []types.PVCMetric{
+			{Namespace: "default", Name: "db", Region: "us-west-2", Provider: "aws", MonthlyCost: 10},
+			{Namespace: "default", Name: "cache", Region: "eu-west-1", Provider: "aws", MonthlyCost: 30},
+		}))
+		gravity, err = g.GetCrossRegionGravity(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, []string{"default/cache", "default/db"}, gravity)
+
+		require.NoError(t, g.DeletePVC(ctx, "default", "cache"))
+		gravity, err = g.GetCrossRegionGravity(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, []string{"default/db"}, gravity)
+	})
+
+	t.Run("GravityMismatches", func(t *testing.T) {
+		g := newGraph(t)
+		perDay := func(n float64) float64 { return n * gib / (24 * 3600) }
+		require.NoError(t, g.SyncPVCs(ctx, []types.PVCMetric{
+			{Namespace: "default", Name: "shared", Provider: "aws", Region: "us-east-1", Zone: "us-east-1b", ReadThroughput: perDay(2)},
+			{Namespace: "default", Name: "db", Provider: "aws", Region: "us-east-1", Zone: "us-east-1b", WriteThroughput: perDay(4), MonthlyCost: 10},
+			{Namespace: "default", Name: "local", Provider: "aws", Region: "us-east-1", Zone: "us-east-1a"},
+		}))
+		east := func(name, zone string, pvcs ...string) types.PodPlacement {
+			return types.PodPlacement{Name: name, Namespace: "default", Node: "node-" + zone, Zone: zone,
+				Region: "us-east-1", Provider: "aws", Workload: types.Workload{Kind: "Deployment", Name: "api"}, PVCs: pvcs}
+		}
+		require.NoError(t, g.SyncPods(ctx, []types.PodPlacement{
+			east("api-1", "us-east-1a", "shared"),
+			east("api-2", "us-east-1b", "shared"),
+			east("web", "us-east-1a", "local", "missing"),
+			{Name: "dr", Namespace: "default", Node: "node-west", Zone: "us-west-2a", Region: "us-west-2", Provider: "aws", PVCs: []string{"db"}},
+		}))
+
+		mismatches, err := g.GetGravityMismatches(ctx)
+		require.NoError(t, err)
+		require.Len(t, mismatches, 2)
+		assert.Equal(t, GravityMismatch{
+			Namespace: "default", PVC: "db", Pod: "dr", Level: GravityRegion,
+			PodRegion: "us-west-2", PVCRegion: "us-east-1", PodZone: "us-west-2a", PVCZone: "us-east-1b",
+			MonthlyCostImpact: mismatches[0].MonthlyCostImpact,
+		}, mismatches[0])
+		assert.InDelta(t, 120*0.02, mismatches[0].MonthlyCostImpact, 1e-9, "120 GiB a month between regions")
+		assert.Equal(t, "api-1", mismatches[1].Pod)
+		assert.Equal(t, GravityZone, mismatches[1].Level)
+		assert.InDelta(t, 30*0.02, mismatches[1].MonthlyCostImpact, 1e-9, "half of 60 GiB a month between AWS zones")
+
+		gravity, err := g.GetCrossRegionGravity(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, []string{"default/db"}, gravity, "pods are placed by their node")
+
+		// A placed pod keeps its region when mapped to another PVC
+		require.NoError(t, g.MapPodToPVC(ctx, "dr", "default", "local"))
+		gravity, err = g.GetCrossRegionGravity(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, []string{"default/db", "default/local"}, gravity)
+	})
+
+	t.Run("Communications", func(t *testing.T) {
+		g := newGraph(t)
+		const mib = 1024 * 1024
+		placed := func(name, provider, region string) types.PodPlacement {
+			return types.PodPlacement{Name: name, Namespace: "default", Provider: provider, Region: region}
+		}
+		require.NoError(t, g.SyncPods(ctx, []types.PodPlacement{
+			placed("api", "aws", "us-east-1"),
+			placed("cache", "aws", "us-east-1"),
+			placed("report", "aws", "eu-west-1"),
+			placed("db", "gcp", "us-central1"),
+		}))
+		pod := func(name string) types.Endpoint { return types.Endpoint{Namespace: "default", Pod: name} }
+		seen := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+		sent := func(src, dst types.Endpoint, bytes uint64) types.Communication {
+			return types.Communication{Source: src, Target: dst, Bytes: bytes, Interval: time.Minute, SeenAt: seen}
+		}
+		internet := types.Endpoint{IP: "203.0.113.7", Provider: "internet"}
+		require.NoError(t, g.RecordCommunications(ctx, []types.Communication{
+			sent(pod("api"), pod("db"), 60*mib),
+			sent(pod("api"), pod("cache"), 60*mib),
+			sent(pod("api"), pod("report"), 6*mib),
+			sent(pod("api"), internet, 600*1024),
+			sent(pod("ghost"), pod("api"), 60*mib),
+		}, 0.5, nil))
+
+		pairs, err := g.GetChattyPairs(ctx, 0)
+		require.NoError(t, err)
+		require.Len(t, pairs, 3, "traffic within a region or from unplaced pods is left out")
+		assert.Equal(t, "db", pairs[0].Target.Pod)
+		assert.Equal(t, ScopeCrossCloud, pairs[0].Scope)
+		assert.Equal(t, types.Endpoint{Namespace: "default", Pod: "api", Provider: "aws", Region: "us-east-1"}, pairs[0].Source)
+		assert.InDelta(t, 60*mib, pairs[0].Bytes, 1e-6)
+		assert.InDelta(t, 0.5*mib, pairs[0].BytesPerSecond, 1e-6, "half of the new rate is kept")
+		assert.True(t, seen.Equal(pairs[0].LastSeen))
+		assert.InDelta(t, 0.5/1024*30*24*3600*0.09, pairs[0].MonthlyTransferCost, 1e-6)
+		assert.Equal(t, "report", pairs[1].Target.Pod)
+		assert.Equal(t, ScopeCrossRegion, pairs[1].Scope)
+		assert.Equal(t, types.Endpoint{IP: "203.0.113.7", Provider: "internet"}, pairs[2].Target)
+
+		pairs, err = g.GetChattyPairs(ctx, 1)
+		require.NoError(t, err)
+		assert.Len(t, pairs, 1)
+
+		workloads, err := g.GetCrossCloudWorkloads(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, []CrossCloudWorkload{{
+			SourcePod: "api", SourceNamespace: "default", SourceProvider: "aws",
+			TargetPod: "db", TargetNamespace: "default", TargetProvider: "gcp",
+		}}, workloads)
+
+		// Quiet intervals only decay
+		require.NoError(t, g.RecordCommunications(ctx, nil, 0.5, nil))
+		pairs, err = g.GetChattyPairs(ctx, 1)
+		require.NoError(t, err)
+		assert.InDelta(t, 30*mib, pairs[0].Bytes, 1e-6)
+		assert.InDelta(t, 0.25*mib, pairs[0].BytesPerSecond, 1e-6)
+
+		// Edges that decay away are removed
+		require.NoError(t, g.RecordCommunications(ctx, nil, 0, nil))
+		pairs, err = g.GetChattyPairs(ctx, 0)
+		require.NoError(t, err)
+		assert.Empty(t, pairs)
+		workloads, err = g.GetCrossCloudWorkloads(ctx)
+		require.NoError(t, err)
+		assert.Empty(t, workloads)
+
+		assert.Error(t, g.RecordCommunications(ctx, nil, 1.5, nil))
+	})
+
+	t.Run("CommunicationsDecayBySource", func(t *testing.T) {
+		g := newGraph(t)
+		require.NoError(t, g.SyncPods(ctx, []types.PodPlacement{
+			{Name: "api", Namespace: "default", Provider: "aws", Region: "us-east-1"},
+			{Name: "db", Namespace: "default", Provider: "gcp", Region: "us-central1"},
+		}))
+		pod := func(name string) types.Endpoint { return types.Endpoint{Namespace: "default", Pod: name} }
+		sent := func(src, dst types.Endpoint) types.Communication {
+			return types.Communication{Source: src, Target: dst, Bytes: gib, Interval: time.Minute, SeenAt: time.Now()}
+		}
+		require.NoError(t, g.RecordCommunications(ctx, []types.Communication{
+			sent(pod("api"), pod("db")),
+			sent(pod("db"), pod("api")),
+		}, 0.5, nil))
+
+		// The agent reporting db decays only db's traffic
+		require.NoError(t, g.RecordCommunications(ctx, nil, 0.5, []types.Endpoint{pod("db")}))
+		pairs, err := g.GetChattyPairs(ctx, 0)
+		require.NoError(t, err)
+		require.Len(t, pairs, 2)
+		bytes := make(map[string]float64)
+		for _, p := range pairs {
+			bytes[p.Source.Pod] = p.Bytes
+		}
+		assert.InDelta(t, gib, bytes["api"], 1e-6)
+		assert.InDelta(t, gib/2, bytes["db"], 1e-6)
+
+		require.NoError(t, g.RecordCommunications(ctx, nil, 0, []types.Endpoint{}))
+		pairs, err = g.GetChattyPairs(ctx, 0)
+		require.NoError(t, err)
+		assert.Len(t, pairs, 2, "no sources decays nothing")
+	})
+
+	t.Run("Dependencies", func(t *testing.T) {
+		g := newGraph(t)
+		require.NoError(t, g.SyncPVCs(ctx, []types.PVCMetric{
+			{Namespace: "default", Name: "data"},
+			{Namespace: "default", Name: "unused"},
+		}))
+		api := types.Workload{Kind: "Deployment", Name: "api"}
+		require.NoError(t, g.SyncPods(ctx, []types.PodPlacement{
+			{Name: "api-1", Namespace: "default", Workload: api, Services: []string{"api", "api-internal"}, PVCs: []string{"data"}},
+			{Name: "api-2", Namespace: "default", Workload: api, Services: []string{"api"}, PVCs: []string{"data"}},
+			{Name: "worker-0", Namespace: "default", Workload: types.Workload{Kind: "StatefulSet", Name: "worker"}, Services: []string{"worker"}},
+			{Name: "report", Namespace: "analytics"},
+			{Name: "far", Namespace: "default", Workload: types.Workload{Kind: "Deployment", Name: "far"}},
+			{Name: "unrelated", Namespace: "default"},
+		}))
+		pod := func(namespace, name string) types.Endpoint { return types.Endpoint{Namespace: namespace, Pod: name} }
+		sent := func(src, dst types.Endpoint) types.Communication {
+			return types.Communication{Source: src, Target: dst, Bytes: gib, Interval: time.Minute, SeenAt: time.Now()}
+		}
+		shared := types.Endpoint{IP: "203.0.113.7", Provider: "internet"}
+		require.NoError(t, g.RecordCommunications(ctx, []types.Communication{
+			sent(pod("default", "worker-0"), pod("default", "api-1")),
+			sent(pod("default", "api-2"), pod("analytics", "report")),
+			sent(pod("analytics", "report"), pod("default", "far")),
+			sent(pod("default", "api-1"), shared),
+			sent(pod("default", "unrelated"), shared),
+		}, 0.5, nil))
+
+		deps, err := g.GetDependencies(ctx, "default", "data", 1)
+		require.NoError(t, err)
+		assert.Equal(t, &Dependencies{
+			Namespace: "default",
+			PVC:       "data",
+			Depth:     1,
+			Pods:      []string{"api-1", "api-2"},
+			Services:  []string{"api", "api-internal"},
+			Workloads: []DependentWorkload{
+				{Namespace: "default", Kind: "Deployment", Name: "api", Distance: 0},
+				{Namespace: "analytics", Kind: "Pod", Name: "report", Distance: 1},
+				{Namespace: "default", Kind: "StatefulSet", Name: "worker", Distance: 1},
+			},
+		}, deps, "traffic counts both ways, but not through external addresses")
+
+		deps, err = g.GetDependencies(ctx, "default", "data", 2)
+		require.NoError(t, err)
+		require.Len(t, deps.Workloads, 4)
+		assert.Equal(t, DependentWorkload{Namespace: "default", Kind: "Deployment", Name: "far", Distance: 2}, deps.Workloads[3])
+
+		deps, err = g.GetDependencies(ctx, "default", "data", 0)
+		require.NoError(t, err)
+		assert.Len(t, deps.Workloads, 1)
+
+		deps, err = g.GetDependencies(ctx, "default", "unused", DefaultDependencyDepth)
+		require.NoError(t, err)
+		assert.Empty(t, deps.Pods)
+		assert.Empty(t, deps.Workloads)
+
+		_, err = g.GetDependencies(ctx, "default", "missing", 1)
+		assert.ErrorIs(t, err, ErrPVCNotFound)
+		_, err = g.GetDependencies(ctx, "default", "data", MaxDependencyDepth+1)
+		assert.Error(t, err)
+	})
+
+	t.Run("DeletePod", func(t *testing.T) {
+		g := newGraph(t)
+		require.NoError(t, g.SyncPVCs(ctx, []types.PVCMetric{
+			{Namespace: "default", Name: "data", StorageClass: "gp3", Provider: "aws", Region: "us-east-1", SizeBytes: gib},
+		}))
+		require.NoError(t, g.SyncPods(ctx, []types.PodPlacement{
+			{Name: "api", Namespace: "default", Node: "west", Provider: "aws", Region: "us-west-2", PVCs: []string{"data"}},
+			{Name: "db", Namespace: "default", Provider: "gcp", Region: "us-central1"},
+		}))
+		require.NoError(t, g.RecordCommunications(ctx, []types.Communication{{
+			Source: types.Endpoint{Namespace: "default", Pod: "api"}, Target: types.Endpoint{Namespace: "default", Pod: "db"},
+			Bytes: gib, Interval: time.Minute, SeenAt: time.Now(),
+		}}, 0.5, nil))
+
+		require.NoError(t, g.DeletePod(ctx, "default", "db"))
+		require.NoError(t, g.DeletePod(ctx, "default", "never-existed"))
+		pairs, err := g.GetChattyPairs(ctx, 0)
+		require.NoError(t, err)
+		assert.Empty(t, pairs)
+		gravity, err := g.GetCrossRegionGravity(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, []string{"default/data"}, gravity)
+
+		require.NoError(t, g.DeletePod(ctx, "default", "api"))
+		gravity, err = g.GetCrossRegionGravity(ctx)
+		require.NoError(t, err)
+		assert.Empty(t, gravity)
+		stats, err := g.GetStorageClassUtilization(ctx)
+		require.NoError(t, err)
+		assert.Len(t, stats, 1, "the PVCs of a deleted pod are kept")
+	})
+
+	t.Run("Sweep", func(t *testing.T) {
+		g := newGraph(t)
+		pvc := func(name string) types.PVCMetric {
+			return types.PVCMetric{Namespace: "default", Name: name, StorageClass: "gp3", Provider: "aws", Region: "us-east-1", SizeBytes: gib}
+		}
+		pod := func(name string, pvcs ...string) types.PodPlacement {
+			return types.PodPlacement{Name: name, Namespace: "default", Provider: "aws", Region: "us-west-2", PVCs: pvcs}
+		}
+		sync := func(pvcs []types.PVCMetric, pods ...types.PodPlacement) {
+			require.NoError(t, g.SyncPVCs(ctx, pvcs))
+			require.NoError(t, g.SyncPods(ctx, pods))
+		}
+
+		sync([]types.PVCMetric{pvc("kept"), pvc("deleted")}, pod("api", "kept"), pod("old", "deleted"))
+		result, err := g.Sweep(ctx, 2)
+		require.NoError(t, err)
+		assert.Equal(t, GCResult{}, result)
+
+		// Unseen for one generation, which is still kept
+		sync([]types.PVCMetric{pvc("kept")}, pod("api", "kept"))
+		result, err = g.Sweep(ctx, 2)
+		require.NoError(t, err)
+		assert.Equal(t, GCResult{}, result)
+
+		sync([]types.PVCMetric{pvc("kept")}, pod("api", "kept"))
+		result, err = g.Sweep(ctx, 2)
+		require.NoError(t, err)
+		assert.Equal(t, GCResult{PVCs: 1, Pods: 1}, result)
+		stats, err := g.GetStorageClassUtilization(ctx)
+		require.NoError(t, err)
+		require.Len(t, stats, 1)
+		assert.Equal(t, int64(1), stats[0].PVCCount)
+
+		// Relationships no sync reports any more are removed on their own
+		sync([]types.PVCMetric{pvc("kept"), pvc("other")}, pod("api", "kept"))
+		require.NoError(t, g.MapPodToPVC(ctx, "api", "default", "other"))
+		_, err = g.Sweep(ctx, 1)
+		require.NoError(t, err)
+		gravity, err := g.GetCrossRegionGravity(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, []string{"default/kept", "default/other"}, gravity)
+		sync([]types.PVCMetric{pvc("kept"), pvc("other")}, pod("api", "kept"))
+		result, err = g.Sweep(ctx, 1)
+		require.NoError(t, err)
+		assert.Equal(t, GCResult{Relationships: 1}, result)
+		gravity, err = g.GetCrossRegionGravity(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, []string{"default/kept"}, gravity)
+
+		_, err = g.Sweep(ctx, 0)
+		assert.Error(t, err)
+	})
+
+	t.Run("DeletePVC", func(t *testing.T) {
+		g := newGraph(t)
+		require.NoError(t, g.SyncPVCs(ctx, []types.PVCMetric{
+			{Namespace: "default", Name: "delete-me", StorageClass: "gp3", SizeBytes: gib},
+		}))
+		require.NoError(t, g.MapPodToPVC(ctx, "pod", "default", "delete-me"))
+		require.NoError(t, g.DeletePVC(ctx, "default", "delete-me"))
+		require.NoError(t, g.DeletePVC(ctx, "default", "never-existed"))
+
+		stats, err := g.GetStorageClassUtilization(ctx)
+		require.NoError(t, err)
+		assert.Empty(t, stats)
+	})
+}
+
+// testSharedSweep checks that two agents syncing and sweeping one graph agree on the
+// generation: what one agent has just synced survives the other's sweep
+func testSharedSweep(t *testing.T, a, b Graph) {
+	ctx := context.Background()
+	pvc := func(name string) []types.PVCMetric {
+		return []types.PVCMetric{{Namespace: "default", Name: name, StorageClass: "gp3", SizeBytes: gib}}
+	}
+
+	// a runs a few cycles before b's first one
+	for range 3 {
+		require.NoError(t, a.SyncPVCs(ctx, pvc("kept")))
+		_, err := a.Sweep(ctx, 2)
+		require.NoError(t, err)
+	}
+
+	require.NoError(t, b.SyncPVCs(ctx, pvc("from-b")))
+	require.NoError(t, a.SyncPVCs(ctx, pvc("kept")))
+	result, err := a.Sweep(ctx, 2)
+	require.NoError(t, err)
+	assert.Equal(t, GCResult{}, result, "b's sync is as recent as a's")
+
+	// Once b stops reporting it, sweeps by either agent count towards its removal
+	require.NoError(t, a.SyncPVCs(ctx, pvc("kept")))
+	result, err = b.Sweep(ctx, 2)
+	require.NoError(t, err)
+	assert.Equal(t, GCResult{}, result)
+	require.NoError(t, a.SyncPVCs(ctx, pvc("kept")))
+	result, err = a.Sweep(ctx, 2)
+	require.NoError(t, err)
+	assert.Equal(t, GCResult{PVCs: 1}, result)
+
+	stats, err := b.GetStorageClassUtilization(ctx)
+	require.NoError(t, err)
+	require.Len(t, stats, 1)
+	assert.Equal(t, int64(1), stats[0].PVCCount)
+}
+
+func TestMemoryGraph_SharedSweep(t *testing.T) {
+	g := NewMemoryGraph()
+	testSharedSweep(t, g, g)
+}
+
+// TestSIG_SharedSweep runs two clients, as two agents would, against the Neo4j at
+// NEO4J_TEST_URI, whose data it deletes
+func TestSIG_SharedSweep(t *testing.T) {
+	uri := os.Getenv("NEO4J_TEST_URI")
+	if uri == "" {
+		t.Skip("Requires a disposable Neo4j instance in NEO4J_TEST_URI")
+	}
+	a, err := NewSIG(uri, "neo4j", os.Getenv("NEO4J_TEST_PASSWORD"))
+	require.NoError(t, err)
+	defer func() { _ = a.Close(context.Background()) }()
+	_, err = neo4j.ExecuteQuery(context.Background(), a.driver, "MATCH (n) DETACH DELETE n", nil,
+		neo4j.EagerResultTransformer)
+	require.NoError(t, err)
+	b, err := NewSIG(uri, "neo4j", os.Getenv("NEO4J_TEST_PASSWORD"))
+	require.NoError(t, err)
+	defer func() { _ = b.Close(context.Background()) }()
+
+	testSharedSweep(t, a, b)
+}
+
+func TestMemoryGraph_Conformance(t *testing.T) {
+	testGraphConformance(t, func(t *testing.T) Graph {
+		return NewMemoryGraph()
+	})
+}
+
+// TestSIG_Conformance runs against the Neo4j at NEO4J_TEST_URI, whose data it deletes
+func TestSIG_Conformance(t *testing.T) {
+	uri := os.Getenv("NEO4J_TEST_URI")
+	if uri == "" {
+		t.Skip("Requires a disposable Neo4j instance in NEO4J_TEST_URI")
+	}
+	sig, err := NewSIG(uri, "neo4j", os.Getenv("NEO4J_TEST_PASSWORD"))
+	require.NoError(t, err)
+	defer func() { _ = sig.Close(context.Background()) }()
+
+	testGraphConformance(t, func(t *testing.T) Graph {
+		_, err := neo4j.ExecuteQuery(context.Background(), sig.driver, "MATCH (n) DETACH DELETE n", nil,
+			neo4j.EagerResultTransformer)
+		require.NoError(t, err)
+		return sig
+	})
+}
@@ -0,0 +1,56 @@
+package graph
+
+import (
+	"cmp"
+	"sort"
+
+	"github.com/cloudvault-io/cloudvault/pkg/cost"
+)
+
+// Levels of a GravityMismatch
+const (
+	GravityRegion = "region"
+	GravityZone   = "zone"
+)
+
+// GravityMismatch is a pod using a PVC in another region or zone, which pays transfer
+// fees on the bytes it reads and writes
+type GravityMismatch struct {
+	Namespace         string
+	PVC               string
+	Pod               string
+	Level             string // GravityRegion or GravityZone
+	PodRegion         string
+	PVCRegion         string
+	PodZone           string
+	PVCZone           string
+	MonthlyCostImpact float64 // Transfer cost of the pod's share of the PVC's current I/O
+}
+
+// newGravityMismatch prices a mismatch. The PVC's I/O throughput (bytes per second)
+// is split evenly between the pods using it.
+func newGravityMismatch(m GravityMismatch, provider string, throughput float64, pods int) GravityMismatch {
+	m.Level = GravityZone
+	if m.PodRegion != "" && m.PVCRegion != "" && m.PodRegion != m.PVCRegion {
+		m.Level = GravityRegion
+	}
+	if pods > 0 {
+		m.MonthlyCostImpact = cost.MonthlyTransferCost(throughput/float64(pods), provider, m.Level == GravityRegion)
+	}
+	return m
+}
+
+// sortGravityMismatches orders mismatches by cost impact, largest first
+func sortGravityMismatches(mismatches []GravityMismatch) {
+	sort.Slice(mismatches, func(i, j int) bool {
+		a, b := mismatches[i], mismatches[j]
+		if a.MonthlyCostImpact != b.MonthlyCostImpact {
+			return a.MonthlyCostImpact > b.MonthlyCostImpact
+		}
+		return cmp.Or(
+			cmp.Compare(a.Namespace, b.Namespace),
+			cmp.Compare(a.PVC, b.PVC),
+			cmp.Compare(a.Pod, b.Pod),
+		) < 0
+	})
+}
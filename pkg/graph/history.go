@@ -0,0 +1,372 @@
+package graph
+
+import (
+	"bufio"
+	"cmp"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/cloudvault-io/cloudvault/pkg/types"
+)
+
+// HistoryStore keeps per-PVC metric history for forecasting and recommendations.
+// TimescaleDB stores it in a database and FileHistoryStore in a local log.
+type HistoryStore interface {
+	// RecordMetrics stores a sample of every PVC in metrics, taken now
+	RecordMetrics(ctx context.Context, metrics []types.PVCMetric) error
+	// GetHistory returns a PVC's used bytes over the last duration, oldest first. Windows
+	// longer than two days are averaged per hour, and longer than a month per day.
+	GetHistory(ctx context.Context, namespace, name string, duration time.Duration) ([]float64, error)
+	// Close releases the store's resources
+	Close() error
+}
+
+var (
+	_ HistoryStore = (*TimescaleDB)(nil)
+	_ HistoryStore = (*FileHistoryStore)(nil)
+)
+
+// DefaultHistoryPath is where the agent keeps the embedded history store, a hostPath or
+// PVC mount so history survives restarts
+const DefaultHistoryPath = "/var/lib/cloudvault/history"
+
+// HistoryRetention is how long FileHistoryStore keeps daily averages; the recommender
+// trains on 90 days of history
+const HistoryRetention = 90 * 24 * time.Hour
+
+// History is kept raw for rawHistoryWindow, then per hour up to hourlyHistoryWindow,
+// then per day
+const (
+	rawHistoryWindow    = 2 * 24 * time.Hour
+	hourlyHistoryWindow = 31 * 24 * time.Hour
+)
+
+// historyResolution is the bucket GetHistory averages a window over, 0 for raw samples
+func historyResolution(window time.Duration) time.Duration {
+	switch {
+	case window <= rawHistoryWindow:
+		return 0
+	case window <= hourlyHistoryWindow:
+		return time.Hour
+	default:
+		return 24 * time.Hour
+	}
+}
+
+// historyRecord is a line of the history log
+type historyRecord struct {
+	Namespace string  `json:"ns"`
+	PVC       string  `json:"pvc"`
+	Time      int64   `json:"t"`           // Unix seconds, the bucket start for averages
+	Bucket    int64   `json:"b,omitempty"` // Seconds averaged over, 0 for raw samples
+	Count     int64   `json:"n,omitempty"` // Samples averaged, 1 when omitted
+	UsedBytes float64 `json:"used"`
+}
+
+// historySample is a raw sample or an average of Count samples over Bucket seconds
+type historySample struct {
+	time   int64
+	bucket int64
+	count  int64
+	used   float64
+}
+
+// rebucket moves s into the bucket of res seconds containing it, unless it already
+// averages a bucket that large
+func (s historySample) rebucket(res int64) historySample {
+	if res <= s.bucket {
+		return s
+	}
+	s.time -= s.time % res
+	s.bucket = res
+	return s
+}
+
+// mergeSamples averages samples in the same bucket. Samples must be sorted by time.
+func mergeSamples(samples []historySample) []historySample {
+	var out []historySample
+	for _, s := range samples {
+		if n := len(out); n > 0 && s.bucket > 0 && out[n-1].bucket == s.bucket && out[n-1].time == s.time {
+			last := &out[n-1]
+			last.used = (last.used*float64(last.count) + s.used*float64(s.count)) / float64(last.count+s.count)
+			last.count += s.count
+			continue
+		}
+		out = append(out, s)
+	}
+	return out
+}
+
+// downsample averages samples older than rawHistoryWindow per hour and older than
+// hourlyHistoryWindow per day, and drops those older than HistoryRetention
+func downsample(samples []historySample, now time.Time) []historySample {
+	rawBefore := now.Add(-rawHistoryWindow).Unix()
+	hourlyBefore := now.Add(-hourlyHistoryWindow).Unix()
+	dropBefore := now.Add(-HistoryRetention).Unix()
+
+	kept := make([]historySample, 0, len(samples))
+	for _, s := range samples {
+		switch {
+		case s.time < dropBefore:
+			continue
+		case s.time < hourlyBefore:
+			s = s.rebucket(int64(24 * time.Hour / time.Second))
+		case s.time < rawBefore:
+			s = s.rebucket(int64(time.Hour / time.Second))
+		}
+		kept = append(kept, s)
+	}
+	return mergeSamples(kept)
+}
+
+// historyKey identifies a PVC's series
+type historyKey struct {
+	namespace string
+	name      string
+}
+
+// FileHistoryStore is an embedded HistoryStore for agents without TimescaleDB. Samples
+// are appended to a log in its directory and downsampled in memory like the TimescaleDB
+// aggregates; the log is rewritten from memory once it holds twice as many records.
+type FileHistoryStore struct {
+	path string
+	now  func() time.Time
+
+	mu            sync.Mutex
+	log           *os.File
+	series        map[historyKey][]historySample
+	samples       int       // Samples held in memory
+	records       int       // Records in the log
+	downsampledAt time.Time // Last downsampling of the series in memory
+}
+
+// NewFileHistoryStore opens the history store in dir, creating it if needed. A record
+// cut short by a crash is skipped.
+func NewFileHistoryStore(dir string) (*FileHistoryStore, error) {
+	return openFileHistoryStore(dir, time.Now)
+}
+
+func openFileHistoryStore(dir string, now func() time.Time) (*FileHistoryStore, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create history directory: %w", err)
+	}
+	s := &FileHistoryStore{
+		path:   filepath.Join(dir, "history.log"),
+		now:    now,
+		series: make(map[historyKey][]historySample),
+	}
+	if err := s.load(); err != nil {
+		return nil, err
+	}
+	s.downsample()
+	if err := s.compact(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// load reads the log into memory
+func (s *FileHistoryStore) load() error {
+	f, err := os.Open(s.path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to open history log: %w", err)
+	}
+	defer func() { _ = f.Close() }()
+
+	skipped := 0
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var r historyRecord
+		if err := json.Unmarshal(scanner.Bytes(), &r); err != nil {
+			skipped++
+			continue
+		}
+		key := historyKey{namespace: r.Namespace, name: r.PVC}
+		s.series[key] = append(s.series[key], historySample{
+			time:   r.Time,
+			bucket: r.Bucket,
+			count:  max(r.Count, 1),
+			used:   r.UsedBytes,
+		})
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("failed to read history log: %w", err)
+	}
+	if skipped > 0 {
+		slog.Warn("Skipped unreadable history records", "path", s.path, "count", skipped)
+	}
+
+	for key, samples := range s.series {
+		slices.SortStableFunc(samples, func(a, b historySample) int { return cmp.Compare(a.time, b.time) })
+		s.series[key] = samples
+	}
+	return nil
+}
+
+// downsample downsamples every series in memory and forgets PVCs with no history left
+func (s *FileHistoryStore) downsample() {
+	now := s.now()
+	s.samples = 0
+	for key, samples := range s.series {
+		samples = downsample(samples, now)
+		if len(samples) == 0 {
+			delete(s.series, key)
+			continue
+		}
+		s.series[key] = samples
+		s.samples += len(samples)
+	}
+	s.downsampledAt = now
+}
+
+// compact replaces the log with the series in memory. The old log stays open for
+// appending until the new one is in place.
+func (s *FileHistoryStore) compact() error {
+	tmp, err := os.CreateTemp(filepath.Dir(s.path), ".history-*.log")
+	if err != nil {
+		return err
+	}
+	defer func() { _ = os.Remove(tmp.Name()) }()
+
+	w := bufio.NewWriter(tmp)
+	enc := json.NewEncoder(w)
+	for key, samples := range s.series {
+		for _, sample := range samples {
+			if err := enc.Encode(newHistoryRecord(key, sample)); err != nil {
+				_ = tmp.Close()
+				return err
+			}
+		}
+	}
+	if err := w.Flush(); err != nil {
+		_ = tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		_ = tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp.Name(), s.path); err != nil {
+		return err
+	}
+	s.records = s.samples
+
+	log, err := os.OpenFile(s.path, os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return err
+	}
+	if s.log != nil {
+		_ = s.log.Close()
+	}
+	s.log = log
+	return nil
+}
+
+func newHistoryRecord(key historyKey, sample historySample) historyRecord {
+	r := historyRecord{
+		Namespace: key.namespace,
+		PVC:       key.name,
+		Time:      sample.time,
+		Bucket:    sample.bucket,
+		UsedBytes: sample.used,
+	}
+	if sample.count > 1 {
+		r.Count = sample.count
+	}
+	return r
+}
+
+// RecordMetrics implements HistoryStore. Samples are appended to the log before they are
+// added to memory.
+func (s *FileHistoryStore) RecordMetrics(ctx context.Context, metrics []types.PVCMetric) error {
+	if len(metrics) == 0 {
+		return nil
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.log == nil {
+		return fmt.Errorf("history store is closed")
+	}
+
+	now := s.now()
+	w := bufio.NewWriter(s.log)
+	enc := json.NewEncoder(w)
+	for _, m := range metrics {
+		key := historyKey{namespace: m.Namespace, name: m.Name}
+		sample := historySample{time: now.Unix(), count: 1, used: float64(m.UsedBytes)}
+		if err := enc.Encode(newHistoryRecord(key, sample)); err != nil {
+			return err
+		}
+	}
+	if err := w.Flush(); err != nil {
+		return fmt.Errorf("failed to append to history log: %w", err)
+	}
+	if err := s.log.Sync(); err != nil {
+		return fmt.Errorf("failed to sync history log: %w", err)
+	}
+
+	for _, m := range metrics {
+		key := historyKey{namespace: m.Namespace, name: m.Name}
+		s.series[key] = append(s.series[key], historySample{time: now.Unix(), count: 1, used: float64(m.UsedBytes)})
+	}
+	s.samples += len(metrics)
+	s.records += len(metrics)
+
+	if now.Sub(s.downsampledAt) >= time.Hour {
+		s.downsample()
+	}
+	if s.records > 2*s.samples {
+		if err := s.compact(); err != nil {
+			return fmt.Errorf("failed to compact history log: %w", err)
+		}
+	}
+	return nil
+}
+
+// GetHistory implements HistoryStore
+func (s *FileHistoryStore) GetHistory(ctx context.Context, namespace, name string, duration time.Duration) ([]float64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	since := s.now().Add(-duration).Unix()
+	res := int64(historyResolution(duration) / time.Second)
+	var window []historySample
+	for _, sample := range s.series[historyKey{namespace: namespace, name: name}] {
+		// A bucket is in the window when it ends inside it
+		if sample = sample.rebucket(res); sample.time+sample.bucket > since {
+			window = append(window, sample)
+		}
+	}
+
+	var history []float64
+	for _, sample := range mergeSamples(window) {
+		history = append(history, sample.used)
+	}
+	return history, nil
+}
+
+// Close implements HistoryStore
+func (s *FileHistoryStore) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.log == nil {
+		return nil
+	}
+	err := s.log.Close()
+	s.log = nil
+	return err
+}
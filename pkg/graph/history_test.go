@@ -0,0 +1,108 @@
+package graph
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/cloudvault-io/cloudvault/pkg/types"
+)
+
+// fakeClock is a settable clock for FileHistoryStore
+type fakeClock struct{ t time.Time }
+
+func (c *fakeClock) now() time.Time { return c.t }
+
+func TestFileHistoryStore_Persists(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	clock := &fakeClock{t: time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)}
+
+	store, err := openFileHistoryStore(dir, clock.now)
+	require.NoError(t, err)
+	for _, used := range []int64{100, 200, 300} {
+		require.NoError(t, store.RecordMetrics(ctx, []types.PVCMetric{
+			{Namespace: "default", Name: "data", UsedBytes: used},
+			{Namespace: "default", Name: "logs", UsedBytes: 1},
+		}))
+		clock.t = clock.t.Add(5 * time.Minute)
+	}
+	require.NoError(t, store.Close())
+	assert.Error(t, store.RecordMetrics(ctx, []types.PVCMetric{{Namespace: "default", Name: "data"}}))
+
+	// A crash can cut the last record short
+	f, err := os.OpenFile(filepath.Join(dir, "history.log"), os.O_WRONLY|os.O_APPEND, 0o644)
+	require.NoError(t, err)
+	_, err = f.WriteString(`{"ns":"default","pvc":"da`)
+	require.NoError(t, err)
+	require.NoError(t, f.Close())
+
+	store, err = openFileHistoryStore(dir, clock.now)
+	require.NoError(t, err)
+	defer func() { _ = store.Close() }()
+
+	history, err := store.GetHistory(ctx, "default", "data", time.Hour)
+	require.NoError(t, err)
+	assert.Equal(t, []float64{100, 200, 300}, history)
+	history, err = store.GetHistory(ctx, "default", "missing", time.Hour)
+	require.NoError(t, err)
+	assert.Empty(t, history)
+}
+
+func TestFileHistoryStore_Downsamples(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	start := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	clock := &fakeClock{t: start}
+
+	store, err := openFileHistoryStore(dir, clock.now)
+	require.NoError(t, err)
+
+	// 100 days of samples every 10 minutes; used bytes is the day number
+	const days = 100
+	for clock.t.Before(start.Add(days * 24 * time.Hour)) {
+		day := int64(clock.t.Sub(start) / (24 * time.Hour))
+		require.NoError(t, store.RecordMetrics(ctx, []types.PVCMetric{{Namespace: "default", Name: "data", UsedBytes: day}}))
+		clock.t = clock.t.Add(10 * time.Minute)
+	}
+
+	raw, err := store.GetHistory(ctx, "default", "data", 24*time.Hour)
+	require.NoError(t, err)
+	assert.Len(t, raw, 6*24-1, "the sample taken exactly a day ago is outside the window")
+
+	hourly, err := store.GetHistory(ctx, "default", "data", 7*24*time.Hour)
+	require.NoError(t, err)
+	assert.Len(t, hourly, 7*24)
+	assert.Equal(t, float64(days-7), hourly[0])
+
+	daily, err := store.GetHistory(ctx, "default", "data", HistoryRetention)
+	require.NoError(t, err)
+	assert.Len(t, daily, 90, "at least 90 days of history are kept")
+	assert.Equal(t, float64(days-90), daily[0])
+	assert.Equal(t, float64(days-1), daily[len(daily)-1])
+
+	// The log was compacted along the way: far fewer records than samples taken
+	require.NoError(t, store.Close())
+	data, err := os.ReadFile(filepath.Join(dir, "history.log"))
+	require.NoError(t, err)
+	assert.Less(t, strings.Count(string(data), "\n"), 2*(2*6*24+29*24+60))
+
+	reopened, err := openFileHistoryStore(dir, clock.now)
+	require.NoError(t, err)
+	defer func() { _ = reopened.Close() }()
+	again, err := reopened.GetHistory(ctx, "default", "data", HistoryRetention)
+	require.NoError(t, err)
+	assert.Equal(t, daily, again)
+}
+
+func TestHistoryResolution(t *testing.T) {
+	assert.Equal(t, time.Duration(0), historyResolution(time.Hour))
+	assert.Equal(t, time.Hour, historyResolution(30*24*time.Hour))
+	assert.Equal(t, 24*time.Hour, historyResolution(90*24*time.Hour))
+}
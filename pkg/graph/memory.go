@@ -0,0 +1,594 @@
+package graph
+
+import (
+	"cmp"
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/cloudvault-io/cloudvault/pkg/types"
+)
+
+// objectKey identifies a namespaced node
+type objectKey struct {
+	namespace string
+	name      string
+}
+
+type pvcNode struct {
+	storageClass    string
+	sizeBytes       int64
+	usedBytes       int64
+	region          string
+	zone            string
+	provider        string
+	clusterID       string
+	monthlyCost     float64
+	readIOPS        float64
+	writeIOPS       float64
+	readThroughput  float64
+	writeThroughput float64
+	generation      int64
+}
+
+type podNode struct {
+	node       string
+	zone       string
+	region     string
+	provider   string
+	workload   types.Workload
+	services   []string
+	generation int64
+}
+
+type nodeNode struct {
+	zone     string
+	region   string
+	provider string
+}
+
+// targetKey is the target of a COMMUNICATES_WITH edge: a pod, or an external address
+type targetKey struct {
+	pod objectKey
+	ip  string
+}
+
+type externalNode struct {
+	provider string
+	region   string
+}
+
+type communicationEdge struct {
+	bytes          float64
+	bytesPerSecond float64
+	lastSeen       time.Time
+}
+
+// MemoryGraph is a Graph held in process memory, for agents without Neo4j and for
+// tests. Empty strings stand for unknown values, as NULL properties do in Neo4j.
+type MemoryGraph struct {
+	mu           sync.RWMutex
+	generation   int64
+	pvcs         map[objectKey]*pvcNode
+	pods         map[objectKey]*podNode
+	nodes        map[string]*nodeNode
+	uses         map[objectKey]map[objectKey]int64 // Pod -[:USES]-> PVC, by generation
+	externals    map[string]*externalNode
+	communicates map[objectKey]map[targetKey]*communicationEdge // Pod -[:COMMUNICATES_WITH]-> Pod|External
+}
+
+// NewMemoryGraph creates an empty in-memory graph
+func NewMemoryGraph() *MemoryGraph {
+	return &MemoryGraph{
+		pvcs:         make(map[objectKey]*pvcNode),
+		pods:         make(map[objectKey]*podNode),
+		nodes:        make(map[string]*nodeNode),
+		uses:         make(map[objectKey]map[objectKey]int64),
+		externals:    make(map[string]*externalNode),
+		communicates: make(map[objectKey]map[targetKey]*communicationEdge),
+	}
+}
+
+// SyncPVCs creates or updates the PVCs of metrics
+func (g *MemoryGraph) SyncPVCs(ctx context.Context, metrics []types.PVCMetric) error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	for _, m := range metrics {
+		g.pvcs[objectKey{m.Namespace, m.Name}] = &pvcNode{
+			storageClass:    m.StorageClass,
+			sizeBytes:       m.SizeBytes,
+			usedBytes:       m.UsedBytes,
+			region:          m.Region,
+			zone:            m.Zone,
+			provider:        m.Provider,
+			clusterID:       m.ClusterID,
+			monthlyCost:     m.MonthlyCost,
+			readIOPS:        m.ReadIOPS,
+			writeIOPS:       m.WriteIOPS,
+			readThroughput:  m.ReadThroughput,
+			writeThroughput: m.WriteThroughput,
+			generation:      g.generation,
+		}
+	}
+	return nil
+}
+
+// SyncPods creates or updates pods with their placement, owner and PVCs
+func (g *MemoryGraph) SyncPods(ctx context.Context, pods []types.PodPlacement) error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	for _, p := range pods {
+		key := objectKey{p.Namespace, p.Name}
+		g.pods[key] = &podNode{
+			node:       p.Node,
+			zone:       p.Zone,
+			region:     p.Region,
+			provider:   p.Provider,
+			workload:   p.Workload,
+			services:   p.Services,
+			generation: g.generation,
+		}
+		if p.Node != "" {
+			g.nodes[p.Node] = &nodeNode{zone: p.Zone, region: p.Region, provider: p.Provider}
+		}
+		for _, name := range p.PVCs {
+			g.use(key, objectKey{p.Namespace, name})
+		}
+	}
+	return nil
+}
+
+// use records that a pod uses a PVC, if the PVC is known. It reports whether it is.
+func (g *MemoryGraph) use(podKey, pvcKey objectKey) bool {
+	if _, ok := g.pvcs[pvcKey]; !ok {
+		return false
+	}
+	if g.uses[podKey] == nil {
+		g.uses[podKey] = make(map[objectKey]int64)
+	}
+	g.uses[podKey][pvcKey] = g.generation
+	return true
+}
+
+// pod returns a pod, adding it with an unknown placement if it is not in the graph
+func (g *MemoryGraph) pod(key objectKey) *podNode {
+	pod, ok := g.pods[key]
+	if !ok {
+		pod = &podNode{generation: g.generation}
+		g.pods[key] = pod
+	}
+	return pod
+}
+
+// MapPodToPVC records that a pod uses a PVC already in the graph
+func (g *MemoryGraph) MapPodToPVC(ctx context.Context, podName, namespace, pvcName string) error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	pvcKey := objectKey{namespace, pvcName}
+	podKey := objectKey{namespace, podName}
+	if !g.use(podKey, pvcKey) {
+		return nil
+	}
+	pod := g.pod(podKey)
+	pod.generation = g.generation
+	if pvc := g.pvcs[pvcKey]; pvc.region != "" && pod.region == "" {
+		pod.region, pod.provider = pvc.region, pvc.provider
+	}
+	return nil
+}
+
+// GetCrossRegionGravity finds PVCs whose Pods are in a different region than the Storage
+func (g *MemoryGraph) GetCrossRegionGravity(ctx context.Context) ([]string, error) {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+
+	type row struct {
+		id   string
+		cost float64
+	}
+	var rows []row
+	for podKey, pvcKeys := range g.uses {
+		pod := g.pods[podKey]
+		for pvcKey := range pvcKeys {
+			pvc := g.pvcs[pvcKey]
+			if pod.region != "" && pvc.region != "" && pod.region != pvc.region {
+				rows = append(rows, row{pvcKey.namespace + "/" + pvcKey.name, pvc.monthlyCost})
+			}
+		}
+	}
+	sort.Slice(rows, func(i, j int) bool {
+		if rows[i].cost != rows[j].cost {
+			return rows[i].cost > rows[j].cost
+		}
+		return rows[i].id < rows[j].id
+	})
+
+	var pvcs []string
+	for _, r := range rows {
+		pvcs = append(pvcs, r.id)
+	}
+	return pvcs, nil
+}
+
+// GetGravityMismatches finds pods using a PVC in another region or zone
+func (g *MemoryGraph) GetGravityMismatches(ctx context.Context) ([]GravityMismatch, error) {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+
+	podsOf := make(map[objectKey]int)
+	for _, pvcKeys := range g.uses {
+		for pvcKey := range pvcKeys {
+			podsOf[pvcKey]++
+		}
+	}
+
+	var mismatches []GravityMismatch
+	for podKey, pvcKeys := range g.uses {
+		pod := g.pods[podKey]
+		for pvcKey := range pvcKeys {
+			pvc := g.pvcs[pvcKey]
+			regions := pod.region != "" && pvc.region != "" && pod.region != pvc.region
+			zones := pod.zone != "" && pvc.zone != "" && pod.zone != pvc.zone
+			if !regions && !zones {
+				continue
+			}
+			mismatches = append(mismatches, newGravityMismatch(GravityMismatch{
+				Namespace: pvcKey.namespace,
+				PVC:       pvcKey.name,
+				Pod:       podKey.name,
+				PodRegion: pod.region,
+				PVCRegion: pvc.region,
+				PodZone:   pod.zone,
+				PVCZone:   pvc.zone,
+			}, pvc.provider, pvc.readThroughput+pvc.writeThroughput, podsOf[pvcKey]))
+		}
+	}
+	sortGravityMismatches(mismatches)
+	return mismatches, nil
+}
+
+// RecordCommunications decays the COMMUNICATES_WITH edges from sources, then adds comms
+// to them
+func (g *MemoryGraph) RecordCommunications(ctx context.Context, comms []types.Communication, decay float64, sources []types.Endpoint) error {
+	if err := checkDecay(decay); err != nil {
+		return err
+	}
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	decayed := g.communicates
+	if sources != nil {
+		decayed = make(map[objectKey]map[targetKey]*communicationEdge, len(sources))
+		for _, src := range sources {
+			key := objectKey{src.Namespace, src.Pod}
+			decayed[key] = g.communicates[key]
+		}
+	}
+	for _, edges := range decayed {
+		for _, edge := range edges {
+			edge.bytes *= decay
+			edge.bytesPerSecond *= decay
+		}
+	}
+
+	for _, c := range comms {
+		if c.Source.Pod == "" {
+			continue
+		}
+		srcKey := objectKey{c.Source.Namespace, c.Source.Pod}
+		g.pod(srcKey)
+		dstKey := targetKey{ip: c.Target.IP}
+		if c.Target.IP != "" {
+			g.externals[c.Target.IP] = &externalNode{provider: c.Target.Provider, region: c.Target.Region}
+		} else {
+			dstKey.pod = objectKey{c.Target.Namespace, c.Target.Pod}
+			g.pod(dstKey.pod)
+		}
+
+		edges := g.communicates[srcKey]
+		if edges == nil {
+			edges = make(map[targetKey]*communicationEdge)
+			g.communicates[srcKey] = edges
+		}
+		edge := edges[dstKey]
+		if edge == nil {
+			edge = &communicationEdge{}
+			edges[dstKey] = edge
+		}
+		edge.bytes += float64(c.Bytes)
+		edge.bytesPerSecond += communicationRate(c, decay)
+		edge.lastSeen = c.SeenAt
+	}
+
+	for srcKey, edges := range g.communicates {
+		for dstKey, edge := range edges {
+			if edge.bytes < minCommunicationBytes {
+				delete(edges, dstKey)
+			}
+		}
+		if len(edges) == 0 {
+			delete(g.communicates, srcKey)
+		}
+	}
+	g.pruneExternals()
+	return nil
+}
+
+// pruneExternals removes external addresses no pod communicates with any more
+func (g *MemoryGraph) pruneExternals() {
+	referenced := make(map[string]bool)
+	for _, edges := range g.communicates {
+		for dstKey := range edges {
+			if dstKey.ip != "" {
+				referenced[dstKey.ip] = true
+			}
+		}
+	}
+	for ip := range g.externals {
+		if !referenced[ip] {
+			delete(g.externals, ip)
+		}
+	}
+}
+
+// GetChattyPairs returns traffic crossing a cloud or region boundary, most expensive first
+func (g *MemoryGraph) GetChattyPairs(ctx context.Context, limit int) ([]CommunicationPair, error) {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+
+	var pairs []CommunicationPair
+	for srcKey, edges := range g.communicates {
+		src := g.pods[srcKey]
+		for dstKey, edge := range edges {
+			target := types.Endpoint{IP: dstKey.ip}
+			if ext := g.externals[dstKey.ip]; ext != nil {
+				target.Provider, target.Region = ext.provider, ext.region
+			} else if dst := g.pods[dstKey.pod]; dst != nil {
+				target.Namespace, target.Pod = dstKey.pod.namespace, dstKey.pod.name
+				target.Provider, target.Region = dst.provider, dst.region
+			}
+			pair, ok := newCommunicationPair(CommunicationPair{
+				Source: types.Endpoint{
+					Namespace: srcKey.namespace,
+					Pod:       srcKey.name,
+					Provider:  src.provider,
+					Region:    src.region,
+				},
+				Target:         target,
+				Bytes:          edge.bytes,
+				BytesPerSecond: edge.bytesPerSecond,
+				LastSeen:       edge.lastSeen,
+			})
+			if ok {
+				pairs = append(pairs, pair)
+			}
+		}
+	}
+	return sortCommunicationPairs(pairs, limit), nil
+}
+
+// GetCrossCloudWorkloads identifies workloads that communicate across cloud providers
+func (g *MemoryGraph) GetCrossCloudWorkloads(ctx context.Context) ([]CrossCloudWorkload, error) {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+
+	var workloads []CrossCloudWorkload
+	for srcKey, targets := range g.communicates {
+		src := g.pods[srcKey]
+		for target := range targets {
+			if target.ip != "" {
+				continue
+			}
+			dstKey := target.pod
+			dst := g.pods[dstKey]
+			if src.provider == "" || dst.provider == "" || src.provider == dst.provider {
+				continue
+			}
+			workloads = append(workloads, CrossCloudWorkload{
+				SourcePod:       srcKey.name,
+				SourceNamespace: srcKey.namespace,
+				SourceProvider:  src.provider,
+				TargetPod:       dstKey.name,
+				TargetNamespace: dstKey.namespace,
+				TargetProvider:  dst.provider,
+			})
+		}
+	}
+	sort.Slice(workloads, func(i, j int) bool {
+		a, b := workloads[i], workloads[j]
+		return cmp.Or(
+			cmp.Compare(a.SourceNamespace, b.SourceNamespace),
+			cmp.Compare(a.SourcePod, b.SourcePod),
+			cmp.Compare(a.TargetNamespace, b.TargetNamespace),
+			cmp.Compare(a.TargetPod, b.TargetPod),
+		) < 0
+	})
+	return workloads, nil
+}
+
+// GetDependencies returns what depends on a PVC, following traffic up to depth hops
+func (g *MemoryGraph) GetDependencies(ctx context.Context, namespace, pvc string, depth int) (*Dependencies, error) {
+	if err := checkDepth(depth); err != nil {
+		return nil, err
+	}
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	pvcKey := objectKey{namespace, pvc}
+	if _, ok := g.pvcs[pvcKey]; !ok {
+		return nil, ErrPVCNotFound
+	}
+
+	distance := make(map[objectKey]int)
+	var frontier []objectKey
+	for podKey, pvcKeys := range g.uses {
+		if _, ok := pvcKeys[pvcKey]; ok {
+			distance[podKey] = 0
+			frontier = append(frontier, podKey)
+		}
+	}
+	// Traffic makes pods depend on each other whichever way it flows
+	neighbours := make(map[objectKey][]objectKey)
+	for srcKey, edges := range g.communicates {
+		for target := range edges {
+			if target.ip == "" {
+				neighbours[srcKey] = append(neighbours[srcKey], target.pod)
+				neighbours[target.pod] = append(neighbours[target.pod], srcKey)
+			}
+		}
+	}
+	for d := 1; d <= depth && len(frontier) > 0; d++ {
+		var next []objectKey
+		for _, key := range frontier {
+			for _, n := range neighbours[key] {
+				if _, seen := distance[n]; !seen {
+					distance[n] = d
+					next = append(next, n)
+				}
+			}
+		}
+		frontier = next
+	}
+
+	pods := make([]dependentPod, 0, len(distance))
+	for key, d := range distance {
+		p := dependentPod{namespace: key.namespace, name: key.name, distance: d}
+		if pod := g.pods[key]; pod != nil {
+			p.workload, p.services = pod.workload, pod.services
+		}
+		pods = append(pods, p)
+	}
+	return newDependencies(namespace, pvc, depth, pods), nil
+}
+
+// GetStorageClassUtilization returns utilization stats by storage class
+func (g *MemoryGraph) GetStorageClassUtilization(ctx context.Context) ([]StorageClassStats, error) {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+
+	byClass := make(map[string]*StorageClassStats)
+	sized := make(map[string]int)
+	for _, pvc := range g.pvcs {
+		if pvc.storageClass == "" {
+			continue
+		}
+		stat, ok := byClass[pvc.storageClass]
+		if !ok {
+			stat = &StorageClassStats{StorageClass: pvc.storageClass}
+			byClass[pvc.storageClass] = stat
+		}
+		stat.PVCCount++
+		stat.TotalSizeBytes += pvc.sizeBytes
+		stat.TotalUsedBytes += pvc.usedBytes
+		stat.TotalMonthlyCost += pvc.monthlyCost
+		// The average covers PVCs of known size; AvgUtilization sums until divided below
+		if pvc.sizeBytes > 0 {
+			stat.AvgUtilization += float64(pvc.usedBytes) / float64(pvc.sizeBytes) * 100
+			sized[pvc.storageClass]++
+		}
+	}
+
+	stats := make([]StorageClassStats, 0, len(byClass))
+	for class, stat := range byClass {
+		if n := sized[class]; n > 0 {
+			stat.AvgUtilization /= float64(n)
+		}
+		stats = append(stats, *stat)
+	}
+	sort.Slice(stats, func(i, j int) bool {
+		if stats[i].TotalMonthlyCost != stats[j].TotalMonthlyCost {
+			return stats[i].TotalMonthlyCost > stats[j].TotalMonthlyCost
+		}
+		return stats[i].StorageClass < stats[j].StorageClass
+	})
+	return stats, nil
+}
+
+// DeletePVC removes a PVC and its relationships from the graph
+func (g *MemoryGraph) DeletePVC(ctx context.Context, namespace, name string) error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	key := objectKey{namespace, name}
+	delete(g.pvcs, key)
+	for _, pvcKeys := range g.uses {
+		delete(pvcKeys, key)
+	}
+	return nil
+}
+
+// DeletePod removes a pod and its relationships from the graph
+func (g *MemoryGraph) DeletePod(ctx context.Context, namespace, name string) error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.deletePod(objectKey{namespace, name})
+	g.pruneExternals()
+	return nil
+}
+
+func (g *MemoryGraph) deletePod(key objectKey) {
+	delete(g.pods, key)
+	delete(g.uses, key)
+	delete(g.communicates, key)
+	for srcKey, edges := range g.communicates {
+		delete(edges, targetKey{pod: key})
+		if len(edges) == 0 {
+			delete(g.communicates, srcKey)
+		}
+	}
+}
+
+// Sweep removes what was not synced in the last generations and starts the next one
+func (g *MemoryGraph) Sweep(ctx context.Context, generations int) (GCResult, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	stale, err := staleGeneration(g.generation, generations)
+	if err != nil {
+		return GCResult{}, err
+	}
+
+	var result GCResult
+	for key, pvc := range g.pvcs {
+		if pvc.generation <= stale {
+			delete(g.pvcs, key)
+			for _, pvcKeys := range g.uses {
+				delete(pvcKeys, key)
+			}
+			result.PVCs++
+		}
+	}
+	for key, pod := range g.pods {
+		if pod.generation <= stale {
+			g.deletePod(key)
+			result.Pods++
+		}
+	}
+	for podKey, pvcKeys := range g.uses {
+		for pvcKey, generation := range pvcKeys {
+			if generation <= stale {
+				delete(pvcKeys, pvcKey)
+				result.Relationships++
+			}
+		}
+		if len(pvcKeys) == 0 {
+			delete(g.uses, podKey)
+		}
+	}
+	scheduled := make(map[string]bool)
+	for _, pod := range g.pods {
+		scheduled[pod.node] = true
+	}
+	for name := range g.nodes {
+		if !scheduled[name] {
+			delete(g.nodes, name)
+		}
+	}
+	g.pruneExternals()
+
+	g.generation++
+	return result, nil
+}
+
+// Close is a no-op; the graph lives as long as the process
+func (g *MemoryGraph) Close(ctx context.Context) error {
+	return nil
+}
@@ -3,16 +3,21 @@ package graph
 import (
 	"context"
 	"fmt"
+	"time"
 
 	"github.com/cloudvault-io/cloudvault/pkg/types"
 	"github.com/neo4j/neo4j-go-driver/v5/neo4j"
 )
 
-// SIG represents the Storage Intelligence Graph
+// SIG is the Storage Intelligence Graph stored in Neo4j. Its sync generation is kept in
+// the graph, so every agent of the DaemonSet stamps and sweeps by the same counter.
 type SIG struct {
 	driver neo4j.DriverWithContext
 }
 
+// syncGenerationID identifies the SyncGeneration node holding the graph's generation
+const syncGenerationID = "sig"
+
 // NewSIG creates a new Storage Intelligence Graph client
 func NewSIG(uri, username, password string) (*SIG, error) {
 	driver, err := neo4j.NewDriverWithContext(uri, neo4j.BasicAuth(username, password, ""))
@@ -26,9 +31,52 @@ func NewSIG(uri, username, password string) (*SIG, error) {
 		return nil, fmt.Errorf("failed to verify neo4j connectivity: %w", err)
 	}
 
+	// Agents starting together must not create two generation counters
+	if _, err := neo4j.ExecuteQuery(ctx, driver, `
+		CREATE CONSTRAINT sync_generation_id IF NOT EXISTS
+		FOR (g:SyncGeneration) REQUIRE g.id IS UNIQUE
+	`, nil, neo4j.EagerResultTransformer); err != nil {
+		_ = driver.Close(ctx)
+		return nil, fmt.Errorf("failed to create the sync generation constraint: %w", err)
+	}
 	return &SIG{driver: driver}, nil
 }
 
+// currentGeneration returns the generation syncs stamp what they write with. A graph
+// without a counter, such as one written by agents that kept it in memory, continues
+// from the newest stamp.
+func currentGeneration(ctx context.Context, tx neo4j.ManagedTransaction) (int64, error) {
+	params := map[string]interface{}{"id": syncGenerationID}
+	result, err := tx.Run(ctx, `MATCH (g:SyncGeneration {id: $id}) RETURN g.value`, params)
+	if err != nil {
+		return 0, err
+	}
+	records, err := result.Collect(ctx)
+	if err != nil {
+		return 0, err
+	}
+	if len(records) == 0 {
+		result, err = tx.Run(ctx, `
+			OPTIONAL MATCH (n) WHERE n:PVC OR n:Pod
+			WITH coalesce(max(n.generation), 0) AS current
+			MERGE (g:SyncGeneration {id: $id})
+			ON CREATE SET g.value = current
+			RETURN g.value
+		`, params)
+		if err != nil {
+			return 0, err
+		}
+		if records, err = result.Collect(ctx); err != nil {
+			return 0, err
+		}
+	}
+	if len(records) == 0 {
+		return 0, fmt.Errorf("no sync generation")
+	}
+	generation, _ := records[0].Values[0].(int64)
+	return generation, nil
+}
+
 // SyncPVCs creates or updates multiple PVC nodes and their relationships (Phase 9 optimization)
 func (s *SIG) SyncPVCs(ctx context.Context, metrics []types.PVCMetric) error {
 	session := s.driver.NewSession(ctx, neo4j.SessionConfig{AccessMode: neo4j.AccessModeWrite})
@@ -44,11 +92,15 @@ func (s *SIG) SyncPVCs(ctx context.Context, metrics []types.PVCMetric) error {
 			    pvc.size_bytes = item.sizeBytes,
 			    pvc.used_bytes = item.usedBytes,
 			    pvc.region = item.region,
+			    pvc.zone = item.zone,
 			    pvc.provider = item.provider,
 			    pvc.cluster_id = item.clusterID,
 			    pvc.monthly_cost = item.monthlyCost,
 			    pvc.read_iops = item.readIOPS,
-			    pvc.write_iops = item.writeIOPS
+			    pvc.write_iops = item.writeIOPS,
+			    pvc.read_bps = item.readBps,
+			    pvc.write_bps = item.writeBps,
+			    pvc.generation = $generation
 			MERGE (pvc)-[:BELONGS_TO]->(ns)
 			
 			// Create cluster and region relationships
@@ -68,19 +120,27 @@ func (s *SIG) SyncPVCs(ctx context.Context, metrics []types.PVCMetric) error {
 			batch = append(batch, map[string]interface{}{
 				"namespace":    m.Namespace,
 				"name":         m.Name,
-				"storageClass": m.StorageClass,
+				"storageClass": nullable(m.StorageClass),
 				"sizeBytes":    m.SizeBytes,
 				"usedBytes":    m.UsedBytes,
-				"region":       m.Region,
-				"provider":     m.Provider,
-				"clusterID":    m.ClusterID,
+				"region":       nullable(m.Region),
+				"zone":         nullable(m.Zone),
+				"provider":     nullable(m.Provider),
+				"clusterID":    nullable(m.ClusterID),
 				"monthlyCost":  m.MonthlyCost,
 				"readIOPS":     m.ReadIOPS,
 				"writeIOPS":    m.WriteIOPS,
+				"readBps":      m.ReadThroughput,
+				"writeBps":     m.WriteThroughput,
 			})
 		}
 
-		result, err := tx.Run(ctx, query, map[string]interface{}{"batch": batch})
+		generation, err := currentGeneration(ctx, tx)
+		if err != nil {
+			return nil, err
+		}
+		params := map[string]interface{}{"batch": batch, "generation": generation}
+		result, err := tx.Run(ctx, query, params)
 		if err != nil {
 			return nil, err
 		}
@@ -99,6 +159,91 @@ func (s *SIG) SyncPVC(ctx context.Context, namespace, name, storageClass string,
 	}})
 }
 
+// SyncPods creates or updates Pod nodes with the Node, Zone and Workload they belong to
+func (s *SIG) SyncPods(ctx context.Context, pods []types.PodPlacement) error {
+	session := s.driver.NewSession(ctx, neo4j.SessionConfig{AccessMode: neo4j.AccessModeWrite})
+	defer func() { _ = session.Close(ctx) }()
+
+	_, err := session.ExecuteWrite(ctx, func(tx neo4j.ManagedTransaction) (interface{}, error) {
+		query := `
+			UNWIND $batch AS item
+			MERGE (pod:Pod {name: item.name, namespace: item.namespace})
+			SET pod.node = item.node,
+			    pod.zone = item.zone,
+			    pod.region = item.region,
+			    pod.provider = item.provider,
+			    pod.generation = $generation
+
+			// Placement and ownership are replaced, not accumulated
+			WITH pod, item
+			OPTIONAL MATCH (pod)-[old:SCHEDULED_ON|OWNED_BY]->()
+			DELETE old
+			WITH DISTINCT pod, item
+			OPTIONAL MATCH (:Service)-[selects:SELECTS]->(pod)
+			DELETE selects
+
+			WITH DISTINCT pod, item
+			CALL {
+				WITH pod, item
+				WITH pod, item WHERE item.node IS NOT NULL
+				MERGE (node:Node {name: item.node})
+				SET node.zone = item.zone, node.region = item.region, node.provider = item.provider
+				MERGE (pod)-[:SCHEDULED_ON]->(node)
+				WITH node, item WHERE item.zone IS NOT NULL
+				MERGE (zone:Zone {name: item.zone})
+				SET zone.region = item.region, zone.provider = item.provider
+				MERGE (node)-[:LOCATED_IN]->(zone)
+			}
+			CALL {
+				WITH pod, item
+				WITH pod, item WHERE item.workloadKind IS NOT NULL
+				MERGE (workload:Workload {kind: item.workloadKind, name: item.workloadName, namespace: item.namespace})
+				MERGE (pod)-[:OWNED_BY]->(workload)
+			}
+			CALL {
+				WITH pod, item
+				UNWIND item.services AS serviceName
+				MERGE (svc:Service {name: serviceName, namespace: item.namespace})
+				MERGE (svc)-[:SELECTS]->(pod)
+			}
+			CALL {
+				WITH pod, item
+				UNWIND item.pvcs AS pvcName
+				MATCH (pvc:PVC {name: pvcName, namespace: item.namespace})
+				MERGE (pod)-[uses:USES]->(pvc)
+				SET uses.generation = $generation
+			}
+		`
+		var batch []map[string]interface{}
+		for _, p := range pods {
+			batch = append(batch, map[string]interface{}{
+				"name":         p.Name,
+				"namespace":    p.Namespace,
+				"node":         nullable(p.Node),
+				"zone":         nullable(p.Zone),
+				"region":       nullable(p.Region),
+				"provider":     nullable(p.Provider),
+				"workloadKind": nullable(p.Workload.Kind),
+				"workloadName": p.Workload.Name,
+				"pvcs":         p.PVCs,
+				"services":     p.Services,
+			})
+		}
+
+		generation, err := currentGeneration(ctx, tx)
+		if err != nil {
+			return nil, err
+		}
+		params := map[string]interface{}{"batch": batch, "generation": generation}
+		result, err := tx.Run(ctx, query, params)
+		if err != nil {
+			return nil, err
+		}
+		return result.Consume(ctx)
+	})
+	return err
+}
+
 // MapPodToPVC creates a connection between a Pod and its used PVC with region tracking
 func (s *SIG) MapPodToPVC(ctx context.Context, podName, namespace, pvcName string) error {
 	session := s.driver.NewSession(ctx, neo4j.SessionConfig{AccessMode: neo4j.AccessModeWrite})
@@ -108,17 +253,24 @@ func (s *SIG) MapPodToPVC(ctx context.Context, podName, namespace, pvcName strin
 		query := `
 			MATCH (pvc:PVC {name: $pvcName, namespace: $namespace})
 			MERGE (pod:Pod {name: $podName, namespace: $namespace})
-			MERGE (pod)-[:USES]->(pvc)
+			MERGE (pod)-[uses:USES]->(pvc)
+			SET pod.generation = $generation, uses.generation = $generation
 			
-			// Copy region info from PVC to Pod for gravity analysis
+			// Copy region info from PVC to Pod for gravity analysis, unless the pod's
+			// placement is known
 			WITH pod, pvc
-			WHERE pvc.region IS NOT NULL
+			WHERE pvc.region IS NOT NULL AND pod.region IS NULL
 			SET pod.region = pvc.region, pod.provider = pvc.provider
 		`
+		generation, err := currentGeneration(ctx, tx)
+		if err != nil {
+			return nil, err
+		}
 		params := map[string]interface{}{
-			"podName":   podName,
-			"namespace": namespace,
-			"pvcName":   pvcName,
+			"podName":    podName,
+			"namespace":  namespace,
+			"pvcName":    pvcName,
+			"generation": generation,
 		}
 		result, err := tx.Run(ctx, query, params)
 		if err != nil {
@@ -142,7 +294,7 @@ func (s *SIG) GetCrossRegionGravity(ctx context.Context) ([]string, error) {
 			       pod.region AS pod_region,
 			       pvc.region AS pvc_region,
 			       pvc.monthly_cost AS cost
-			ORDER BY cost DESC
+			ORDER BY cost DESC, pvc_id
 		`
 		res, err := tx.Run(ctx, query, nil)
 		if err != nil {
@@ -165,6 +317,222 @@ func (s *SIG) GetCrossRegionGravity(ctx context.Context) ([]string, error) {
 	return result.([]string), nil
 }
 
+// GetGravityMismatches finds pods using a PVC in another region or zone
+func (s *SIG) GetGravityMismatches(ctx context.Context) ([]GravityMismatch, error) {
+	session := s.driver.NewSession(ctx, neo4j.SessionConfig{AccessMode: neo4j.AccessModeRead})
+	defer func() { _ = session.Close(ctx) }()
+
+	result, err := session.ExecuteRead(ctx, func(tx neo4j.ManagedTransaction) (interface{}, error) {
+		query := `
+			MATCH (pod:Pod)-[:USES]->(pvc:PVC)
+			WHERE (pod.region IS NOT NULL AND pvc.region IS NOT NULL AND pod.region <> pvc.region)
+			   OR (pod.zone IS NOT NULL AND pvc.zone IS NOT NULL AND pod.zone <> pvc.zone)
+			RETURN pvc.namespace AS namespace,
+			       pvc.name AS pvc,
+			       pod.name AS pod,
+			       pod.region AS pod_region,
+			       pvc.region AS pvc_region,
+			       pod.zone AS pod_zone,
+			       pvc.zone AS pvc_zone,
+			       pvc.provider AS provider,
+			       coalesce(pvc.read_bps, 0.0) + coalesce(pvc.write_bps, 0.0) AS throughput,
+			       size([(user:Pod)-[:USES]->(pvc) | user]) AS pods
+		`
+		res, err := tx.Run(ctx, query, nil)
+		if err != nil {
+			return nil, err
+		}
+		var mismatches []GravityMismatch
+		for res.Next(ctx) {
+			v := res.Record().Values
+			pods, _ := v[9].(int64)
+			mismatches = append(mismatches, newGravityMismatch(GravityMismatch{
+				Namespace: stringValue(v[0]),
+				PVC:       stringValue(v[1]),
+				Pod:       stringValue(v[2]),
+				PodRegion: stringValue(v[3]),
+				PVCRegion: stringValue(v[4]),
+				PodZone:   stringValue(v[5]),
+				PVCZone:   stringValue(v[6]),
+			}, stringValue(v[7]), floatValue(v[8]), int(pods)))
+		}
+		return mismatches, res.Err()
+	})
+	if err != nil {
+		return nil, err
+	}
+	mismatches := result.([]GravityMismatch)
+	sortGravityMismatches(mismatches)
+	return mismatches, nil
+}
+
+// RecordCommunications decays the COMMUNICATES_WITH edges from sources, then adds comms
+// to them
+func (s *SIG) RecordCommunications(ctx context.Context, comms []types.Communication, decay float64, sources []types.Endpoint) error {
+	if err := checkDecay(decay); err != nil {
+		return err
+	}
+	session := s.driver.NewSession(ctx, neo4j.SessionConfig{AccessMode: neo4j.AccessModeWrite})
+	defer func() { _ = session.Close(ctx) }()
+
+	// Sources are matched by "namespace/name"; null decays every edge
+	var decayed any
+	if sources != nil {
+		names := make([]string, 0, len(sources))
+		for _, src := range sources {
+			names = append(names, src.Namespace+"/"+src.Pod)
+		}
+		decayed = names
+	}
+
+	var pods, externals []map[string]interface{}
+	for _, c := range comms {
+		if c.Source.Pod == "" {
+			continue
+		}
+		item := map[string]interface{}{
+			"srcPod":       c.Source.Pod,
+			"srcNamespace": c.Source.Namespace,
+			"bytes":        float64(c.Bytes),
+			"rate":         communicationRate(c, decay),
+			"seenAt":       c.SeenAt,
+		}
+		if c.Target.IP != "" {
+			item["ip"] = c.Target.IP
+			item["provider"] = nullable(c.Target.Provider)
+			item["region"] = nullable(c.Target.Region)
+			externals = append(externals, item)
+		} else {
+			item["dstPod"] = c.Target.Pod
+			item["dstNamespace"] = c.Target.Namespace
+			pods = append(pods, item)
+		}
+	}
+
+	_, err := session.ExecuteWrite(ctx, func(tx neo4j.ManagedTransaction) (interface{}, error) {
+		generation, err := currentGeneration(ctx, tx)
+		if err != nil {
+			return nil, err
+		}
+		queries := []struct {
+			query  string
+			params map[string]interface{}
+		}{
+			{`
+				MATCH (p:Pod)-[c:COMMUNICATES_WITH]->()
+				WHERE $sources IS NULL OR p.namespace + '/' + p.name IN $sources
+				SET c.bytes = coalesce(c.bytes, 0.0) * $decay,
+				    c.bytes_per_second = coalesce(c.bytes_per_second, 0.0) * $decay
+			`, map[string]interface{}{"decay": decay, "sources": decayed}},
+			{`
+				UNWIND $batch AS item
+				MERGE (src:Pod {name: item.srcPod, namespace: item.srcNamespace})
+				ON CREATE SET src.generation = $generation
+				MERGE (dst:Pod {name: item.dstPod, namespace: item.dstNamespace})
+				ON CREATE SET dst.generation = $generation
+				MERGE (src)-[c:COMMUNICATES_WITH]->(dst)
+				SET c.bytes = coalesce(c.bytes, 0.0) + item.bytes,
+				    c.bytes_per_second = coalesce(c.bytes_per_second, 0.0) + item.rate,
+				    c.last_seen = item.seenAt
+			`, map[string]interface{}{"batch": pods, "generation": generation}},
+			{`
+				UNWIND $batch AS item
+				MERGE (src:Pod {name: item.srcPod, namespace: item.srcNamespace})
+				ON CREATE SET src.generation = $generation
+				MERGE (dst:External {ip: item.ip})
+				SET dst.provider = item.provider, dst.region = item.region
+				MERGE (src)-[c:COMMUNICATES_WITH]->(dst)
+				SET c.bytes = coalesce(c.bytes, 0.0) + item.bytes,
+				    c.bytes_per_second = coalesce(c.bytes_per_second, 0.0) + item.rate,
+				    c.last_seen = item.seenAt
+			`, map[string]interface{}{"batch": externals, "generation": generation}},
+			{`
+				MATCH (:Pod)-[c:COMMUNICATES_WITH]->()
+				WHERE c.bytes < $minBytes
+				DELETE c
+			`, map[string]interface{}{"minBytes": float64(minCommunicationBytes)}},
+			{`
+				MATCH (e:External)
+				WHERE NOT (e)--()
+				DELETE e
+			`, nil},
+		}
+		for _, q := range queries {
+			result, err := tx.Run(ctx, q.query, q.params)
+			if err != nil {
+				return nil, err
+			}
+			if _, err := result.Consume(ctx); err != nil {
+				return nil, err
+			}
+		}
+		return nil, nil
+	})
+	return err
+}
+
+// GetChattyPairs returns traffic crossing a cloud or region boundary, most expensive first
+func (s *SIG) GetChattyPairs(ctx context.Context, limit int) ([]CommunicationPair, error) {
+	session := s.driver.NewSession(ctx, neo4j.SessionConfig{AccessMode: neo4j.AccessModeRead})
+	defer func() { _ = session.Close(ctx) }()
+
+	result, err := session.ExecuteRead(ctx, func(tx neo4j.ManagedTransaction) (interface{}, error) {
+		query := `
+			MATCH (src:Pod)-[c:COMMUNICATES_WITH]->(dst)
+			WHERE src.provider IS NOT NULL AND dst.provider IS NOT NULL
+			  AND (src.provider <> dst.provider
+			       OR (src.region IS NOT NULL AND dst.region IS NOT NULL AND src.region <> dst.region))
+			RETURN src.namespace AS source_namespace,
+			       src.name AS source_pod,
+			       src.provider AS source_provider,
+			       src.region AS source_region,
+			       CASE WHEN dst:Pod THEN dst.namespace END AS target_namespace,
+			       CASE WHEN dst:Pod THEN dst.name END AS target_pod,
+			       dst.ip AS target_ip,
+			       dst.provider AS target_provider,
+			       dst.region AS target_region,
+			       c.bytes AS bytes,
+			       c.bytes_per_second AS bytes_per_second,
+			       c.last_seen AS last_seen
+		`
+		res, err := tx.Run(ctx, query, nil)
+		if err != nil {
+			return nil, err
+		}
+		var pairs []CommunicationPair
+		for res.Next(ctx) {
+			v := res.Record().Values
+			lastSeen, _ := v[11].(time.Time)
+			pair, ok := newCommunicationPair(CommunicationPair{
+				Source: types.Endpoint{
+					Namespace: stringValue(v[0]),
+					Pod:       stringValue(v[1]),
+					Provider:  stringValue(v[2]),
+					Region:    stringValue(v[3]),
+				},
+				Target: types.Endpoint{
+					Namespace: stringValue(v[4]),
+					Pod:       stringValue(v[5]),
+					IP:        stringValue(v[6]),
+					Provider:  stringValue(v[7]),
+					Region:    stringValue(v[8]),
+				},
+				Bytes:          floatValue(v[9]),
+				BytesPerSecond: floatValue(v[10]),
+				LastSeen:       lastSeen,
+			})
+			if ok {
+				pairs = append(pairs, pair)
+			}
+		}
+		return pairs, res.Err()
+	})
+	if err != nil {
+		return nil, err
+	}
+	return sortCommunicationPairs(result.([]CommunicationPair), limit), nil
+}
+
 // GetCrossCloudWorkloads identifies workloads that communicate across cloud providers
 func (s *SIG) GetCrossCloudWorkloads(ctx context.Context) ([]CrossCloudWorkload, error) {
 	session := s.driver.NewSession(ctx, neo4j.SessionConfig{AccessMode: neo4j.AccessModeRead})
@@ -181,6 +549,7 @@ func (s *SIG) GetCrossCloudWorkloads(ctx context.Context) ([]CrossCloudWorkload,
 			       pod2.name AS target_pod,
 			       pod2.namespace AS target_namespace,
 			       pod2.provider AS target_provider
+			ORDER BY source_namespace, source_pod, target_namespace, target_pod
 		`
 		res, err := tx.Run(ctx, query, nil)
 		if err != nil {
@@ -207,6 +576,91 @@ func (s *SIG) GetCrossCloudWorkloads(ctx context.Context) ([]CrossCloudWorkload,
 	return result.([]CrossCloudWorkload), nil
 }
 
+// GetDependencies returns what depends on a PVC, following traffic up to depth hops
+func (s *SIG) GetDependencies(ctx context.Context, namespace, pvc string, depth int) (*Dependencies, error) {
+	if err := checkDepth(depth); err != nil {
+		return nil, err
+	}
+	session := s.driver.NewSession(ctx, neo4j.SessionConfig{AccessMode: neo4j.AccessModeRead})
+	defer func() { _ = session.Close(ctx) }()
+
+	result, err := session.ExecuteRead(ctx, func(tx neo4j.ManagedTransaction) (interface{}, error) {
+		params := map[string]interface{}{"name": pvc, "namespace": namespace}
+		res, err := tx.Run(ctx, `
+			MATCH (pvc:PVC {name: $name, namespace: $namespace})
+			OPTIONAL MATCH (pod:Pod)-[:USES]->(pvc)
+			OPTIONAL MATCH (pod)-[:OWNED_BY]->(workload:Workload)
+			OPTIONAL MATCH (svc:Service)-[:SELECTS]->(pod)
+			RETURN pod.namespace, pod.name, workload.kind, workload.name, collect(svc.name)
+		`, params)
+		if err != nil {
+			return nil, err
+		}
+		records, err := res.Collect(ctx)
+		if err != nil {
+			return nil, err
+		}
+		if len(records) == 0 {
+			return nil, ErrPVCNotFound
+		}
+		pods := dependentPods(records, 0)
+
+		if depth > 0 {
+			// Variable-length bounds cannot be parameters; depth was checked above. Paths
+			// stay on pods, since two pods sending to the same address do not depend on
+			// each other.
+			res, err = tx.Run(ctx, fmt.Sprintf(`
+				MATCH (:PVC {name: $name, namespace: $namespace})<-[:USES]-(user:Pod)
+				MATCH path = (user)-[:COMMUNICATES_WITH*1..%d]-(pod:Pod)
+				WHERE all(n IN nodes(path) WHERE n:Pod)
+				WITH pod, min(length(path)) AS distance
+				OPTIONAL MATCH (pod)-[:OWNED_BY]->(workload:Workload)
+				RETURN pod.namespace, pod.name, workload.kind, workload.name, [], distance
+			`, depth), params)
+			if err != nil {
+				return nil, err
+			}
+			if records, err = res.Collect(ctx); err != nil {
+				return nil, err
+			}
+			pods = append(pods, dependentPods(records, -1)...)
+		}
+		return newDependencies(namespace, pvc, depth, pods), nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result.(*Dependencies), nil
+}
+
+// dependentPods reads rows of pod namespace, name, workload kind and name, service
+// names and, when distance is negative, the pod's distance
+func dependentPods(records []*neo4j.Record, distance int) []dependentPod {
+	var pods []dependentPod
+	for _, record := range records {
+		v := record.Values
+		p := dependentPod{
+			namespace: stringValue(v[0]),
+			name:      stringValue(v[1]),
+			workload:  types.Workload{Kind: stringValue(v[2]), Name: stringValue(v[3])},
+			distance:  distance,
+		}
+		if p.name == "" {
+			continue
+		}
+		services, _ := v[4].([]interface{})
+		for _, svc := range services {
+			p.services = append(p.services, stringValue(svc))
+		}
+		if distance < 0 {
+			d, _ := v[5].(int64)
+			p.distance = int(d)
+		}
+		pods = append(pods, p)
+	}
+	return pods
+}
+
 // GetStorageClassUtilization returns utilization stats by storage class
 func (s *SIG) GetStorageClassUtilization(ctx context.Context) ([]StorageClassStats, error) {
 	session := s.driver.NewSession(ctx, neo4j.SessionConfig{AccessMode: neo4j.AccessModeRead})
@@ -221,8 +675,9 @@ func (s *SIG) GetStorageClassUtilization(ctx context.Context) ([]StorageClassSta
 			       sum(pvc.size_bytes) AS total_size,
 			       sum(pvc.used_bytes) AS total_used,
 			       sum(pvc.monthly_cost) AS total_cost,
-			       avg(toFloat(pvc.used_bytes) / toFloat(pvc.size_bytes)) * 100 AS avg_utilization
-			ORDER BY total_cost DESC
+			       avg(CASE WHEN pvc.size_bytes > 0
+			                THEN toFloat(pvc.used_bytes) / toFloat(pvc.size_bytes) END) * 100 AS avg_utilization
+			ORDER BY total_cost DESC, storage_class
 		`
 		res, err := tx.Run(ctx, query, nil)
 		if err != nil {
@@ -237,7 +692,7 @@ func (s *SIG) GetStorageClassUtilization(ctx context.Context) ([]StorageClassSta
 				TotalSizeBytes:   record.Values[2].(int64),
 				TotalUsedBytes:   record.Values[3].(int64),
 				TotalMonthlyCost: record.Values[4].(float64),
-				AvgUtilization:   record.Values[5].(float64),
+				AvgUtilization:   floatValue(record.Values[5]),
 			}
 			stats = append(stats, stat)
 		}
@@ -272,10 +727,146 @@ func (s *SIG) DeletePVC(ctx context.Context, namespace, name string) error {
 	return err
 }
 
+// DeletePod removes a pod and its relationships from the graph
+func (s *SIG) DeletePod(ctx context.Context, namespace, name string) error {
+	session := s.driver.NewSession(ctx, neo4j.SessionConfig{AccessMode: neo4j.AccessModeWrite})
+	defer func() { _ = session.Close(ctx) }()
+
+	_, err := session.ExecuteWrite(ctx, func(tx neo4j.ManagedTransaction) (interface{}, error) {
+		query := `
+			MATCH (pod:Pod {name: $name, namespace: $namespace})
+			DETACH DELETE pod
+		`
+		params := map[string]interface{}{
+			"name":      name,
+			"namespace": namespace,
+		}
+		result, err := tx.Run(ctx, query, params)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := result.Consume(ctx); err != nil {
+			return nil, err
+		}
+		return nil, deleteOrphans(ctx, tx)
+	})
+	return err
+}
+
+// Sweep removes what was not synced in the last generations and starts the next one.
+// The generation is advanced first, which locks the counter, so sweeps by several agents
+// run one after the other and each removes by the generation it started.
+func (s *SIG) Sweep(ctx context.Context, generations int) (GCResult, error) {
+	if _, err := staleGeneration(0, generations); err != nil {
+		return GCResult{}, err
+	}
+	session := s.driver.NewSession(ctx, neo4j.SessionConfig{AccessMode: neo4j.AccessModeWrite})
+	defer func() { _ = session.Close(ctx) }()
+
+	result, err := session.ExecuteWrite(ctx, func(tx neo4j.ManagedTransaction) (interface{}, error) {
+		if _, err := currentGeneration(ctx, tx); err != nil {
+			return nil, err
+		}
+		res, err := tx.Run(ctx, `
+			MATCH (g:SyncGeneration {id: $id})
+			SET g.value = g.value + 1
+			RETURN g.value - 1
+		`, map[string]interface{}{"id": syncGenerationID})
+		if err != nil {
+			return nil, err
+		}
+		record, err := res.Single(ctx)
+		if err != nil {
+			return nil, err
+		}
+		current, _ := record.Values[0].(int64)
+		stale, err := staleGeneration(current, generations)
+		if err != nil {
+			return nil, err
+		}
+
+		// Nodes written before generations were stamped count as stale
+		queries := []string{`
+			MATCH (pvc:PVC) WHERE coalesce(pvc.generation, -1) <= $stale
+			DETACH DELETE pvc
+			RETURN count(*) AS removed
+		`, `
+			MATCH (pod:Pod) WHERE coalesce(pod.generation, -1) <= $stale
+			DETACH DELETE pod
+			RETURN count(*) AS removed
+		`, `
+			MATCH (:Pod)-[uses:USES]->(:PVC) WHERE coalesce(uses.generation, -1) <= $stale
+			DELETE uses
+			RETURN count(*) AS removed
+		`}
+		var counts []int
+		for _, query := range queries {
+			res, err := tx.Run(ctx, query, map[string]interface{}{"stale": stale})
+			if err != nil {
+				return nil, err
+			}
+			record, err := res.Single(ctx)
+			if err != nil {
+				return nil, err
+			}
+			removed, _ := record.Values[0].(int64)
+			counts = append(counts, int(removed))
+		}
+		if err := deleteOrphans(ctx, tx); err != nil {
+			return nil, err
+		}
+		return GCResult{PVCs: counts[0], Pods: counts[1], Relationships: counts[2]}, nil
+	})
+	if err != nil {
+		return GCResult{}, err
+	}
+	return result.(GCResult), nil
+}
+
+// deleteOrphans removes the nodes describing pods' surroundings once nothing refers to
+// them. Nodes are removed before the zones they are located in.
+func deleteOrphans(ctx context.Context, tx neo4j.ManagedTransaction) error {
+	for _, label := range []string{"Workload", "Service", "Node", "Zone", "External"} {
+		res, err := tx.Run(ctx, fmt.Sprintf("MATCH (n:%s) WHERE NOT (n)--() DELETE n", label), nil)
+		if err != nil {
+			return err
+		}
+		if _, err := res.Consume(ctx); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 func (s *SIG) Close(ctx context.Context) error {
 	return s.driver.Close(ctx)
 }
 
+// nullable stores unknown (empty) strings as NULL, so that queries skip them
+func nullable(v string) any {
+	if v == "" {
+		return nil
+	}
+	return v
+}
+
+// stringValue reads a string result that may be NULL
+func stringValue(v any) string {
+	s, _ := v.(string)
+	return s
+}
+
+// floatValue reads a numeric result that is NULL when nothing was aggregated
+func floatValue(v any) float64 {
+	switch v := v.(type) {
+	case float64:
+		return v
+	case int64:
+		return float64(v)
+	}
+	return 0
+}
+
 // CrossCloudWorkload represents a workload that spans multiple cloud providers
 type CrossCloudWorkload struct {
 	SourcePod       string
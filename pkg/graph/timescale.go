@@ -4,10 +4,11 @@ import (
 	"context"
 	"database/sql"
 	"fmt"
+	"slices"
 	"time"
 
 	"github.com/cloudvault-io/cloudvault/pkg/types"
-	_ "github.com/lib/pq"
+	"github.com/lib/pq"
 )
 
 // TimescaleDB handles historical metric persistence for the SIG
@@ -15,7 +16,7 @@ type TimescaleDB struct {
 	db *sql.DB
 }
 
-// NewTimescaleDB creates a new TimescaleDB client
+// NewTimescaleDB creates a new TimescaleDB client and applies pending schema migrations
 func NewTimescaleDB(connStr string) (*TimescaleDB, error) {
 	db, err := sql.Open("postgres", connStr)
 	if err != nil {
@@ -27,73 +28,51 @@ func NewTimescaleDB(connStr string) (*TimescaleDB, error) {
 	}
 
 	ts := &TimescaleDB{db: db}
-	if err := ts.initializeSchema(); err != nil {
+	if err := ts.migrate(context.Background()); err != nil {
+		_ = db.Close()
 		return nil, err
 	}
 
 	return ts, nil
 }
 
-func (t *TimescaleDB) initializeSchema() error {
-	// Create metrics table if not exists
-	_, err := t.db.Exec(`
-		CREATE TABLE IF NOT EXISTS pvc_metrics (
-			time TIMESTAMPTZ NOT NULL,
-			pvc_name TEXT NOT NULL,
-			namespace TEXT NOT NULL,
-			used_bytes BIGINT,
-			egress_bytes BIGINT,
-			iops DOUBLE PRECISION,
-			monthly_cost DOUBLE PRECISION
-		);
-		SELECT create_hypertable('pvc_metrics', 'time', if_not_exists => TRUE);
-	`)
-	return err
-}
-
-// RecordMetrics saves a batch of PVC metrics to TimescaleDB
+// RecordMetrics saves a batch of PVC metrics to TimescaleDB with COPY, in chunks of
+// copyChunkSize rows, then a rollup row per cluster. Chunks are retried on transient
+// errors; one that still fails stops the batch, and chunks already written are kept.
 func (t *TimescaleDB) RecordMetrics(ctx context.Context, metrics []types.PVCMetric) error {
-	tx, err := t.db.BeginTx(ctx, nil)
-	if err != nil {
-		return err
-	}
-	defer func() { _ = tx.Rollback() }()
-
-	stmt, err := tx.PrepareContext(ctx, `
-		INSERT INTO pvc_metrics (time, pvc_name, namespace, used_bytes, egress_bytes, iops, monthly_cost)
-		VALUES ($1, $2, $3, $4, $5, $6, $7)
-	`)
-	if err != nil {
-		return err
+	if len(metrics) == 0 {
+		return nil
 	}
-	defer func() { _ = stmt.Close() }()
 
 	now := time.Now()
-	for _, m := range metrics {
-		_, err := stmt.ExecContext(ctx,
-			now,
-			m.Name,
-			m.Namespace,
-			m.UsedBytes,
-			m.EgressBytes,
-			m.ReadIOPS+m.WriteIOPS,
-			m.MonthlyCost,
-		)
-		if err != nil {
-			return err
+	for chunk := range slices.Chunk(metrics, copyChunkSize) {
+		if err := writeWithRetry(ctx, "pvc_metrics", func(ctx context.Context) error {
+			return t.copyPVCMetrics(ctx, now, chunk)
+		}); err != nil {
+			return fmt.Errorf("failed to write pvc_metrics: %w", err)
 		}
 	}
 
-	return tx.Commit()
+	rollups := rollupMetrics(now.Truncate(clusterRollupBucket), metrics)
+	if err := writeWithRetry(ctx, "cluster_metrics", func(ctx context.Context) error {
+		return t.insertClusterRollups(ctx, rollups)
+	}); err != nil {
+		return fmt.Errorf("failed to write cluster_metrics: %w", err)
+	}
+	return nil
 }
 
-// GetHistory retrieves historical metrics for a specific PVC to feed AI models
+// GetHistory retrieves historical used bytes for a specific PVC to feed AI models. Long
+// windows are read from the hourly or daily aggregates, one average per bucket.
 func (t *TimescaleDB) GetHistory(ctx context.Context, namespace, name string, duration time.Duration) ([]float64, error) {
-	rows, err := t.db.QueryContext(ctx, `
-		SELECT used_bytes FROM pvc_metrics 
-		WHERE namespace = $1 AND pvc_name = $2 AND time > $3 
-		ORDER BY time ASC
-	`, namespace, name, time.Now().Add(-duration))
+	// A bucket is in the window when it ends inside it
+	table, timeColumn := historySource(duration)
+	since := time.Now().Add(-duration - historyResolution(duration))
+	rows, err := t.db.QueryContext(ctx, fmt.Sprintf(`
+		SELECT used_bytes FROM %[1]s
+		WHERE namespace = $1 AND pvc_name = $2 AND %[2]s > $3
+		ORDER BY %[2]s ASC
+	`, table, timeColumn), namespace, name, since)
 	if err != nil {
 		return nil, err
 	}
@@ -101,12 +80,100 @@ func (t *TimescaleDB) GetHistory(ctx context.Context, namespace, name string, du
 
 	var history []float64
 	for rows.Next() {
-		var val int64
-		if err := rows.Scan(&val); err == nil {
-			history = append(history, float64(val))
+		var val sql.NullFloat64
+		if err := rows.Scan(&val); err != nil {
+			return nil, err
+		}
+		if val.Valid {
+			history = append(history, val.Float64)
+		}
+	}
+	return history, rows.Err()
+}
+
+// LoadAccessStates returns the persisted access state of every PVC, keyed by "namespace/name"
+func (t *TimescaleDB) LoadAccessStates(ctx context.Context) (map[string]types.AccessState, error) {
+	rows, err := t.db.QueryContext(ctx, `
+		SELECT namespace, pvc_name, used_bytes, read_bytes_total, write_bytes_total,
+		       first_seen, last_seen, last_accessed_at
+		FROM pvc_access
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = rows.Close() }()
+
+	states := make(map[string]types.AccessState)
+	for rows.Next() {
+		var s types.AccessState
+		var lastAccessed sql.NullTime
+		if err := rows.Scan(&s.Namespace, &s.Name, &s.UsedBytes, &s.ReadBytesTotal, &s.WriteBytesTotal,
+			&s.FirstSeen, &s.LastSeen, &lastAccessed); err != nil {
+			return nil, err
 		}
+		s.LastAccessedAt = lastAccessed.Time
+		states[s.Namespace+"/"+s.Name] = s
 	}
-	return history, nil
+	return states, rows.Err()
+}
+
+// accessColumns are the pvc_access columns SaveAccessStates writes, in COPY order
+var accessColumns = []string{
+	"namespace", "pvc_name", "used_bytes", "read_bytes_total", "write_bytes_total",
+	"first_seen", "last_seen", "last_accessed_at",
+}
+
+// SaveAccessStates upserts the given access states in one batch and drops PVCs not seen
+// within types.AccessStateRetention. Agents sharing the table each save the PVCs they
+// track, so rows are only pruned by age and access times never move back.
+func (t *TimescaleDB) SaveAccessStates(ctx context.Context, states map[string]types.AccessState) error {
+	cutoff := time.Now().Add(-types.AccessStateRetention)
+	return writeWithRetry(ctx, "pvc_access", func(ctx context.Context) error {
+		return t.inTx(ctx, func(tx *sql.Tx) error {
+			if _, err := tx.ExecContext(ctx,
+				`CREATE TEMP TABLE pvc_access_batch (LIKE pvc_access) ON COMMIT DROP`,
+			); err != nil {
+				return err
+			}
+			stmt, err := tx.PrepareContext(ctx, pq.CopyIn("pvc_access_batch", accessColumns...))
+			if err != nil {
+				return err
+			}
+			defer func() { _ = stmt.Close() }()
+			for _, s := range states {
+				var lastAccessed sql.NullTime
+				if !s.LastAccessedAt.IsZero() {
+					lastAccessed = sql.NullTime{Time: s.LastAccessedAt, Valid: true}
+				}
+				if _, err := stmt.ExecContext(ctx, s.Namespace, s.Name, s.UsedBytes, s.ReadBytesTotal,
+					s.WriteBytesTotal, s.FirstSeen, s.LastSeen, lastAccessed); err != nil {
+					return err
+				}
+			}
+			if _, err := stmt.ExecContext(ctx); err != nil {
+				return err
+			}
+
+			if _, err := tx.ExecContext(ctx, `
+				INSERT INTO pvc_access AS a (namespace, pvc_name, used_bytes, read_bytes_total,
+				                             write_bytes_total, first_seen, last_seen, last_accessed_at)
+				SELECT namespace, pvc_name, used_bytes, read_bytes_total,
+				       write_bytes_total, first_seen, last_seen, last_accessed_at
+				FROM pvc_access_batch
+				ON CONFLICT (namespace, pvc_name) DO UPDATE SET
+					used_bytes = EXCLUDED.used_bytes,
+					read_bytes_total = EXCLUDED.read_bytes_total,
+					write_bytes_total = EXCLUDED.write_bytes_total,
+					first_seen = LEAST(a.first_seen, EXCLUDED.first_seen),
+					last_seen = GREATEST(a.last_seen, EXCLUDED.last_seen),
+					last_accessed_at = GREATEST(a.last_accessed_at, EXCLUDED.last_accessed_at)
+			`); err != nil {
+				return err
+			}
+			_, err = tx.ExecContext(ctx, `DELETE FROM pvc_access WHERE last_seen < $1`, cutoff)
+			return err
+		})
+	})
 }
 
 func (t *TimescaleDB) Close() error {
@@ -0,0 +1,220 @@
+package graph
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"log/slog"
+	"net"
+	"slices"
+	"time"
+
+	"github.com/lib/pq"
+
+	"github.com/cloudvault-io/cloudvault/pkg/integrations"
+	"github.com/cloudvault-io/cloudvault/pkg/types"
+)
+
+// copyChunkSize bounds the rows sent by one COPY, so a retry resends at most this many
+const copyChunkSize = 5000
+
+// writeAttempts is how many times a write failing with a transient error is tried
+const writeAttempts = 3
+
+// writeRetryBackoff is the wait before the first retry; it doubles for each retry after
+var writeRetryBackoff = 250 * time.Millisecond
+
+// clusterRollupBucket is the period a cluster rollup row covers. Agents recording in the
+// same period overwrite one row, so the table holds one row per cluster and period however
+// many agents write it.
+const clusterRollupBucket = time.Minute
+
+// pvcMetricsColumns are the pvc_metrics columns RecordMetrics writes, in COPY order
+var pvcMetricsColumns = []string{
+	"time", "pvc_name", "namespace", "used_bytes", "egress_bytes", "iops", "monthly_cost",
+	"size_bytes", "storage_class", "cluster", "provider", "region",
+}
+
+// ClusterRollup totals a cluster's PVCs for one clusterRollupBucket, so trends can be read
+// without scanning the per-PVC rows
+type ClusterRollup struct {
+	Time        time.Time `json:"time"`
+	Cluster     string    `json:"cluster"`
+	Provider    string    `json:"provider"`
+	Region      string    `json:"region"`
+	PVCs        int       `json:"pvcs"`
+	SizeBytes   int64     `json:"size_bytes"`
+	UsedBytes   int64     `json:"used_bytes"`
+	EgressBytes uint64    `json:"egress_bytes"`
+	IOPS        float64   `json:"iops"`
+	MonthlyCost float64   `json:"monthly_cost"`
+}
+
+// rollupMetrics totals metrics per cluster, in the order clusters first appear
+func rollupMetrics(now time.Time, metrics []types.PVCMetric) []ClusterRollup {
+	var rollups []ClusterRollup
+	for _, m := range metrics {
+		i := slices.IndexFunc(rollups, func(r ClusterRollup) bool { return r.Cluster == m.ClusterID })
+		if i < 0 {
+			rollups = append(rollups, ClusterRollup{Time: now, Cluster: m.ClusterID, Provider: m.Provider, Region: m.Region})
+			i = len(rollups) - 1
+		}
+		r := &rollups[i]
+		r.PVCs++
+		r.SizeBytes += m.SizeBytes
+		r.UsedBytes += m.UsedBytes
+		r.EgressBytes += m.EgressBytes
+		r.IOPS += m.ReadIOPS + m.WriteIOPS
+		r.MonthlyCost += m.MonthlyCost
+	}
+	return rollups
+}
+
+// isTransientError reports whether a failed write may succeed when retried: the
+// connection broke, the server is short of resources or shutting down, or the
+// transaction lost a serialization conflict or deadlock
+func isTransientError(err error) bool {
+	if errors.Is(err, driver.ErrBadConn) || errors.Is(err, io.ErrUnexpectedEOF) {
+		return true
+	}
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) {
+		switch pqErr.Code.Class() {
+		case "08", "40", "53":
+			return true
+		case "57":
+			return pqErr.Code != "57014" // query_canceled, by the caller or a statement timeout
+		}
+		return false
+	}
+	var netErr net.Error
+	return errors.As(err, &netErr)
+}
+
+// writeWithRetry runs write, retrying transient errors, and records its latency and
+// outcome for table
+func writeWithRetry(ctx context.Context, table string, write func(ctx context.Context) error) error {
+	start := time.Now()
+	err := retryTransient(ctx, table, write)
+	integrations.TimescaleWriteDuration.WithLabelValues(table).Observe(time.Since(start).Seconds())
+	if err != nil {
+		integrations.TimescaleWriteFailures.WithLabelValues(table).Inc()
+	}
+	return err
+}
+
+// retryTransient tries write up to writeAttempts times while it fails with transient
+// errors, doubling the wait between attempts
+func retryTransient(ctx context.Context, table string, write func(ctx context.Context) error) error {
+	backoff := writeRetryBackoff
+	for attempt := 1; ; attempt++ {
+		err := write(ctx)
+		if err == nil || attempt == writeAttempts || !isTransientError(err) {
+			return err
+		}
+		integrations.TimescaleWriteRetries.WithLabelValues(table).Inc()
+		slog.Warn("Retrying TimescaleDB write", "table", table, "attempt", attempt, "error", err)
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+	}
+}
+
+// inTx runs fn in a transaction, committed when fn succeeds
+func (t *TimescaleDB) inTx(ctx context.Context, fn func(tx *sql.Tx) error) error {
+	tx, err := t.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback() }()
+	if err := fn(tx); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// copyPVCMetrics writes one chunk of metrics with COPY
+func (t *TimescaleDB) copyPVCMetrics(ctx context.Context, now time.Time, metrics []types.PVCMetric) error {
+	return t.inTx(ctx, func(tx *sql.Tx) error {
+		stmt, err := tx.PrepareContext(ctx, pq.CopyIn("pvc_metrics", pvcMetricsColumns...))
+		if err != nil {
+			return err
+		}
+		defer func() { _ = stmt.Close() }()
+
+		for _, m := range metrics {
+			if _, err := stmt.ExecContext(ctx,
+				now,
+				m.Name,
+				m.Namespace,
+				m.UsedBytes,
+				int64(m.EgressBytes),
+				m.ReadIOPS+m.WriteIOPS,
+				m.MonthlyCost,
+				m.SizeBytes,
+				m.StorageClass,
+				m.ClusterID,
+				m.Provider,
+				m.Region,
+			); err != nil {
+				return err
+			}
+		}
+		// An Exec without arguments flushes the buffered rows
+		_, err = stmt.ExecContext(ctx)
+		return err
+	})
+}
+
+// insertClusterRollups writes one row per cluster, replacing the row of the same period
+func (t *TimescaleDB) insertClusterRollups(ctx context.Context, rollups []ClusterRollup) error {
+	return t.inTx(ctx, func(tx *sql.Tx) error {
+		for _, r := range rollups {
+			if _, err := tx.ExecContext(ctx, `
+				INSERT INTO cluster_metrics (time, cluster, provider, region, pvc_count, size_bytes,
+				                             used_bytes, egress_bytes, iops, monthly_cost)
+				VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+				ON CONFLICT (cluster, time) DO UPDATE SET
+					provider = EXCLUDED.provider, region = EXCLUDED.region, pvc_count = EXCLUDED.pvc_count,
+					size_bytes = EXCLUDED.size_bytes, used_bytes = EXCLUDED.used_bytes,
+					egress_bytes = EXCLUDED.egress_bytes, iops = EXCLUDED.iops, monthly_cost = EXCLUDED.monthly_cost
+			`, r.Time, r.Cluster, r.Provider, r.Region, r.PVCs, r.SizeBytes,
+				r.UsedBytes, int64(r.EgressBytes), r.IOPS, r.MonthlyCost); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// GetClusterTrend returns a cluster's rollups over the last duration, oldest first
+func (t *TimescaleDB) GetClusterTrend(ctx context.Context, cluster string, duration time.Duration) ([]ClusterRollup, error) {
+	rows, err := t.db.QueryContext(ctx, `
+		SELECT time, cluster, provider, region, pvc_count, size_bytes, used_bytes, egress_bytes, iops, monthly_cost
+		FROM cluster_metrics
+		WHERE cluster = $1 AND time > $2
+		ORDER BY time ASC
+	`, cluster, time.Now().Add(-duration))
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = rows.Close() }()
+
+	var trend []ClusterRollup
+	for rows.Next() {
+		var r ClusterRollup
+		var egress int64
+		if err := rows.Scan(&r.Time, &r.Cluster, &r.Provider, &r.Region, &r.PVCs, &r.SizeBytes,
+			&r.UsedBytes, &egress, &r.IOPS, &r.MonthlyCost); err != nil {
+			return nil, err
+		}
+		r.EgressBytes = uint64(egress)
+		trend = append(trend, r)
+	}
+	return trend, rows.Err()
+}
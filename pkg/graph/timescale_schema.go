@@ -0,0 +1,281 @@
+package graph
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log/slog"
+	"time"
+)
+
+// DefaultMetricsRetention is how long raw PVC samples are kept; the hourly and daily
+// aggregates keep them summarized after that
+const DefaultMetricsRetention = 30 * 24 * time.Hour
+
+// MinMetricsRetention keeps raw samples until the continuous aggregates have been
+// refreshed over them
+const MinMetricsRetention = 7 * 24 * time.Hour
+
+// schemaLockID serializes migrations between agents connecting to the same database
+const schemaLockID = 0x636c6f75647661 // "cloudva"
+
+// schemaMigration is one step of the TimescaleDB schema. Applied migrations are recorded
+// in cloudvault_schema_migrations and never run again, so released ones must not change.
+type schemaMigration struct {
+	version     int
+	description string
+	sql         string
+	// refresh lists continuous aggregates to materialize over every existing row once sql
+	// commits, since refreshing cannot run in a transaction. The migration is recorded
+	// after they are refreshed, so a failed refresh is retried on the next start.
+	refresh []string
+}
+
+var schemaMigrations = []schemaMigration{
+	{
+		version:     1,
+		description: "pvc_metrics hypertable and pvc_access",
+		sql: `
+			CREATE TABLE IF NOT EXISTS pvc_metrics (
+				time TIMESTAMPTZ NOT NULL,
+				pvc_name TEXT NOT NULL,
+				namespace TEXT NOT NULL,
+				used_bytes BIGINT,
+				egress_bytes BIGINT,
+				iops DOUBLE PRECISION,
+				monthly_cost DOUBLE PRECISION
+			);
+			SELECT create_hypertable('pvc_metrics', 'time', if_not_exists => TRUE);
+
+			CREATE TABLE IF NOT EXISTS pvc_access (
+				namespace TEXT NOT NULL,
+				pvc_name TEXT NOT NULL,
+				used_bytes BIGINT,
+				read_bytes_total DOUBLE PRECISION,
+				write_bytes_total DOUBLE PRECISION,
+				first_seen TIMESTAMPTZ NOT NULL,
+				last_seen TIMESTAMPTZ NOT NULL,
+				last_accessed_at TIMESTAMPTZ,
+				PRIMARY KEY (namespace, pvc_name)
+			);
+		`,
+	},
+	{
+		version:     2,
+		description: "pvc_metrics size, storage class and location columns",
+		sql: `
+			ALTER TABLE pvc_metrics
+				ADD COLUMN IF NOT EXISTS size_bytes BIGINT,
+				ADD COLUMN IF NOT EXISTS storage_class TEXT,
+				ADD COLUMN IF NOT EXISTS cluster TEXT,
+				ADD COLUMN IF NOT EXISTS provider TEXT,
+				ADD COLUMN IF NOT EXISTS region TEXT;
+			CREATE INDEX IF NOT EXISTS pvc_metrics_pvc_time_idx ON pvc_metrics (namespace, pvc_name, time DESC);
+		`,
+	},
+	{
+		// Created WITH NO DATA so they can be created inside the migration's transaction,
+		// then refreshed over existing rows: the policies only look back a few days, and
+		// real-time aggregation only covers buckets after the last refresh.
+		version:     3,
+		description: "hourly and daily continuous aggregates",
+		sql: `
+			CREATE MATERIALIZED VIEW IF NOT EXISTS pvc_metrics_hourly
+			WITH (timescaledb.continuous, timescaledb.materialized_only = false) AS
+			SELECT time_bucket(INTERVAL '1 hour', time) AS bucket, namespace, pvc_name,
+				avg(used_bytes) AS used_bytes,
+				max(used_bytes) AS max_used_bytes,
+				max(size_bytes) AS size_bytes,
+				avg(egress_bytes) AS egress_bytes,
+				avg(iops) AS iops,
+				avg(monthly_cost) AS monthly_cost
+			FROM pvc_metrics
+			GROUP BY bucket, namespace, pvc_name
+			WITH NO DATA;
+			SELECT add_continuous_aggregate_policy('pvc_metrics_hourly',
+				start_offset => INTERVAL '3 days', end_offset => INTERVAL '1 hour',
+				schedule_interval => INTERVAL '30 minutes', if_not_exists => TRUE);
+
+			CREATE MATERIALIZED VIEW IF NOT EXISTS pvc_metrics_daily
+			WITH (timescaledb.continuous, timescaledb.materialized_only = false) AS
+			SELECT time_bucket(INTERVAL '1 day', time) AS bucket, namespace, pvc_name,
+				avg(used_bytes) AS used_bytes,
+				max(used_bytes) AS max_used_bytes,
+				max(size_bytes) AS size_bytes,
+				avg(egress_bytes) AS egress_bytes,
+				avg(iops) AS iops,
+				avg(monthly_cost) AS monthly_cost
+			FROM pvc_metrics
+			GROUP BY bucket, namespace, pvc_name
+			WITH NO DATA;
+			SELECT add_continuous_aggregate_policy('pvc_metrics_daily',
+				start_offset => INTERVAL '4 days', end_offset => INTERVAL '1 hour',
+				schedule_interval => INTERVAL '12 hours', if_not_exists => TRUE);
+		`,
+		refresh: []string{"pvc_metrics_hourly", "pvc_metrics_daily"},
+	},
+	{
+		version:     4,
+		description: "pvc_metrics compression",
+		sql: `
+			ALTER TABLE pvc_metrics SET (
+				timescaledb.compress,
+				timescaledb.compress_segmentby = 'namespace, pvc_name',
+				timescaledb.compress_orderby = 'time DESC'
+			);
+			SELECT add_compression_policy('pvc_metrics', INTERVAL '7 days', if_not_exists => TRUE);
+		`,
+	},
+	{
+		version:     5,
+		description: "cluster_metrics rollups",
+		sql: `
+			CREATE TABLE IF NOT EXISTS cluster_metrics (
+				time TIMESTAMPTZ NOT NULL,
+				cluster TEXT NOT NULL,
+				provider TEXT,
+				region TEXT,
+				pvc_count INTEGER NOT NULL,
+				size_bytes BIGINT,
+				used_bytes BIGINT,
+				egress_bytes BIGINT,
+				iops DOUBLE PRECISION,
+				monthly_cost DOUBLE PRECISION
+			);
+			SELECT create_hypertable('cluster_metrics', 'time', if_not_exists => TRUE);
+			CREATE UNIQUE INDEX IF NOT EXISTS cluster_metrics_cluster_time_idx ON cluster_metrics (cluster, time DESC);
+		`,
+	},
+}
+
+// migrate applies the schema migrations not yet recorded in the database, in order
+func (t *TimescaleDB) migrate(ctx context.Context) error {
+	if _, err := t.db.ExecContext(ctx, `
+		CREATE TABLE IF NOT EXISTS cloudvault_schema_migrations (
+			version INTEGER PRIMARY KEY,
+			description TEXT NOT NULL,
+			applied_at TIMESTAMPTZ NOT NULL DEFAULT now()
+		)
+	`); err != nil {
+		return fmt.Errorf("failed to create schema migrations table: %w", err)
+	}
+
+	for _, m := range schemaMigrations {
+		applied, err := t.applyMigration(ctx, m)
+		if err != nil {
+			return fmt.Errorf("schema migration %d (%s) failed: %w", m.version, m.description, err)
+		}
+		if applied {
+			slog.Info("Applied TimescaleDB schema migration", "version", m.version, "description", m.description)
+		}
+	}
+	return nil
+}
+
+// applyMigration runs m in its own transaction unless it was already applied, then
+// refreshes its continuous aggregates
+func (t *TimescaleDB) applyMigration(ctx context.Context, m schemaMigration) (bool, error) {
+	tx, err := t.db.BeginTx(ctx, nil)
+	if err != nil {
+		return false, err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	if _, err := tx.ExecContext(ctx, `SELECT pg_advisory_xact_lock($1)`, schemaLockID); err != nil {
+		return false, err
+	}
+	var applied bool
+	if err := tx.QueryRowContext(ctx,
+		`SELECT EXISTS (SELECT 1 FROM cloudvault_schema_migrations WHERE version = $1)`, m.version,
+	).Scan(&applied); err != nil {
+		return false, err
+	}
+	if applied {
+		return false, nil
+	}
+
+	if _, err := tx.ExecContext(ctx, m.sql); err != nil {
+		return false, err
+	}
+	if len(m.refresh) == 0 {
+		if err := recordMigration(ctx, tx, m); err != nil {
+			return false, err
+		}
+		return true, tx.Commit()
+	}
+
+	if err := tx.Commit(); err != nil {
+		return false, err
+	}
+	for _, view := range m.refresh {
+		if _, err := t.db.ExecContext(ctx,
+			`CALL refresh_continuous_aggregate($1::regclass, NULL, now() - INTERVAL '1 hour')`, view,
+		); err != nil {
+			return false, fmt.Errorf("failed to refresh %s: %w", view, err)
+		}
+	}
+	return true, recordMigration(ctx, t.db, m)
+}
+
+// execer runs statements on a database or in a transaction
+type execer interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+}
+
+// recordMigration marks m applied; another agent may have recorded it first
+func recordMigration(ctx context.Context, db execer, m schemaMigration) error {
+	_, err := db.ExecContext(ctx, `
+		INSERT INTO cloudvault_schema_migrations (version, description) VALUES ($1, $2)
+		ON CONFLICT (version) DO NOTHING
+	`, m.version, m.description)
+	return err
+}
+
+// SchemaVersion returns the newest schema migration applied to the database
+func (t *TimescaleDB) SchemaVersion(ctx context.Context) (int, error) {
+	var version int
+	err := t.db.QueryRowContext(ctx, `SELECT coalesce(max(version), 0) FROM cloudvault_schema_migrations`).Scan(&version)
+	return version, err
+}
+
+// SetRetention drops raw samples older than retention. The hourly and daily aggregates
+// are not affected, so long histories stay available.
+func (t *TimescaleDB) SetRetention(ctx context.Context, retention time.Duration) error {
+	if retention < MinMetricsRetention {
+		return fmt.Errorf("metrics retention must be at least %s, got %s", MinMetricsRetention, retention)
+	}
+
+	tx, err := t.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	if _, err := tx.ExecContext(ctx, `SELECT remove_retention_policy('pvc_metrics', if_exists => TRUE)`); err != nil {
+		return fmt.Errorf("failed to remove retention policy: %w", err)
+	}
+	if _, err := tx.ExecContext(ctx,
+		`SELECT add_retention_policy('pvc_metrics', drop_after => $1::interval)`, pgInterval(retention),
+	); err != nil {
+		return fmt.Errorf("failed to add retention policy: %w", err)
+	}
+	return tx.Commit()
+}
+
+// pgInterval formats d as a PostgreSQL interval, to the second
+func pgInterval(d time.Duration) string {
+	return fmt.Sprintf("%d seconds", int64(d/time.Second))
+}
+
+// historySource picks what GetHistory reads a window from: raw samples, or the hourly or
+// daily aggregate for the window's historyResolution
+func historySource(window time.Duration) (table, timeColumn string) {
+	switch historyResolution(window) {
+	case 0:
+		return "pvc_metrics", "time"
+	case time.Hour:
+		return "pvc_metrics_hourly", "bucket"
+	default:
+		return "pvc_metrics_daily", "bucket"
+	}
+}
@@ -2,9 +2,18 @@ package graph
 
 import (
 	"context"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"regexp"
+	"slices"
 	"testing"
 	"time"
 
+	"github.com/lib/pq"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+
+	"github.com/cloudvault-io/cloudvault/pkg/integrations"
 	"github.com/cloudvault-io/cloudvault/pkg/types"
 )
 
@@ -71,6 +80,26 @@ func TestTimescaleDB_RecordMetrics(t *testing.T) {
 	if err != nil {
 		t.Fatalf("Failed to record metrics: %v", err)
 	}
+
+	trend, err := db.GetClusterTrend(ctx, "", time.Hour)
+	if err != nil {
+		t.Fatalf("Failed to get cluster trend: %v", err)
+	}
+	if len(trend) == 0 || trend[len(trend)-1].PVCs != 2 {
+		t.Errorf("Expected a rollup of 2 PVCs, got %+v", trend)
+	}
+
+	// Another agent recording the same period replaces the rollup
+	if err := db.RecordMetrics(ctx, metrics); err != nil {
+		t.Fatalf("Failed to record metrics again: %v", err)
+	}
+	again, err := db.GetClusterTrend(ctx, "", time.Hour)
+	if err != nil {
+		t.Fatalf("Failed to get cluster trend: %v", err)
+	}
+	if len(again) > len(trend)+1 {
+		t.Errorf("Expected at most one rollup per period, got %d rows after %d", len(again), len(trend))
+	}
 }
 
 func TestTimescaleDB_GetHistory(t *testing.T) {
@@ -184,8 +213,143 @@ func TestTimescaleDB_Close(t *testing.T) {
 	}
 }
 
+func TestTimescaleDB_SchemaVersion(t *testing.T) {
+	t.Skip("Requires TimescaleDB instance")
+
+	connStr := "host=localhost port=5432 user=postgres password=postgres dbname=test sslmode=disable"
+	db, err := NewTimescaleDB(connStr)
+	if err != nil {
+		t.Skipf("Cannot connect to database: %v", err)
+	}
+	defer func() {
+		_ = db.Close()
+	}()
+
+	ctx := context.Background()
+	version, err := db.SchemaVersion(ctx)
+	if err != nil {
+		t.Fatalf("SchemaVersion failed: %v", err)
+	}
+	if want := schemaMigrations[len(schemaMigrations)-1].version; version != want {
+		t.Errorf("Expected schema version %d, got %d", want, version)
+	}
+
+	// Migrations already applied are skipped
+	if err := db.migrate(ctx); err != nil {
+		t.Fatalf("Re-running migrations failed: %v", err)
+	}
+	if err := db.SetRetention(ctx, DefaultMetricsRetention); err != nil {
+		t.Fatalf("SetRetention failed: %v", err)
+	}
+	if _, err := db.GetHistory(ctx, "default", "test-pvc-1", 90*24*time.Hour); err != nil {
+		t.Fatalf("GetHistory from the daily aggregate failed: %v", err)
+	}
+}
+
+func TestTimescaleDB_AggregatesBackfilled(t *testing.T) {
+	t.Skip("Requires TimescaleDB instance")
+
+	connStr := "host=localhost port=5432 user=postgres password=postgres dbname=test sslmode=disable"
+	db, err := NewTimescaleDB(connStr)
+	if err != nil {
+		t.Skipf("Cannot connect to database: %v", err)
+	}
+	defer func() {
+		_ = db.Close()
+	}()
+
+	// An upgraded database: raw rows from before the aggregates existed
+	ctx := context.Background()
+	for _, stmt := range []string{
+		`DROP MATERIALIZED VIEW IF EXISTS pvc_metrics_hourly CASCADE`,
+		`DROP MATERIALIZED VIEW IF EXISTS pvc_metrics_daily CASCADE`,
+		`DELETE FROM cloudvault_schema_migrations WHERE version = 3`,
+		`INSERT INTO pvc_metrics (time, pvc_name, namespace, used_bytes)
+		 VALUES (now() - INTERVAL '20 days', 'backfill-pvc', 'default', 1000)`,
+	} {
+		if _, err := db.db.ExecContext(ctx, stmt); err != nil {
+			t.Fatalf("Failed to set up upgrade: %v", err)
+		}
+	}
+	if err := db.migrate(ctx); err != nil {
+		t.Fatalf("Migration failed: %v", err)
+	}
+
+	// Read only what was materialized, not the real-time part of the views
+	for _, view := range []string{"pvc_metrics_hourly", "pvc_metrics_daily"} {
+		if _, err := db.db.ExecContext(ctx,
+			`ALTER MATERIALIZED VIEW `+view+` SET (timescaledb.materialized_only = true)`); err != nil {
+			t.Fatalf("Failed to read %s materialized only: %v", view, err)
+		}
+		var rows int
+		err := db.db.QueryRowContext(ctx,
+			`SELECT count(*) FROM `+view+` WHERE pvc_name = 'backfill-pvc'`).Scan(&rows)
+		_, _ = db.db.ExecContext(ctx, `ALTER MATERIALIZED VIEW `+view+` SET (timescaledb.materialized_only = false)`)
+		if err != nil {
+			t.Fatalf("Failed to query %s: %v", view, err)
+		}
+		if rows != 1 {
+			t.Errorf("Expected rows older than the refresh policy window in %s, got %d", view, rows)
+		}
+	}
+}
+
 // Mock tests that don't require database connection
 
+func TestSchemaMigrations_Ordered(t *testing.T) {
+	for i, m := range schemaMigrations {
+		if m.version != i+1 {
+			t.Errorf("Migration %d has version %d, want %d", i, m.version, i+1)
+		}
+		if m.description == "" || m.sql == "" {
+			t.Errorf("Migration %d is missing a description or SQL", m.version)
+		}
+		// Aggregates created empty are refreshed over existing rows
+		for _, match := range regexp.MustCompile(`CREATE MATERIALIZED VIEW IF NOT EXISTS (\w+)`).FindAllStringSubmatch(m.sql, -1) {
+			if !slices.Contains(m.refresh, match[1]) {
+				t.Errorf("Migration %d creates %s without refreshing it", m.version, match[1])
+			}
+		}
+	}
+}
+
+func TestHistorySource(t *testing.T) {
+	tests := []struct {
+		window time.Duration
+		table  string
+		column string
+	}{
+		{time.Hour, "pvc_metrics", "time"},
+		{48 * time.Hour, "pvc_metrics", "time"},
+		{7 * 24 * time.Hour, "pvc_metrics_hourly", "bucket"},
+		{30 * 24 * time.Hour, "pvc_metrics_hourly", "bucket"},
+		{90 * 24 * time.Hour, "pvc_metrics_daily", "bucket"},
+	}
+
+	for _, tt := range tests {
+		table, column := historySource(tt.window)
+		if table != tt.table {
+			t.Errorf("historySource(%s) = %s, want %s", tt.window, table, tt.table)
+		}
+		if column != tt.column {
+			t.Errorf("historySource(%s) time column = %s, want %s", tt.window, column, tt.column)
+		}
+	}
+}
+
+func TestSetRetention_TooShort(t *testing.T) {
+	db := &TimescaleDB{}
+	if err := db.SetRetention(context.Background(), 24*time.Hour); err == nil {
+		t.Error("Expected error for retention shorter than the aggregate refresh window")
+	}
+}
+
+func TestPgInterval(t *testing.T) {
+	if got := pgInterval(DefaultMetricsRetention); got != "2592000 seconds" {
+		t.Errorf("pgInterval(30d) = %s, want 2592000 seconds", got)
+	}
+}
+
 func TestTimescaleDB_MetricsSerialization(t *testing.T) {
 	// Test that metrics are properly structured
 	metrics := []types.PVCMetric{
@@ -215,3 +379,147 @@ func TestTimescaleDB_MetricsSerialization(t *testing.T) {
 		t.Errorf("Expected UsedBytes 107374182400, got %d", m.UsedBytes)
 	}
 }
+
+func TestTimescaleDB_AccessStates(t *testing.T) {
+	t.Skip("Requires TimescaleDB instance")
+
+	connStr := "host=localhost port=5432 user=postgres password=postgres dbname=test sslmode=disable"
+	db, err := NewTimescaleDB(connStr)
+	if err != nil {
+		t.Skipf("Cannot connect to database: %v", err)
+	}
+	defer func() {
+		_ = db.Close()
+	}()
+
+	ctx := context.Background()
+	now := time.Now().UTC().Truncate(time.Second)
+	states := map[string]types.AccessState{
+		"default/test-pvc-1": {Namespace: "default", Name: "test-pvc-1", UsedBytes: 100, FirstSeen: now, LastSeen: now, LastAccessedAt: now},
+		"default/test-pvc-2": {Namespace: "default", Name: "test-pvc-2", UsedBytes: 200, FirstSeen: now, LastSeen: now},
+	}
+	if err := db.SaveAccessStates(ctx, states); err != nil {
+		t.Fatalf("SaveAccessStates failed: %v", err)
+	}
+
+	loaded, err := db.LoadAccessStates(ctx)
+	if err != nil {
+		t.Fatalf("LoadAccessStates failed: %v", err)
+	}
+	if !loaded["default/test-pvc-1"].LastAccessedAt.Equal(now) {
+		t.Errorf("Expected last access %v, got %v", now, loaded["default/test-pvc-1"].LastAccessedAt)
+	}
+	if !loaded["default/test-pvc-2"].LastAccessedAt.IsZero() {
+		t.Error("Expected no last access for test-pvc-2")
+	}
+
+	// Another agent saving older state for one PVC neither drops the other nor moves
+	// the last access back
+	other := map[string]types.AccessState{
+		"default/test-pvc-1": {Namespace: "default", Name: "test-pvc-1", UsedBytes: 100,
+			FirstSeen: now.Add(-48 * time.Hour), LastSeen: now.Add(-time.Hour), LastAccessedAt: now.Add(-2 * time.Hour)},
+	}
+	if err := db.SaveAccessStates(ctx, other); err != nil {
+		t.Fatalf("SaveAccessStates failed: %v", err)
+	}
+	loaded, err = db.LoadAccessStates(ctx)
+	if err != nil {
+		t.Fatalf("LoadAccessStates failed: %v", err)
+	}
+	if _, ok := loaded["default/test-pvc-2"]; !ok {
+		t.Error("Expected test-pvc-2 to survive another agent's save")
+	}
+	if got := loaded["default/test-pvc-1"]; !got.LastAccessedAt.Equal(now) || !got.FirstSeen.Equal(now.Add(-48*time.Hour)) {
+		t.Errorf("Expected last access %v and first seen %v, got %+v", now, now.Add(-48*time.Hour), got)
+	}
+}
+
+func TestRollupMetrics(t *testing.T) {
+	now := time.Now()
+	rollups := rollupMetrics(now, []types.PVCMetric{
+		{ClusterID: "prod", Provider: "aws", Region: "us-east-1", SizeBytes: 100, UsedBytes: 50, EgressBytes: 10, ReadIOPS: 1, WriteIOPS: 2, MonthlyCost: 1.5},
+		{ClusterID: "prod", Provider: "aws", Region: "us-east-1", SizeBytes: 200, UsedBytes: 20, EgressBytes: 5, ReadIOPS: 3, MonthlyCost: 2.5},
+		{ClusterID: "dev", Provider: "gcp", Region: "us-central1", SizeBytes: 10},
+	})
+
+	if len(rollups) != 2 {
+		t.Fatalf("Expected 2 rollups, got %d", len(rollups))
+	}
+	want := ClusterRollup{
+		Time: now, Cluster: "prod", Provider: "aws", Region: "us-east-1",
+		PVCs: 2, SizeBytes: 300, UsedBytes: 70, EgressBytes: 15, IOPS: 6, MonthlyCost: 4,
+	}
+	if rollups[0] != want {
+		t.Errorf("Expected %+v, got %+v", want, rollups[0])
+	}
+	if rollups[1].Cluster != "dev" || rollups[1].PVCs != 1 {
+		t.Errorf("Expected a dev rollup of 1 PVC, got %+v", rollups[1])
+	}
+}
+
+func TestIsTransientError(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"connection failure", &pq.Error{Code: "08006"}, true},
+		{"serialization failure", &pq.Error{Code: "40001"}, true},
+		{"too many connections", &pq.Error{Code: "53300"}, true},
+		{"admin shutdown", &pq.Error{Code: "57P01"}, true},
+		{"query canceled", &pq.Error{Code: "57014"}, false},
+		{"unique violation", &pq.Error{Code: "23505"}, false},
+		{"bad connection", fmt.Errorf("write: %w", driver.ErrBadConn), true},
+		{"other", errors.New("boom"), false},
+	}
+
+	for _, tt := range tests {
+		if got := isTransientError(tt.err); got != tt.want {
+			t.Errorf("%s: isTransientError = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestWriteWithRetry(t *testing.T) {
+	backoff := writeRetryBackoff
+	writeRetryBackoff = time.Millisecond
+	defer func() { writeRetryBackoff = backoff }()
+	ctx := context.Background()
+
+	retries := testutil.ToFloat64(integrations.TimescaleWriteRetries.WithLabelValues("test"))
+	calls := 0
+	err := writeWithRetry(ctx, "test", func(ctx context.Context) error {
+		if calls++; calls == 1 {
+			return &pq.Error{Code: "08006"}
+		}
+		return nil
+	})
+	if err != nil || calls != 2 {
+		t.Errorf("Expected success on the second attempt, got %v after %d calls", err, calls)
+	}
+	if got := testutil.ToFloat64(integrations.TimescaleWriteRetries.WithLabelValues("test")) - retries; got != 1 {
+		t.Errorf("Expected 1 retry recorded, got %v", got)
+	}
+
+	failures := testutil.ToFloat64(integrations.TimescaleWriteFailures.WithLabelValues("test"))
+	calls = 0
+	err = writeWithRetry(ctx, "test", func(ctx context.Context) error {
+		calls++
+		return &pq.Error{Code: "08006"}
+	})
+	if err == nil || calls != writeAttempts {
+		t.Errorf("Expected failure after %d attempts, got %v after %d calls", writeAttempts, err, calls)
+	}
+
+	calls = 0
+	err = writeWithRetry(ctx, "test", func(ctx context.Context) error {
+		calls++
+		return &pq.Error{Code: "23505"}
+	})
+	if err == nil || calls != 1 {
+		t.Errorf("Expected no retry of a permanent error, got %d calls", calls)
+	}
+	if got := testutil.ToFloat64(integrations.TimescaleWriteFailures.WithLabelValues("test")) - failures; got != 2 {
+		t.Errorf("Expected 2 failures recorded, got %v", got)
+	}
+}
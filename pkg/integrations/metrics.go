@@ -19,9 +19,72 @@ var (
 		Buckets: prometheus.DefBuckets,
 	})
 
+	// EnricherDuration measures the time each collection enricher takes per cycle
+	EnricherDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "cloudvault_enricher_duration_seconds",
+		Help:    "Time taken by each PVC enricher per collection cycle",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"enricher"})
+
+	// EnricherErrors counts failed enricher runs
+	EnricherErrors = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "cloudvault_enricher_errors_total",
+		Help: "The total number of failed PVC enricher runs",
+	}, []string{"enricher"})
+
 	// PVCCount tracks the number of PVCs managed by CloudVault
 	PVCCount = promauto.NewGauge(prometheus.GaugeOpts{
 		Name: "cloudvault_managed_pvcs",
 		Help: "The total number of PVCs currently being tracked",
 	})
+
+	// EbpfFlowMapEntries tracks the number of flows held in the eBPF flow map
+	EbpfFlowMapEntries = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "cloudvault_ebpf_flow_map_entries",
+		Help: "The number of flows currently held in the eBPF flow map",
+	})
+
+	// EbpfFlowMapPressure tracks how full the eBPF flow map is
+	EbpfFlowMapPressure = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "cloudvault_ebpf_flow_map_pressure_ratio",
+		Help: "Entries in the eBPF flow map as a fraction of its capacity",
+	})
+
+	// EbpfFlowEvictions counts flows the kernel evicted from the full eBPF flow map
+	EbpfFlowEvictions = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "cloudvault_ebpf_flow_evictions_total",
+		Help: "The estimated number of flows evicted from the eBPF flow map",
+	})
+
+	// EbpfFlowInsertFailures counts flows the eBPF programs could not record
+	EbpfFlowInsertFailures = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "cloudvault_ebpf_flow_insert_failures_total",
+		Help: "The number of flows that could not be inserted into the eBPF flow map",
+	})
+
+	// SIGRemovals counts PVCs, pods and relationships removed from the Storage
+	// Intelligence Graph, by sweeps of what syncs stopped reporting or by delete events
+	SIGRemovals = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "cloudvault_sig_removed_total",
+		Help: "The number of objects removed from the Storage Intelligence Graph",
+	}, []string{"kind", "reason"})
+
+	// TimescaleWriteDuration measures TimescaleDB writes, retries included
+	TimescaleWriteDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "cloudvault_timescale_write_duration_seconds",
+		Help:    "Time taken by each TimescaleDB write, including retries",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"table"})
+
+	// TimescaleWriteRetries counts TimescaleDB writes retried after a transient error
+	TimescaleWriteRetries = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "cloudvault_timescale_write_retries_total",
+		Help: "The number of TimescaleDB writes retried after a transient error",
+	}, []string{"table"})
+
+	// TimescaleWriteFailures counts TimescaleDB writes that failed after any retries
+	TimescaleWriteFailures = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "cloudvault_timescale_write_failures_total",
+		Help: "The number of TimescaleDB writes that failed",
+	}, []string{"table"})
 )
@@ -4,38 +4,72 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"io"
+	"log/slog"
 	"net/http"
 	"net/url"
 	"strconv"
+	"strings"
 	"time"
 )
 
 // PrometheusClient handles interactions with a Prometheus server.
 // Refactored for Phase 3 to support batch vector queries and eliminate N+1 bottlenecks.
 type PrometheusClient struct {
-	baseURL string
-	client  *http.Client
+	baseURL          string
+	client           *http.Client
+	maxResponseBytes int64
+	onWarnings       func(query string, warnings []string)
 }
 
-// NewPrometheusClient creates a new Prometheus client
+// NewPrometheusClient creates a new Prometheus client without authentication
 func NewPrometheusClient(url string) (*PrometheusClient, error) {
+	return NewPrometheusClientWithOptions(url, PrometheusOptions{})
+}
+
+// NewPrometheusClientWithOptions creates a Prometheus client with auth, TLS and custom headers
+func NewPrometheusClientWithOptions(url string, opts PrometheusOptions) (*PrometheusClient, error) {
 	if url == "" {
 		return nil, fmt.Errorf("prometheus URL cannot be empty")
 	}
+	transport, err := opts.newTransport()
+	if err != nil {
+		return nil, err
+	}
+	if opts.Timeout == 0 {
+		opts.Timeout = 10 * time.Second
+	}
+	if opts.MaxResponseBytes <= 0 {
+		opts.MaxResponseBytes = DefaultMaxResponseBytes
+	}
 	return &PrometheusClient{
-		baseURL: url,
+		baseURL: strings.TrimSuffix(url, "/"),
 		client: &http.Client{
-			Timeout: 10 * time.Second,
+			Timeout:   opts.Timeout,
+			Transport: transport,
 		},
+		maxResponseBytes: opts.MaxResponseBytes,
+		onWarnings:       logWarnings,
 	}, nil
 }
 
-// PVCUsageMetrics contains usage data fetched from Prometheus
+// SetWarningHandler replaces the default (logging) handler for query warnings.
+// Prometheus reports partial responses, e.g. an unreachable Thanos store, as warnings.
+func (p *PrometheusClient) SetWarningHandler(handler func(query string, warnings []string)) {
+	p.onWarnings = handler
+}
+
+func logWarnings(query string, warnings []string) {
+	slog.Warn("prometheus returned a partial response", "query", query, "warnings", warnings)
+}
+
+// PVCUsageMetrics contains usage data fetched from Prometheus.
+// Presence in Prometheus only means the volume is mounted, not that it is being accessed;
+// last access is derived from counter deltas by the collector's access enricher.
 type PVCUsageMetrics struct {
 	UsedBytes       int64
 	ReadBytesTotal  float64
 	WriteBytesTotal float64
-	LastActivity    time.Time
 }
 
 // GetAllPVCMetrics fetches usage metrics for all PVCs in the cluster in a single batch query.
@@ -61,23 +95,33 @@ func (p *PrometheusClient) GetAllPVCMetrics(ctx context.Context) (map[string]map
 		}
 
 		metricsMap[ns][pvc] = &PVCUsageMetrics{
-			UsedBytes:    int64(res.Value),
-			LastActivity: time.Now(), // If it's in Prometheus, it's recently active
+			UsedBytes: int64(res.Value),
 		}
 	}
 
 	return metricsMap, nil
 }
 
-// queryVector executes a PromQL query that returns a vector of results
-func (p *PrometheusClient) queryVector(ctx context.Context, query string) ([]QueryResult, error) {
-	u, err := url.Parse(fmt.Sprintf("%s/api/v1/query", p.baseURL))
+// apiResponse is the envelope of every Prometheus HTTP API response
+type apiResponse struct {
+	Status    string   `json:"status"`
+	ErrorType string   `json:"errorType"`
+	Error     string   `json:"error"`
+	Warnings  []string `json:"warnings"`
+	Data      struct {
+		ResultType string          `json:"resultType"`
+		Result     json.RawMessage `json:"result"`
+	} `json:"data"`
+}
+
+// get calls a Prometheus API endpoint and returns the decoded envelope
+func (p *PrometheusClient) get(ctx context.Context, path, query string, params url.Values) (*apiResponse, error) {
+	u, err := url.Parse(p.baseURL + path)
 	if err != nil {
 		return nil, err
 	}
-	q := u.Query()
-	q.Set("query", query)
-	u.RawQuery = q.Encode()
+	params.Set("query", query)
+	u.RawQuery = params.Encode()
 
 	req, err := http.NewRequestWithContext(ctx, "GET", u.String(), nil)
 	if err != nil {
@@ -90,39 +134,56 @@ func (p *PrometheusClient) queryVector(ctx context.Context, query string) ([]Que
 	}
 	defer func() { _ = resp.Body.Close() }()
 
+	body, err := io.ReadAll(io.LimitReader(resp.Body, p.maxResponseBytes+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(body)) > p.maxResponseBytes {
+		return nil, fmt.Errorf("prometheus response exceeds %d bytes", p.maxResponseBytes)
+	}
+
+	var result apiResponse
+	decodeErr := json.Unmarshal(body, &result)
+
 	if resp.StatusCode != http.StatusOK {
+		if decodeErr == nil && result.Error != "" {
+			return nil, fmt.Errorf("prometheus returned status %d: %s: %s", resp.StatusCode, result.ErrorType, result.Error)
+		}
 		return nil, fmt.Errorf("prometheus returned status %d", resp.StatusCode)
 	}
-
-	var result struct {
-		Status string `json:"status"`
-		Data   struct {
-			ResultType string `json:"resultType"`
-			Result     []struct {
-				Metric map[string]string `json:"metric"`
-				Value  []interface{}     `json:"value"`
-			} `json:"result"`
-		} `json:"data"`
+	if decodeErr != nil {
+		return nil, decodeErr
+	}
+	if result.Status != "success" {
+		return nil, fmt.Errorf("query failed: %s %s", result.Status, result.Error)
 	}
+	if len(result.Warnings) > 0 && p.onWarnings != nil {
+		p.onWarnings(query, result.Warnings)
+	}
+	return &result, nil
+}
 
-	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+// queryVector executes a PromQL query that returns a vector of results
+func (p *PrometheusClient) queryVector(ctx context.Context, query string) ([]QueryResult, error) {
+	result, err := p.get(ctx, "/api/v1/query", query, url.Values{})
+	if err != nil {
 		return nil, err
 	}
 
-	if result.Status != "success" {
-		return nil, fmt.Errorf("query failed: %s", result.Status)
+	var vector []struct {
+		Metric map[string]string `json:"metric"`
+		Value  []interface{}     `json:"value"`
+	}
+	if err := json.Unmarshal(result.Data.Result, &vector); err != nil {
+		return nil, err
 	}
 
 	var queryResults []QueryResult
-	for _, res := range result.Data.Result {
-		if len(res.Value) < 2 {
-			continue
-		}
-		strVal, ok := res.Value[1].(string)
+	for _, res := range vector {
+		_, val, ok := parseSample(res.Value)
 		if !ok {
 			continue
 		}
-		val, _ := strconv.ParseFloat(strVal, 64)
 		queryResults = append(queryResults, QueryResult{
 			Labels: res.Metric,
 			Value:  val,
@@ -132,6 +193,27 @@ func (p *PrometheusClient) queryVector(ctx context.Context, query string) ([]Que
 	return queryResults, nil
 }
 
+// parseSample decodes a [timestamp, "value"] pair
+func parseSample(pair []interface{}) (time.Time, float64, bool) {
+	if len(pair) < 2 {
+		return time.Time{}, 0, false
+	}
+	ts, ok := pair[0].(float64)
+	if !ok {
+		return time.Time{}, 0, false
+	}
+	strVal, ok := pair[1].(string)
+	if !ok {
+		return time.Time{}, 0, false
+	}
+	val, err := strconv.ParseFloat(strVal, 64)
+	if err != nil {
+		return time.Time{}, 0, false
+	}
+	sec := int64(ts)
+	return time.Unix(sec, int64((ts-float64(sec))*1e9)), val, true
+}
+
 // QueryResult represents a single Prometheus vector result
 type QueryResult struct {
 	Labels map[string]string
@@ -151,87 +233,17 @@ func (p *PrometheusClient) GetPVCMetrics(ctx context.Context, pvcName, namespace
 		metrics.UsedBytes = int64(val)
 	}
 
-	// 2. Get Activity (Read + Write) to detect Zombies
-	// We check for any I/O activity in the last 24 hours.
-	// Query: sum(rate(container_fs_reads_bytes_total{namespace="<ns>"}[24h])) + sum(rate(container_fs_writes_bytes_total{namespace="<ns>"}[24h]))
-	// Note: Mapping PVC to container usually requires joining kube_pod_spec_volumes_persistentvolumeclaims_info
-	// For MVP, we'll try a simpler heuristic: check if `kubelet_volume_stats_used_bytes` has changed variance over time?
-	// No, that's size.
-	// Let's stick to `UsedBytes` for now as the primary reliable metric for "Oversized".
-	// For "Zombie", we can check if `kubelet_volume_stats_used_bytes` > 0 BUT no pod is mounted?
-	// Actually, `kubelet_volume_stats` only exists if a Pod is mounted!
-	// So if `kubelet_volume_stats` is missing for a PVC that is Bound, it MIGHT be unused (Zombie candidate).
-
-	// Enhancing Zombie Logic:
-	// If we get a result for `kubelet_volume_stats_used_bytes`, the volume is mounted and active on a node -> NOT Zombie (mostly).
-	// If we get NO result, but PVC exists -> Likely Zombie (not mounted).
-
-	if err == nil {
-		// Metric exists, so volume is mounted.
-		// We'll mark it as "active recently"
-		metrics.LastActivity = time.Now()
-	}
-
 	return metrics, nil
 }
 
 // queryScalar executes a PromQL query that returns a single scalar value
 func (p *PrometheusClient) queryScalar(ctx context.Context, query string) (float64, error) {
-	u, err := url.Parse(fmt.Sprintf("%s/api/v1/query", p.baseURL))
-	if err != nil {
-		return 0, err
-	}
-	q := u.Query()
-	q.Set("query", query)
-	u.RawQuery = q.Encode()
-
-	req, err := http.NewRequestWithContext(ctx, "GET", u.String(), nil)
-	if err != nil {
-		return 0, err
-	}
-
-	resp, err := p.client.Do(req)
+	results, err := p.queryVector(ctx, query)
 	if err != nil {
 		return 0, err
 	}
-	defer func() { _ = resp.Body.Close() }()
-
-	if resp.StatusCode != http.StatusOK {
-		return 0, fmt.Errorf("prometheus returned status %d", resp.StatusCode)
-	}
-
-	var result struct {
-		Status string `json:"status"`
-		Data   struct {
-			ResultType string `json:"resultType"`
-			Result     []struct {
-				Value []interface{} `json:"value"`
-			} `json:"result"`
-		} `json:"data"`
-	}
-
-	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
-		return 0, err
-	}
-
-	if result.Status != "success" {
-		return 0, fmt.Errorf("query failed: %s", result.Status)
-	}
-
-	if len(result.Data.Result) == 0 {
+	if len(results) == 0 {
 		return 0, fmt.Errorf("no data")
 	}
-
-	// Prometheus value is [timestamp, string_value]
-	// Example: [1435781456.789, "1"]
-	if len(result.Data.Result[0].Value) < 2 {
-		return 0, fmt.Errorf("unexpected value format")
-	}
-
-	strVal, ok := result.Data.Result[0].Value[1].(string)
-	if !ok {
-		return 0, fmt.Errorf("value is not a string")
-	}
-
-	return strconv.ParseFloat(strVal, 64)
+	return results[0].Value, nil
 }
@@ -0,0 +1,101 @@
+package integrations
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net"
+	"strings"
+)
+
+// UnknownDestination is the destination key used when Prometheus knows how many bytes
+// a pod sent but not where they went (no flow metrics available)
+const UnknownDestination = "unknown"
+
+// GetPodEgressBytes returns cumulative transmit bytes per pod IP, in the same
+// source IP -> destination -> bytes shape as the eBPF agent.
+//
+// Byte counts come from cAdvisor's container_network_transmit_bytes_total. When Cilium
+// Hubble flow metrics are available, each pod's bytes are split across destinations in
+// proportion to its forwarded flows; otherwise they are reported under UnknownDestination.
+func (p *PrometheusClient) GetPodEgressBytes(ctx context.Context) (map[string]map[string]uint64, error) {
+	// 1. Pod -> IP (kube-state-metrics); host-network pods share the node IP and are skipped
+	infos, err := p.queryVector(ctx, `kube_pod_info{host_network!="true"}`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch pod info: %w", err)
+	}
+	podIPs := make(map[string]string)
+	for _, info := range infos {
+		ip := info.Labels["pod_ip"]
+		if ip == "" || info.Labels["namespace"] == "" || info.Labels["pod"] == "" {
+			continue
+		}
+		podIPs[info.Labels["namespace"]+"/"+info.Labels["pod"]] = ip
+	}
+
+	// 2. Bytes per pod (cAdvisor)
+	transmit, err := p.queryVector(ctx, `sum by(namespace, pod) (container_network_transmit_bytes_total{interface!="lo"})`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch pod transmit bytes: %w", err)
+	}
+
+	// 3. Optional destination split (Hubble)
+	flows := p.hubbleFlows(ctx, podIPs)
+
+	result := make(map[string]map[string]uint64)
+	for _, t := range transmit {
+		pod := t.Labels["namespace"] + "/" + t.Labels["pod"]
+		srcIP, ok := podIPs[pod]
+		if !ok || t.Value <= 0 {
+			continue
+		}
+		total := uint64(t.Value)
+		if result[srcIP] == nil {
+			result[srcIP] = make(map[string]uint64)
+		}
+
+		dsts := flows[pod]
+		var flowCount float64
+		for _, n := range dsts {
+			flowCount += n
+		}
+		if flowCount == 0 {
+			result[srcIP][UnknownDestination] += total
+			continue
+		}
+		for dst, n := range dsts {
+			result[srcIP][dst] += uint64(float64(total) * n / flowCount)
+		}
+	}
+
+	return result, nil
+}
+
+// hubbleFlows returns forwarded flow counts per source pod ("namespace/pod") and destination IP.
+// Missing Hubble metrics are not an error: the map is simply empty.
+func (p *PrometheusClient) hubbleFlows(ctx context.Context, podIPs map[string]string) map[string]map[string]float64 {
+	results, err := p.queryVector(ctx, `sum by(source, destination) (hubble_flows_processed_total{verdict="FORWARDED"})`)
+	if err != nil {
+		slog.Debug("hubble flow metrics unavailable", "error", err)
+		return nil
+	}
+
+	flows := make(map[string]map[string]float64)
+	for _, res := range results {
+		src, dst := res.Labels["source"], res.Labels["destination"]
+		if src == "" || dst == "" || res.Value <= 0 {
+			continue
+		}
+		// Hubble identifies pods as "namespace/pod"; resolve pod destinations to their IP
+		if net.ParseIP(dst) == nil && strings.Contains(dst, "/") {
+			if ip, ok := podIPs[dst]; ok {
+				dst = ip
+			}
+		}
+		if flows[src] == nil {
+			flows[src] = make(map[string]float64)
+		}
+		flows[src][dst] += res.Value
+	}
+	return flows
+}
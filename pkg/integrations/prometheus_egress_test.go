@@ -0,0 +1,82 @@
+package integrations
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newEgressTestServer(t *testing.T, hubble string) *PrometheusClient {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query().Get("query")
+		w.Header().Set("Content-Type", "application/json")
+		switch {
+		case strings.HasPrefix(query, "kube_pod_info"):
+			_, _ = w.Write([]byte(prometheusVectorResponse([]map[string]string{
+				{"namespace": "prod", "pod": "db-0", "pod_ip": "10.0.1.5"},
+				{"namespace": "prod", "pod": "api-0", "pod_ip": "10.0.1.9"},
+				{"namespace": "prod", "pod": "pending", "pod_ip": ""},
+			}, []string{"1", "1", "1"})))
+		case strings.Contains(query, "container_network_transmit_bytes_total"):
+			_, _ = w.Write([]byte(prometheusVectorResponse([]map[string]string{
+				{"namespace": "prod", "pod": "db-0"},
+				{"namespace": "prod", "pod": "api-0"},
+				{"namespace": "prod", "pod": "pending"},
+			}, []string{"1000", "300", "50"})))
+		case strings.Contains(query, "hubble_flows_processed_total"):
+			if hubble == "" {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			_, _ = w.Write([]byte(hubble))
+		default:
+			_, _ = w.Write([]byte(prometheusVectorResponse(nil, nil)))
+		}
+	}))
+	t.Cleanup(srv.Close)
+
+	client, err := NewPrometheusClient(srv.URL)
+	require.NoError(t, err)
+	return client
+}
+
+func TestGetPodEgressBytes_WithoutHubble(t *testing.T) {
+	client := newEgressTestServer(t, "")
+
+	data, err := client.GetPodEgressBytes(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, map[string]map[string]uint64{
+		"10.0.1.5": {UnknownDestination: 1000},
+		"10.0.1.9": {UnknownDestination: 300},
+	}, data)
+}
+
+func TestGetPodEgressBytes_HubbleSplit(t *testing.T) {
+	hubble := prometheusVectorResponse([]map[string]string{
+		{"source": "prod/db-0", "destination": "1.1.1.1"},
+		{"source": "prod/db-0", "destination": "prod/api-0"},
+	}, []string{"3", "1"})
+	client := newEgressTestServer(t, hubble)
+
+	data, err := client.GetPodEgressBytes(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, map[string]uint64{"1.1.1.1": 750, "10.0.1.9": 250}, data["10.0.1.5"])
+	assert.Equal(t, map[string]uint64{UnknownDestination: 300}, data["10.0.1.9"])
+}
+
+func TestGetPodEgressBytes_Error(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer srv.Close()
+
+	client, _ := NewPrometheusClient(srv.URL)
+	_, err := client.GetPodEgressBytes(context.Background())
+	assert.Error(t, err)
+}
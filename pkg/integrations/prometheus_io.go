@@ -0,0 +1,292 @@
+package integrations
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net"
+	"strings"
+	"time"
+)
+
+// DefaultIOWindow is the look-back window for average and p95 I/O rates
+const DefaultIOWindow = time.Hour
+
+// MinIOWindow is the shortest look-back window: the rate subqueries take one sample a
+// minute, so a shorter window holds none
+const MinIOWindow = time.Minute
+
+// PVCIOMetrics contains rate-based I/O statistics for a PVC over a window.
+// Averages and p95 values are per second.
+type PVCIOMetrics struct {
+	Node   string
+	Device string
+
+	ReadIOPS        float64
+	WriteIOPS       float64
+	ReadThroughput  float64
+	WriteThroughput float64
+
+	ReadIOPSP95        float64
+	WriteIOPSP95       float64
+	ReadThroughputP95  float64
+	WriteThroughputP95 float64
+
+	// Cumulative device counters, compared between collections to detect access
+	ReadBytesTotal  float64
+	WriteBytesTotal float64
+}
+
+// diskCounters names the four counters needed for IOPS and throughput from one exporter
+type diskCounters struct {
+	reads, writes, readBytes, writeBytes string
+	aggregate                            string
+}
+
+var (
+	nodeExporterCounters = diskCounters{
+		reads:      "node_disk_reads_completed_total",
+		writes:     "node_disk_writes_completed_total",
+		readBytes:  "node_disk_read_bytes_total",
+		writeBytes: "node_disk_written_bytes_total",
+	}
+	// cAdvisor reports per container; summing per device yields the device total. It only
+	// sees the devices behind container filesystems, so it fills in the few PVC devices
+	// node_exporter misses rather than replacing it.
+	cadvisorCounters = diskCounters{
+		reads:      "container_fs_reads_total",
+		writes:     "container_fs_writes_total",
+		readBytes:  "container_fs_reads_bytes_total",
+		writeBytes: "container_fs_writes_bytes_total",
+		aggregate:  "sum by(instance, node, device)",
+	}
+)
+
+// GetAllPVCIOMetrics resolves every PVC to the block device backing it (PVC -> PV -> CSI volume
+// handle -> node_disk_info serial) and returns average and p95 IOPS/throughput over the window.
+// All lookups are batch queries, so the number of requests does not grow with the PVC count.
+func (p *PrometheusClient) GetAllPVCIOMetrics(ctx context.Context, window time.Duration) (map[string]map[string]*PVCIOMetrics, error) {
+	if window <= 0 {
+		window = DefaultIOWindow
+	}
+	if window < MinIOWindow {
+		return nil, fmt.Errorf("I/O window must be at least %s, got %s", MinIOWindow, window)
+	}
+
+	// 1. PVC -> PV (kube-state-metrics)
+	claims, err := p.queryVector(ctx, `kube_persistentvolumeclaim_info`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch PVC info: %w", err)
+	}
+
+	// 2. PV -> volume handle (kube-state-metrics)
+	volumes, err := p.queryVector(ctx, `kube_persistentvolume_info`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch PV info: %w", err)
+	}
+	handles := make(map[string]string)
+	for _, v := range volumes {
+		if handle := volumeHandle(v.Labels); handle != "" {
+			handles[v.Labels["persistentvolume"]] = handle
+		}
+	}
+
+	// 3. Volume handle -> node device (node_exporter)
+	disks, err := p.queryVector(ctx, `node_disk_info`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch disk info: %w", err)
+	}
+	nodes := p.nodeNamesByAddress(ctx)
+	devices := make(map[string]string)
+	for _, d := range disks {
+		for _, id := range []string{d.Labels["serial"], d.Labels["wwn"], d.Labels["path"]} {
+			if key := normalizeVolumeID(id); key != "" {
+				devices[key] = deviceKey(d.Labels, nodes)
+			}
+		}
+	}
+
+	// 4. Device rates
+	rates := p.queryDiskRates(ctx, nodeExporterCounters, window, nodes)
+	if missingDeviceRates(devices, rates) {
+		// Fall back to cAdvisor for devices node_exporter does not cover
+		for key, r := range p.queryDiskRates(ctx, cadvisorCounters, window, nodes) {
+			if _, ok := rates[key]; !ok {
+				rates[key] = r
+			}
+		}
+	}
+
+	result := make(map[string]map[string]*PVCIOMetrics)
+	for _, c := range claims {
+		ns, pvc := c.Labels["namespace"], c.Labels["persistentvolumeclaim"]
+		handle := handles[c.Labels["volumename"]]
+		if ns == "" || pvc == "" || handle == "" {
+			continue
+		}
+		dev, ok := devices[normalizeVolumeID(handle)]
+		if !ok {
+			continue
+		}
+		r, ok := rates[dev]
+		if !ok {
+			continue
+		}
+		if result[ns] == nil {
+			result[ns] = make(map[string]*PVCIOMetrics)
+		}
+		result[ns][pvc] = r
+	}
+
+	return result, nil
+}
+
+// nodeNamesByAddress maps node IPs to node names from kube-state-metrics. node_exporter
+// series are usually labelled with the node IP and cAdvisor's with the node name, and
+// devices only join across the two once both are keyed by name. Without
+// kube_node_info, addresses are kept as they are.
+func (p *PrometheusClient) nodeNamesByAddress(ctx context.Context) map[string]string {
+	nodes := make(map[string]string)
+	infos, err := p.queryVector(ctx, `kube_node_info`)
+	if err != nil {
+		slog.Debug("node info query failed", "error", err)
+		return nodes
+	}
+	for _, info := range infos {
+		if ip, name := info.Labels["internal_ip"], info.Labels["node"]; ip != "" && name != "" {
+			nodes[ip] = name
+		}
+	}
+	return nodes
+}
+
+// queryDiskRates fetches average and p95 rates plus cumulative byte counters for all devices
+// of one exporter, keyed by deviceKey
+func (p *PrometheusClient) queryDiskRates(ctx context.Context, counters diskCounters, window time.Duration, nodes map[string]string) map[string]*PVCIOMetrics {
+	rates := make(map[string]*PVCIOMetrics)
+	subquery := fmt.Sprintf("[%dm:1m]", int(window.Minutes()))
+
+	series := []struct {
+		metric   string
+		avg, p95 func(m *PVCIOMetrics) *float64
+	}{
+		{counters.reads, func(m *PVCIOMetrics) *float64 { return &m.ReadIOPS }, func(m *PVCIOMetrics) *float64 { return &m.ReadIOPSP95 }},
+		{counters.writes, func(m *PVCIOMetrics) *float64 { return &m.WriteIOPS }, func(m *PVCIOMetrics) *float64 { return &m.WriteIOPSP95 }},
+		{counters.readBytes, func(m *PVCIOMetrics) *float64 { return &m.ReadThroughput }, func(m *PVCIOMetrics) *float64 { return &m.ReadThroughputP95 }},
+		{counters.writeBytes, func(m *PVCIOMetrics) *float64 { return &m.WriteThroughput }, func(m *PVCIOMetrics) *float64 { return &m.WriteThroughputP95 }},
+	}
+
+	for _, s := range series {
+		rate := fmt.Sprintf("rate(%s[5m])", s.metric)
+		if counters.aggregate != "" {
+			rate = fmt.Sprintf("%s (%s)", counters.aggregate, rate)
+		}
+
+		queries := []struct {
+			query string
+			field func(m *PVCIOMetrics) *float64
+		}{
+			{fmt.Sprintf("avg_over_time((%s)%s)", rate, subquery), s.avg},
+			{fmt.Sprintf("quantile_over_time(0.95, (%s)%s)", rate, subquery), s.p95},
+		}
+
+		for _, q := range queries {
+			p.recordDiskSeries(ctx, rates, q.query, q.field, nodes)
+		}
+	}
+
+	totals := []struct {
+		metric string
+		field  func(m *PVCIOMetrics) *float64
+	}{
+		{counters.readBytes, func(m *PVCIOMetrics) *float64 { return &m.ReadBytesTotal }},
+		{counters.writeBytes, func(m *PVCIOMetrics) *float64 { return &m.WriteBytesTotal }},
+	}
+	for _, t := range totals {
+		query := t.metric
+		if counters.aggregate != "" {
+			query = fmt.Sprintf("%s (%s)", counters.aggregate, t.metric)
+		}
+		p.recordDiskSeries(ctx, rates, query, t.field, nodes)
+	}
+
+	return rates
+}
+
+// recordDiskSeries runs one per-device query and stores each value in the given field
+func (p *PrometheusClient) recordDiskSeries(ctx context.Context, rates map[string]*PVCIOMetrics, query string, field func(m *PVCIOMetrics) *float64, nodes map[string]string) {
+	results, err := p.queryVector(ctx, query)
+	if err != nil {
+		slog.Debug("disk rate query failed", "query", query, "error", err)
+		return
+	}
+	for _, res := range results {
+		key := deviceKey(res.Labels, nodes)
+		if key == "" {
+			continue
+		}
+		m, ok := rates[key]
+		if !ok {
+			node, device, _ := strings.Cut(key, "/")
+			m = &PVCIOMetrics{Node: node, Device: device}
+			rates[key] = m
+		}
+		*field(m) = res.Value
+	}
+}
+
+// missingDeviceRates reports whether any resolved device has no rate series
+func missingDeviceRates(devices map[string]string, rates map[string]*PVCIOMetrics) bool {
+	for _, dev := range devices {
+		if _, ok := rates[dev]; !ok {
+			return true
+		}
+	}
+	return false
+}
+
+// volumeHandle picks the cloud volume identifier from kube_persistentvolume_info labels
+func volumeHandle(labels map[string]string) string {
+	for _, l := range []string{"csi_volume_handle", "ebs_volume_id", "gce_persistent_disk_name", "azure_disk_name"} {
+		if v := labels[l]; v != "" {
+			return v
+		}
+	}
+	return ""
+}
+
+// normalizeVolumeID reduces a volume handle or disk serial to a comparable form.
+// EBS handles ("vol-0abc") appear as NVMe serials ("vol0abc"); GCE handles are
+// resource paths whose last segment is the disk name used as the serial.
+func normalizeVolumeID(id string) string {
+	if i := strings.LastIndex(id, "/"); i >= 0 {
+		id = id[i+1:]
+	}
+	var b strings.Builder
+	for _, r := range strings.ToLower(id) {
+		if (r >= 'a' && r <= 'z') || (r >= '0' && r <= '9') {
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+// deviceKey identifies a block device as "<node>/<device>". The node is the series' node
+// label or else the host of its instance, resolved to a node name through nodes.
+func deviceKey(labels map[string]string, nodes map[string]string) string {
+	device := strings.TrimPrefix(labels["device"], "/dev/")
+	if device == "" {
+		return ""
+	}
+	node := labels["node"]
+	if node == "" {
+		node = labels["instance"]
+		if host, _, err := net.SplitHostPort(node); err == nil {
+			node = host
+		}
+	}
+	if name, ok := nodes[node]; ok {
+		node = name
+	}
+	return node + "/" + device
+}
@@ -0,0 +1,142 @@
+package integrations
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNormalizeVolumeID(t *testing.T) {
+	assert.Equal(t, "vol0abc123", normalizeVolumeID("vol-0abc123"))
+	assert.Equal(t, "vol0abc123", normalizeVolumeID("vol0abc123"))
+	assert.Equal(t, "pvc1234", normalizeVolumeID("projects/p/zones/us-central1-a/disks/pvc-1234"))
+	assert.Equal(t, "", normalizeVolumeID(""))
+}
+
+func TestDeviceKey(t *testing.T) {
+	nodes := map[string]string{"10.0.0.2": "node-b"}
+	assert.Equal(t, "10.0.0.1/nvme1n1", deviceKey(map[string]string{"instance": "10.0.0.1:9100", "device": "nvme1n1"}, nodes))
+	assert.Equal(t, "node-b/nvme1n1", deviceKey(map[string]string{"instance": "10.0.0.2:9100", "device": "nvme1n1"}, nodes))
+	assert.Equal(t, "node-a/nvme1n1", deviceKey(map[string]string{"node": "node-a", "instance": "x", "device": "/dev/nvme1n1"}, nodes))
+	assert.Equal(t, "", deviceKey(map[string]string{"instance": "10.0.0.1:9100"}, nodes))
+}
+
+func TestGetAllPVCIOMetrics(t *testing.T) {
+	disk := map[string]string{"instance": "10.0.0.1:9100", "device": "nvme1n1"}
+	responses := map[string]string{
+		"kube_persistentvolumeclaim_info": prometheusVectorResponse(
+			[]map[string]string{
+				{"namespace": "prod", "persistentvolumeclaim": "db-data", "volumename": "pv-1"},
+				{"namespace": "prod", "persistentvolumeclaim": "unbound", "volumename": ""},
+			}, []string{"1", "1"}),
+		"kube_persistentvolume_info": prometheusVectorResponse(
+			[]map[string]string{{"persistentvolume": "pv-1", "csi_volume_handle": "vol-0abc123"}}, []string{"1"}),
+		"node_disk_info": prometheusVectorResponse(
+			[]map[string]string{{"instance": "10.0.0.1:9100", "device": "nvme1n1", "serial": "vol0abc123"}}, []string{"1"}),
+		"avg_over_time((rate(node_disk_reads_completed_total":            prometheusVectorResponse([]map[string]string{disk}, []string{"120"}),
+		"quantile_over_time(0.95, (rate(node_disk_reads_completed_total": prometheusVectorResponse([]map[string]string{disk}, []string{"400"}),
+		"avg_over_time((rate(node_disk_writes_completed_total":           prometheusVectorResponse([]map[string]string{disk}, []string{"80"}),
+		"avg_over_time((rate(node_disk_read_bytes_total":                 prometheusVectorResponse([]map[string]string{disk}, []string{"1048576"}),
+		"avg_over_time((rate(node_disk_written_bytes_total":              prometheusVectorResponse([]map[string]string{disk}, []string{"524288"}),
+		"node_disk_read_bytes_total":                                     prometheusVectorResponse([]map[string]string{disk}, []string{"987654321"}),
+	}
+
+	var queries []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query().Get("query")
+		queries = append(queries, query)
+		w.Header().Set("Content-Type", "application/json")
+		for prefix, resp := range responses {
+			if strings.HasPrefix(query, prefix) {
+				_, _ = w.Write([]byte(resp))
+				return
+			}
+		}
+		_, _ = w.Write([]byte(prometheusVectorResponse(nil, nil)))
+	}))
+	defer srv.Close()
+
+	client, _ := NewPrometheusClient(srv.URL)
+	result, err := client.GetAllPVCIOMetrics(context.Background(), 30*time.Minute)
+	require.NoError(t, err)
+
+	io := result["prod"]["db-data"]
+	require.NotNil(t, io)
+	assert.Equal(t, "10.0.0.1", io.Node)
+	assert.Equal(t, "nvme1n1", io.Device)
+	assert.InDelta(t, 120, io.ReadIOPS, 0.001)
+	assert.InDelta(t, 400, io.ReadIOPSP95, 0.001)
+	assert.InDelta(t, 80, io.WriteIOPS, 0.001)
+	assert.InDelta(t, 1048576, io.ReadThroughput, 0.001)
+	assert.InDelta(t, 524288, io.WriteThroughput, 0.001)
+	assert.InDelta(t, 987654321, io.ReadBytesTotal, 0.001)
+	assert.Nil(t, result["prod"]["unbound"])
+
+	// node_exporter covered the device, so cAdvisor must not be queried
+	for _, q := range queries {
+		assert.NotContains(t, q, "container_fs_")
+	}
+	assert.Contains(t, strings.Join(queries, "\n"), "[30m:1m]")
+}
+
+// node_exporter is scraped by node IP and cAdvisor through the kubelet by node name; the
+// device only joins once kube_node_info resolves the IP
+func TestGetAllPVCIOMetrics_CadvisorFallback(t *testing.T) {
+	dev := map[string]string{"instance": "node-a", "node": "node-a", "device": "/dev/nvme1n1"}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query().Get("query")
+		w.Header().Set("Content-Type", "application/json")
+		switch {
+		case query == "kube_persistentvolumeclaim_info":
+			_, _ = w.Write([]byte(prometheusVectorResponse([]map[string]string{{"namespace": "prod", "persistentvolumeclaim": "db-data", "volumename": "pv-1"}}, []string{"1"})))
+		case query == "kube_persistentvolume_info":
+			_, _ = w.Write([]byte(prometheusVectorResponse([]map[string]string{{"persistentvolume": "pv-1", "ebs_volume_id": "vol-0abc123"}}, []string{"1"})))
+		case query == "node_disk_info":
+			_, _ = w.Write([]byte(prometheusVectorResponse([]map[string]string{{"instance": "10.0.0.1:9100", "device": "nvme1n1", "serial": "vol0abc123"}}, []string{"1"})))
+		case query == "kube_node_info":
+			_, _ = w.Write([]byte(prometheusVectorResponse([]map[string]string{{"node": "node-a", "internal_ip": "10.0.0.1"}}, []string{"1"})))
+		case strings.Contains(query, "container_fs_writes_total"):
+			_, _ = w.Write([]byte(prometheusVectorResponse([]map[string]string{dev}, []string{"55"})))
+		default:
+			_, _ = w.Write([]byte(prometheusVectorResponse(nil, nil)))
+		}
+	}))
+	defer srv.Close()
+
+	client, _ := NewPrometheusClient(srv.URL)
+	result, err := client.GetAllPVCIOMetrics(context.Background(), 0)
+	require.NoError(t, err)
+	require.NotNil(t, result["prod"]["db-data"])
+	assert.Equal(t, "node-a", result["prod"]["db-data"].Node)
+	assert.InDelta(t, 55, result["prod"]["db-data"].WriteIOPS, 0.001)
+}
+
+func TestGetAllPVCIOMetrics_Error(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer srv.Close()
+
+	client, _ := NewPrometheusClient(srv.URL)
+	_, err := client.GetAllPVCIOMetrics(context.Background(), time.Hour)
+	assert.Error(t, err)
+}
+
+func TestGetAllPVCIOMetrics_WindowTooShort(t *testing.T) {
+	queried := false
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		queried = true
+	}))
+	defer srv.Close()
+
+	client, _ := NewPrometheusClient(srv.URL)
+	_, err := client.GetAllPVCIOMetrics(context.Background(), 30*time.Second)
+	assert.Error(t, err, "a [0m:1m] subquery is invalid PromQL")
+	assert.False(t, queried)
+}
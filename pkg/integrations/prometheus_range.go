@@ -0,0 +1,73 @@
+package integrations
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+// Sample is a single point of a range query series
+type Sample struct {
+	Time  time.Time
+	Value float64
+}
+
+// RangeResult is one series of a matrix result
+type RangeResult struct {
+	Labels  map[string]string
+	Samples []Sample
+}
+
+// QueryRange evaluates a PromQL expression over [start, end] at the given step and returns
+// the resulting matrix. It is used to backfill history that predates the agent.
+func (p *PrometheusClient) QueryRange(ctx context.Context, query string, start, end time.Time, step time.Duration) ([]RangeResult, error) {
+	if !end.After(start) {
+		return nil, fmt.Errorf("range end must be after start")
+	}
+	if step <= 0 {
+		return nil, fmt.Errorf("range step must be positive")
+	}
+
+	params := url.Values{}
+	params.Set("start", formatTime(start))
+	params.Set("end", formatTime(end))
+	params.Set("step", strconv.FormatFloat(step.Seconds(), 'f', -1, 64))
+
+	result, err := p.get(ctx, "/api/v1/query_range", query, params)
+	if err != nil {
+		return nil, err
+	}
+	if result.Data.ResultType != "matrix" {
+		return nil, fmt.Errorf("unexpected result type %q for range query", result.Data.ResultType)
+	}
+
+	var matrix []struct {
+		Metric map[string]string `json:"metric"`
+		Values [][]interface{}   `json:"values"`
+	}
+	if err := json.Unmarshal(result.Data.Result, &matrix); err != nil {
+		return nil, err
+	}
+
+	series := make([]RangeResult, 0, len(matrix))
+	for _, m := range matrix {
+		rr := RangeResult{Labels: m.Metric, Samples: make([]Sample, 0, len(m.Values))}
+		for _, pair := range m.Values {
+			ts, val, ok := parseSample(pair)
+			if !ok {
+				continue
+			}
+			rr.Samples = append(rr.Samples, Sample{Time: ts, Value: val})
+		}
+		series = append(series, rr)
+	}
+	return series, nil
+}
+
+// formatTime renders a timestamp the way the Prometheus API expects (unix seconds)
+func formatTime(t time.Time) string {
+	return strconv.FormatFloat(float64(t.UnixNano())/1e9, 'f', 3, 64)
+}
@@ -206,11 +206,6 @@ func TestGetPVCMetrics_Success(t *testing.T) {
 	if metrics.UsedBytes != expectedBytes {
 		t.Errorf("Expected UsedBytes %d, got %d", expectedBytes, metrics.UsedBytes)
 	}
-
-	// Should have set LastActivity since metric exists
-	if metrics.LastActivity.IsZero() {
-		t.Error("Expected LastActivity to be set")
-	}
 }
 
 func TestGetPVCMetrics_NoData(t *testing.T) {
@@ -247,11 +242,6 @@ func TestGetPVCMetrics_NoData(t *testing.T) {
 	if metrics.UsedBytes != 0 {
 		t.Errorf("Expected UsedBytes 0, got %d", metrics.UsedBytes)
 	}
-
-	// LastActivity should be zero since no data
-	if !metrics.LastActivity.IsZero() {
-		t.Error("Expected LastActivity to be zero")
-	}
 }
 
 func TestGetAllPVCMetrics_Success(t *testing.T) {
@@ -304,7 +294,7 @@ func TestGetAllPVCMetrics_PrometheusError(t *testing.T) {
 	assert.Error(t, err)
 }
 
-func TestGetPVCMetrics_WithActivity(t *testing.T) {
+func TestGetPVCMetrics_UsedBytes(t *testing.T) {
 	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		w.Header().Set("Content-Type", "application/json")
 		_, _ = w.Write([]byte(prometheusScalarResponse("536870912")))
@@ -315,7 +305,6 @@ func TestGetPVCMetrics_WithActivity(t *testing.T) {
 	m, err := client.GetPVCMetrics(context.Background(), "pvc-1", "default")
 	require.NoError(t, err)
 	assert.Equal(t, int64(536870912), m.UsedBytes)
-	assert.False(t, m.LastActivity.IsZero())
 }
 
 func TestQueryScalar_InvalidJSON(t *testing.T) {
@@ -338,3 +327,74 @@ func TestQueryScalar_InvalidJSON(t *testing.T) {
 		t.Error("Expected error for invalid JSON, got nil")
 	}
 }
+
+func TestQueryRange_Matrix(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/api/v1/query_range", r.URL.Path)
+		assert.Equal(t, "1700000000.000", r.URL.Query().Get("start"))
+		assert.Equal(t, "1700003600.000", r.URL.Query().Get("end"))
+		assert.Equal(t, "300", r.URL.Query().Get("step"))
+		_, _ = w.Write([]byte(`{"status":"success","data":{"resultType":"matrix","result":[
+			{"metric":{"persistentvolumeclaim":"db-data"},"values":[[1700000000,"10"],[1700000300.5,"20"]]}
+		]}}`))
+	}))
+	defer srv.Close()
+
+	client, _ := NewPrometheusClient(srv.URL)
+	start := time.Unix(1700000000, 0)
+	series, err := client.QueryRange(context.Background(), `kubelet_volume_stats_used_bytes`, start, start.Add(time.Hour), 5*time.Minute)
+	require.NoError(t, err)
+	require.Len(t, series, 1)
+	assert.Equal(t, "db-data", series[0].Labels["persistentvolumeclaim"])
+	require.Len(t, series[0].Samples, 2)
+	assert.Equal(t, start, series[0].Samples[0].Time)
+	assert.InDelta(t, 20, series[0].Samples[1].Value, 0.001)
+	assert.Equal(t, int64(1700000300500), series[0].Samples[1].Time.UnixMilli())
+}
+
+func TestQueryRange_InvalidArguments(t *testing.T) {
+	client, _ := NewPrometheusClient("http://localhost:9090")
+	now := time.Now()
+	_, err := client.QueryRange(context.Background(), `up`, now, now.Add(-time.Hour), time.Minute)
+	assert.Error(t, err)
+	_, err = client.QueryRange(context.Background(), `up`, now, now.Add(time.Hour), 0)
+	assert.Error(t, err)
+}
+
+func TestQueryVector_Warnings(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"status":"success","warnings":["store 10.0.0.3:10901 unavailable"],"data":{"resultType":"vector","result":[]}}`))
+	}))
+	defer srv.Close()
+
+	client, _ := NewPrometheusClient(srv.URL)
+	var got []string
+	client.SetWarningHandler(func(query string, warnings []string) { got = warnings })
+
+	_, err := client.queryVector(context.Background(), `up`)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"store 10.0.0.3:10901 unavailable"}, got)
+}
+
+func TestQueryVector_ResponseTooLarge(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(prometheusVectorResponse([]map[string]string{{"pod": "a"}, {"pod": "b"}}, []string{"1", "2"})))
+	}))
+	defer srv.Close()
+
+	client, _ := NewPrometheusClientWithOptions(srv.URL, PrometheusOptions{MaxResponseBytes: 64})
+	_, err := client.queryVector(context.Background(), `up`)
+	assert.ErrorContains(t, err, "exceeds 64 bytes")
+}
+
+func TestQueryVector_ErrorBody(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusUnprocessableEntity)
+		_, _ = w.Write([]byte(`{"status":"error","errorType":"execution","error":"query timed out"}`))
+	}))
+	defer srv.Close()
+
+	client, _ := NewPrometheusClient(srv.URL)
+	_, err := client.queryVector(context.Background(), `up`)
+	assert.ErrorContains(t, err, "query timed out")
+}
@@ -0,0 +1,118 @@
+package integrations
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/cloudvault-io/cloudvault/pkg/types"
+)
+
+// DefaultMaxResponseBytes caps how much of a Prometheus response body is read
+const DefaultMaxResponseBytes = 32 << 20
+
+// PrometheusOptions configures authentication, TLS and limits for a PrometheusClient
+type PrometheusOptions struct {
+	// Basic auth
+	Username string
+	Password string
+
+	// BearerTokenFile is re-read on every request so rotated (projected) tokens are picked up
+	BearerTokenFile string
+
+	// TLS: custom CA and client certificate for mTLS
+	CAFile             string
+	CertFile           string
+	KeyFile            string
+	InsecureSkipVerify bool
+
+	// Headers are added to every request, e.g. X-Scope-OrgID for Mimir/Cortex tenants
+	Headers map[string]string
+
+	// MaxResponseBytes rejects larger responses (DefaultMaxResponseBytes when zero)
+	MaxResponseBytes int64
+
+	Timeout time.Duration
+}
+
+// PrometheusOptionsFromConfig reads the Prometheus connection settings from the config
+func PrometheusOptionsFromConfig(cfg *types.Config) PrometheusOptions {
+	return PrometheusOptions{
+		Username:           cfg.PrometheusUsername,
+		Password:           cfg.PrometheusPassword,
+		BearerTokenFile:    cfg.PrometheusBearerTokenFile,
+		CAFile:             cfg.PrometheusCAFile,
+		CertFile:           cfg.PrometheusCertFile,
+		KeyFile:            cfg.PrometheusKeyFile,
+		InsecureSkipVerify: cfg.PrometheusInsecureSkipVerify,
+		Headers:            cfg.PrometheusHeaders,
+		MaxResponseBytes:   cfg.PrometheusMaxResponseBytes,
+	}
+}
+
+// newTransport builds the round tripper chain for the given options
+func (o PrometheusOptions) newTransport() (http.RoundTripper, error) {
+	base := http.DefaultTransport.(*http.Transport).Clone()
+
+	if o.CAFile != "" || o.CertFile != "" || o.InsecureSkipVerify {
+		tlsConfig := &tls.Config{
+			MinVersion:         tls.VersionTLS12,
+			InsecureSkipVerify: o.InsecureSkipVerify, // #nosec G402 -- explicit opt-in
+		}
+		if o.CAFile != "" {
+			ca, err := os.ReadFile(o.CAFile)
+			if err != nil {
+				return nil, fmt.Errorf("failed to read prometheus CA file: %w", err)
+			}
+			pool := x509.NewCertPool()
+			if !pool.AppendCertsFromPEM(ca) {
+				return nil, fmt.Errorf("no certificates found in prometheus CA file %s", o.CAFile)
+			}
+			tlsConfig.RootCAs = pool
+		}
+		if o.CertFile != "" || o.KeyFile != "" {
+			cert, err := tls.LoadX509KeyPair(o.CertFile, o.KeyFile)
+			if err != nil {
+				return nil, fmt.Errorf("failed to load prometheus client certificate: %w", err)
+			}
+			tlsConfig.Certificates = []tls.Certificate{cert}
+		}
+		base.TLSClientConfig = tlsConfig
+	}
+
+	if o.Username != "" && o.BearerTokenFile != "" {
+		return nil, fmt.Errorf("prometheus basic auth and bearer token are mutually exclusive")
+	}
+
+	return &authRoundTripper{opts: o, next: base}, nil
+}
+
+// authRoundTripper adds credentials and custom headers to outgoing requests
+type authRoundTripper struct {
+	opts PrometheusOptions
+	next http.RoundTripper
+}
+
+func (rt *authRoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	req = req.Clone(req.Context())
+	for k, v := range rt.opts.Headers {
+		req.Header.Set(k, v)
+	}
+
+	switch {
+	case rt.opts.BearerTokenFile != "":
+		token, err := os.ReadFile(rt.opts.BearerTokenFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read prometheus bearer token: %w", err)
+		}
+		req.Header.Set("Authorization", "Bearer "+strings.TrimSpace(string(token)))
+	case rt.opts.Username != "":
+		req.SetBasicAuth(rt.opts.Username, rt.opts.Password)
+	}
+
+	return rt.next.RoundTrip(req)
+}
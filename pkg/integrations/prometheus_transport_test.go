@@ -0,0 +1,92 @@
+package integrations
+
+import (
+	"context"
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/cloudvault-io/cloudvault/pkg/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPrometheusOptions_BasicAuthAndHeaders(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		user, pass, ok := r.BasicAuth()
+		assert.True(t, ok)
+		assert.Equal(t, "cloudvault", user)
+		assert.Equal(t, "s3cret", pass)
+		assert.Equal(t, "team-a", r.Header.Get("X-Scope-OrgID"))
+		_, _ = w.Write([]byte(prometheusScalarResponse("1")))
+	}))
+	defer srv.Close()
+
+	cfg := types.DefaultConfig()
+	cfg.PrometheusUsername = "cloudvault"
+	cfg.PrometheusPassword = "s3cret"
+	cfg.PrometheusHeaders = map[string]string{"X-Scope-OrgID": "team-a"}
+
+	client, err := NewPrometheusClientWithOptions(srv.URL, PrometheusOptionsFromConfig(cfg))
+	require.NoError(t, err)
+	_, err = client.queryScalar(context.Background(), `up`)
+	require.NoError(t, err)
+}
+
+func TestPrometheusOptions_BearerTokenFileIsReread(t *testing.T) {
+	tokenFile := filepath.Join(t.TempDir(), "token")
+	require.NoError(t, os.WriteFile(tokenFile, []byte("first\n"), 0600))
+
+	var seen []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		seen = append(seen, r.Header.Get("Authorization"))
+		_, _ = w.Write([]byte(prometheusScalarResponse("1")))
+	}))
+	defer srv.Close()
+
+	client, err := NewPrometheusClientWithOptions(srv.URL, PrometheusOptions{BearerTokenFile: tokenFile})
+	require.NoError(t, err)
+
+	_, _ = client.queryScalar(context.Background(), `up`)
+	require.NoError(t, os.WriteFile(tokenFile, []byte("second"), 0600))
+	_, _ = client.queryScalar(context.Background(), `up`)
+
+	assert.Equal(t, []string{"Bearer first", "Bearer second"}, seen)
+}
+
+func TestPrometheusOptions_CustomCA(t *testing.T) {
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(prometheusScalarResponse("1")))
+	}))
+	defer srv.Close()
+
+	// Without the CA the server certificate is rejected
+	untrusted, err := NewPrometheusClient(srv.URL)
+	require.NoError(t, err)
+	_, err = untrusted.queryScalar(context.Background(), `up`)
+	assert.Error(t, err)
+
+	caFile := filepath.Join(t.TempDir(), "ca.pem")
+	caPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: srv.Certificate().Raw})
+	require.NoError(t, os.WriteFile(caFile, caPEM, 0600))
+
+	trusted, err := NewPrometheusClientWithOptions(srv.URL, PrometheusOptions{CAFile: caFile})
+	require.NoError(t, err)
+	_, err = trusted.queryScalar(context.Background(), `up`)
+	assert.NoError(t, err)
+}
+
+func TestPrometheusOptions_InvalidSettings(t *testing.T) {
+	dir := t.TempDir()
+	_, err := NewPrometheusClientWithOptions("http://localhost:9090", PrometheusOptions{CAFile: filepath.Join(dir, "missing.pem")})
+	assert.Error(t, err)
+
+	_, err = NewPrometheusClientWithOptions("http://localhost:9090", PrometheusOptions{CertFile: filepath.Join(dir, "c.pem"), KeyFile: filepath.Join(dir, "k.pem")})
+	assert.Error(t, err)
+
+	_, err = NewPrometheusClientWithOptions("http://localhost:9090", PrometheusOptions{Username: "u", BearerTokenFile: "/var/run/token"})
+	assert.Error(t, err)
+}
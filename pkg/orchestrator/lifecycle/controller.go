@@ -2,12 +2,14 @@ package lifecycle
 
 import (
 	"context"
+	"fmt"
 	"log/slog"
 	"time"
 
 	"github.com/cloudvault-io/cloudvault/pkg/collector"
 	"github.com/cloudvault-io/cloudvault/pkg/cost"
 	"github.com/cloudvault-io/cloudvault/pkg/graph"
+	"github.com/cloudvault-io/cloudvault/pkg/integrations"
 	"github.com/cloudvault-io/cloudvault/pkg/types"
 	"github.com/cloudvault-io/cloudvault/pkg/types/apis/v1alpha1"
 )
@@ -18,11 +20,13 @@ type LifecycleController struct {
 	pvcCollector *collector.PVCCollector
 	manager      *MigrationManager
 	recommender  *IntelligentRecommender
-	sig          *graph.SIG
-	timescale    *graph.TimescaleDB
+	sig          graph.Graph
+	history      graph.HistoryStore
 	optimizer    *cost.Optimizer
 	interval     time.Duration
 	policies     []v1alpha1.StorageLifecyclePolicy
+
+	gcGenerations int // Sync passes a PVC or pod may go unseen before it leaves the SIG
 }
 
 // NewLifecycleController creates a new autonomous controller with full SIG integration.
@@ -30,8 +34,8 @@ func NewLifecycleController(
 	interval time.Duration,
 	client *collector.KubernetesClient,
 	recommender *IntelligentRecommender,
-	sig *graph.SIG,
-	timescale *graph.TimescaleDB,
+	sig graph.Graph,
+	history graph.HistoryStore,
 ) *LifecycleController {
 	mgr := NewMigrationManager(client)
 	return &LifecycleController{
@@ -39,9 +43,11 @@ func NewLifecycleController(
 		manager:     mgr,
 		recommender: recommender,
 		sig:         sig,
-		timescale:   timescale,
+		history:     history,
 		optimizer:   cost.NewOptimizer(),
 		interval:    interval,
+
+		gcGenerations: graph.DefaultGCGenerations,
 	}
 }
 
@@ -51,6 +57,54 @@ func (c *LifecycleController) SetPolicies(policies []v1alpha1.StorageLifecyclePo
 	slog.Info("Updated lifecycle policies", "count", len(policies))
 }
 
+// SetGCGenerations sets how many sync passes a PVC or pod may go unseen before it is
+// removed from the SIG
+func (c *LifecycleController) SetGCGenerations(n int) {
+	if n > 0 {
+		c.gcGenerations = n
+	}
+}
+
+// WatchDeletions removes pods and PVCs from the SIG as soon as they are deleted from the
+// cluster, instead of when a sweep finds them unseen. It must be called before the
+// client's informers are started.
+func (c *LifecycleController) WatchDeletions() error {
+	if c.client == nil || c.sig == nil {
+		return nil
+	}
+	return c.client.OnDelete(
+		func(namespace, name string) { c.removeDeleted("pod", namespace, name, c.sig.DeletePod) },
+		func(namespace, name string) { c.removeDeleted("pvc", namespace, name, c.sig.DeletePVC) },
+	)
+}
+
+// removeDeleted removes an object deleted from the cluster from the SIG
+func (c *LifecycleController) removeDeleted(kind, namespace, name string, remove func(ctx context.Context, namespace, name string) error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	if err := remove(ctx, namespace, name); err != nil {
+		slog.Error("Failed to remove deleted object from SIG", "kind", kind, "namespace", namespace, "name", name, "error", err)
+		return
+	}
+	integrations.SIGRemovals.WithLabelValues(kind, "deleted").Inc()
+}
+
+// sweepGraph removes PVCs, pods and relationships the last syncs no longer reported
+func (c *LifecycleController) sweepGraph(ctx context.Context) {
+	result, err := c.sig.Sweep(ctx, c.gcGenerations)
+	if err != nil {
+		slog.Error("Failed to sweep stale SIG objects", "error", err)
+		return
+	}
+	integrations.SIGRemovals.WithLabelValues("pvc", "sweep").Add(float64(result.PVCs))
+	integrations.SIGRemovals.WithLabelValues("pod", "sweep").Add(float64(result.Pods))
+	integrations.SIGRemovals.WithLabelValues("relationship", "sweep").Add(float64(result.Relationships))
+	if result != (graph.GCResult{}) {
+		slog.Info("Removed stale objects from SIG",
+			"pvcs", result.PVCs, "pods", result.Pods, "relationships", result.Relationships)
+	}
+}
+
 // SetPVCCollector sets the PVC collector for metrics gathering
 func (c *LifecycleController) SetPVCCollector(collector *collector.PVCCollector) {
 	c.pvcCollector = collector
@@ -98,8 +152,10 @@ func (c *LifecycleController) processOptimization(ctx context.Context) error {
 
 	// 2. Sync metrics to Storage Intelligence Graph (SIG)
 	if c.sig != nil {
+		synced := true
 		if err := c.sig.SyncPVCs(ctx, metrics); err != nil {
 			slog.Error("Failed to sync PVCs to SIG", "error", err)
+			synced = false
 		} else {
 			slog.Info("Synced PVCs to Storage Intelligence Graph")
 		}
@@ -107,23 +163,36 @@ func (c *LifecycleController) processOptimization(ctx context.Context) error {
 		// 3. Map Pod-to-PVC relationships for data gravity analysis
 		if err := c.syncPodRelationships(ctx, metrics); err != nil {
 			slog.Error("Failed to sync pod relationships", "error", err)
+			synced = false
+		}
+
+		// A failed sync would make everything it missed look stale
+		if synced {
+			c.sweepGraph(ctx)
 		}
 
-		// 4. Detect cross-region gravity issues
-		crossRegionPVCs, err := c.sig.GetCrossRegionGravity(ctx)
+		// 4. Detect pods using storage in another region or zone
+		mismatches, err := c.sig.GetGravityMismatches(ctx)
 		if err != nil {
-			slog.Error("Failed to detect cross-region gravity", "error", err)
-		} else if len(crossRegionPVCs) > 0 {
-			slog.Warn("Detected cross-region data gravity issues", "count", len(crossRegionPVCs), "pvcs", crossRegionPVCs)
+			slog.Error("Failed to detect data gravity issues", "error", err)
+		} else if len(mismatches) > 0 {
+			var impact float64
+			pvcs := make([]string, 0, len(mismatches))
+			for _, m := range mismatches {
+				impact += m.MonthlyCostImpact
+				pvcs = append(pvcs, fmt.Sprintf("%s/%s (%s, %s)", m.Namespace, m.PVC, m.Pod, m.Level))
+			}
+			slog.Warn("Detected data gravity issues", "count", len(mismatches),
+				"monthly_cost_impact", impact, "pvcs", pvcs)
 		}
 	}
 
-	// 5. Record metrics to TimescaleDB for historical analysis
-	if c.timescale != nil {
-		if err := c.timescale.RecordMetrics(ctx, metrics); err != nil {
-			slog.Error("Failed to record metrics to TimescaleDB", "error", err)
+	// 5. Record metrics to the history store for historical analysis
+	if c.history != nil {
+		if err := c.history.RecordMetrics(ctx, metrics); err != nil {
+			slog.Error("Failed to record metrics history", "error", err)
 		} else {
-			slog.Debug("Recorded metrics to TimescaleDB for AI training")
+			slog.Debug("Recorded metrics history for AI training")
 		}
 	}
 
@@ -179,11 +248,24 @@ func (c *LifecycleController) processOptimization(ctx context.Context) error {
 	return nil
 }
 
-// syncPodRelationships creates Pod-to-PVC relationships in the SIG for data gravity analysis
+// syncPodRelationships creates Pod-to-PVC relationships in the SIG for data gravity analysis.
+// With a cluster client pods are synced with their placement and owner; otherwise
+// only the mounts recorded in metrics are known.
 func (c *LifecycleController) syncPodRelationships(ctx context.Context, metrics []types.PVCMetric) error {
 	if c.sig == nil {
 		return nil
 	}
+	if c.client != nil {
+		placements, err := c.client.ListPodPlacements(ctx)
+		if err != nil {
+			return err
+		}
+		if err := c.sig.SyncPods(ctx, placements); err != nil {
+			return err
+		}
+		slog.Info("Synced pod placements to SIG", "count", len(placements))
+		return nil
+	}
 
 	relationshipCount := 0
 	for _, metric := range metrics {
@@ -205,10 +287,12 @@ func (c *LifecycleController) syncPodRelationships(ctx context.Context, metrics
 
 // GetStatus returns the current status of the lifecycle controller
 func (c *LifecycleController) GetStatus() LifecycleStatus {
+	_, timescale := c.history.(*graph.TimescaleDB)
 	return LifecycleStatus{
 		ActivePolicies:   len(c.policies),
 		SIGEnabled:       c.sig != nil,
-		TimescaleEnabled: c.timescale != nil,
+		TimescaleEnabled: timescale,
+		HistoryEnabled:   c.history != nil,
 	}
 }
 
@@ -222,4 +306,5 @@ type LifecycleStatus struct {
 	ActivePolicies   int
 	SIGEnabled       bool
 	TimescaleEnabled bool
+	HistoryEnabled   bool // TimescaleDB or the embedded history store
 }
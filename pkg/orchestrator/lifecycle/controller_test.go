@@ -5,8 +5,11 @@ import (
 	"testing"
 	"time"
 
+	"github.com/cloudvault-io/cloudvault/pkg/graph"
+	"github.com/cloudvault-io/cloudvault/pkg/integrations"
 	"github.com/cloudvault-io/cloudvault/pkg/types"
 	"github.com/cloudvault-io/cloudvault/pkg/types/apis/v1alpha1"
+	"github.com/prometheus/client_golang/prometheus/testutil"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -135,3 +138,50 @@ func TestLifecycleController_Start_CancelImmediate(t *testing.T) {
 	err := lc.Start(ctx)
 	assert.ErrorIs(t, err, context.Canceled)
 }
+
+func TestLifecycleController_SyncPodRelationships_MountedPods(t *testing.T) {
+	sig := graph.NewMemoryGraph()
+	ctx := context.Background()
+	metrics := []types.PVCMetric{
+		{Name: "pvc-1", Namespace: "default", Region: "us-east-1", Zone: "us-east-1a", MountedPods: []string{"pod-1"}},
+	}
+	require.NoError(t, sig.SyncPVCs(ctx, metrics))
+	require.NoError(t, sig.SyncPods(ctx, []types.PodPlacement{{Name: "pod-1", Namespace: "default", Zone: "us-east-1b"}}))
+
+	// Without a cluster client the mounts recorded in the metrics are used
+	lc := NewLifecycleController(0, nil, nil, sig, nil)
+	require.NoError(t, lc.syncPodRelationships(ctx, metrics))
+	mismatches, err := sig.GetGravityMismatches(ctx)
+	require.NoError(t, err)
+	require.Len(t, mismatches, 1)
+	assert.Equal(t, graph.GravityZone, mismatches[0].Level)
+}
+
+func TestLifecycleController_GarbageCollection(t *testing.T) {
+	sig := graph.NewMemoryGraph()
+	ctx := context.Background()
+	require.NoError(t, sig.SyncPVCs(ctx, []types.PVCMetric{
+		{Name: "gone", Namespace: "default", StorageClass: "gp3"},
+		{Name: "deleted", Namespace: "default", StorageClass: "gp3"},
+	}))
+	lc := NewLifecycleController(0, nil, nil, sig, nil)
+	lc.SetGCGenerations(1)
+	swept := integrations.SIGRemovals.WithLabelValues("pvc", "sweep")
+	deleted := integrations.SIGRemovals.WithLabelValues("pvc", "deleted")
+	sweptBefore, deletedBefore := testutil.ToFloat64(swept), testutil.ToFloat64(deleted)
+
+	lc.removeDeleted("pvc", "default", "deleted", sig.DeletePVC)
+	assert.Equal(t, deletedBefore+1, testutil.ToFloat64(deleted))
+
+	lc.sweepGraph(ctx)
+	stats, err := sig.GetStorageClassUtilization(ctx)
+	require.NoError(t, err)
+	require.Len(t, stats, 1, "objects synced in the current generation are kept")
+	assert.Equal(t, int64(1), stats[0].PVCCount)
+
+	lc.sweepGraph(ctx)
+	stats, err = sig.GetStorageClassUtilization(ctx)
+	require.NoError(t, err)
+	assert.Empty(t, stats)
+	assert.Equal(t, sweptBefore+1, testutil.ToFloat64(swept))
+}
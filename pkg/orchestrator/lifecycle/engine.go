@@ -63,7 +63,9 @@ func (e *PolicyEngine) Evaluate(pvc types.PVCMetric, policy *v1alpha1.StorageLif
 		return nil, nil
 	}
 
-	pvcAge := time.Since(pvc.CreatedAt)
+	// Tiers advance with idle time: a PVC that is old but still read from stays hot.
+	// Without access data this is the PVC's age.
+	idle := pvc.IdleDuration()
 
 	// Identify current tier index based on storage class
 	var currentTierIndex = -1
@@ -74,7 +76,7 @@ func (e *PolicyEngine) Evaluate(pvc types.PVCMetric, policy *v1alpha1.StorageLif
 		}
 	}
 
-	// Find the most advanced tier the PVC is eligible for based on age
+	// Find the most advanced tier the PVC is eligible for based on idle time
 	var targetTierIndex = -1
 	for i := len(policy.Spec.Tiers) - 1; i >= 0; i-- {
 		tier := policy.Spec.Tiers[i]
@@ -84,7 +86,7 @@ func (e *PolicyEngine) Evaluate(pvc types.PVCMetric, policy *v1alpha1.StorageLif
 			return nil, fmt.Errorf("invalid duration %s in policy %s: %w", tier.Duration, policy.Name, err)
 		}
 
-		if pvcAge > duration {
+		if idle > duration {
 			targetTierIndex = i
 			break
 		}
@@ -94,10 +94,11 @@ func TestPolicyEngine_Evaluate(t *testing.T) {
 
 	now := time.Now()
 	tests := []struct {
-		name         string
-		storageClass string
-		createdAt    time.Time
-		expectedTier string
+		name           string
+		storageClass   string
+		createdAt      time.Time
+		lastAccessedAt time.Time
+		expectedTier   string
 	}{
 		{
 			name:         "stay-hot-young-pvc",
@@ -129,13 +130,28 @@ func TestPolicyEngine_Evaluate(t *testing.T) {
 			createdAt:    now.Add(-40 * 24 * time.Hour),
 			expectedTier: "cold",
 		},
+		{
+			name:           "old-but-recently-accessed-stays-hot",
+			storageClass:   "gp3",
+			createdAt:      now.Add(-90 * 24 * time.Hour),
+			lastAccessedAt: now.Add(-2 * 24 * time.Hour),
+			expectedTier:   "",
+		},
+		{
+			name:           "idle-since-last-access",
+			storageClass:   "gp3",
+			createdAt:      now.Add(-90 * 24 * time.Hour),
+			lastAccessedAt: now.Add(-10 * 24 * time.Hour),
+			expectedTier:   "warm",
+		},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			pvc := types.PVCMetric{
-				StorageClass: tt.storageClass,
-				CreatedAt:    tt.createdAt,
+				StorageClass:   tt.storageClass,
+				CreatedAt:      tt.createdAt,
+				LastAccessedAt: tt.lastAccessedAt,
 			}
 			tier, err := engine.Evaluate(pvc, &policy)
 			if err != nil {
@@ -5,6 +5,7 @@ import (
 	"testing"
 	"time"
 
+	"github.com/cloudvault-io/cloudvault/pkg/graph"
 	"github.com/cloudvault-io/cloudvault/pkg/types"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -81,6 +82,37 @@ func TestCalculateImpact_Empty(t *testing.T) {
 	assert.Empty(t, impact.AffectedWorkloads)
 }
 
+func TestCalculateImpact_Graph(t *testing.T) {
+	ctx := context.Background()
+	sig := graph.NewMemoryGraph()
+	require.NoError(t, sig.SyncPVCs(ctx, []types.PVCMetric{
+		{Name: "data", Namespace: "default"},
+		{Name: "logs", Namespace: "default"},
+	}))
+	api := types.Workload{Kind: "Deployment", Name: "api"}
+	require.NoError(t, sig.SyncPods(ctx, []types.PodPlacement{
+		{Name: "api-1", Namespace: "default", Workload: api, PVCs: []string{"data", "logs"}},
+		{Name: "api-2", Namespace: "default", Workload: api, PVCs: []string{"data"}},
+		{Name: "client", Namespace: "default"},
+	}))
+	require.NoError(t, sig.RecordCommunications(ctx, []types.Communication{{
+		Source:   types.Endpoint{Namespace: "default", Pod: "client"},
+		Target:   types.Endpoint{Namespace: "default", Pod: "api-2"},
+		Bytes:    1 << 20,
+		Interval: time.Minute,
+		SeenAt:   time.Now(),
+	}}, 0.5, nil))
+
+	exec := &MigrationExecutor{}
+	exec.SetGraph(sig)
+	impact := exec.calculateImpact(ctx, []types.PVCMetric{
+		{Name: "data", Namespace: "default"},
+		{Name: "logs", Namespace: "default"},
+		{Name: "unknown", Namespace: "default"},
+	})
+	assert.Equal(t, []string{"Deployment default/api", "Pod default/client"}, impact.AffectedWorkloads)
+}
+
 // ── estimateMigrationDuration ────────────────────────────────────────────────
 
 func TestEstimateMigrationDuration_WithData(t *testing.T) {
@@ -7,6 +7,7 @@ import (
 	"log/slog"
 	"time"
 
+	"github.com/cloudvault-io/cloudvault/pkg/graph"
 	"github.com/cloudvault-io/cloudvault/pkg/types"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
@@ -24,6 +25,7 @@ type MigrationExecutor struct {
 	argoNamespace string
 	veleroEnabled bool
 	migrations    map[string]*MigrationStatus
+	sig           graph.Graph
 }
 
 // MigrationPlan defines the migration strategy
@@ -110,6 +112,12 @@ func NewMigrationExecutor(config *rest.Config, argoNamespace string) (*Migration
 	}, nil
 }
 
+// SetGraph makes migration impact count every workload depending on a PVC in the SIG,
+// rather than only the pods mounting it
+func (m *MigrationExecutor) SetGraph(g graph.Graph) {
+	m.sig = g
+}
+
 // CreateMigrationPlan creates a migration plan from a recommendation
 func (m *MigrationExecutor) CreateMigrationPlan(ctx context.Context, rec types.Recommendation, pvcs []types.PVCMetric) (*MigrationPlan, error) {
 	duration := estimateMigrationDuration(pvcs)
@@ -743,19 +751,15 @@ func estimateMigrationDuration(pvcs []types.PVCMetric) time.Duration {
 func (m *MigrationExecutor) calculateImpact(ctx context.Context, pvcs []types.PVCMetric) MigrationImpact {
 	totalSize := int64(0)
 	workloads := []string{}
+	seen := make(map[string]bool)
 
 	for _, pvc := range pvcs {
 		totalSize += pvc.UsedBytes
 
-		// Find pods using this PVC (if client is available)
-		if m.sourceClient != nil {
-			pods, _ := m.sourceClient.CoreV1().Pods(pvc.Namespace).List(ctx, metav1.ListOptions{})
-			for _, pod := range pods.Items {
-				for _, vol := range pod.Spec.Volumes {
-					if vol.PersistentVolumeClaim != nil && vol.PersistentVolumeClaim.ClaimName == pvc.Name {
-						workloads = append(workloads, pod.Name)
-					}
-				}
+		for _, w := range m.affectedWorkloads(ctx, pvc) {
+			if !seen[w] {
+				seen[w] = true
+				workloads = append(workloads, w)
 			}
 		}
 	}
@@ -769,6 +773,35 @@ func (m *MigrationExecutor) calculateImpact(ctx context.Context, pvcs []types.PV
 	}
 }
 
+// affectedWorkloads lists what depends on a PVC: its dependencies in the SIG when there
+// is one, otherwise the pods mounting it (if client is available)
+func (m *MigrationExecutor) affectedWorkloads(ctx context.Context, pvc types.PVCMetric) []string {
+	if m.sig != nil {
+		deps, err := m.sig.GetDependencies(ctx, pvc.Namespace, pvc.Name, graph.DefaultDependencyDepth)
+		if err == nil {
+			workloads := make([]string, 0, len(deps.Workloads))
+			for _, w := range deps.Workloads {
+				workloads = append(workloads, w.String())
+			}
+			return workloads
+		}
+		slog.Debug("Falling back to mounting pods for migration impact", "pvc", pvc.Namespace+"/"+pvc.Name, "error", err)
+	}
+
+	var workloads []string
+	if m.sourceClient != nil {
+		pods, _ := m.sourceClient.CoreV1().Pods(pvc.Namespace).List(ctx, metav1.ListOptions{})
+		for _, pod := range pods.Items {
+			for _, vol := range pod.Spec.Volumes {
+				if vol.PersistentVolumeClaim != nil && vol.PersistentVolumeClaim.ClaimName == pvc.Name {
+					workloads = append(workloads, pod.Name)
+				}
+			}
+		}
+	}
+	return workloads
+}
+
 func (m *MigrationExecutor) assessRisk(rec types.Recommendation, impact MigrationImpact) string {
 	if impact.DataTransferSize > 100*1024*1024*1024 { // > 100GB
 		return "high"
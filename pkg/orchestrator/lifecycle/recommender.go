@@ -17,7 +17,7 @@ type IntelligentRecommender struct {
 	rlAgent       *ai.RLAgent
 	forecaster    *ai.CostForecaster
 	anomalyEngine *ai.AnomalyEngine
-	tsdb          *graph.TimescaleDB
+	history       graph.HistoryStore
 }
 
 // OptimizationRecommendation contains suggested actions for a PVC
@@ -29,26 +29,29 @@ type OptimizationRecommendation struct {
 	Confidence  float64
 }
 
-func NewIntelligentRecommender(tsdb *graph.TimescaleDB) *IntelligentRecommender {
+func NewIntelligentRecommender(history graph.HistoryStore) *IntelligentRecommender {
 	return &IntelligentRecommender{
 		rlAgent:       ai.NewRLAgent(),
 		forecaster:    ai.NewCostForecaster(),
 		anomalyEngine: ai.NewAnomalyEngine(0.05), // 5% contamination
-		tsdb:          tsdb,
+		history:       history,
 	}
 }
 
 // Train updates the internal models based on historical success/failure data.
 // This is the "Revolutionary" self-learning pillar of CloudVault.
 func (r *IntelligentRecommender) Train(ctx context.Context, namespace, name string) error {
-	if r.tsdb == nil {
-		return fmt.Errorf("TSDB required for training")
+	if r.history == nil {
+		return fmt.Errorf("history store required for training")
 	}
 
 	// Fetch historic utilization and egress patterns
-	history, err := r.tsdb.GetHistory(ctx, namespace, name, 90*24*time.Hour) // 90 day window
-	if err != nil || len(history) < 10 {
-		return fmt.Errorf("insufficient data for training: %w", err)
+	history, err := r.history.GetHistory(ctx, namespace, name, 90*24*time.Hour) // 90 day window
+	if err != nil {
+		return fmt.Errorf("failed to read history for training: %w", err)
+	}
+	if len(history) < 10 {
+		return fmt.Errorf("insufficient data for training: %d samples", len(history))
 	}
 
 	// 1. Train LSTM on the utilization sequence
@@ -91,19 +94,29 @@ func (r *IntelligentRecommender) Recommend(pvc types.PVCMetric, policy *v1alpha1
 		usageRatio = float64(pvc.UsedBytes) / float64(pvc.SizeBytes)
 	}
 
-	// 2. Try using TSDB history for better anomaly detection
+	// 2. Try using stored history for better anomaly detection. It holds used bytes,
+	// turned into ratios of the current size; without a size the live usage is all we have.
 	history := []float64{usageRatio}
-	if r.tsdb != nil {
-		if h, err := r.tsdb.GetHistory(context.Background(), pvc.Namespace, pvc.Name, 30*24*time.Hour); err == nil && len(h) > 0 {
-			history = h
-			usageRatio = h[len(h)-1] // Use latest from history
-		} else {
-			// No history available yet, use current live usage
-			history = []float64{usageRatio}
+	if r.history != nil && pvc.SizeBytes > 0 {
+		if h, err := r.history.GetHistory(context.Background(), pvc.Namespace, pvc.Name, 30*24*time.Hour); err == nil && len(h) > 0 {
+			history = make([]float64, len(h))
+			for i, used := range h {
+				history[i] = used / float64(pvc.SizeBytes)
+			}
+			usageRatio = history[len(history)-1] // Use latest from history
 		}
 	}
 
 	// 3. Identification of Zombie Volumes (Highest Priority)
+	if pvc.IsZombie() {
+		return &OptimizationRecommendation{
+			TargetClass: pvc.StorageClass,
+			TargetTier:  "cold",
+			TargetSize:  FormatQuantity(pvc.SizeBytes),
+			Reason:      fmt.Sprintf("Optimization: Zombie Volume idle for %.0f days", pvc.IdleDuration().Hours()/24),
+			Confidence:  0.95,
+		}
+	}
 	if usageRatio < 0.05 && (len(history) > 0 && r.anomalyEngine.IsZombie(history)) {
 		return &OptimizationRecommendation{
 			TargetClass: pvc.StorageClass,
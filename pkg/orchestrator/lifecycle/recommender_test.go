@@ -5,6 +5,7 @@ import (
 	"testing"
 
 	"github.com/cloudvault-io/cloudvault/pkg/ai"
+	"github.com/cloudvault-io/cloudvault/pkg/graph"
 	"github.com/cloudvault-io/cloudvault/pkg/types"
 	"github.com/cloudvault-io/cloudvault/pkg/types/apis/v1alpha1"
 )
@@ -39,12 +40,14 @@ func TestIntelligentRecommender_Train_NoTSDB(t *testing.T) {
 }
 
 func TestIntelligentRecommender_Train_InsufficientData(t *testing.T) {
-	t.Skip("Requires TimescaleDB instance")
-
-	// This would test with a mock TSDB that returns insufficient data
-	recommender := NewIntelligentRecommender(nil)
+	history, err := graph.NewFileHistoryStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("Failed to open history store: %v", err)
+	}
+	defer func() { _ = history.Close() }()
+	recommender := NewIntelligentRecommender(history)
 
-	err := recommender.Train(context.Background(), "default", "test-pvc")
+	err = recommender.Train(context.Background(), "default", "test-pvc")
 	if err == nil {
 		t.Error("Expected error for insufficient training data")
 	}
@@ -112,6 +115,34 @@ func TestIntelligentRecommender_Recommend_RightSizing(t *testing.T) {
 	}
 }
 
+func TestIntelligentRecommender_Recommend_WithHistory(t *testing.T) {
+	history, err := graph.NewFileHistoryStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("Failed to open history store: %v", err)
+	}
+	defer func() { _ = history.Close() }()
+	recommender := NewIntelligentRecommender(history)
+
+	const gib = 1024 * 1024 * 1024
+	oversized := types.PVCMetric{Name: "oversized-pvc", Namespace: "default", StorageClass: "gp3", SizeBytes: 100 * gib, UsedBytes: 20 * gib}
+	zombie := types.PVCMetric{Name: "zombie-pvc", Namespace: "default", StorageClass: "gp3", SizeBytes: 100 * gib, UsedBytes: 100 * 1024 * 1024}
+	for i := 0; i < 5; i++ {
+		if err := history.RecordMetrics(context.Background(), []types.PVCMetric{oversized, zombie}); err != nil {
+			t.Fatalf("RecordMetrics failed: %v", err)
+		}
+	}
+
+	// History holds used bytes, which must be compared with the size
+	rec := recommender.Recommend(oversized, &v1alpha1.StorageLifecyclePolicy{})
+	if rec == nil || rec.TargetSize != "30Gi" {
+		t.Fatalf("Expected right-sizing to 30Gi from history, got %+v", rec)
+	}
+	rec = recommender.Recommend(zombie, &v1alpha1.StorageLifecyclePolicy{})
+	if rec == nil || rec.TargetTier != "cold" {
+		t.Fatalf("Expected cold tier for a zombie volume from history, got %+v", rec)
+	}
+}
+
 func TestIntelligentRecommender_Recommend_ZombieVolume(t *testing.T) {
 	recommender := NewIntelligentRecommender(nil)
 
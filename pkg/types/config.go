@@ -13,13 +13,64 @@ type Config struct {
 	DashboardPort int           `yaml:"dashboard_port" json:"dashboard_port"`
 	Provider      string        `yaml:"provider" json:"provider"`
 
+	// Prometheus authentication and transport (Thanos, Mimir, Cortex and other gateways)
+	PrometheusUsername           string            `yaml:"prometheus_username" json:"prometheus_username"`
+	PrometheusPassword           string            `yaml:"prometheus_password" json:"prometheus_password"`
+	PrometheusBearerTokenFile    string            `yaml:"prometheus_bearer_token_file" json:"prometheus_bearer_token_file"`
+	PrometheusCAFile             string            `yaml:"prometheus_ca_file" json:"prometheus_ca_file"`
+	PrometheusCertFile           string            `yaml:"prometheus_cert_file" json:"prometheus_cert_file"`
+	PrometheusKeyFile            string            `yaml:"prometheus_key_file" json:"prometheus_key_file"`
+	PrometheusInsecureSkipVerify bool              `yaml:"prometheus_insecure_skip_verify" json:"prometheus_insecure_skip_verify"`
+	PrometheusHeaders            map[string]string `yaml:"prometheus_headers" json:"prometheus_headers"` // e.g. X-Scope-OrgID
+	PrometheusMaxResponseBytes   int64             `yaml:"prometheus_max_response_bytes" json:"prometheus_max_response_bytes"`
+
+	// UsageExecFallback enables exec'ing `du` into pods when neither Prometheus
+	// nor the kubelet stats/summary endpoint report usage for a PVC
+	UsageExecFallback bool `yaml:"usage_exec_fallback" json:"usage_exec_fallback"`
+
+	// DisabledEnrichers removes collection enrichers by name (pods, usage, iops, access, egress, volume, cost)
+	DisabledEnrichers []string `yaml:"disabled_enrichers" json:"disabled_enrichers"`
+
+	// AccessStatePath persists last-access tracking to a JSON file when TimescaleDB is not
+	// configured, access.json in the history directory when empty
+	AccessStatePath string `yaml:"access_state_path" json:"access_state_path"`
+
+	// EgressSource selects where egress data comes from: ebpf, cgroup, prometheus or merged.
+	// cgroup is eBPF attributed to pods by cgroup instead of source address.
+	EgressSource string `yaml:"egress_source" json:"egress_source"`
+
+	// EbpfAttachVeth also attaches the eBPF counters to veth devices, not just physical interfaces
+	EbpfAttachVeth bool `yaml:"ebpf_attach_veth" json:"ebpf_attach_veth"`
+	// EbpfTrackSrcPorts also records source ports in flows, with ephemeral ports in one bucket
+	EbpfTrackSrcPorts bool `yaml:"ebpf_track_src_ports" json:"ebpf_track_src_ports"`
+	// EbpfPinPath is the bpffs directory where eBPF maps are pinned to survive restarts,
+	// /sys/fs/bpf/cloudvault when empty
+	EbpfPinPath string `yaml:"ebpf_pin_path" json:"ebpf_pin_path"`
+
+	// CgroupRoot is where the host's cgroups are mounted, /sys/fs/cgroup when empty
+	CgroupRoot string `yaml:"cgroup_root" json:"cgroup_root"`
+
+	// MountInfoPath lists the mounts used to map traced block devices to PVs,
+	// /proc/self/mountinfo when empty
+	MountInfoPath string `yaml:"mountinfo_path" json:"mountinfo_path"`
+
 	// Storage Intelligence Graph (Neo4j)
 	Neo4jURI      string `yaml:"neo4j_uri" json:"neo4j_uri"`
 	Neo4jUser     string `yaml:"neo4j_user" json:"neo4j_user"`
 	Neo4jPassword string `yaml:"neo4j_password" json:"neo4j_password"`
 
+	// SIGGCGenerations is how many sync passes a PVC or pod may go unseen before it is
+	// removed from the graph, graph.DefaultGCGenerations when zero
+	SIGGCGenerations int `yaml:"sig_gc_generations" json:"sig_gc_generations"`
+
 	// TimescaleDB for historical metrics
 	TimescaleConn string `yaml:"timescale_conn" json:"timescale_conn"`
+	// MetricsRetention is how long raw samples are kept in TimescaleDB, after which only
+	// the hourly and daily aggregates remain; graph.DefaultMetricsRetention when zero
+	MetricsRetention time.Duration `yaml:"metrics_retention" json:"metrics_retention"`
+	// HistoryPath is the directory of the embedded per-PVC history store used when
+	// TimescaleDB is not configured, graph.DefaultHistoryPath when empty
+	HistoryPath string `yaml:"history_path" json:"history_path"`
 
 	// Mock mode for testing
 	Mock bool `yaml:"mock" json:"mock"`
@@ -45,5 +96,6 @@ func DefaultConfig() *Config {
 		DashboardPort: 8080,
 		Provider:      "aws",
 		Neo4jUser:     "neo4j",
+		EgressSource:  "ebpf",
 	}
 }
@@ -26,12 +26,41 @@ type PVCMetric struct {
 	UsedBytes    int64  `json:"used_bytes"`   // Actual usage (requires metrics-server)
 	EgressBytes  uint64 `json:"egress_bytes"` // Network traffic (requires eBPF)
 
-	// Performance metrics (future - requires Prometheus or cloud APIs)
+	// Egress breakdown of the pods mounting this PVC
+	ExternalEgressBytes uint64              `json:"external_egress_bytes"` // Leaves the cluster network
+	EgressDestinations  []EgressDestination `json:"egress_destinations"`   // Sorted by bytes, descending
+
+	// Volume details (bound PV and its StorageClass)
+	VolumeName            string `json:"volume_name"`
+	Provisioner           string `json:"provisioner"`
+	VolumeType            string `json:"volume_type"` // Cloud disk type: gp3, io2, pd-ssd, Premium_LRS, ...
+	ProvisionedIOPS       int64  `json:"provisioned_iops"`
+	ProvisionedThroughput int64  `json:"provisioned_throughput_mibps"` // MiB per second
+	Zone                  string `json:"zone"`
+	CSIVolumeHandle       string `json:"csi_volume_handle"`
+	ReclaimPolicy         string `json:"reclaim_policy"`
+
+	// Filesystem statistics (kubelet stats/summary)
+	CapacityBytes int64 `json:"capacity_bytes"` // Filesystem capacity as seen by the kubelet
+	Inodes        int64 `json:"inodes"`
+	InodesUsed    int64 `json:"inodes_used"`
+
+	// Performance metrics (Prometheus node_exporter/cAdvisor disk rates, averaged over a window)
 	ReadIOPS        float64 `json:"read_iops"`
 	WriteIOPS       float64 `json:"write_iops"`
 	ReadThroughput  float64 `json:"read_throughput_bps"`  // bytes per second
 	WriteThroughput float64 `json:"write_throughput_bps"` // bytes per second
 
+	// Peak performance (p95 over the same window)
+	ReadIOPSP95        float64 `json:"read_iops_p95"`
+	WriteIOPSP95       float64 `json:"write_iops_p95"`
+	ReadThroughputP95  float64 `json:"read_throughput_p95_bps"`
+	WriteThroughputP95 float64 `json:"write_throughput_p95_bps"`
+
+	// Cumulative device byte counters, compared between collections to detect access
+	ReadBytesTotal  float64 `json:"read_bytes_total"`
+	WriteBytesTotal float64 `json:"write_bytes_total"`
+
 	// Cost information
 	HourlyCost  float64 `json:"hourly_cost"`
 	MonthlyCost float64 `json:"monthly_cost"`
@@ -41,11 +70,108 @@ type PVCMetric struct {
 
 	// Metadata
 	CreatedAt      time.Time         `json:"created_at"`
-	LastAccessedAt time.Time         `json:"last_accessed_at"` // Future - requires eBPF or audit logs
+	LastAccessedAt time.Time         `json:"last_accessed_at"` // Idle since: last observed I/O or usage change
 	Labels         map[string]string `json:"labels"`
 	Annotations    map[string]string `json:"annotations"`
 }
 
+// AccessStateRetention is how long access state is kept for a PVC that is no longer seen
+const AccessStateRetention = 90 * 24 * time.Hour
+
+// AccessState is what the collector remembers about a PVC between collections to
+// derive its last access time. It is persisted so idle time survives agent restarts.
+type AccessState struct {
+	Namespace       string    `json:"namespace"`
+	Name            string    `json:"name"`
+	UsedBytes       int64     `json:"used_bytes"`
+	ReadBytesTotal  float64   `json:"read_bytes_total"`
+	WriteBytesTotal float64   `json:"write_bytes_total"`
+	FirstSeen       time.Time `json:"first_seen"`
+	LastSeen        time.Time `json:"last_seen"`
+	LastAccessedAt  time.Time `json:"last_accessed_at"` // zero until access is observed
+}
+
+// EgressDestination is the traffic sent to one destination address
+type EgressDestination struct {
+	IP       string       `json:"ip"`
+	Provider string       `json:"provider"` // internal, internet, aws, gcp, azure
+	Region   string       `json:"region"`
+	External bool         `json:"external"`
+	Bytes    uint64       `json:"bytes"`
+	Ports    []EgressPort `json:"ports,omitempty"` // Largest first; only when the source reports ports
+}
+
+// EgressPort is the traffic over one L4 protocol and destination port
+type EgressPort struct {
+	Protocol string `json:"protocol"`       // tcp, udp, icmp, icmpv6 or the IP protocol number
+	Port     uint16 `json:"port,omitempty"` // Zero for protocols without ports
+	Bytes    uint64 `json:"bytes"`
+}
+
+// EgressFlow is the traffic from one source to one destination over one L4 protocol and port
+type EgressFlow struct {
+	Src      string `json:"src"`
+	Dst      string `json:"dst"`
+	Protocol string `json:"protocol"`
+	DstPort  uint16 `json:"dst_port,omitempty"`
+	SrcPort  uint16 `json:"src_port,omitempty"` // Set when source ports are tracked; 32768 stands for any ephemeral port
+	Bytes    uint64 `json:"bytes"`
+	Packets  uint64 `json:"packets"`
+}
+
+// BlockDeviceIO is the I/O completed on one block device since tracing started
+type BlockDeviceIO struct {
+	Major      uint32 `json:"major"`
+	Minor      uint32 `json:"minor"`
+	ReadOps    uint64 `json:"read_ops"`
+	WriteOps   uint64 `json:"write_ops"`
+	ReadBytes  uint64 `json:"read_bytes"`
+	WriteBytes uint64 `json:"write_bytes"`
+	// Latency histograms: slot 0 counts requests under 1us, slot i those taking
+	// [2^(i-1), 2^i) us
+	ReadLatency  []uint64  `json:"read_latency_us_log2"`
+	WriteLatency []uint64  `json:"write_latency_us_log2"`
+	LastIO       time.Time `json:"last_io"`
+}
+
+// PodPlacement is where a pod runs, what owns it, the PVCs it mounts and the services
+// selecting it
+type PodPlacement struct {
+	Name      string   `json:"name"`
+	Namespace string   `json:"namespace"`
+	Node      string   `json:"node"` // Empty until scheduled
+	Zone      string   `json:"zone"`
+	Region    string   `json:"region"`
+	Provider  string   `json:"provider"`
+	Workload  Workload `json:"workload"` // Zero for bare pods
+	PVCs      []string `json:"pvcs"`
+	Services  []string `json:"services"` // Services of the pod's namespace selecting it
+}
+
+// Workload is the controller owning a pod, with ReplicaSets resolved to their Deployment
+type Workload struct {
+	Kind string `json:"kind"` // Deployment, StatefulSet, DaemonSet, Job, ...
+	Name string `json:"name"`
+}
+
+// Endpoint is one side of observed traffic: a pod, or an address outside the cluster
+type Endpoint struct {
+	Namespace string `json:"namespace,omitempty"`
+	Pod       string `json:"pod,omitempty"`
+	IP        string `json:"ip,omitempty"`       // Set instead of Pod for addresses no pod holds
+	Provider  string `json:"provider,omitempty"` // Where the address is; pods take their node's
+	Region    string `json:"region,omitempty"`
+}
+
+// Communication is the traffic sent from one pod to an endpoint over an interval
+type Communication struct {
+	Source   Endpoint      `json:"source"`
+	Target   Endpoint      `json:"target"`
+	Bytes    uint64        `json:"bytes"`
+	Interval time.Duration `json:"interval"`
+	SeenAt   time.Time     `json:"seen_at"`
+}
+
 // ClusterInfo represents Kubernetes cluster metadata
 type ClusterInfo struct {
 	ID       string `json:"id"`
@@ -76,12 +202,51 @@ type CostSummary struct {
 	ZombieVolumes    []PVCMetric        `json:"zombie_volumes"`
 	BudgetLimit      float64            `json:"budget_limit"`  // Monthly budget cap
 	ActiveAlerts     []string           `json:"active_alerts"` // Governance alerts
+
+	// Ephemeral is node root-disk spend attributed to pods. It is reported separately
+	// and not included in TotalMonthlyCost, which covers PVCs only.
+	Ephemeral *EphemeralCostSummary `json:"ephemeral,omitempty"`
+}
+
+// EphemeralStorageMetric is one pod's use of node disk outside PVCs:
+// disk-backed emptyDir volumes, container writable layers and logs
+type EphemeralStorageMetric struct {
+	Namespace string `json:"namespace"`
+	Pod       string `json:"pod"`
+	Node      string `json:"node"`
+	ClusterID string `json:"cluster_id"`
+
+	UsedBytes      int64 `json:"used_bytes"` // Total as accounted by the kubelet for eviction
+	EmptyDirBytes  int64 `json:"empty_dir_bytes"`
+	ContainerBytes int64 `json:"container_bytes"` // Writable layers plus logs
+
+	// Sum of container ephemeral-storage requests and limits (0 when unset)
+	RequestBytes int64 `json:"request_bytes"`
+	LimitBytes   int64 `json:"limit_bytes"`
+
+	// Root filesystem of the node, whose cost is shared among its pods
+	NodeCapacityBytes int64 `json:"node_capacity_bytes"`
+	// Summed Weight of every pod on the node, including pods outside the collection's
+	// scope (0 when unknown, in which case only the metrics at hand are summed)
+	NodeWeightBytes int64 `json:"node_weight_bytes,omitempty"`
+
+	MonthlyCost float64 `json:"monthly_cost"`
+}
+
+// EphemeralCostSummary aggregates attributed node root-disk cost
+type EphemeralCostSummary struct {
+	TotalMonthlyCost float64                  `json:"total_monthly_cost"`
+	TotalUsedBytes   int64                    `json:"total_used_bytes"`
+	ByNamespace      map[string]float64       `json:"by_namespace"`
+	ByNode           map[string]float64       `json:"by_node"`
+	TopPods          []EphemeralStorageMetric `json:"top_pods"`
 }
 
 // Recommendation represents an optimization recommendation
 type Recommendation struct {
-	Type             string  `json:"type"` // storage_class, delete_zombie, resize, move_cloud
+	Type             string  `json:"type"` // storage_class, delete_zombie, resize, move_cloud, ephemeral_request
 	PVC              string  `json:"pvc"`
+	Pod              string  `json:"pod,omitempty"` // Set for pod-level (ephemeral storage) recommendations
 	Namespace        string  `json:"namespace"`
 	CurrentState     string  `json:"current_state"`
 	RecommendedState string  `json:"recommended_state"`
@@ -90,6 +255,21 @@ type Recommendation struct {
 	Impact           string  `json:"impact"` // low, medium, high
 }
 
+// Weight is the pod's claim on node disk: what it uses, or what it reserved through its
+// request if that is more, since requested space is held back from scheduling
+func (m *EphemeralStorageMetric) Weight() int64 {
+	return max(m.UsedBytes, m.RequestBytes)
+}
+
+// PricingClass returns the name used for pricing lookups: the resolved cloud
+// volume type when known, otherwise the StorageClass name
+func (p *PVCMetric) PricingClass() string {
+	if p.VolumeType != "" {
+		return p.VolumeType
+	}
+	return p.StorageClass
+}
+
 // SizeGB returns the size in gigabytes
 func (p *PVCMetric) SizeGB() float64 {
 	return float64(p.SizeBytes) / (1024 * 1024 * 1024)
@@ -113,6 +293,14 @@ func (p *PVCMetric) TotalIOPS() float64 {
 	return p.ReadIOPS + p.WriteIOPS
 }
 
+// PeakIOPS returns p95 read + write IOPS, falling back to the average when no p95 is known
+func (p *PVCMetric) PeakIOPS() float64 {
+	if peak := p.ReadIOPSP95 + p.WriteIOPSP95; peak > 0 {
+		return peak
+	}
+	return p.TotalIOPS()
+}
+
 // IsZombie checks if the PVC is a zombie (unused for > 30 days)
 func (p *PVCMetric) IsZombie() bool {
 	if p.LastAccessedAt.IsZero() {
@@ -122,6 +310,15 @@ func (p *PVCMetric) IsZombie() bool {
 	return daysSinceAccess > 30
 }
 
+// IdleDuration returns how long the PVC has gone without observed access,
+// falling back to its age when no access data is available
+func (p *PVCMetric) IdleDuration() time.Duration {
+	if !p.LastAccessedAt.IsZero() {
+		return time.Since(p.LastAccessedAt)
+	}
+	return time.Since(p.CreatedAt)
+}
+
 // AnnualCost returns the yearly cost
 func (p *PVCMetric) AnnualCost() float64 {
 	return p.MonthlyCost * 12
@@ -4,25 +4,29 @@ import (
 	"encoding/json"
 	"fmt"
 	"log"
+	"math"
 	"net/http"
+	"strconv"
 	"strings"
 	"time"
 )
 
 // Response format expected by CloudVault's PrometheusClient
 type PromResponse struct {
-	Status string `json:"status"`
-	Data   struct {
-		ResultType string `json:"resultType"`
-		Result     []struct {
-			Metric map[string]string `json:"metric"`
-			Value  []interface{}     `json:"value"` // [timestamp, "string_value"]
-		} `json:"result"`
+	Status   string   `json:"status"`
+	Warnings []string `json:"warnings,omitempty"`
+	Data     struct {
+		ResultType string        `json:"resultType"`
+		Result     []interface{} `json:"result"`
 	} `json:"data"`
 }
 
+// maxMatrixPoints mirrors Prometheus' limit of 11,000 points per series
+const maxMatrixPoints = 11000
+
 func main() {
 	http.HandleFunc("/api/v1/query", handleQuery)
+	http.HandleFunc("/api/v1/query_range", handleQueryRange)
 	port := ":9090"
 	fmt.Printf("🔥 Mock Prometheus running on %s\n", port)
 	if err := http.ListenAndServe(port, nil); err != nil {
@@ -40,49 +44,104 @@ func handleQuery(w http.ResponseWriter, r *http.Request) {
 	}
 	resp.Data.ResultType = "vector"
 
-	// Generate mock data based on query content
-	// We match the PVCs seen in the user's cluster output
+	// Construct result
+	// timestamp is current time, value is string
+	now := float64(time.Now().Unix())
+	result := struct {
+		Metric map[string]string `json:"metric"`
+		Value  []interface{}     `json:"value"`
+	}{
+		Metric: map[string]string{},
+		Value:  []interface{}{now, strconv.FormatFloat(mockValue(query), 'f', -1, 64)},
+	}
+
+	resp.Data.Result = append(resp.Data.Result, result)
+	writeResponse(w, http.StatusOK, resp)
+}
+
+// handleQueryRange serves a matrix for the requested window. Each series oscillates
+// gently around the instant value so history backfill has something realistic to chart.
+func handleQueryRange(w http.ResponseWriter, r *http.Request) {
+	params := r.URL.Query()
+	query := params.Get("query")
+	fmt.Printf("📥 Range query: %s\n", query)
+
+	start, errStart := strconv.ParseFloat(params.Get("start"), 64)
+	end, errEnd := strconv.ParseFloat(params.Get("end"), 64)
+	step, errStep := strconv.ParseFloat(params.Get("step"), 64)
+	if errStart != nil || errEnd != nil || errStep != nil || step <= 0 || end < start {
+		writeError(w, "bad_data", "invalid start, end or step")
+		return
+	}
+	if (end-start)/step > maxMatrixPoints {
+		writeError(w, "bad_data", "exceeded maximum resolution of 11,000 points per timeseries. Try decreasing the query resolution (?step=XX)")
+		return
+	}
+
+	base := mockValue(query)
+	var values [][]interface{}
+	for ts := start; ts <= end; ts += step {
+		v := base * (1 + 0.05*math.Sin(ts/3600))
+		values = append(values, []interface{}{ts, strconv.FormatFloat(v, 'f', -1, 64)})
+	}
+
+	resp := PromResponse{Status: "success"}
+	resp.Data.ResultType = "matrix"
+	resp.Data.Result = append(resp.Data.Result, struct {
+		Metric map[string]string `json:"metric"`
+		Values [][]interface{}   `json:"values"`
+	}{
+		Metric: map[string]string{},
+		Values: values,
+	})
 
-	value := "0"
+	// Let clients exercise their partial-response handling
+	if params.Get("partial_response") == "true" {
+		resp.Warnings = []string{"mock: store unavailable, returning partial data"}
+	}
+	writeResponse(w, http.StatusOK, resp)
+}
 
+// mockValue generates mock data based on query content.
+// We match the PVCs seen in the user's cluster output.
+func mockValue(query string) float64 {
 	// Simulate "postgres-data" (Active, 50% full)
 	if strings.Contains(query, "postgres-data") {
 		if strings.Contains(query, "used_bytes") {
-			value = "53687091200" // 50GB
+			return 53687091200 // 50GB
 		}
 	} else if strings.Contains(query, "postgres-backup") {
 		// Simulate "postgres-backup" (Low usage, 10GB used of 200GB -> 5% -> Oversized)
 		if strings.Contains(query, "used_bytes") {
-			value = "10737418240" // 10GB
+			return 10737418240 // 10GB
 		}
 	} else if strings.Contains(query, "old-logs-archive") {
 		// Simulate "old-logs-archive" (Zombie? No, lets make it empty but unused)
 		if strings.Contains(query, "used_bytes") {
-			value = "1024" // 1KB (Empty)
+			return 1024 // 1KB (Empty)
 		}
 		// Activity query would return 0/empty, triggering Zombie logic in CloudVault if we had implemented the 2nd query fully.
 		// NOTE: Current CloudVault implementation mainly uses UsedBytes.
 	} else if strings.Contains(query, "test-db") {
 		// Simulate "test-db" (Oversized, 20GB size, 100MB usage)
 		if strings.Contains(query, "used_bytes") {
-			value = "104857600" // 100MB
+			return 104857600 // 100MB
 		}
 	}
+	return 0
+}
 
-	// Construct result
-	// timestamp is current time, value is string
-	now := float64(time.Now().Unix())
-	result := struct {
-		Metric map[string]string `json:"metric"`
-		Value  []interface{}     `json:"value"`
-	}{
-		Metric: map[string]string{},
-		Value:  []interface{}{now, value},
-	}
-
-	resp.Data.Result = append(resp.Data.Result, result)
+func writeError(w http.ResponseWriter, errorType, msg string) {
+	writeResponse(w, http.StatusBadRequest, map[string]string{
+		"status":    "error",
+		"errorType": errorType,
+		"error":     msg,
+	})
+}
 
+func writeResponse(w http.ResponseWriter, status int, resp interface{}) {
 	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
 	if err := json.NewEncoder(w).Encode(resp); err != nil {
 		fmt.Printf("Error encoding response: %v\n", err)
 	}